@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"regexp"
 	"strings"
@@ -42,6 +43,7 @@ import (
 	"github.com/apache/yunikorn-k8shim/pkg/admission/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/admission/metadata"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
 	schedulerconf "github.com/apache/yunikorn-k8shim/pkg/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/log"
@@ -54,6 +56,7 @@ const (
 	schedulerValidateConfURLPattern = "http://%s/ws/v1/validate-conf"
 	mutateURL                       = "/mutate"
 	validateConfURL                 = "/validate-conf"
+	maxAuditNoteLength              = 1024 // maximum length of the note of an event
 )
 
 var (
@@ -82,6 +85,7 @@ func InitAdmissionController(conf *conf.AdmissionControllerConf, pcCache *Priori
 		nsCache:           nsCache,
 		annotationHandler: metadata.NewUserGroupAnnotationHandler(conf),
 	}
+	initAdmissionMetrics()
 
 	log.Log(log.Admission).Info("Initialized YuniKorn Admission Controller")
 	return hook
@@ -138,6 +142,14 @@ func (c *AdmissionController) mutate(req *admissionv1.AdmissionRequest) *admissi
 		zap.String("Kind", req.Kind.Kind),
 		zap.Any("UserInfo", req.UserInfo))
 
+	response := c.process(req, namespace)
+	if c.shouldAudit(namespace) {
+		return c.audit(req, namespace, response)
+	}
+	return response
+}
+
+func (c *AdmissionController) process(req *admissionv1.AdmissionRequest, namespace string) *admissionv1.AdmissionResponse {
 	if req.Operation == admissionv1.Update {
 		if req.Kind.Kind == metadata.Pod {
 			return c.processPodUpdate(req, namespace)
@@ -151,9 +163,98 @@ func (c *AdmissionController) mutate(req *admissionv1.AdmissionRequest) *admissi
 		return c.processPod(req, namespace)
 	}
 
+	if req.Kind.Kind == metadata.JobSet {
+		return c.processJobSet(req, namespace)
+	}
+
+	if kind := c.conf.GetWorkloadKind(req.Kind.Group, req.Kind.Kind); kind != nil {
+		return c.processCustomWorkload(req, namespace, kind)
+	}
+
 	return c.processWorkload(req, namespace)
 }
 
+// shouldAudit returns true if the decisions for the namespace are recorded instead of applied. The namespace annotation
+// overrides the admission config.
+func (c *AdmissionController) shouldAudit(namespace string) bool {
+	if flag := c.nsCache.audit(namespace); flag != UNSET {
+		return flag == TRUE
+	}
+	return c.conf.GetAudit()
+}
+
+// audit records the decision of the response in the log, as an event on the namespace and in the metrics, and returns
+// a response that admits the object unchanged. Admitted objects without changes are not recorded as an event.
+func (c *AdmissionController) audit(req *admissionv1.AdmissionRequest, namespace string, response *admissionv1.AdmissionResponse) *admissionv1.AdmissionResponse {
+	decision := auditDecisionAllow
+	detail := ""
+	summary := ""
+	switch {
+	case !response.Allowed:
+		decision = auditDecisionReject
+		if response.Result != nil {
+			detail = response.Result.Message
+			summary = detail
+		}
+	case len(response.Patch) > 0:
+		decision = auditDecisionPatch
+		detail = string(response.Patch)
+		summary = "patched " + getPatchPaths(response.Patch)
+	}
+	name := getObjectName(req)
+	log.Log(log.Admission).Info("admission audit, decision not applied",
+		zap.String("namespace", namespace),
+		zap.String("kind", req.Kind.Kind),
+		zap.String("name", name),
+		zap.String("UID", string(req.UID)),
+		zap.String("operation", string(req.Operation)),
+		zap.String("decision", decision),
+		zap.String("detail", detail))
+	auditDecisions.WithLabelValues(decision).Inc()
+	if decision != auditDecisionAllow {
+		// the full patch is only logged: the note of an event is limited in size
+		note := fmt.Sprintf("%s %s %s/%s: %s", req.Operation, req.Kind.Kind, namespace, name, summary)
+		if len(note) > maxAuditNoteLength {
+			note = note[:maxAuditNoteLength-3] + "..."
+		}
+		events.GetRecorder().Eventf(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, nil,
+			v1.EventTypeNormal, "AdmissionAudit", decision, "%s", note)
+	}
+	return withWarnings(admissionResponseBuilder(string(req.UID), true, "", nil), response.Warnings)
+}
+
+// getPatchPaths returns the paths changed by the JSON patch, in patch order without duplicates
+func getPatchPaths(patch []byte) string {
+	var ops []common.PatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return "unknown paths"
+	}
+	paths := make([]string, 0, len(ops))
+	seen := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		if !seen[op.Path] {
+			seen[op.Path] = true
+			paths = append(paths, op.Path)
+		}
+	}
+	return strings.Join(paths, ", ")
+}
+
+// getObjectName returns the name of the object in the request, the generate name if the name is not set yet
+func getObjectName(req *admissionv1.AdmissionRequest) string {
+	if req.Name != "" {
+		return req.Name
+	}
+	var obj metav1.PartialObjectMetadata
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		return ""
+	}
+	if obj.Name != "" {
+		return obj.Name
+	}
+	return obj.GenerateName
+}
+
 func (c *AdmissionController) processPod(req *admissionv1.AdmissionRequest, namespace string) *admissionv1.AdmissionResponse {
 	var patch []common.PatchOperation
 	var uid = string(req.UID)
@@ -194,11 +295,21 @@ func (c *AdmissionController) processPod(req *admissionv1.AdmissionRequest, name
 		log.Log(log.Admission).Info("bypassing namespace", zap.String("namespace", namespace))
 		return admissionResponseBuilder(uid, true, "", nil)
 	}
+	var rule *conf.AdmissionRule
+	if c.shouldLabelNamespace(namespace) {
+		rule = c.conf.GetMatchingRule(namespace, &pod)
+	}
+	// the annotations of the rule can complete the gang definition: validate the annotations the pod ends up with
+	rejectResponse, warnings := c.validateGangAnnotations(uid, getRuleAnnotations(&pod, rule))
+	if rejectResponse != nil {
+		return rejectResponse
+	}
 	patch = updateSchedulerName(patch)
 
 	if c.shouldLabelNamespace(namespace) {
-		patch = c.updateLabels(namespace, &pod, patch)
+		patch = c.updateLabels(namespace, &pod, rule, patch)
 		patch = c.updatePreemptionInfo(&pod, patch)
+		patch = updateRuleAnnotations(&pod, rule, patch)
 	} else {
 		patch = disableYuniKorn(namespace, &pod, patch)
 	}
@@ -214,7 +325,7 @@ func (c *AdmissionController) processPod(req *admissionv1.AdmissionRequest, name
 		return admissionResponseBuilder(uid, false, err.Error(), nil)
 	}
 
-	return admissionResponseBuilder(uid, true, "", patchBytes)
+	return withWarnings(admissionResponseBuilder(uid, true, "", patchBytes), warnings)
 }
 
 func (c *AdmissionController) processWorkload(req *admissionv1.AdmissionRequest, namespace string) *admissionv1.AdmissionResponse {
@@ -250,6 +361,10 @@ func (c *AdmissionController) processWorkload(req *admissionv1.AdmissionRequest,
 	if err != nil {
 		return admissionResponseBuilder(uid, false, err.Error(), nil)
 	}
+	rejectResponse, warnings := c.validateGangAnnotations(uid, annotations)
+	if rejectResponse != nil {
+		return rejectResponse
+	}
 
 	userName := req.UserInfo.Username
 	groups := req.UserInfo.Groups
@@ -261,24 +376,186 @@ func (c *AdmissionController) processWorkload(req *admissionv1.AdmissionRequest,
 		return failureResponse
 	}
 
+	var patch []common.PatchOperation
 	if !userInfoSet && !c.conf.GetBypassAuth() {
-		patch, err := c.annotationHandler.GetPatchForWorkload(req, userName, groups)
+		patch, err = c.annotationHandler.GetPatchForWorkload(req, userName, groups)
 		if err != nil {
 			log.Log(log.Admission).Error("could not generate patch for workload", zap.Error(err))
 			return admissionResponseBuilder(uid, false, err.Error(), nil)
 		}
+	}
+
+	gangPatch, err := c.getAutoGangPatch(req, namespace)
+	if err != nil {
+		log.Log(log.Admission).Error("could not derive the gang of the workload", zap.Error(err))
+		return admissionResponseBuilder(uid, false, err.Error(), nil)
+	}
+	patch = mergePatch(patch, gangPatch)
+
+	return withWarnings(workloadResponse(req, patch), warnings)
+}
+
+// processJobSet derives the gang of a JobSet. JobSets are not part of the core API, only the gang is added to them.
+func (c *AdmissionController) processJobSet(req *admissionv1.AdmissionRequest, namespace string) *admissionv1.AdmissionResponse {
+	if !c.shouldProcessNamespace(namespace) {
+		log.Log(log.Admission).Info("bypassing namespace", zap.String("namespace", namespace))
+		return admissionResponseBuilder(string(req.UID), true, "", nil)
+	}
+	templateAnnotations, _, err := metadata.GetPodTemplateAnnotations(req)
+	if err != nil {
+		return admissionResponseBuilder(string(req.UID), false, err.Error(), nil)
+	}
+	rejectResponse, warnings := c.validateGangAnnotations(string(req.UID), templateAnnotations...)
+	if rejectResponse != nil {
+		return rejectResponse
+	}
+	patch, err := c.getAutoGangPatch(req, namespace)
+	if err != nil {
+		log.Log(log.Admission).Error("could not derive the gang of the workload", zap.Error(err))
+		return admissionResponseBuilder(string(req.UID), false, err.Error(), nil)
+	}
+	return withWarnings(workloadResponse(req, patch), warnings)
+}
+
+// processCustomWorkload adds the user info annotation and the application ID label to the pod templates of a custom
+// workload, for example an MPIJob. The pods of the workload are checked by the user info of the workload.
+func (c *AdmissionController) processCustomWorkload(req *admissionv1.AdmissionRequest, namespace string, kind *conf.WorkloadKind) *admissionv1.AdmissionResponse {
+	uid := string(req.UID)
+	if !c.shouldProcessNamespace(namespace) {
+		log.Log(log.Admission).Info("bypassing namespace", zap.String("namespace", namespace))
+		return admissionResponseBuilder(uid, true, "", nil)
+	}
+	workload, err := metadata.GetWorkload(req, kind)
+	if err != nil {
+		log.Log(log.Admission).Error("unmarshal failed", zap.Error(err))
+		return admissionResponseBuilder(uid, false, err.Error(), nil)
+	}
+	templateAnnotations := make([]map[string]string, 0, len(workload.Templates))
+	for _, t := range workload.Templates {
+		templateAnnotations = append(templateAnnotations, t.Annotations)
+	}
+	rejectResponse, warnings := c.validateGangAnnotations(uid, templateAnnotations...)
+	if rejectResponse != nil {
+		return rejectResponse
+	}
+
+	userName := req.UserInfo.Username
+	groups := req.UserInfo.Groups
+	var userInfo string
+	if !c.conf.GetBypassAuth() {
+		if userInfo, err = c.annotationHandler.GetUserInfoAnnotation(userName, groups); err != nil {
+			return admissionResponseBuilder(uid, false, err.Error(), nil)
+		}
+	}
+	var appID string
+	if kind.GenerateApplicationID && c.shouldLabelNamespace(namespace) {
+		appID = workload.GetApplicationID(namespace)
+	}
 
-		patchBytes, patchErr := json.Marshal(patch)
-		if patchErr != nil {
-			log.Log(log.Admission).Error("failed to marshal patch", zap.Error(patchErr))
-			return admissionResponseBuilder(uid, false, patchErr.Error(), nil)
+	var patch []common.PatchOperation
+	for _, t := range workload.Templates {
+		failureResponse, userInfoSet := c.checkUserInfoAnnotation(func() (string, bool) {
+			a, ok := t.Annotations[common.UserInfoAnnotation]
+			return a, ok
+		}, userName, groups, uid)
+		if failureResponse != nil {
+			return failureResponse
+		}
+		var annotations, labels map[string]string
+		if !userInfoSet && userInfo != "" {
+			annotations = map[string]string{common.UserInfoAnnotation: userInfo}
 		}
-		log.Log(log.Admission).Info("updating annotations on workload", zap.String("type", req.Kind.Kind),
-			zap.Any("generated patch", patch))
-		return admissionResponseBuilder(uid, true, "", patchBytes)
+		if appID != "" && t.Labels[constants.CanonicalLabelApplicationID] == "" &&
+			t.Labels[constants.LabelApplicationID] == "" && t.Labels[constants.SparkLabelAppID] == "" {
+			labels = map[string]string{constants.CanonicalLabelApplicationID: appID}
+		}
+		patch = append(patch, t.GetPatch(annotations, labels)...)
 	}
+	return withWarnings(workloadResponse(req, patch), warnings)
+}
 
-	return admissionResponseBuilder(uid, true, "", nil)
+// validateGangAnnotations validates the gang scheduling annotations of a pod or of the pod templates of a workload
+// with the parsing of the scheduler. Invalid annotations are only found by the scheduler after the pods exist: the
+// request is rejected, or the errors are returned as warnings if the validation only warns.
+func (c *AdmissionController) validateGangAnnotations(uid string, annotations ...map[string]string) (*admissionv1.AdmissionResponse, []string) {
+	var warnings []string
+	for _, a := range annotations {
+		err := utils.ValidateGangAnnotations(a)
+		if err == nil {
+			continue
+		}
+		if c.conf.GetGangAnnotationsValidation() == conf.ValidationModeWarn {
+			log.Log(log.Admission).Warn("invalid gang scheduling annotations", zap.Error(err))
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		log.Log(log.Admission).Info("rejecting invalid gang scheduling annotations", zap.Error(err))
+		return admissionResponseBuilder(uid, false, err.Error(), nil), nil
+	}
+	return nil, warnings
+}
+
+// withWarnings adds the warnings to the response, the warnings are shown to the client that sent the request
+func withWarnings(response *admissionv1.AdmissionResponse, warnings []string) *admissionv1.AdmissionResponse {
+	if len(warnings) > 0 {
+		response.Warnings = append(response.Warnings, warnings...)
+	}
+	return response
+}
+
+func workloadResponse(req *admissionv1.AdmissionRequest, patch []common.PatchOperation) *admissionv1.AdmissionResponse {
+	uid := string(req.UID)
+	if len(patch) == 0 {
+		return admissionResponseBuilder(uid, true, "", nil)
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		log.Log(log.Admission).Error("failed to marshal patch", zap.Error(err))
+		return admissionResponseBuilder(uid, false, err.Error(), nil)
+	}
+	log.Log(log.Admission).Info("updating annotations on workload", zap.String("type", req.Kind.Kind),
+		zap.Any("generated patch", patch))
+	return admissionResponseBuilder(uid, true, "", patchBytes)
+}
+
+// getAutoGangPatch returns the patch that adds the gang to the workload, if auto gang is enabled for the namespace.
+// The namespace annotation overrides the admission config.
+func (c *AdmissionController) getAutoGangPatch(req *admissionv1.AdmissionRequest, namespace string) ([]common.PatchOperation, error) {
+	enabled := c.conf.GetAutoGang()
+	if flag := c.nsCache.autoGang(namespace); flag != UNSET {
+		enabled = flag == TRUE
+	}
+	if !enabled {
+		return nil, nil
+	}
+	return metadata.GetAutoGangPatch(req, namespace, c.conf.GetAutoGangSchedulingPolicyParameters())
+}
+
+// mergePatch adds the operations to the patch. Operations that add a map to a path already set in the patch are
+// merged into the existing operation, all keys of the added map are kept.
+func mergePatch(patch []common.PatchOperation, operations []common.PatchOperation) []common.PatchOperation {
+	for _, op := range operations {
+		merged := false
+		for i := range patch {
+			if patch[i].Op != "add" || op.Op != "add" || patch[i].Path != op.Path {
+				continue
+			}
+			existing, ok1 := patch[i].Value.(map[string]string)
+			added, ok2 := op.Value.(map[string]string)
+			if ok1 && ok2 {
+				values := make(map[string]string, len(existing)+len(added))
+				maps.Copy(values, existing)
+				maps.Copy(values, added)
+				patch[i].Value = values
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			patch = append(patch, op)
+		}
+	}
+	return patch
 }
 
 func (c *AdmissionController) processPodUpdate(req *admissionv1.AdmissionRequest, namespace string) *admissionv1.AdmissionResponse {
@@ -416,14 +693,14 @@ func (c *AdmissionController) updatePreemptionInfo(pod *v1.Pod, patch []common.P
 	return patch
 }
 
-func (c *AdmissionController) updateLabels(namespace string, pod *v1.Pod, patch []common.PatchOperation) []common.PatchOperation {
+func (c *AdmissionController) updateLabels(namespace string, pod *v1.Pod, rule *conf.AdmissionRule, patch []common.PatchOperation) []common.PatchOperation {
 	log.Log(log.Admission).Info("updating pod labels",
 		zap.String("podName", pod.Name),
 		zap.String("generateName", pod.GenerateName),
 		zap.String("namespace", namespace),
 		zap.Any("labels", pod.Labels))
 
-	result := updatePodLabel(pod, namespace, c.conf.GetGenerateUniqueAppIds())
+	result := updatePodLabel(pod, namespace, c.conf.GetGenerateUniqueAppIds(), c.conf.GetEnablePodGroups(), rule)
 
 	patch = append(patch, common.PatchOperation{
 		Op:    "add",
@@ -434,6 +711,51 @@ func (c *AdmissionController) updateLabels(namespace string, pod *v1.Pod, patch
 	return patch
 }
 
+// updateRuleAnnotations adds the annotations of the matching rule that are not set on the pod
+func updateRuleAnnotations(pod *v1.Pod, rule *conf.AdmissionRule, patch []common.PatchOperation) []common.PatchOperation {
+	if rule == nil || len(rule.Annotations) == 0 {
+		return patch
+	}
+	log.Log(log.Admission).Info("updating pod annotations from rule",
+		zap.String("podName", pod.Name),
+		zap.String("rule", rule.Name))
+
+	// check for an existing patch on annotations and update it
+	for _, p := range patch {
+		if p.Op == "add" && p.Path == "/metadata/annotations" {
+			if annotations, ok := p.Value.(map[string]string); ok {
+				for k, v := range rule.Annotations {
+					if _, exists := annotations[k]; !exists {
+						annotations[k] = v
+					}
+				}
+				return patch
+			}
+		}
+	}
+
+	return append(patch, common.PatchOperation{
+		Op:    "add",
+		Path:  "/metadata/annotations",
+		Value: getRuleAnnotations(pod, rule),
+	})
+}
+
+// getRuleAnnotations returns the annotations of the pod completed with the annotations of the rule it does not set
+func getRuleAnnotations(pod *v1.Pod, rule *conf.AdmissionRule) map[string]string {
+	if rule == nil || len(rule.Annotations) == 0 {
+		return pod.Annotations
+	}
+	result := make(map[string]string, len(rule.Annotations)+len(pod.Annotations))
+	for k, v := range rule.Annotations {
+		result[k] = v
+	}
+	for k, v := range pod.Annotations {
+		result[k] = v
+	}
+	return result
+}
+
 func disableYuniKorn(namespace string, pod *v1.Pod, patch []common.PatchOperation) []common.PatchOperation {
 	log.Log(log.Admission).Info("disabling yunikorn on pod since namespace is set to no-label",
 		zap.String("podName", pod.Name),
@@ -27,18 +27,22 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"gotest.tools/v3/assert"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	authv1 "k8s.io/api/authentication/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8sEvents "k8s.io/client-go/tools/events"
 
 	"github.com/apache/yunikorn-k8shim/pkg/admission/common"
 	"github.com/apache/yunikorn-k8shim/pkg/admission/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/common/events"
 )
 
 type responseMode int
@@ -75,7 +79,7 @@ func TestUpdateLabels(t *testing.T) {
 	}
 
 	c := createAdmissionControllerForTest()
-	patch = c.updateLabels("default", pod, patch)
+	patch = c.updateLabels("default", pod, nil, patch)
 
 	assert.Equal(t, len(patch), 1)
 	assert.Equal(t, patch[0].Op, "add")
@@ -111,7 +115,7 @@ func TestUpdateLabels(t *testing.T) {
 		Spec:   v1.PodSpec{},
 		Status: v1.PodStatus{},
 	}
-	patch = c.updateLabels("default", pod, patch)
+	patch = c.updateLabels("default", pod, nil, patch)
 
 	assert.Equal(t, len(patch), 1)
 	assert.Equal(t, patch[0].Op, "add")
@@ -148,7 +152,7 @@ func TestUpdateLabels(t *testing.T) {
 		Status: v1.PodStatus{},
 	}
 
-	patch = c.updateLabels("default", pod, patch)
+	patch = c.updateLabels("default", pod, nil, patch)
 
 	assert.Equal(t, len(patch), 1)
 	assert.Equal(t, patch[0].Op, "add")
@@ -182,7 +186,7 @@ func TestUpdateLabels(t *testing.T) {
 		Status: v1.PodStatus{},
 	}
 
-	patch = c.updateLabels("default", pod, patch)
+	patch = c.updateLabels("default", pod, nil, patch)
 
 	assert.Equal(t, len(patch), 1)
 	assert.Equal(t, patch[0].Op, "add")
@@ -210,7 +214,7 @@ func TestUpdateLabels(t *testing.T) {
 		Status: v1.PodStatus{},
 	}
 
-	patch = c.updateLabels("default", pod, patch)
+	patch = c.updateLabels("default", pod, nil, patch)
 
 	assert.Equal(t, len(patch), 1)
 	assert.Equal(t, patch[0].Op, "add")
@@ -236,7 +240,7 @@ func TestUpdateLabels(t *testing.T) {
 		Status:     v1.PodStatus{},
 	}
 
-	patch = c.updateLabels("default", pod, patch)
+	patch = c.updateLabels("default", pod, nil, patch)
 
 	assert.Equal(t, len(patch), 1)
 	assert.Equal(t, patch[0].Op, "add")
@@ -609,6 +613,405 @@ func TestMutate(t *testing.T) {
 	assert.Equal(t, 0, len(resp.Patch), "non-empty patch for replicaset")
 }
 
+func TestMutateAutoGang(t *testing.T) {
+	parallelism := int32(2)
+	indexed := batchv1.IndexedCompletion
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "test-ns"},
+		Spec: batchv1.JobSpec{
+			CompletionMode: &indexed,
+			Parallelism:    &parallelism,
+			Completions:    &parallelism,
+		},
+	}
+	jobJSON, err := json.Marshal(job)
+	assert.NilError(t, err, "failed to marshal job")
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "test-ns",
+		Kind:      metav1.GroupVersionKind{Kind: "Job"},
+		UserInfo:  authv1.UserInfo{Username: "testExtUser"},
+		Object:    runtime.RawExtension{Raw: jobJSON},
+	}
+
+	// auto gang disabled: only the user info is added
+	ac := prepareController(t, "", "", "^kube-system$", "", "", false, true)
+	resp := ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for job")
+	annotations := annotationsFromDeployment(t, resp.Patch)
+	assert.Equal(t, len(annotations), 1, "unexpected annotations")
+	assert.Assert(t, annotations[common.UserInfoAnnotation] != nil, "user info annotation not set")
+
+	// auto gang enabled for the namespace: the gang is merged with the user info
+	ac.nsCache.nameSpaces["test-ns"] = nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET, autoGang: TRUE}
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for job")
+	annotations = annotationsFromDeployment(t, resp.Patch)
+	assert.Assert(t, annotations[common.UserInfoAnnotation] != nil, "user info annotation not set")
+	assert.Equal(t, annotations[constants.AnnotationTaskGroupName], "job")
+	assert.Assert(t, annotations[constants.AnnotationTaskGroups] != nil, "task groups annotation not set")
+	ops := parsePatch(t, resp.Patch)
+	assert.Equal(t, len(ops), 2, "unexpected patch operations")
+	assert.Equal(t, ops[1].Path, "/spec/template/metadata/labels")
+
+	// auto gang disabled for the namespace overrides the config
+	ac = prepareController(t, "", "", "^kube-system$", "", "", true, true)
+	ac.conf = createConfigWithOverrides(map[string]string{
+		conf.AMAccessControlBypassAuth: "true",
+		conf.AMAutoGangEnable:          "true",
+	})
+	resp = ac.mutate(req)
+	assert.Equal(t, len(parsePatch(t, resp.Patch)), 2, "gang not added to job")
+	ac.nsCache.nameSpaces["test-ns"] = nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET, autoGang: FALSE}
+	resp = ac.mutate(req)
+	assert.Equal(t, len(resp.Patch), 0, "non-empty patch for job")
+
+	// JobSet in a bypassed namespace
+	req.Kind = metav1.GroupVersionKind{Kind: "JobSet"}
+	req.Namespace = "kube-system"
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for jobset")
+	assert.Equal(t, len(resp.Patch), 0, "non-empty patch for jobset")
+}
+
+func TestMutateGangValidation(t *testing.T) {
+	invalid := map[string]string{
+		constants.AnnotationTaskGroups:    `[{"name":"driver","minMember":1,"minResource":{"cpu":"1"}}]`,
+		constants.AnnotationTaskGroupName: "executor",
+	}
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "test-ns", Annotations: invalid}}
+	podJSON, err := json.Marshal(pod)
+	assert.NilError(t, err, "failed to marshal pod")
+	podReq := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "test-ns",
+		Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: podJSON},
+	}
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "test-ns"},
+		Spec: batchv1.JobSpec{Template: v1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Annotations: invalid},
+		}},
+	}
+	jobJSON, err := json.Marshal(job)
+	assert.NilError(t, err, "failed to marshal job")
+	jobReq := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "test-ns",
+		Kind:      metav1.GroupVersionKind{Kind: "Job"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: jobJSON},
+	}
+
+	// warn by default: allowed with a warning, the pod is still mutated
+	ac := prepareController(t, "", "", "^kube-system$", "", "", true, true)
+	for _, req := range []*admissionv1.AdmissionRequest{podReq, jobReq} {
+		resp := ac.mutate(req)
+		assert.Check(t, resp.Allowed, "response not allowed for %s", req.Kind.Kind)
+		assert.Equal(t, len(resp.Warnings), 1, "unexpected warnings for %s", req.Kind.Kind)
+	}
+	assert.Equal(t, schedulerName(t, ac.mutate(podReq).Patch), "yunikorn", "pod not mutated")
+
+	// reject: the invalid objects are not admitted
+	ac.conf = createConfigWithOverrides(map[string]string{
+		conf.AMAccessControlBypassAuth:   "true",
+		conf.AMValidationGangAnnotations: conf.ValidationModeReject,
+	})
+	for _, req := range []*admissionv1.AdmissionRequest{podReq, jobReq} {
+		resp := ac.mutate(req)
+		assert.Check(t, !resp.Allowed, "invalid annotations allowed for %s", req.Kind.Kind)
+		assert.Assert(t, strings.Contains(resp.Result.Message, "taskGroup executor is not defined"),
+			"unexpected message: %s", resp.Result.Message)
+	}
+
+	// bypassed namespace is not validated
+	ac.conf = createConfigWithOverrides(map[string]string{conf.AMAccessControlBypassAuth: "true"})
+	podReq.Namespace = "kube-system"
+	resp := ac.mutate(podReq)
+	assert.Check(t, resp.Allowed, "response not allowed for bypassed namespace")
+	assert.Equal(t, len(resp.Warnings), 0, "unexpected warnings for bypassed namespace")
+}
+
+func TestMutateRules(t *testing.T) {
+	controller := true
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            "pod",
+		Namespace:       "test-ns",
+		Annotations:     map[string]string{constants.AnnotationTaskGroupName: "driver"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "job", UID: "job-uid", Controller: &controller}},
+	}}
+	podJSON, err := json.Marshal(pod)
+	assert.NilError(t, err, "failed to marshal pod")
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "test-ns",
+		Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: podJSON},
+	}
+
+	ac := prepareController(t, "", "", "^kube-system$", "", "", true, true)
+	ac.conf = createConfigWithOverrides(map[string]string{
+		conf.AMAccessControlBypassAuth: "true",
+		conf.AMRules: `[{"name": "jobs", "match": {"namespace": "^test-", "controllerKind": "Job"}, "queue": "root.jobs",
+			"applicationId": "controller", "annotations": {"yunikorn.apache.org/task-group-name": "workers", "test": "value"}}]`,
+	})
+	resp := ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for pod")
+	podLabels := labels(t, resp.Patch)
+	assert.Equal(t, podLabels[constants.CanonicalLabelApplicationID], "job-job-uid")
+	assert.Equal(t, podLabels[constants.CanonicalLabelQueueName], "root.jobs")
+	var annotations map[string]interface{}
+	for _, op := range parsePatch(t, resp.Patch) {
+		if op.Path == "/metadata/annotations" {
+			annotations, _ = op.Value.(map[string]interface{})
+		}
+	}
+	assert.Equal(t, annotations["test"], "value", "annotation of the rule not added")
+	assert.Equal(t, annotations[constants.AnnotationTaskGroupName], "driver", "annotation of the pod overwritten")
+	assert.Assert(t, annotations[constants.AnnotationAllowPreemption] != nil, "preemption annotation not set")
+
+	// no matching rule: defaults apply
+	req.Namespace = "other-ns"
+	resp = ac.mutate(req)
+	podLabels = labels(t, resp.Patch)
+	assert.Equal(t, podLabels[constants.CanonicalLabelApplicationID], "yunikorn-other-ns-autogen")
+	assert.Assert(t, podLabels[constants.CanonicalLabelQueueName] == nil, "queue set without a matching rule")
+
+	// the task group set by the rule is validated against the task groups of the pod
+	req.Namespace = "test-ns"
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: `[{"name": "driver", "minMember": 1, "minResource": {"cpu": "1"}}]`}
+	req.Object.Raw, err = json.Marshal(pod)
+	assert.NilError(t, err, "failed to marshal pod")
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed in warn mode")
+	assert.Equal(t, len(resp.Warnings), 1, "task group of the rule not validated")
+	assert.Assert(t, strings.Contains(resp.Warnings[0], "taskGroup workers is not defined"), "unexpected warning: %s", resp.Warnings[0])
+}
+
+func TestMutateCustomWorkload(t *testing.T) {
+	mpiJob := `{"metadata": {"name": "mpi", "namespace": "test-ns"}, "spec": {"mpiReplicaSpecs": {
+		"Launcher": {"template": {"metadata": {"labels": {"app": "mpi"}}}},
+		"Worker": {"template": {"spec": {}}}}}}`
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "test-ns",
+		Kind:      metav1.GroupVersionKind{Group: "kubeflow.org", Version: "v2beta1", Kind: "MPIJob"},
+		Operation: admissionv1.Create,
+		UserInfo:  authv1.UserInfo{Username: "testuser", Groups: []string{"devs"}},
+		Object:    runtime.RawExtension{Raw: []byte(mpiJob)},
+	}
+
+	// custom workloads are not mutated unless configured
+	ac := prepareController(t, "", "", "^kube-system$", "", "", false, true)
+	resp := ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for mpijob")
+	assert.Equal(t, len(resp.Patch), 0, "custom workload mutated without configuration")
+
+	ac = InitAdmissionController(createConfigWithOverrides(map[string]string{
+		conf.AMWorkloads: `[{"group": "kubeflow.org", "kind": "MPIJob"}]`,
+	}), createPriorityClassCacheForTest(), createNamespaceClassCacheForTest())
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for mpijob")
+	ops := parsePatch(t, resp.Patch)
+	assert.Equal(t, len(ops), 3, "unexpected patch operations")
+	assert.Equal(t, ops[0].Path, "/spec/mpiReplicaSpecs/Launcher/template/metadata/annotations")
+	annotations, ok := ops[0].Value.(map[string]interface{})
+	assert.Assert(t, ok, "annotations not a map")
+	assert.Equal(t, annotations[common.UserInfoAnnotation], `{"user":"testuser","groups":["devs"]}`)
+	assert.Equal(t, ops[1].Path, "/spec/mpiReplicaSpecs/Launcher/template/metadata/labels")
+	podLabels, ok := ops[1].Value.(map[string]interface{})
+	assert.Assert(t, ok, "labels not a map")
+	assert.Equal(t, podLabels["app"], "mpi")
+	assert.Equal(t, podLabels[constants.CanonicalLabelApplicationID], "test-ns-mpi")
+	assert.Equal(t, ops[2].Path, "/spec/mpiReplicaSpecs/Worker/template/metadata")
+
+	// the user info set on a template is checked
+	req.Object.Raw = []byte(`{"metadata": {"name": "mpi"}, "spec": {"mpiReplicaSpecs": {
+		"Worker": {"template": {"metadata": {"annotations": {"yunikorn.apache.org/user.info": "{\"user\":\"other\"}"}}}}}}}`)
+	resp = ac.mutate(req)
+	assert.Check(t, !resp.Allowed, "user info set by an unauthorized user allowed")
+
+	// bypassed namespace and unknown group are not mutated
+	req.Object.Raw = []byte(mpiJob)
+	req.Namespace = "kube-system"
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for bypassed namespace")
+	assert.Equal(t, len(resp.Patch), 0, "non-empty patch for bypassed namespace")
+	req.Namespace = "test-ns"
+	req.Kind.Group = "example.com"
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for unknown kind")
+	assert.Equal(t, len(resp.Patch), 0, "non-empty patch for unknown kind")
+}
+
+func TestMutateCustomWorkloadPod(t *testing.T) {
+	const operator = "system:serviceaccount:mpi-operator:mpi-operator"
+	mpiJob := `{"metadata": {"name": "mpi", "namespace": "test-ns", "uid": "mpi-uid"}, "spec": {"mpiReplicaSpecs": {
+		"Launcher": {"template": {"metadata": {"labels": {"app": "mpi"}}}}}}}`
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "test-ns",
+		Kind:      metav1.GroupVersionKind{Group: "kubeflow.org", Version: "v2beta1", Kind: "MPIJob"},
+		Operation: admissionv1.Create,
+		UserInfo:  authv1.UserInfo{Username: "testuser", Groups: []string{"devs"}},
+		Object:    runtime.RawExtension{Raw: []byte(mpiJob)},
+	}
+	newController := func(externalUsers string) *AdmissionController {
+		return InitAdmissionController(createConfigWithOverrides(map[string]string{
+			conf.AMWorkloads:                  `[{"group": "kubeflow.org", "kind": "MPIJob"}]`,
+			conf.AMAccessControlExternalUsers: externalUsers,
+		}), createPriorityClassCacheForTest(), createNamespaceClassCacheForTest())
+	}
+	ac := newController("^" + operator + "$")
+	resp := ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed for mpijob")
+
+	// the operator creates the launcher pod from the patched template
+	controller := true
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            "mpi-launcher",
+		Namespace:       "test-ns",
+		Annotations:     map[string]string{},
+		Labels:          map[string]string{},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "MPIJob", Name: "mpi", UID: "mpi-uid", Controller: &controller}},
+	}}
+	for _, op := range parsePatch(t, resp.Patch) {
+		values, ok := op.Value.(map[string]interface{})
+		assert.Assert(t, ok, "unexpected patch value for %s", op.Path)
+		for k, v := range values {
+			switch op.Path {
+			case "/spec/mpiReplicaSpecs/Launcher/template/metadata/annotations":
+				pod.Annotations[k] = fmt.Sprint(v)
+			case "/spec/mpiReplicaSpecs/Launcher/template/metadata/labels":
+				pod.Labels[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	assert.Assert(t, pod.Annotations[common.UserInfoAnnotation] != "", "user info not set on the template")
+	podJSON, err := json.Marshal(pod)
+	assert.NilError(t, err, "failed to marshal pod")
+	podReq := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "test-ns",
+		Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+		Operation: admissionv1.Create,
+		UserInfo:  authv1.UserInfo{Username: operator},
+		Object:    runtime.RawExtension{Raw: podJSON},
+	}
+
+	// trusted operator: the pod keeps the submitter and the application of the workload
+	resp = ac.mutate(podReq)
+	assert.Check(t, resp.Allowed, "pod of a trusted operator not allowed: %v", resp.Result)
+	for _, op := range parsePatch(t, resp.Patch) {
+		if op.Path == "/metadata/annotations" {
+			annotations, ok := op.Value.(map[string]interface{})
+			assert.Assert(t, ok, "annotations not a map")
+			assert.Equal(t, annotations[common.UserInfoAnnotation], `{"user":"testuser","groups":["devs"]}`)
+		}
+	}
+	assert.Equal(t, labels(t, resp.Patch)[constants.CanonicalLabelApplicationID], "test-ns-mpi")
+
+	// operator not trusted: the user info set on the template rejects the pod
+	resp = newController("").mutate(podReq)
+	assert.Check(t, !resp.Allowed, "pod of an untrusted operator allowed")
+	assert.Assert(t, strings.Contains(resp.Result.Message, "not allowed to set user annotation"), "unexpected message: %s", resp.Result.Message)
+}
+
+func TestMutateAudit(t *testing.T) {
+	recorder := k8sEvents.NewFakeRecorder(1024)
+	events.SetRecorder(recorder)
+	defer events.SetRecorder(events.NewMockedRecorder())
+
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "pod-", Namespace: "test-ns"}}
+	podJSON, err := json.Marshal(pod)
+	assert.NilError(t, err, "failed to marshal pod")
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "test-ns",
+		Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: podJSON},
+	}
+	ac := prepareController(t, "", "", "^kube-system$", "", "", true, true)
+	ac.conf = createConfigWithOverrides(map[string]string{
+		conf.AMAccessControlBypassAuth:   "true",
+		conf.AMAuditEnable:               "true",
+		conf.AMValidationGangAnnotations: conf.ValidationModeReject,
+	})
+	patched := testutil.ToFloat64(auditDecisions.WithLabelValues(auditDecisionPatch))
+	rejected := testutil.ToFloat64(auditDecisions.WithLabelValues(auditDecisionReject))
+	allowed := testutil.ToFloat64(auditDecisions.WithLabelValues(auditDecisionAllow))
+
+	// patch is recorded, not applied
+	resp := ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed in audit mode")
+	assert.Equal(t, len(resp.Patch), 0, "patch applied in audit mode")
+	assert.Equal(t, testutil.ToFloat64(auditDecisions.WithLabelValues(auditDecisionPatch)), patched+1)
+	event := <-recorder.Events
+	assert.Assert(t, strings.Contains(event, "AdmissionAudit CREATE Pod test-ns/pod-"), "unexpected event: %s", event)
+	assert.Assert(t, strings.Contains(event, "patched /spec/schedulerName, /metadata/labels"), "paths missing in event: %s", event)
+	assert.Assert(t, !strings.Contains(event, constants.SchedulerName), "patch values in event: %s", event)
+
+	// rejection is recorded, the object is admitted
+	invalid := pod.DeepCopy()
+	invalid.Annotations = map[string]string{constants.AnnotationTaskGroups: "invalid"}
+	req.Object.Raw, err = json.Marshal(invalid)
+	assert.NilError(t, err, "failed to marshal pod")
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed in audit mode")
+	assert.Equal(t, testutil.ToFloat64(auditDecisions.WithLabelValues(auditDecisionReject)), rejected+1)
+	event = <-recorder.Events
+	assert.Assert(t, strings.Contains(event, "invalid annotation "+constants.AnnotationTaskGroups), "unexpected event: %s", event)
+
+	// a long rejection message is truncated in the event
+	invalid.Annotations = map[string]string{
+		constants.AnnotationTaskGroups:    `[{"name": "driver", "minMember": 1, "minResource": {"cpu": "1"}}]`,
+		constants.AnnotationTaskGroupName: strings.Repeat("x", 2*maxAuditNoteLength),
+	}
+	req.Object.Raw, err = json.Marshal(invalid)
+	assert.NilError(t, err, "failed to marshal pod")
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed in audit mode")
+	event = <-recorder.Events
+	assert.Assert(t, strings.HasSuffix(event, "..."), "event not truncated: %s", event)
+	assert.Assert(t, len(event) <= len("Normal AdmissionAudit ")+maxAuditNoteLength, "event too long: %d", len(event))
+
+	// unchanged object is only counted
+	req.Namespace = "kube-system"
+	resp = ac.mutate(req)
+	assert.Check(t, resp.Allowed, "response not allowed in audit mode")
+	assert.Equal(t, testutil.ToFloat64(auditDecisions.WithLabelValues(auditDecisionAllow)), allowed+1)
+	assert.Equal(t, len(recorder.Events), 0, "unexpected event for an unchanged object")
+
+	// the namespace annotation overrides the config
+	req.Namespace = "test-ns"
+	req.Object.Raw = podJSON
+	ac.nsCache.nameSpaces["test-ns"] = nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET, autoGang: UNSET, audit: FALSE}
+	resp = ac.mutate(req)
+	assert.Assert(t, len(resp.Patch) > 0, "patch not applied with audit disabled for the namespace")
+	ac.conf = createConfigWithOverrides(map[string]string{conf.AMAccessControlBypassAuth: "true"})
+	ac.nsCache.nameSpaces["test-ns"] = nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET, autoGang: UNSET, audit: TRUE}
+	resp = ac.mutate(req)
+	assert.Equal(t, len(resp.Patch), 0, "patch applied with audit enabled for the namespace")
+}
+
+func TestMergePatch(t *testing.T) {
+	patch := []common.PatchOperation{
+		{Op: "add", Path: "/spec/schedulerName", Value: "yunikorn"},
+		{Op: "add", Path: "/metadata/annotations", Value: map[string]string{"a": "1", "b": "1"}},
+	}
+	patch = mergePatch(patch, []common.PatchOperation{
+		{Op: "add", Path: "/metadata/annotations", Value: map[string]string{"b": "2", "c": "2"}},
+		{Op: "add", Path: "/metadata/labels", Value: map[string]string{"d": "2"}},
+	})
+	assert.Equal(t, len(patch), 3)
+	assert.DeepEqual(t, patch[1].Value, map[string]string{"a": "1", "b": "2", "c": "2"})
+	assert.DeepEqual(t, patch[2].Value, map[string]string{"d": "2"})
+}
+
 func TestMutateUpdate(t *testing.T) {
 	var ac *AdmissionController
 	var pod v1.Pod
@@ -26,7 +26,9 @@ import (
 	"strings"
 
 	"go.uber.org/zap"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	informersv1 "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
 
@@ -42,10 +44,24 @@ const (
 	WebHookPrefix             = AdmissionControllerPrefix + "webHook."
 	FilteringPrefix           = AdmissionControllerPrefix + "filtering."
 	AccessControlPrefix       = AdmissionControllerPrefix + "accessControl."
+	AutoGangPrefix            = AdmissionControllerPrefix + "autoGang."
+	ValidationPrefix          = AdmissionControllerPrefix + "validation."
+	AuditPrefix               = AdmissionControllerPrefix + "audit."
+
+	// rules configuration
+	AMRules = AdmissionControllerPrefix + "rules"
+
+	// custom workloads configuration: none by default, a built-in kind is enabled by its group and kind
+	AMWorkloads = AdmissionControllerPrefix + "workloads"
 
 	// webhook configuration
-	AMWebHookAMServiceName           = WebHookPrefix + "amServiceName"
-	AMWebHookSchedulerServiceAddress = WebHookPrefix + "schedulerServiceAddress"
+	AMWebHookAMServiceName             = WebHookPrefix + "amServiceName"
+	AMWebHookSchedulerServiceAddress   = WebHookPrefix + "schedulerServiceAddress"
+	AMWebHookFailurePolicy             = WebHookPrefix + "failurePolicy"
+	AMWebHookTimeoutSeconds            = WebHookPrefix + "timeoutSeconds"
+	AMWebHookNamespaceSelector         = WebHookPrefix + "namespaceSelector"
+	AMWebHookObjectSelector            = WebHookPrefix + "objectSelector"
+	AMWebHookGenerateNamespaceSelector = WebHookPrefix + "generateNamespaceSelector"
 
 	// filtering configuration
 	AMFilteringProcessNamespaces    = FilteringPrefix + "processNamespaces"
@@ -53,6 +69,7 @@ const (
 	AMFilteringLabelNamespaces      = FilteringPrefix + "labelNamespaces"
 	AMFilteringNoLabelNamespaces    = FilteringPrefix + "noLabelNamespaces"
 	AMFilteringGenerateUniqueAppIds = FilteringPrefix + "generateUniqueAppId"
+	AMFilteringEnablePodGroups      = FilteringPrefix + "enablePodGroups"
 
 	// access control configuration
 	AMAccessControlBypassAuth       = AccessControlPrefix + "bypassAuth"
@@ -60,12 +77,33 @@ const (
 	AMAccessControlSystemUsers      = AccessControlPrefix + "systemUsers"
 	AMAccessControlExternalUsers    = AccessControlPrefix + "externalUsers"
 	AMAccessControlExternalGroups   = AccessControlPrefix + "externalGroups"
+
+	// auto gang configuration
+	AMAutoGangEnable                     = AutoGangPrefix + "enable"
+	AMAutoGangSchedulingPolicyParameters = AutoGangPrefix + "schedulingPolicyParameters"
+
+	// validation configuration
+	AMValidationGangAnnotations = ValidationPrefix + "gangAnnotations"
+
+	// audit configuration
+	AMAuditEnable = AuditPrefix + "enable"
+)
+
+// modes of the validation of the gang annotations: reject invalid objects or only return a warning
+const (
+	ValidationModeReject = "reject"
+	ValidationModeWarn   = "warn"
 )
 
 const (
 	// webhook defaults
-	DefaultWebHookAmServiceName           = "yunikorn-admission-controller-service"
-	DefaultWebHookSchedulerServiceAddress = "yunikorn-service:9080"
+	DefaultWebHookAmServiceName             = "yunikorn-admission-controller-service"
+	DefaultWebHookSchedulerServiceAddress   = "yunikorn-service:9080"
+	DefaultWebHookFailurePolicy             = "Ignore"
+	DefaultWebHookTimeoutSeconds            = 10
+	DefaultWebHookNamespaceSelector         = ""
+	DefaultWebHookObjectSelector            = ""
+	DefaultWebHookGenerateNamespaceSelector = false
 
 	// filtering defaults
 	DefaultFilteringProcessNamespaces    = ""
@@ -73,6 +111,7 @@ const (
 	DefaultFilteringLabelNamespaces      = ""
 	DefaultFilteringNoLabelNamespaces    = ""
 	DefaultFilteringGenerateUniqueAppIds = false
+	DefaultFilteringEnablePodGroups      = false
 
 	// access control defaults
 	DefaultAccessControlBypassAuth       = false
@@ -80,6 +119,22 @@ const (
 	DefaultAccessControlSystemUsers      = "^system:serviceaccount:kube-system:"
 	DefaultAccessControlExternalUsers    = ""
 	DefaultAccessControlExternalGroups   = ""
+
+	// auto gang defaults
+	DefaultAutoGangEnable                     = false
+	DefaultAutoGangSchedulingPolicyParameters = ""
+
+	// validation defaults
+	DefaultValidationGangAnnotations = ValidationModeWarn
+
+	// audit defaults
+	DefaultAuditEnable = false
+
+	// rules defaults
+	DefaultRules = ""
+
+	// custom workloads defaults
+	DefaultWorkloads = ""
 )
 
 type AdmissionControllerConf struct {
@@ -91,16 +146,28 @@ type AdmissionControllerConf struct {
 	policyGroup             string
 	amServiceName           string
 	schedulerServiceAddress string
+	failurePolicy           admissionregistrationv1.FailurePolicyType
+	timeoutSeconds          int32
+	namespaceSelector       *metav1.LabelSelector
+	objectSelector          *metav1.LabelSelector
+	generateSelector        bool
 	processNamespaces       []*regexp.Regexp
 	bypassNamespaces        []*regexp.Regexp
 	labelNamespaces         []*regexp.Regexp
 	noLabelNamespaces       []*regexp.Regexp
 	generateUniqueAppIds    bool
+	enablePodGroups         bool
 	bypassAuth              bool
 	trustControllers        bool
 	systemUsers             []*regexp.Regexp
 	externalUsers           []*regexp.Regexp
 	externalGroups          []*regexp.Regexp
+	autoGang                bool
+	autoGangPolicy          string
+	gangValidation          string
+	audit                   bool
+	rules                   []*AdmissionRule
+	workloads               []*WorkloadKind
 	configMaps              []*v1.ConfigMap
 
 	lock locking.RWMutex
@@ -160,6 +227,41 @@ func (acc *AdmissionControllerConf) GetSchedulerServiceAddress() string {
 	return acc.schedulerServiceAddress
 }
 
+func (acc *AdmissionControllerConf) GetWebHookFailurePolicy() admissionregistrationv1.FailurePolicyType {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.failurePolicy
+}
+
+func (acc *AdmissionControllerConf) GetWebHookTimeoutSeconds() int32 {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.timeoutSeconds
+}
+
+// GetWebHookNamespaceSelector returns the namespace selector of the mutating webhook. If generating the selector is
+// enabled the process and bypass namespaces that match a single name are added to the configured selector. The
+// namespace annotations cannot override a generated selector. If the failure policy is Fail the namespace of the
+// scheduler and kube-system are always excluded: an unavailable admission controller must not block their pods.
+func (acc *AdmissionControllerConf) GetWebHookNamespaceSelector() *metav1.LabelSelector {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	selector := acc.namespaceSelector.DeepCopy()
+	if acc.generateSelector {
+		selector.MatchExpressions = append(selector.MatchExpressions, generateNamespaceRequirements(acc.processNamespaces, acc.bypassNamespaces)...)
+	}
+	if acc.failurePolicy == admissionregistrationv1.Fail {
+		selector.MatchExpressions = excludeNamespaces(selector.MatchExpressions, metav1.NamespaceSystem, acc.namespace)
+	}
+	return selector
+}
+
+func (acc *AdmissionControllerConf) GetWebHookObjectSelector() *metav1.LabelSelector {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.objectSelector.DeepCopy()
+}
+
 func (acc *AdmissionControllerConf) GetProcessNamespaces() []*regexp.Regexp {
 	acc.lock.RLock()
 	defer acc.lock.RUnlock()
@@ -190,6 +292,14 @@ func (acc *AdmissionControllerConf) GetGenerateUniqueAppIds() bool {
 	return acc.generateUniqueAppIds
 }
 
+// GetEnablePodGroups returns true if the members of a scheduler-plugins PodGroup should form one application. This
+// should match the enablePodGroups setting of the scheduler.
+func (acc *AdmissionControllerConf) GetEnablePodGroups() bool {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.enablePodGroups
+}
+
 func (acc *AdmissionControllerConf) GetBypassAuth() bool {
 	acc.lock.RLock()
 	defer acc.lock.RUnlock()
@@ -220,6 +330,63 @@ func (acc *AdmissionControllerConf) GetExternalGroups() []*regexp.Regexp {
 	return acc.externalGroups
 }
 
+func (acc *AdmissionControllerConf) GetAutoGang() bool {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.autoGang
+}
+
+func (acc *AdmissionControllerConf) GetAutoGangSchedulingPolicyParameters() string {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.autoGangPolicy
+}
+
+func (acc *AdmissionControllerConf) GetGangAnnotationsValidation() string {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.gangValidation
+}
+
+func (acc *AdmissionControllerConf) GetAudit() bool {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.audit
+}
+
+func (acc *AdmissionControllerConf) GetRules() []*AdmissionRule {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.rules
+}
+
+// GetMatchingRule returns the first rule that matches the pod, nil if no rule matches
+func (acc *AdmissionControllerConf) GetMatchingRule(namespace string, pod *v1.Pod) *AdmissionRule {
+	for _, rule := range acc.GetRules() {
+		if rule.Matches(namespace, pod) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// GetWorkloadKinds returns the custom workload kinds, sorted by group, version and resource
+func (acc *AdmissionControllerConf) GetWorkloadKinds() []*WorkloadKind {
+	acc.lock.RLock()
+	defer acc.lock.RUnlock()
+	return acc.workloads
+}
+
+// GetWorkloadKind returns the custom workload kind of the group, nil if the kind is not a custom workload
+func (acc *AdmissionControllerConf) GetWorkloadKind(group string, kind string) *WorkloadKind {
+	for _, workload := range acc.GetWorkloadKinds() {
+		if workload.Group == group && workload.Kind == kind {
+			return workload
+		}
+	}
+	return nil
+}
+
 type configMapUpdateHandler struct {
 	conf *AdmissionControllerConf
 }
@@ -308,6 +475,11 @@ func (acc *AdmissionControllerConf) updateConfigMaps(configMaps []*v1.ConfigMap,
 	// webhook
 	acc.amServiceName = parseConfigString(configs, AMWebHookAMServiceName, DefaultWebHookAmServiceName)
 	acc.schedulerServiceAddress = parseConfigString(configs, AMWebHookSchedulerServiceAddress, DefaultWebHookSchedulerServiceAddress)
+	acc.failurePolicy = parseConfigFailurePolicy(configs, AMWebHookFailurePolicy, DefaultWebHookFailurePolicy)
+	acc.timeoutSeconds = parseConfigTimeoutSeconds(configs, AMWebHookTimeoutSeconds, DefaultWebHookTimeoutSeconds)
+	acc.namespaceSelector = parseConfigLabelSelector(configs, AMWebHookNamespaceSelector, DefaultWebHookNamespaceSelector)
+	acc.objectSelector = parseConfigLabelSelector(configs, AMWebHookObjectSelector, DefaultWebHookObjectSelector)
+	acc.generateSelector = parseConfigBool(configs, AMWebHookGenerateNamespaceSelector, DefaultWebHookGenerateNamespaceSelector)
 
 	// filtering
 	acc.processNamespaces = parseConfigRegexps(configs, AMFilteringProcessNamespaces, DefaultFilteringProcessNamespaces)
@@ -315,6 +487,7 @@ func (acc *AdmissionControllerConf) updateConfigMaps(configMaps []*v1.ConfigMap,
 	acc.labelNamespaces = parseConfigRegexps(configs, AMFilteringLabelNamespaces, DefaultFilteringLabelNamespaces)
 	acc.noLabelNamespaces = parseConfigRegexps(configs, AMFilteringNoLabelNamespaces, DefaultFilteringNoLabelNamespaces)
 	acc.generateUniqueAppIds = parseConfigBool(configs, AMFilteringGenerateUniqueAppIds, DefaultFilteringGenerateUniqueAppIds)
+	acc.enablePodGroups = parseConfigBool(configs, AMFilteringEnablePodGroups, DefaultFilteringEnablePodGroups)
 
 	// access control
 	acc.bypassAuth = parseConfigBool(configs, AMAccessControlBypassAuth, DefaultAccessControlBypassAuth)
@@ -323,6 +496,22 @@ func (acc *AdmissionControllerConf) updateConfigMaps(configMaps []*v1.ConfigMap,
 	acc.externalUsers = parseConfigRegexps(configs, AMAccessControlExternalUsers, DefaultAccessControlExternalUsers)
 	acc.externalGroups = parseConfigRegexps(configs, AMAccessControlExternalGroups, DefaultAccessControlExternalGroups)
 
+	// auto gang
+	acc.autoGang = parseConfigBool(configs, AMAutoGangEnable, DefaultAutoGangEnable)
+	acc.autoGangPolicy = parseConfigString(configs, AMAutoGangSchedulingPolicyParameters, DefaultAutoGangSchedulingPolicyParameters)
+
+	// validation
+	acc.gangValidation = parseConfigValidationMode(configs, AMValidationGangAnnotations, DefaultValidationGangAnnotations)
+
+	// audit
+	acc.audit = parseConfigBool(configs, AMAuditEnable, DefaultAuditEnable)
+
+	// rules
+	acc.rules = parseConfigRules(configs, AMRules, DefaultRules)
+
+	// custom workloads
+	acc.workloads = parseConfigWorkloads(configs, AMWorkloads, DefaultWorkloads)
+
 	// logging
 	log.UpdateLoggingConfig(configs)
 
@@ -342,15 +531,27 @@ func (acc *AdmissionControllerConf) dumpConfigurationInternal() {
 		zap.String("policyGroup", acc.policyGroup),
 		zap.String("amServiceName", acc.amServiceName),
 		zap.String("schedulerServiceAddress", acc.schedulerServiceAddress),
+		zap.String("webHookFailurePolicy", string(acc.failurePolicy)),
+		zap.Int32("webHookTimeoutSeconds", acc.timeoutSeconds),
+		zap.String("webHookNamespaceSelector", metav1.FormatLabelSelector(acc.namespaceSelector)),
+		zap.String("webHookObjectSelector", metav1.FormatLabelSelector(acc.objectSelector)),
+		zap.Bool("webHookGenerateNamespaceSelector", acc.generateSelector),
 		zap.Strings("processNamespaces", regexpsString(acc.processNamespaces)),
 		zap.Strings("bypassNamespaces", regexpsString(acc.bypassNamespaces)),
 		zap.Strings("labelNamespaces", regexpsString(acc.labelNamespaces)),
 		zap.Strings("noLabelNamespaces", regexpsString(acc.noLabelNamespaces)),
+		zap.Bool("enablePodGroups", acc.enablePodGroups),
 		zap.Bool("bypassAuth", acc.bypassAuth),
 		zap.Bool("trustControllers", acc.trustControllers),
 		zap.Strings("systemUsers", regexpsString(acc.systemUsers)),
 		zap.Strings("externalUsers", regexpsString(acc.externalUsers)),
-		zap.Strings("externalGroups", regexpsString(acc.externalGroups)))
+		zap.Strings("externalGroups", regexpsString(acc.externalGroups)),
+		zap.Bool("autoGang", acc.autoGang),
+		zap.String("autoGangSchedulingPolicyParameters", acc.autoGangPolicy),
+		zap.String("gangAnnotationsValidation", acc.gangValidation),
+		zap.Bool("audit", acc.audit),
+		zap.Strings("rules", rulesString(acc.rules)),
+		zap.Strings("workloads", workloadsString(acc.workloads)))
 }
 
 func regexpsString(regexes []*regexp.Regexp) []string {
@@ -375,6 +576,99 @@ func parseConfigRegexps(config map[string]string, key string, defaultValue strin
 	return result
 }
 
+func rulesString(rules []*AdmissionRule) []string {
+	result := make([]string, 0)
+	for _, rule := range rules {
+		result = append(result, rule.Name)
+	}
+	return result
+}
+
+func parseConfigRules(config map[string]string, key string, defaultValue string) []*AdmissionRule {
+	value := parseConfigString(config, key, defaultValue)
+	result, err := parseRules(value)
+	if err != nil {
+		log.Log(log.AdmissionConf).Error("Unable to parse rules, using default",
+			zap.String("key", key), zap.String("value", value), zap.String("default", defaultValue), zap.Error(err))
+		result, err = parseRules(defaultValue)
+		if err != nil {
+			log.Log(log.AdmissionConf).Fatal("BUG: can't parse default rules", zap.Error(err))
+		}
+	}
+	return result
+}
+
+func workloadsString(workloads []*WorkloadKind) []string {
+	result := make([]string, 0)
+	for _, workload := range workloads {
+		result = append(result, workload.Kind+"."+workload.Group)
+	}
+	return result
+}
+
+func parseConfigWorkloads(config map[string]string, key string, defaultValue string) []*WorkloadKind {
+	value := parseConfigString(config, key, defaultValue)
+	result, err := parseWorkloadKinds(value)
+	if err != nil {
+		log.Log(log.AdmissionConf).Error("Unable to parse workloads, using default",
+			zap.String("key", key), zap.String("value", value), zap.String("default", defaultValue), zap.Error(err))
+		result, err = parseWorkloadKinds(defaultValue)
+		if err != nil {
+			log.Log(log.AdmissionConf).Fatal("BUG: can't parse default workloads", zap.Error(err))
+		}
+	}
+	return result
+}
+
+func parseConfigFailurePolicy(config map[string]string, key string, defaultValue string) admissionregistrationv1.FailurePolicyType {
+	value := parseConfigString(config, key, defaultValue)
+	result, err := parseFailurePolicy(value)
+	if err != nil {
+		log.Log(log.AdmissionConf).Error("Unable to parse failure policy, using default",
+			zap.String("key", key), zap.String("value", value), zap.String("default", defaultValue), zap.Error(err))
+		result, err = parseFailurePolicy(defaultValue)
+		if err != nil {
+			log.Log(log.AdmissionConf).Fatal("BUG: can't parse default failure policy", zap.Error(err))
+		}
+	}
+	return result
+}
+
+func parseConfigTimeoutSeconds(config map[string]string, key string, defaultValue int32) int32 {
+	value := parseConfigString(config, key, strconv.Itoa(int(defaultValue)))
+	result, err := parseTimeoutSeconds(value)
+	if err != nil {
+		log.Log(log.AdmissionConf).Error("Unable to parse timeout, using default",
+			zap.String("key", key), zap.String("value", value), zap.Int32("default", defaultValue), zap.Error(err))
+		result = defaultValue
+	}
+	return result
+}
+
+func parseConfigLabelSelector(config map[string]string, key string, defaultValue string) *metav1.LabelSelector {
+	value := parseConfigString(config, key, defaultValue)
+	result, err := parseLabelSelector(value)
+	if err != nil {
+		log.Log(log.AdmissionConf).Error("Unable to parse label selector, using default",
+			zap.String("key", key), zap.String("value", value), zap.String("default", defaultValue), zap.Error(err))
+		result, err = parseLabelSelector(defaultValue)
+		if err != nil {
+			log.Log(log.AdmissionConf).Fatal("BUG: can't parse default label selector", zap.Error(err))
+		}
+	}
+	return result
+}
+
+func parseConfigValidationMode(config map[string]string, key string, defaultValue string) string {
+	value := parseConfigString(config, key, defaultValue)
+	if value != ValidationModeReject && value != ValidationModeWarn {
+		log.Log(log.AdmissionConf).Error("Unknown validation mode, using default",
+			zap.String("key", key), zap.String("value", value), zap.String("default", defaultValue))
+		return defaultValue
+	}
+	return value
+}
+
 func parseConfigBool(config map[string]string, key string, defaultValue bool) bool {
 	value := parseConfigString(config, key, fmt.Sprintf("%t", defaultValue))
 	result, err := strconv.ParseBool(value)
@@ -22,7 +22,9 @@ import (
 	"testing"
 
 	"gotest.tools/v3/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	schedulerconf "github.com/apache/yunikorn-k8shim/pkg/conf"
 )
@@ -30,19 +32,27 @@ import (
 func TestConfigMapVars(t *testing.T) {
 	// test valid settings
 	conf := NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{
-		schedulerconf.CMSvcPolicyGroup:   "testPolicyGroup",
-		AMWebHookAMServiceName:           "testYunikornService",
-		AMWebHookSchedulerServiceAddress: "testAddress",
-		AMFilteringProcessNamespaces:     "testProcessNamespaces",
-		AMFilteringBypassNamespaces:      "testBypassNamespaces",
-		AMFilteringLabelNamespaces:       "testLabelNamespaces",
-		AMFilteringNoLabelNamespaces:     "testNolabelNamespaces",
-		AMFilteringGenerateUniqueAppIds:  "true",
-		AMAccessControlBypassAuth:        "true",
-		AMAccessControlSystemUsers:       "^systemuser$",
-		AMAccessControlExternalUsers:     "^yunikorn$",
-		AMAccessControlExternalGroups:    "^devs$",
-		AMAccessControlTrustControllers:  "false",
+		schedulerconf.CMSvcPolicyGroup:       "testPolicyGroup",
+		AMWebHookAMServiceName:               "testYunikornService",
+		AMWebHookSchedulerServiceAddress:     "testAddress",
+		AMFilteringProcessNamespaces:         "testProcessNamespaces",
+		AMFilteringBypassNamespaces:          "testBypassNamespaces",
+		AMFilteringLabelNamespaces:           "testLabelNamespaces",
+		AMFilteringNoLabelNamespaces:         "testNolabelNamespaces",
+		AMFilteringGenerateUniqueAppIds:      "true",
+		AMFilteringEnablePodGroups:           "true",
+		AMAccessControlBypassAuth:            "true",
+		AMAccessControlSystemUsers:           "^systemuser$",
+		AMAccessControlExternalUsers:         "^yunikorn$",
+		AMAccessControlExternalGroups:        "^devs$",
+		AMAccessControlTrustControllers:      "false",
+		AMAutoGangEnable:                     "true",
+		AMAutoGangSchedulingPolicyParameters: "placeholderTimeoutInSeconds=60",
+		AMValidationGangAnnotations:          "reject",
+		AMAuditEnable:                        "true",
+		AMWebHookFailurePolicy:               "Fail",
+		AMWebHookTimeoutSeconds:              "5",
+		AMWebHookObjectSelector:              "team=ml",
 	}}})
 	assert.Equal(t, conf.GetPolicyGroup(), "testPolicyGroup")
 	assert.Equal(t, conf.GetAmServiceName(), "testYunikornService")
@@ -52,11 +62,22 @@ func TestConfigMapVars(t *testing.T) {
 	assert.Equal(t, conf.GetLabelNamespaces()[0].String(), "testLabelNamespaces")
 	assert.Equal(t, conf.GetNoLabelNamespaces()[0].String(), "testNolabelNamespaces")
 	assert.Equal(t, conf.GetGenerateUniqueAppIds(), true)
+	assert.Equal(t, conf.GetEnablePodGroups(), true)
 	assert.Equal(t, conf.GetBypassAuth(), true)
 	assert.Equal(t, conf.GetSystemUsers()[0].String(), "^systemuser$")
 	assert.Equal(t, conf.GetExternalUsers()[0].String(), "^yunikorn$")
 	assert.Equal(t, conf.GetExternalGroups()[0].String(), "^devs$")
 	assert.Equal(t, conf.GetTrustControllers(), false)
+	assert.Equal(t, conf.GetAutoGang(), true)
+	assert.Equal(t, conf.GetAutoGangSchedulingPolicyParameters(), "placeholderTimeoutInSeconds=60")
+	assert.Equal(t, conf.GetGangAnnotationsValidation(), ValidationModeReject)
+	assert.Equal(t, conf.GetAudit(), true)
+	assert.Equal(t, conf.GetWebHookFailurePolicy(), admissionregistrationv1.Fail)
+	assert.Equal(t, conf.GetWebHookTimeoutSeconds(), int32(5))
+	assert.DeepEqual(t, conf.GetWebHookObjectSelector(), &metav1.LabelSelector{
+		MatchLabels:      map[string]string{"team": "ml"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{},
+	})
 
 	// test missing settings
 	conf = NewAdmissionControllerConf([]*v1.ConfigMap{nil, nil})
@@ -68,21 +89,38 @@ func TestConfigMapVars(t *testing.T) {
 	assert.Equal(t, conf.GetBypassNamespaces()[0].String(), DefaultFilteringBypassNamespaces)
 	assert.Equal(t, 0, len(conf.GetLabelNamespaces()))
 	assert.Equal(t, 0, len(conf.GetNoLabelNamespaces()))
+	assert.Equal(t, conf.GetEnablePodGroups(), DefaultFilteringEnablePodGroups)
 	assert.Equal(t, conf.GetBypassAuth(), DefaultAccessControlBypassAuth)
 	assert.Equal(t, conf.GetSystemUsers()[0].String(), DefaultAccessControlSystemUsers)
 	assert.Equal(t, 0, len(conf.GetExternalUsers()))
 	assert.Equal(t, 0, len(conf.GetExternalGroups()))
 	assert.Equal(t, conf.GetTrustControllers(), DefaultAccessControlTrustControllers)
+	assert.Equal(t, conf.GetAutoGang(), DefaultAutoGangEnable)
+	assert.Equal(t, conf.GetAutoGangSchedulingPolicyParameters(), DefaultAutoGangSchedulingPolicyParameters)
+	assert.Equal(t, conf.GetGangAnnotationsValidation(), DefaultValidationGangAnnotations)
+	assert.Equal(t, conf.GetAudit(), DefaultAuditEnable)
+	assert.Equal(t, string(conf.GetWebHookFailurePolicy()), DefaultWebHookFailurePolicy)
+	assert.Equal(t, conf.GetWebHookTimeoutSeconds(), int32(DefaultWebHookTimeoutSeconds))
+	assert.DeepEqual(t, conf.GetWebHookNamespaceSelector(), &metav1.LabelSelector{})
+	assert.DeepEqual(t, conf.GetWebHookObjectSelector(), &metav1.LabelSelector{})
 
-	// test faulty settings for boolean values
+	// test faulty settings for boolean and enumerated values
 	conf = NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{
 		AMAccessControlBypassAuth:       "xyz",
 		AMAccessControlTrustControllers: "xyz",
 		AMFilteringGenerateUniqueAppIds: "xyz",
+		AMValidationGangAnnotations:     "xyz",
+		AMWebHookFailurePolicy:          "xyz",
+		AMWebHookTimeoutSeconds:         "xyz",
+		AMWebHookNamespaceSelector:      "env in xyz",
 	}}})
 	assert.Equal(t, conf.GetBypassAuth(), DefaultAccessControlBypassAuth)
 	assert.Equal(t, conf.GetTrustControllers(), DefaultAccessControlTrustControllers)
 	assert.Equal(t, conf.GetGenerateUniqueAppIds(), DefaultFilteringGenerateUniqueAppIds)
+	assert.Equal(t, conf.GetGangAnnotationsValidation(), DefaultValidationGangAnnotations)
+	assert.Equal(t, string(conf.GetWebHookFailurePolicy()), DefaultWebHookFailurePolicy)
+	assert.Equal(t, conf.GetWebHookTimeoutSeconds(), int32(DefaultWebHookTimeoutSeconds))
+	assert.DeepEqual(t, conf.GetWebHookNamespaceSelector(), &metav1.LabelSelector{})
 
 	// test faulty settings for regexp values
 	conf = NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{
@@ -0,0 +1,120 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ApplicationIDFromController derives the application ID of the pod from its immediate controller, the owner reference
+// of the pod: the Job of a CronJob pod, the ReplicaSet of a Deployment pod. The controllers higher up are not resolved:
+// a new ReplicaSet of a Deployment, or a new Job of a CronJob, is a new application.
+const ApplicationIDFromController = "controller"
+
+// AdmissionRule sets the queue, the application ID and default annotations of the pods it matches. Rules are
+// configured as a JSON list, the first matching rule is applied. Values already set on the pod are not changed.
+type AdmissionRule struct {
+	Name  string    `json:"name"`
+	Match RuleMatch `json:"match"`
+	// queue set as the canonical queue label
+	Queue string `json:"queue,omitempty"`
+	// source of the application ID, only ApplicationIDFromController is supported
+	ApplicationID string `json:"applicationId,omitempty"`
+	// annotations added to the pod, for example the task group name
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	namespace      *regexp.Regexp
+	serviceAccount *regexp.Regexp
+}
+
+// RuleMatch selects the pods of a rule, all set conditions must match
+type RuleMatch struct {
+	// regular expressions matched against the namespace and the service account of the pod
+	Namespace      string `json:"namespace,omitempty"`
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// labels the pod must have with the same value
+	Labels map[string]string `json:"labels,omitempty"`
+	// kind of the immediate controller of the pod, for example Job or ReplicaSet
+	ControllerKind string `json:"controllerKind,omitempty"`
+}
+
+// Matches returns true if the pod in the namespace matches all conditions of the rule
+func (r *AdmissionRule) Matches(namespace string, pod *v1.Pod) bool {
+	if r.namespace != nil && !r.namespace.MatchString(namespace) {
+		return false
+	}
+	if r.serviceAccount != nil && !r.serviceAccount.MatchString(pod.Spec.ServiceAccountName) {
+		return false
+	}
+	for k, v := range r.Match.Labels {
+		if value, ok := pod.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	if r.Match.ControllerKind != "" {
+		controller := metav1.GetControllerOf(pod)
+		if controller == nil || !strings.EqualFold(controller.Kind, r.Match.ControllerKind) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseRules(value string) ([]*AdmissionRule, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	var rules []*AdmissionRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules {
+		if rule == nil {
+			return nil, fmt.Errorf("rule %d is empty", i)
+		}
+		var err error
+		if rule.Match.Namespace != "" {
+			if rule.namespace, err = regexp.Compile(rule.Match.Namespace); err != nil {
+				return nil, fmt.Errorf("rule %s: invalid namespace: %w", rule.Name, err)
+			}
+		}
+		if rule.Match.ServiceAccount != "" {
+			if rule.serviceAccount, err = regexp.Compile(rule.Match.ServiceAccount); err != nil {
+				return nil, fmt.Errorf("rule %s: invalid serviceAccount: %w", rule.Name, err)
+			}
+		}
+		if rule.Queue != "" {
+			if errs := validation.IsValidLabelValue(rule.Queue); len(errs) > 0 {
+				return nil, fmt.Errorf("rule %s: invalid queue %s: %s", rule.Name, rule.Queue, strings.Join(errs, ", "))
+			}
+		}
+		if rule.ApplicationID != "" && rule.ApplicationID != ApplicationIDFromController {
+			return nil, fmt.Errorf("rule %s: unknown applicationId %s", rule.Name, rule.ApplicationID)
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,89 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package conf
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testRules = `[
+	{"name": "spark", "match": {"namespace": "^spark-", "labels": {"spark-role": "driver"}}, "queue": "root.spark"},
+	{"name": "jobs", "match": {"serviceAccount": "^batch$", "controllerKind": "job"}, "applicationId": "controller",
+		"annotations": {"yunikorn.apache.org/task-group-name": "workers"}},
+	{"name": "default", "queue": "root.default"}
+]`
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules("")
+	assert.NilError(t, err)
+	assert.Equal(t, len(rules), 0)
+
+	rules, err = parseRules(testRules)
+	assert.NilError(t, err)
+	assert.Equal(t, len(rules), 3)
+	assert.Equal(t, rules[0].Queue, "root.spark")
+	assert.Equal(t, rules[1].ApplicationID, ApplicationIDFromController)
+	assert.Equal(t, rules[1].Annotations["yunikorn.apache.org/task-group-name"], "workers")
+
+	tests := map[string]string{
+		"invalid json":           `[{"name": "a"`,
+		"empty rule":             `[null]`,
+		"invalid namespace":      `[{"name": "a", "match": {"namespace": "?"}}]`,
+		"invalid serviceAccount": `[{"name": "a", "match": {"serviceAccount": "?"}}]`,
+		"invalid queue":          `[{"name": "a", "queue": "root.a b"}]`,
+		"unknown applicationId":  `[{"name": "a", "applicationId": "namespace"}]`,
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err = parseRules(value)
+			assert.Assert(t, err != nil, "expected error for %s", value)
+		})
+	}
+}
+
+func TestGetMatchingRule(t *testing.T) {
+	conf := NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{AMRules: testRules}}})
+	assert.Equal(t, len(conf.GetRules()), 3)
+
+	controller := true
+	driver := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"spark-role": "driver"}}}
+	job := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "job", Controller: &controller}}},
+		Spec:       v1.PodSpec{ServiceAccountName: "batch"},
+	}
+	assert.Equal(t, conf.GetMatchingRule("spark-test", driver).Name, "spark")
+	assert.Equal(t, conf.GetMatchingRule("test", driver).Name, "default", "namespace should not match")
+	assert.Equal(t, conf.GetMatchingRule("spark-test", &v1.Pod{}).Name, "default", "labels should not match")
+	assert.Equal(t, conf.GetMatchingRule("test", job).Name, "jobs")
+	job.Spec.ServiceAccountName = "default"
+	assert.Equal(t, conf.GetMatchingRule("test", job).Name, "default", "service account should not match")
+	job.Spec.ServiceAccountName = "batch"
+	job.OwnerReferences[0].Kind = "ReplicaSet"
+	assert.Equal(t, conf.GetMatchingRule("test", job).Name, "default", "owner kind should not match")
+
+	// rules are hot-reloaded, invalid rules are removed
+	conf.updateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{AMRules: `[{"name": "default", "queue": "root.other"}]`}}}, false)
+	assert.Equal(t, conf.GetMatchingRule("test", job).Queue, "root.other")
+	conf.updateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{AMRules: `[{"name": "default"`}}}, false)
+	assert.Assert(t, conf.GetMatchingRule("test", job) == nil, "invalid rules should be ignored")
+}
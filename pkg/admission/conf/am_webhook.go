@@ -0,0 +1,123 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// limits of the webhook timeout enforced by the API server
+const (
+	minWebHookTimeoutSeconds = 1
+	maxWebHookTimeoutSeconds = 30
+)
+
+func parseFailurePolicy(value string) (admissionregistrationv1.FailurePolicyType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "ignore":
+		return admissionregistrationv1.Ignore, nil
+	case "fail":
+		return admissionregistrationv1.Fail, nil
+	}
+	return "", fmt.Errorf("unknown failure policy %s", value)
+}
+
+func parseTimeoutSeconds(value string) (int32, error) {
+	timeout, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if timeout < minWebHookTimeoutSeconds || timeout > maxWebHookTimeoutSeconds {
+		return 0, fmt.Errorf("timeout %d is not between %d and %d seconds", timeout, minWebHookTimeoutSeconds, maxWebHookTimeoutSeconds)
+	}
+	return int32(timeout), nil
+}
+
+// parseLabelSelector parses a label selector in the kubectl syntax, for example "env in (dev,test),!skip". An empty
+// value selects all objects.
+func parseLabelSelector(value string) (*metav1.LabelSelector, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return &metav1.LabelSelector{}, nil
+	}
+	return metav1.ParseToLabelSelector(value)
+}
+
+// generateNamespaceRequirements converts the process and bypass namespaces into requirements on the namespace name
+// label. Only regular expressions matching a single name, like ^kube-system$, can be converted: the other bypass
+// namespaces are left to the admission controller, the process namespaces are only converted if all can be.
+func generateNamespaceRequirements(processNamespaces []*regexp.Regexp, bypassNamespaces []*regexp.Regexp) []metav1.LabelSelectorRequirement {
+	var requirements []metav1.LabelSelectorRequirement
+	if names, ok := namespaceNames(processNamespaces, true); ok && len(names) > 0 {
+		requirements = append(requirements, metav1.LabelSelectorRequirement{
+			Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: names,
+		})
+	}
+	if names, _ := namespaceNames(bypassNamespaces, false); len(names) > 0 {
+		requirements = append(requirements, metav1.LabelSelectorRequirement{
+			Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: names,
+		})
+	}
+	return requirements
+}
+
+// excludeNamespaces adds the names to the requirement that excludes namespaces by their name label, the requirement is
+// added if there is none
+func excludeNamespaces(requirements []metav1.LabelSelectorRequirement, names ...string) []metav1.LabelSelectorRequirement {
+	for i := range requirements {
+		if requirements[i].Key == v1.LabelMetadataName && requirements[i].Operator == metav1.LabelSelectorOpNotIn {
+			for _, name := range names {
+				if name != "" && !slices.Contains(requirements[i].Values, name) {
+					requirements[i].Values = append(requirements[i].Values, name)
+				}
+			}
+			return requirements
+		}
+	}
+	return excludeNamespaces(append(requirements, metav1.LabelSelectorRequirement{
+		Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn,
+	}), names...)
+}
+
+// namespaceNames returns the names matched by the regular expressions that match a single name. If all is set, the
+// conversion stops at the first regular expression that cannot be converted.
+func namespaceNames(regexes []*regexp.Regexp, all bool) ([]string, bool) {
+	var names []string
+	for _, re := range regexes {
+		pattern := re.String()
+		name := strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+		if len(name) != len(pattern)-2 || len(validation.IsDNS1123Label(name)) > 0 {
+			if all {
+				return nil, false
+			}
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, true
+}
@@ -0,0 +1,145 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package conf
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseWebHookSettings(t *testing.T) {
+	policy, err := parseFailurePolicy("fail")
+	assert.NilError(t, err)
+	assert.Equal(t, policy, admissionregistrationv1.Fail)
+	policy, err = parseFailurePolicy("Ignore")
+	assert.NilError(t, err)
+	assert.Equal(t, policy, admissionregistrationv1.Ignore)
+	_, err = parseFailurePolicy("xyz")
+	assert.ErrorContains(t, err, "unknown failure policy")
+
+	timeout, err := parseTimeoutSeconds("30")
+	assert.NilError(t, err)
+	assert.Equal(t, timeout, int32(30))
+	for _, value := range []string{"", "xyz", "0", "31"} {
+		_, err = parseTimeoutSeconds(value)
+		assert.Assert(t, err != nil, "expected error for %s", value)
+	}
+
+	selector, err := parseLabelSelector(" ")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, selector, &metav1.LabelSelector{})
+	selector, err = parseLabelSelector("team=ml,env notin (prod)")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, selector, &metav1.LabelSelector{
+		MatchLabels:      map[string]string{"team": "ml"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"prod"}}},
+	})
+	_, err = parseLabelSelector("env in prod")
+	assert.Assert(t, err != nil, "expected error for invalid selector")
+}
+
+func TestGenerateNamespaceRequirements(t *testing.T) {
+	tests := map[string]struct {
+		process  string
+		bypass   string
+		expected []metav1.LabelSelectorRequirement
+	}{
+		"none": {},
+		"literal names": {
+			process: "^ml$,^batch$",
+			bypass:  "^kube-system$",
+			expected: []metav1.LabelSelectorRequirement{
+				{Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{"ml", "batch"}},
+				{Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system"}},
+			},
+		},
+		"process pattern": {
+			process: "^ml$,^batch-",
+			bypass:  "^kube-system$,^kube-,kube-public",
+			expected: []metav1.LabelSelectorRequirement{
+				{Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system"}},
+			},
+		},
+		"unanchored": {
+			process: "ml$",
+			bypass:  "^kube.system$",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			process, err := parseRegexes(test.process)
+			assert.NilError(t, err)
+			bypass, err := parseRegexes(test.bypass)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, generateNamespaceRequirements(process, bypass), test.expected)
+		})
+	}
+}
+
+func TestGetWebHookNamespaceSelector(t *testing.T) {
+	conf := NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{
+		AMWebHookNamespaceSelector: "env=dev",
+	}}})
+	assert.DeepEqual(t, conf.GetWebHookNamespaceSelector(), &metav1.LabelSelector{
+		MatchLabels:      map[string]string{"env": "dev"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{},
+	})
+
+	// the generated requirements are added to the configured selector without changing it
+	conf = NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{
+		AMWebHookNamespaceSelector:         "env=dev",
+		AMWebHookGenerateNamespaceSelector: "true",
+	}}})
+	expected := &metav1.LabelSelector{
+		MatchLabels: map[string]string{"env": "dev"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system"}},
+		},
+	}
+	assert.DeepEqual(t, conf.GetWebHookNamespaceSelector(), expected)
+	assert.DeepEqual(t, conf.GetWebHookNamespaceSelector(), expected)
+
+	// failure policy Fail: the namespace of the scheduler and kube-system are always excluded
+	conf = NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{
+		AMWebHookNamespaceSelector: "env=dev",
+		AMWebHookFailurePolicy:     "Fail",
+	}}})
+	assert.DeepEqual(t, conf.GetWebHookNamespaceSelector(), &metav1.LabelSelector{
+		MatchLabels: map[string]string{"env": "dev"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system", conf.GetNamespace()}},
+		},
+	})
+
+	// the excluded namespaces are merged with the generated bypass namespaces
+	conf = NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{
+		AMWebHookFailurePolicy:             "Fail",
+		AMWebHookGenerateNamespaceSelector: "true",
+		AMFilteringBypassNamespaces:        "^kube-system$,^batch$",
+	}}})
+	assert.DeepEqual(t, conf.GetWebHookNamespaceSelector(), &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system", "batch", conf.GetNamespace()}},
+		},
+	})
+}
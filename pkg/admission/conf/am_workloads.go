@@ -0,0 +1,133 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WorkloadKind is a custom resource that creates pods from one or more pod templates. The user info annotation and
+// the application ID label are added to the pod templates of the workload when it is created. The operator of the
+// workload creates the pods with the user info annotation: its service account must be allowed to set the annotation,
+// as a system user or an external user, otherwise the pods are rejected.
+type WorkloadKind struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Kind     string `json:"kind"`
+	Resource string `json:"resource"`
+	// JSON pointers to the objects holding the annotations and labels of the pods, usually the metadata of a pod
+	// template. A "*" token matches every entry of a list or a map.
+	PodTemplatePaths []string `json:"podTemplatePaths"`
+	// add the same application ID label to all pod templates that do not define one
+	GenerateApplicationID bool `json:"generateApplicationId,omitempty"`
+}
+
+// builtinWorkloadKinds are the definitions of the common batch operators. They are not enabled by default: a configured
+// kind with only the group and the kind enables the built-in definition. Spark sets the application ID on its pods: it
+// is not generated.
+var builtinWorkloadKinds = []*WorkloadKind{
+	{
+		Group: "sparkoperator.k8s.io", Version: "v1beta2", Kind: "SparkApplication", Resource: "sparkapplications",
+		PodTemplatePaths: []string{"/spec/driver", "/spec/executor"},
+	},
+	{
+		Group: "kubeflow.org", Version: "v2beta1", Kind: "MPIJob", Resource: "mpijobs",
+		PodTemplatePaths:      []string{"/spec/mpiReplicaSpecs/*/template/metadata"},
+		GenerateApplicationID: true,
+	},
+	{
+		Group: "kubeflow.org", Version: "v1", Kind: "PyTorchJob", Resource: "pytorchjobs",
+		PodTemplatePaths:      []string{"/spec/pytorchReplicaSpecs/*/template/metadata"},
+		GenerateApplicationID: true,
+	},
+	{
+		Group: "ray.io", Version: "v1", Kind: "RayCluster", Resource: "rayclusters",
+		PodTemplatePaths:      []string{"/spec/headGroupSpec/template/metadata", "/spec/workerGroupSpecs/*/template/metadata"},
+		GenerateApplicationID: true,
+	},
+	{
+		Group: "argoproj.io", Version: "v1alpha1", Kind: "Workflow", Resource: "workflows",
+		PodTemplatePaths:      []string{"/spec/templates/*/metadata"},
+		GenerateApplicationID: true,
+	},
+}
+
+// parseWorkloadKinds returns the configured workload kinds, sorted by group, version and resource. A kind with only the
+// group and the kind uses the built-in definition.
+func parseWorkloadKinds(value string) ([]*WorkloadKind, error) {
+	var configured []*WorkloadKind
+	if value = strings.TrimSpace(value); value != "" {
+		if err := json.Unmarshal([]byte(value), &configured); err != nil {
+			return nil, err
+		}
+	}
+	kinds := make(map[string]*WorkloadKind)
+	for i, kind := range configured {
+		if kind == nil {
+			return nil, fmt.Errorf("workload %d is empty", i)
+		}
+		if kind.Version == "" && kind.Resource == "" && len(kind.PodTemplatePaths) == 0 {
+			builtin := getBuiltinWorkloadKind(kind.Group, kind.Kind)
+			if builtin == nil {
+				return nil, fmt.Errorf("workload %s: not a built-in workload, version, resource and pod template paths are required", kind.Kind)
+			}
+			kinds[builtin.Group+"/"+builtin.Kind] = builtin
+			continue
+		}
+		if kind.Group == "" || kind.Version == "" || kind.Kind == "" || kind.Resource == "" {
+			return nil, fmt.Errorf("workload %d: group, version, kind and resource are required", i)
+		}
+		if len(kind.PodTemplatePaths) == 0 {
+			return nil, fmt.Errorf("workload %s: no pod template paths", kind.Kind)
+		}
+		for _, path := range kind.PodTemplatePaths {
+			if !strings.HasPrefix(path, "/") || path == "/" {
+				return nil, fmt.Errorf("workload %s: invalid pod template path %s", kind.Kind, path)
+			}
+		}
+		kinds[kind.Group+"/"+kind.Kind] = kind
+	}
+	result := make([]*WorkloadKind, 0, len(kinds))
+	for _, kind := range kinds {
+		result = append(result, kind)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Group != result[j].Group {
+			return result[i].Group < result[j].Group
+		}
+		if result[i].Version != result[j].Version {
+			return result[i].Version < result[j].Version
+		}
+		return result[i].Resource < result[j].Resource
+	})
+	return result, nil
+}
+
+// getBuiltinWorkloadKind returns the built-in definition of the kind, nil if the kind is not built-in
+func getBuiltinWorkloadKind(group, kind string) *WorkloadKind {
+	for _, builtin := range builtinWorkloadKinds {
+		if builtin.Group == group && builtin.Kind == kind {
+			return builtin
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package conf
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseWorkloadKinds(t *testing.T) {
+	kinds, err := parseWorkloadKinds("")
+	assert.NilError(t, err)
+	assert.Equal(t, len(kinds), 0, "built-in kinds should not be enabled by default")
+
+	// built-in kinds are enabled by group and kind, other kinds need a full definition
+	kinds, err = parseWorkloadKinds(`[
+		{"group": "kubeflow.org", "kind": "PyTorchJob"},
+		{"group": "argoproj.io", "kind": "Workflow"},
+		{"group": "kubeflow.org", "version": "v2", "kind": "MPIJob", "resource": "mpijobs", "podTemplatePaths": ["/spec/template/metadata"]},
+		{"group": "example.com", "version": "v1", "kind": "BatchJob", "resource": "batchjobs", "podTemplatePaths": ["/spec/template/metadata"]}
+	]`)
+	assert.NilError(t, err)
+	assert.Equal(t, len(kinds), 4)
+	assert.Equal(t, kinds[0].Kind, "Workflow", "kinds should be sorted by group")
+	assert.Equal(t, kinds[1].Kind, "BatchJob", "kinds should be sorted by group")
+	assert.Equal(t, kinds[2].Resource, "pytorchjobs", "built-in definition not used")
+	assert.Equal(t, kinds[3].Version, "v2", "configured definition not used")
+
+	tests := map[string]string{
+		"invalid json":     `[{"group": "a"`,
+		"unknown built-in": `[{"group": "example.com", "kind": "BatchJob"}]`,
+		"empty kind":       `[null]`,
+		"missing resource": `[{"group": "example.com", "version": "v1", "kind": "BatchJob", "podTemplatePaths": ["/spec"]}]`,
+		"no paths":         `[{"group": "example.com", "version": "v1", "kind": "BatchJob", "resource": "batchjobs"}]`,
+		"relative path":    `[{"group": "example.com", "version": "v1", "kind": "BatchJob", "resource": "batchjobs", "podTemplatePaths": ["spec"]}]`,
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err = parseWorkloadKinds(value)
+			assert.Assert(t, err != nil, "expected error for %s", value)
+		})
+	}
+}
+
+func TestGetWorkloadKind(t *testing.T) {
+	conf := NewAdmissionControllerConf([]*v1.ConfigMap{nil, nil})
+	assert.Assert(t, conf.GetWorkloadKind("kubeflow.org", "PyTorchJob") == nil, "built-in kinds should be opt-in")
+
+	conf = NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{
+		AMWorkloads: `[{"group": "kubeflow.org", "kind": "PyTorchJob"}]`}}})
+	assert.Equal(t, conf.GetWorkloadKind("kubeflow.org", "PyTorchJob").Resource, "pytorchjobs")
+	assert.Assert(t, conf.GetWorkloadKind("batch", "Job") == nil, "core kinds are not custom workloads")
+	assert.Assert(t, conf.GetWorkloadKind("example.com", "PyTorchJob") == nil, "group should match")
+
+	// invalid configuration uses the default: no kinds
+	conf = NewAdmissionControllerConf([]*v1.ConfigMap{nil, {Data: map[string]string{AMWorkloads: `[null]`}}})
+	assert.Equal(t, len(conf.GetWorkloadKinds()), 0)
+}
@@ -139,3 +139,28 @@ func fromCronJob(req *admissionv1.AdmissionRequest) (*extractResult, error) {
 		path:        cronJobPodAnnotationsPath,
 	}, nil
 }
+
+// GetPodTemplateAnnotations returns the annotations of the pod templates of the workload, false if the kind of the
+// workload is not supported. A JobSet has a pod template per replicated job.
+func GetPodTemplateAnnotations(req *admissionv1.AdmissionRequest) ([]map[string]string, bool, error) {
+	if req.Kind.Kind == JobSet {
+		var js jobSet
+		if err := json.Unmarshal(req.Object.Raw, &js); err != nil {
+			return nil, true, err
+		}
+		annotations := make([]map[string]string, 0, len(js.Spec.ReplicatedJobs))
+		for _, rj := range js.Spec.ReplicatedJobs {
+			annotations = append(annotations, rj.Template.Spec.Template.Annotations)
+		}
+		return annotations, true, nil
+	}
+	extractFn, ok := extractors[req.Kind.Kind]
+	if !ok {
+		return nil, false, nil
+	}
+	result, err := extractFn(req)
+	if err != nil {
+		return nil, true, err
+	}
+	return []map[string]string{result.annotations}, true, nil
+}
@@ -0,0 +1,241 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/yunikorn-k8shim/pkg/admission/common"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+)
+
+// JobSet is the kind of the JobSet API (jobset.x-k8s.io), which is not part of the core Kubernetes API
+const JobSet = "JobSet"
+
+const (
+	jobPodTemplatePath    = "/spec/template/metadata"
+	jobSetPodTemplatePath = "/spec/replicatedJobs/%d/template/spec/template/metadata"
+)
+
+// jobSet contains the fields of a jobset.x-k8s.io JobSet used to derive the gang
+type jobSet struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              jobSetSpec `json:"spec,omitempty"`
+}
+
+type jobSetSpec struct {
+	ReplicatedJobs []replicatedJob `json:"replicatedJobs,omitempty"`
+}
+
+type replicatedJob struct {
+	Name     string                  `json:"name"`
+	Replicas *int32                  `json:"replicas,omitempty"`
+	Template batchv1.JobTemplateSpec `json:"template"`
+}
+
+// taskGroup is the task group definition of the task groups annotation
+type taskGroup struct {
+	Name         string                       `json:"name"`
+	MinMember    int32                        `json:"minMember"`
+	MinResource  map[string]resource.Quantity `json:"minResource,omitempty"`
+	NodeSelector map[string]string            `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration          `json:"tolerations,omitempty"`
+	Affinity     *corev1.Affinity             `json:"affinity,omitempty"`
+}
+
+// gangTemplate is a pod template of a workload, all pods created from the template form one task group
+type gangTemplate struct {
+	taskGroup    string
+	members      int32
+	template     *corev1.PodTemplateSpec
+	metadataPath string
+	// the metadata object does not exist in the pod template
+	missing bool
+}
+
+// GetAutoGangPatch derives the gang of an Indexed Job or a JobSet and returns the patch that adds the application ID,
+// task groups and scheduling policy to the pod templates of the workload. A task group is created per pod template:
+// the minMember is the number of pods running in parallel and the minResource the requests of the pod template.
+// Returns nil if the workload is not supported, already defines a gang, or has no name to derive the application ID.
+func GetAutoGangPatch(req *admissionv1.AdmissionRequest, namespace string, schedulingPolicy string) ([]common.PatchOperation, error) {
+	var name string
+	var templates []gangTemplate
+	switch req.Kind.Kind {
+	case Job:
+		var job batchv1.Job
+		if err := json.Unmarshal(req.Object.Raw, &job); err != nil {
+			return nil, err
+		}
+		if job.Spec.CompletionMode == nil || *job.Spec.CompletionMode != batchv1.IndexedCompletion {
+			return nil, nil
+		}
+		name = job.Name
+		templates = append(templates, gangTemplate{
+			taskGroup:    job.Name,
+			members:      getJobParallelism(job.Spec),
+			template:     &job.Spec.Template,
+			metadataPath: jobPodTemplatePath,
+		})
+	case JobSet:
+		var js jobSet
+		if err := json.Unmarshal(req.Object.Raw, &js); err != nil {
+			return nil, err
+		}
+		name = js.Name
+		for i := range js.Spec.ReplicatedJobs {
+			rj := &js.Spec.ReplicatedJobs[i]
+			replicas := int32(1)
+			if rj.Replicas != nil {
+				replicas = *rj.Replicas
+			}
+			templates = append(templates, gangTemplate{
+				taskGroup:    rj.Name,
+				members:      replicas * getJobParallelism(rj.Template.Spec),
+				template:     &rj.Template.Spec.Template,
+				metadataPath: fmt.Sprintf(jobSetPodTemplatePath, i),
+			})
+		}
+	default:
+		return nil, nil
+	}
+	// the pods need a shared application ID: it is derived from the name of the workload
+	if name == "" || len(templates) == 0 {
+		return nil, nil
+	}
+	// a workload without metadata in a pod template needs the whole object added, not only its annotations and labels
+	var raw interface{}
+	if err := json.Unmarshal(req.Object.Raw, &raw); err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		tokens := strings.Split(strings.TrimPrefix(templates[i].metadataPath, "/"), "/")
+		found := findPodTemplates(raw, tokens, "", nil)
+		templates[i].missing = len(found) == 1 && found[0].missing
+	}
+	taskGroups := make([]taskGroup, 0, len(templates))
+	for _, t := range templates {
+		if t.template.Annotations[constants.AnnotationTaskGroups] != "" {
+			return nil, nil
+		}
+		if t.members <= 0 {
+			continue
+		}
+		taskGroups = append(taskGroups, taskGroup{
+			Name:         t.taskGroup,
+			MinMember:    t.members,
+			MinResource:  getPodTemplateRequests(t.template),
+			NodeSelector: t.template.Spec.NodeSelector,
+			Tolerations:  t.template.Spec.Tolerations,
+			Affinity:     t.template.Spec.Affinity,
+		})
+	}
+	if len(taskGroups) == 0 {
+		return nil, nil
+	}
+	taskGroupsDef, err := json.Marshal(taskGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	appID := getWorkloadApplicationID(namespace, name)
+	patch := make([]common.PatchOperation, 0, 2*len(templates))
+	for _, t := range templates {
+		annotations := make(map[string]string)
+		maps.Copy(annotations, t.template.Annotations)
+		annotations[constants.AnnotationTaskGroups] = string(taskGroupsDef)
+		annotations[constants.AnnotationTaskGroupName] = t.taskGroup
+		if schedulingPolicy != "" && annotations[constants.AnnotationSchedulingPolicyParam] == "" {
+			annotations[constants.AnnotationSchedulingPolicyParam] = schedulingPolicy
+		}
+		var labels map[string]string
+		if t.template.Labels[constants.CanonicalLabelApplicationID] == "" && t.template.Labels[constants.LabelApplicationID] == "" {
+			labels = make(map[string]string)
+			maps.Copy(labels, t.template.Labels)
+			labels[constants.CanonicalLabelApplicationID] = appID
+		}
+		if t.missing {
+			patch = append(patch, common.PatchOperation{
+				Op:    "add",
+				Path:  t.metadataPath,
+				Value: map[string]interface{}{"annotations": annotations, "labels": labels},
+			})
+			continue
+		}
+		patch = append(patch, common.PatchOperation{
+			Op:    "add",
+			Path:  t.metadataPath + "/annotations",
+			Value: annotations,
+		})
+		if labels != nil {
+			patch = append(patch, common.PatchOperation{
+				Op:    "add",
+				Path:  t.metadataPath + "/labels",
+				Value: labels,
+			})
+		}
+	}
+	return patch, nil
+}
+
+// getWorkloadApplicationID returns the application ID shared by the pods of a workload
+func getWorkloadApplicationID(namespace string, name string) string {
+	return fmt.Sprintf("%.63s", namespace+"-"+name)
+}
+
+// getJobParallelism returns the number of pods of the job that run in parallel
+func getJobParallelism(spec batchv1.JobSpec) int32 {
+	parallelism := int32(1)
+	if spec.Parallelism != nil {
+		parallelism = *spec.Parallelism
+	}
+	if spec.Completions != nil {
+		parallelism = min(parallelism, *spec.Completions)
+	}
+	return parallelism
+}
+
+// getPodTemplateRequests returns the resources requested by a pod created from the template: the sum of the
+// requests of the containers, or the largest request of an init container if that is higher.
+func getPodTemplateRequests(template *corev1.PodTemplateSpec) map[string]resource.Quantity {
+	requests := make(map[string]resource.Quantity)
+	for _, container := range template.Spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			total := requests[string(name)]
+			total.Add(quantity)
+			requests[string(name)] = total
+		}
+	}
+	for _, container := range template.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, ok := requests[string(name)]; !ok || quantity.Cmp(current) > 0 {
+				requests[string(name)] = quantity.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
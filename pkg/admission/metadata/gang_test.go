@@ -0,0 +1,245 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metadata
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/apache/yunikorn-k8shim/pkg/admission/common"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+)
+
+func getGangPodTemplate() v1.PodTemplateSpec {
+	return v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"key": "value"},
+		},
+		Spec: v1.PodSpec{
+			NodeSelector: map[string]string{"zone": "a"},
+			Tolerations:  []v1.Toleration{{Key: "gpu", Operator: v1.TolerationOpExists}},
+			InitContainers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			}}}},
+			Containers: []v1.Container{
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("1"),
+					v1.ResourceMemory: resource.MustParse("1Gi"),
+				}}},
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("500m"),
+					v1.ResourceMemory: resource.MustParse("1Gi"),
+				}}},
+			},
+		},
+	}
+}
+
+func getIndexedJob(name string, parallelism, completions int32) *batchv1.Job {
+	indexed := batchv1.IndexedCompletion
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: batchv1.JobSpec{
+			CompletionMode: &indexed,
+			Parallelism:    &parallelism,
+			Completions:    &completions,
+			Template:       getGangPodTemplate(),
+		},
+	}
+}
+
+func getPatchTaskGroups(t *testing.T, value interface{}) []taskGroup {
+	annotations, ok := value.(map[string]string)
+	assert.Assert(t, ok, "patch value is not a map")
+	var taskGroups []taskGroup
+	assert.NilError(t, json.Unmarshal([]byte(annotations[constants.AnnotationTaskGroups]), &taskGroups))
+	return taskGroups
+}
+
+func TestGetAutoGangPatchJob(t *testing.T) {
+	req := getAdmissionRequest(t, getIndexedJob("job", 4, 3), Job)
+	patch, err := GetAutoGangPatch(req, "ns", "placeholderTimeoutInSeconds=60")
+	assert.NilError(t, err)
+	assert.Equal(t, len(patch), 2)
+	assert.Equal(t, patch[0].Path, "/spec/template/metadata/annotations")
+	annotations, ok := patch[0].Value.(map[string]string)
+	assert.Assert(t, ok)
+	assert.Equal(t, annotations["key"], "value")
+	assert.Equal(t, annotations[constants.AnnotationTaskGroupName], "job")
+	assert.Equal(t, annotations[constants.AnnotationSchedulingPolicyParam], "placeholderTimeoutInSeconds=60")
+	taskGroups := getPatchTaskGroups(t, patch[0].Value)
+	assert.Equal(t, len(taskGroups), 1)
+	assert.Equal(t, taskGroups[0].Name, "job")
+	assert.Equal(t, taskGroups[0].MinMember, int32(3), "minMember should be limited by the completions")
+	cpu := taskGroups[0].MinResource["cpu"]
+	assert.Equal(t, cpu.MilliValue(), int64(1500))
+	memory := taskGroups[0].MinResource["memory"]
+	assert.Equal(t, memory.String(), "4Gi", "init container request should be used")
+	assert.DeepEqual(t, taskGroups[0].NodeSelector, map[string]string{"zone": "a"})
+	assert.Equal(t, len(taskGroups[0].Tolerations), 1)
+	assert.Equal(t, patch[1].Path, "/spec/template/metadata/labels")
+	labels, ok := patch[1].Value.(map[string]string)
+	assert.Assert(t, ok)
+	assert.Equal(t, labels[constants.CanonicalLabelApplicationID], "ns-job")
+
+	// existing application ID and scheduling policy are kept
+	job := getIndexedJob("job", 2, 2)
+	job.Spec.Template.Labels = map[string]string{constants.CanonicalLabelApplicationID: "app-1"}
+	job.Spec.Template.Annotations[constants.AnnotationSchedulingPolicyParam] = "gangSchedulingStyle=Hard"
+	patch, err = GetAutoGangPatch(getAdmissionRequest(t, job, Job), "ns", "placeholderTimeoutInSeconds=60")
+	assert.NilError(t, err)
+	assert.Equal(t, len(patch), 1)
+	annotations, ok = patch[0].Value.(map[string]string)
+	assert.Assert(t, ok)
+	assert.Equal(t, annotations[constants.AnnotationSchedulingPolicyParam], "gangSchedulingStyle=Hard")
+
+	// existing gang
+	job = getIndexedJob("job", 2, 2)
+	job.Spec.Template.Annotations[constants.AnnotationTaskGroups] = "[]"
+	patch, err = GetAutoGangPatch(getAdmissionRequest(t, job, Job), "ns", "")
+	assert.NilError(t, err)
+	assert.Assert(t, patch == nil)
+
+	// non indexed job
+	job = getIndexedJob("job", 2, 2)
+	job.Spec.CompletionMode = nil
+	patch, err = GetAutoGangPatch(getAdmissionRequest(t, job, Job), "ns", "")
+	assert.NilError(t, err)
+	assert.Assert(t, patch == nil)
+
+	// generated name
+	patch, err = GetAutoGangPatch(getAdmissionRequest(t, getIndexedJob("", 2, 2), Job), "ns", "")
+	assert.NilError(t, err)
+	assert.Assert(t, patch == nil)
+
+	// unsupported kind
+	patch, err = GetAutoGangPatch(getAdmissionRequest(t, getIndexedJob("job", 2, 2), Deployment), "ns", "")
+	assert.NilError(t, err)
+	assert.Assert(t, patch == nil)
+
+	// invalid object
+	req = getAdmissionRequest(t, nil, Job)
+	req.Object.Raw = []byte{0, 1, 2, 3, 4}
+	_, err = GetAutoGangPatch(req, "ns", "")
+	assert.ErrorContains(t, err, "invalid character")
+}
+
+func TestGetAutoGangPatchJobSet(t *testing.T) {
+	replicas := int32(2)
+	leader := getIndexedJob("", 1, 1)
+	workers := getIndexedJob("", 4, 8)
+	js := &jobSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "training"},
+		Spec: jobSetSpec{ReplicatedJobs: []replicatedJob{
+			{Name: "leader", Template: batchv1.JobTemplateSpec{Spec: leader.Spec}},
+			{Name: "workers", Replicas: &replicas, Template: batchv1.JobTemplateSpec{Spec: workers.Spec}},
+		}},
+	}
+	patch, err := GetAutoGangPatch(getAdmissionRequest(t, js, JobSet), "ns", "")
+	assert.NilError(t, err)
+	assert.Equal(t, len(patch), 4)
+	assert.Equal(t, patch[0].Path, "/spec/replicatedJobs/0/template/spec/template/metadata/annotations")
+	assert.Equal(t, patch[1].Path, "/spec/replicatedJobs/0/template/spec/template/metadata/labels")
+	assert.Equal(t, patch[2].Path, "/spec/replicatedJobs/1/template/spec/template/metadata/annotations")
+	assert.Equal(t, patch[3].Path, "/spec/replicatedJobs/1/template/spec/template/metadata/labels")
+	annotations, ok := patch[2].Value.(map[string]string)
+	assert.Assert(t, ok)
+	assert.Equal(t, annotations[constants.AnnotationTaskGroupName], "workers")
+	taskGroups := getPatchTaskGroups(t, patch[2].Value)
+	assert.Equal(t, len(taskGroups), 2)
+	assert.Equal(t, taskGroups[0].Name, "leader")
+	assert.Equal(t, taskGroups[0].MinMember, int32(1))
+	assert.Equal(t, taskGroups[1].Name, "workers")
+	assert.Equal(t, taskGroups[1].MinMember, int32(8))
+	assert.DeepEqual(t, getPatchTaskGroups(t, patch[0].Value), taskGroups)
+	labels, ok := patch[1].Value.(map[string]string)
+	assert.Assert(t, ok)
+	assert.Equal(t, labels[constants.CanonicalLabelApplicationID], "ns-training")
+}
+
+// applyAddPatch applies the add operations of the patch to the raw object, the parent of each path must exist
+func applyAddPatch(t *testing.T, raw []byte, patch []common.PatchOperation) []byte {
+	var obj interface{}
+	assert.NilError(t, json.Unmarshal(raw, &obj))
+	for _, op := range patch {
+		assert.Equal(t, op.Op, "add")
+		value, err := json.Marshal(op.Value)
+		assert.NilError(t, err)
+		var added interface{}
+		assert.NilError(t, json.Unmarshal(value, &added))
+		tokens := strings.Split(strings.TrimPrefix(op.Path, "/"), "/")
+		node := obj
+		for _, token := range tokens[:len(tokens)-1] {
+			switch parent := node.(type) {
+			case map[string]interface{}:
+				node = parent[token]
+			case []interface{}:
+				i, err := strconv.Atoi(token)
+				assert.NilError(t, err)
+				node = parent[i]
+			}
+			assert.Assert(t, node != nil, "path %s does not exist", op.Path)
+		}
+		parent, ok := node.(map[string]interface{})
+		assert.Assert(t, ok, "parent of %s is not an object", op.Path)
+		parent[tokens[len(tokens)-1]] = added
+	}
+	result, err := json.Marshal(obj)
+	assert.NilError(t, err)
+	return result
+}
+
+func TestGetAutoGangPatchJobSetNoMetadata(t *testing.T) {
+	raw := `{"apiVersion": "jobset.x-k8s.io/v1alpha2", "kind": "JobSet", "metadata": {"name": "training"},
+		"spec": {"replicatedJobs": [
+			{"name": "leader", "template": {"spec": {"completionMode": "Indexed", "template": {"spec": {"containers": [{"name": "c"}]}}}}},
+			{"name": "workers", "replicas": 2, "template": {"spec": {"completionMode": "Indexed", "parallelism": 2,
+				"template": {"metadata": {"labels": {"app": "workers"}}, "spec": {"containers": [{"name": "c"}]}}}}}
+		]}}`
+	req := &admissionv1.AdmissionRequest{
+		Object: runtime.RawExtension{Raw: []byte(raw)},
+		Kind:   metav1.GroupVersionKind{Kind: JobSet},
+	}
+	patch, err := GetAutoGangPatch(req, "ns", "")
+	assert.NilError(t, err)
+	assert.Equal(t, len(patch), 3)
+	assert.Equal(t, patch[0].Path, "/spec/replicatedJobs/0/template/spec/template/metadata")
+	assert.Equal(t, patch[1].Path, "/spec/replicatedJobs/1/template/spec/template/metadata/annotations")
+	assert.Equal(t, patch[2].Path, "/spec/replicatedJobs/1/template/spec/template/metadata/labels")
+
+	var js jobSet
+	assert.NilError(t, json.Unmarshal(applyAddPatch(t, req.Object.Raw, patch), &js))
+	for i, name := range []string{"leader", "workers"} {
+		template := js.Spec.ReplicatedJobs[i].Template.Spec.Template
+		assert.Equal(t, template.Annotations[constants.AnnotationTaskGroupName], name)
+		assert.Assert(t, template.Annotations[constants.AnnotationTaskGroups] != "")
+		assert.Equal(t, template.Labels[constants.CanonicalLabelApplicationID], "ns-training")
+	}
+	assert.Equal(t, js.Spec.ReplicatedJobs[1].Template.Spec.Template.Labels["app"], "workers")
+}
@@ -134,22 +134,31 @@ func (u *UserGroupAnnotationHandler) GetPatchForPod(annotations map[string]strin
 	return patchOp, nil
 }
 
+// GetUserInfoAnnotation returns the value of the user info annotation for the user and groups
+func (u *UserGroupAnnotationHandler) GetUserInfoAnnotation(user string, groups []string) (string, error) {
+	userGroups := &si.UserGroupInformation{
+		User:   user,
+		Groups: groups,
+	}
+	jsonBytes, err := json.Marshal(userGroups)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
 func (u *UserGroupAnnotationHandler) getPatchOperation(annotations map[string]string, path, user string, groups []string) (*common.PatchOperation, error) {
 	newAnnotations := make(map[string]string)
 	for k, v := range annotations {
 		newAnnotations[k] = v
 	}
 
-	userGroups := &si.UserGroupInformation{
-		User:   user,
-		Groups: groups,
-	}
-	jsonBytes, err := json.Marshal(userGroups)
+	userInfo, err := u.GetUserInfoAnnotation(user, groups)
 	if err != nil {
 		return nil, err
 	}
 
-	newAnnotations[common.UserInfoAnnotation] = string(jsonBytes)
+	newAnnotations[common.UserInfoAnnotation] = userInfo
 
 	return &common.PatchOperation{
 		Op:    "add",
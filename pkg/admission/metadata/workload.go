@@ -0,0 +1,190 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metadata
+
+import (
+	"encoding/json"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"github.com/apache/yunikorn-k8shim/pkg/admission/common"
+	"github.com/apache/yunikorn-k8shim/pkg/admission/conf"
+)
+
+const anyToken = "*"
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Workload is a custom workload with the pod templates found at the paths of its kind
+type Workload struct {
+	Name      string
+	Templates []*PodTemplate
+}
+
+// PodTemplate is the object holding the annotations and labels of the pods created from a template of a workload
+type PodTemplate struct {
+	Path        string
+	Annotations map[string]string
+	Labels      map[string]string
+	// the object does not exist in the workload, its parent does
+	missing bool
+}
+
+type workloadMeta struct {
+	Name string `json:"name"`
+}
+
+// GetWorkload returns the pod templates of a custom workload. Paths that do not resolve in the workload are skipped.
+func GetWorkload(req *admissionv1.AdmissionRequest, kind *conf.WorkloadKind) (*Workload, error) {
+	var obj struct {
+		Metadata workloadMeta `json:"metadata"`
+	}
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := json.Unmarshal(req.Object.Raw, &raw); err != nil {
+		return nil, err
+	}
+	workload := &Workload{Name: obj.Metadata.Name}
+	for _, path := range kind.PodTemplatePaths {
+		tokens := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		workload.Templates = findPodTemplates(raw, tokens, "", workload.Templates)
+	}
+	return workload, nil
+}
+
+func findPodTemplates(node interface{}, tokens []string, path string, templates []*PodTemplate) []*PodTemplate {
+	if len(tokens) == 0 {
+		values, ok := node.(map[string]interface{})
+		if !ok {
+			return templates
+		}
+		return append(templates, &PodTemplate{
+			Path:        path,
+			Annotations: toStringMap(values["annotations"]),
+			Labels:      toStringMap(values["labels"]),
+		})
+	}
+	token, rest := tokens[0], tokens[1:]
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if token == anyToken {
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				templates = findPodTemplates(value[key], rest, path+"/"+pointerEscaper.Replace(key), templates)
+			}
+			return templates
+		}
+		child, ok := value[token]
+		if !ok || child == nil {
+			if len(rest) == 0 {
+				// the pods have no annotations or labels yet: the whole object is added
+				return append(templates, &PodTemplate{Path: path + "/" + token, missing: true})
+			}
+			return templates
+		}
+		return findPodTemplates(child, rest, path+"/"+token, templates)
+	case []interface{}:
+		if token == anyToken {
+			for i := range value {
+				templates = findPodTemplates(value[i], rest, path+"/"+strconv.Itoa(i), templates)
+			}
+			return templates
+		}
+		if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(value) {
+			return findPodTemplates(value[i], rest, path+"/"+token, templates)
+		}
+	}
+	return templates
+}
+
+func toStringMap(value interface{}) map[string]string {
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	result := make(map[string]string, len(values))
+	for k, v := range values {
+		if s, ok := v.(string); ok {
+			result[k] = s
+		}
+	}
+	return result
+}
+
+// GetApplicationID returns the application ID shared by the pods of the workload, empty if the workload has no name
+func (w *Workload) GetApplicationID(namespace string) string {
+	if w.Name == "" {
+		return ""
+	}
+	return getWorkloadApplicationID(namespace, w.Name)
+}
+
+// GetPatch returns the patch that adds the annotations and labels to the pod template. Values already set on the
+// template are not changed.
+func (t *PodTemplate) GetPatch(annotations map[string]string, labels map[string]string) []common.PatchOperation {
+	newAnnotations := mergeMissing(t.Annotations, annotations)
+	newLabels := mergeMissing(t.Labels, labels)
+	if t.missing {
+		value := make(map[string]interface{})
+		if newAnnotations != nil {
+			value["annotations"] = newAnnotations
+		}
+		if newLabels != nil {
+			value["labels"] = newLabels
+		}
+		if len(value) == 0 {
+			return nil
+		}
+		return []common.PatchOperation{{Op: "add", Path: t.Path, Value: value}}
+	}
+	var patch []common.PatchOperation
+	if newAnnotations != nil {
+		patch = append(patch, common.PatchOperation{Op: "add", Path: t.Path + "/annotations", Value: newAnnotations})
+	}
+	if newLabels != nil {
+		patch = append(patch, common.PatchOperation{Op: "add", Path: t.Path + "/labels", Value: newLabels})
+	}
+	return patch
+}
+
+// mergeMissing returns the current values with the added values that are not set, nil if nothing is added
+func mergeMissing(current map[string]string, added map[string]string) map[string]string {
+	var result map[string]string
+	for k, v := range added {
+		if _, ok := current[k]; ok {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string, len(current)+len(added))
+			maps.Copy(result, current)
+		}
+		result[k] = v
+	}
+	return result
+}
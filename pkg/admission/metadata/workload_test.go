@@ -0,0 +1,100 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package metadata
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/apache/yunikorn-k8shim/pkg/admission/common"
+	"github.com/apache/yunikorn-k8shim/pkg/admission/conf"
+)
+
+const testRayCluster = `{
+	"metadata": {"name": "ray"},
+	"spec": {
+		"headGroupSpec": {"template": {"metadata": {"annotations": {"key": "value"}, "labels": {"app": "ray"}}}},
+		"workerGroupSpecs": [
+			{"template": {"metadata": {}}},
+			{"template": {"spec": {}}}
+		]
+	}
+}`
+
+func TestGetWorkload(t *testing.T) {
+	kind := &conf.WorkloadKind{Kind: "RayCluster", PodTemplatePaths: []string{
+		"/spec/headGroupSpec/template/metadata", "/spec/workerGroupSpecs/*/template/metadata", "/spec/missing/template/metadata"}}
+	req := &admissionv1.AdmissionRequest{Object: runtime.RawExtension{Raw: []byte(testRayCluster)}}
+	workload, err := GetWorkload(req, kind)
+	assert.NilError(t, err)
+	assert.Equal(t, workload.Name, "ray")
+	assert.Equal(t, workload.GetApplicationID("default"), "default-ray")
+	assert.Equal(t, len(workload.Templates), 3, "unresolved paths should be skipped")
+	assert.Equal(t, workload.Templates[0].Path, "/spec/headGroupSpec/template/metadata")
+	assert.DeepEqual(t, workload.Templates[0].Annotations, map[string]string{"key": "value"})
+	assert.DeepEqual(t, workload.Templates[0].Labels, map[string]string{"app": "ray"})
+	assert.Equal(t, workload.Templates[1].Path, "/spec/workerGroupSpecs/0/template/metadata")
+	assert.Equal(t, workload.Templates[2].Path, "/spec/workerGroupSpecs/1/template/metadata")
+
+	// map entries are matched in order of their keys
+	kind.PodTemplatePaths = []string{"/spec/replicaSpecs/*/template/metadata"}
+	req.Object.Raw = []byte(`{"spec": {"replicaSpecs": {"Worker": {"template": {}}, "Launcher": {"template": {}}, "a/b": {"template": {}}}}}`)
+	workload, err = GetWorkload(req, kind)
+	assert.NilError(t, err)
+	assert.Equal(t, workload.GetApplicationID("default"), "")
+	assert.Equal(t, len(workload.Templates), 3)
+	assert.Equal(t, workload.Templates[0].Path, "/spec/replicaSpecs/Launcher/template/metadata")
+	assert.Equal(t, workload.Templates[1].Path, "/spec/replicaSpecs/Worker/template/metadata")
+	assert.Equal(t, workload.Templates[2].Path, "/spec/replicaSpecs/a~1b/template/metadata")
+
+	req.Object.Raw = []byte("invalid")
+	_, err = GetWorkload(req, kind)
+	assert.Assert(t, err != nil, "invalid object should fail")
+}
+
+func TestPodTemplateGetPatch(t *testing.T) {
+	template := &PodTemplate{
+		Path:        "/spec/template/metadata",
+		Annotations: map[string]string{"key": "value"},
+		Labels:      map[string]string{"app": "test"},
+	}
+	patch := template.GetPatch(map[string]string{"key": "other", "user": "info"}, map[string]string{"app": "other"})
+	assert.DeepEqual(t, patch, []common.PatchOperation{{
+		Op:    "add",
+		Path:  "/spec/template/metadata/annotations",
+		Value: map[string]string{"key": "value", "user": "info"},
+	}})
+	assert.Equal(t, len(template.GetPatch(nil, nil)), 0)
+
+	// missing metadata is added as a whole
+	template = &PodTemplate{Path: "/spec/template/metadata", missing: true}
+	patch = template.GetPatch(map[string]string{"user": "info"}, map[string]string{"app": "test"})
+	assert.DeepEqual(t, patch, []common.PatchOperation{{
+		Op:   "add",
+		Path: "/spec/template/metadata",
+		Value: map[string]interface{}{
+			"annotations": map[string]string{"user": "info"},
+			"labels":      map[string]string{"app": "test"},
+		},
+	}})
+	assert.Equal(t, len(template.GetPatch(nil, nil)), 0)
+}
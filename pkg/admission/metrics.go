@@ -0,0 +1,59 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package admission
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+)
+
+// AdmissionSubsystem - subsystem name used by the admission controller
+const AdmissionSubsystem = "admission_controller"
+
+// decisions recorded in audit mode
+const (
+	auditDecisionAllow  = "allow"
+	auditDecisionPatch  = "patch"
+	auditDecisionReject = "reject"
+)
+
+var (
+	admissionMetricsOnce sync.Once
+	auditDecisions       = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: constants.MetricsNamespace,
+			Subsystem: AdmissionSubsystem,
+			Name:      "audit_decisions_total",
+			Help:      "Number of admission decisions recorded but not applied in audit mode, by decision.",
+		}, []string{"decision"})
+)
+
+// initAdmissionMetrics registers the metrics of the admission controller on the default prometheus registry
+func initAdmissionMetrics() {
+	admissionMetricsOnce.Do(func() {
+		if err := prometheus.Register(auditDecisions); err != nil {
+			log.Log(log.Admission).Warn("failed to register admission controller metrics", zap.Error(err))
+		}
+	})
+}
@@ -44,7 +44,7 @@ const (
 	TRUE
 )
 
-// nsFlags defines the two flags that can be set on the namespace.
+// nsFlags defines the flags that can be set on the namespace.
 // It needs to support a tri-state value showing presence besides true/false.
 // UNSET: not present
 // FALSE: false
@@ -52,6 +52,8 @@ const (
 type nsFlags struct {
 	enableYuniKorn triState
 	generateAppID  triState
+	autoGang       triState
+	audit          triState
 }
 
 // NewNamespaceCache creates a new cache and registers the handler for the cache with the Informer.
@@ -92,6 +94,30 @@ func (nsc *NamespaceCache) generateAppID(name string) triState {
 	return flag.generateAppID
 }
 
+// autoGang returns the value for the autoGang flag (tri-state UNSET, TRUE or FALSE) for the namespace.
+func (nsc *NamespaceCache) autoGang(name string) triState {
+	nsc.RLock()
+	defer nsc.RUnlock()
+
+	flag, ok := nsc.nameSpaces[name]
+	if !ok {
+		return UNSET
+	}
+	return flag.autoGang
+}
+
+// audit returns the value for the audit flag (tri-state UNSET, TRUE or FALSE) for the namespace.
+func (nsc *NamespaceCache) audit(name string) triState {
+	nsc.RLock()
+	defer nsc.RUnlock()
+
+	flag, ok := nsc.nameSpaces[name]
+	if !ok {
+		return UNSET
+	}
+	return flag.audit
+}
+
 // namespaceExists for test only to see if the namespace has been added to the cache or not.
 func (nsc *NamespaceCache) namespaceExists(name string) bool {
 	nsc.RLock()
@@ -151,12 +177,14 @@ func (h *namespaceUpdateHandler) OnDelete(obj interface{}) {
 // Converts the presence and content into a tri-state nsFlags object containing all nsFlags.
 func getAnnotationValues(ns *v1.Namespace) nsFlags {
 	if ns == nil {
-		return nsFlags{UNSET, UNSET}
+		return nsFlags{UNSET, UNSET, UNSET, UNSET}
 	}
 
 	return nsFlags{
 		enableYuniKorn: getAnnotationValue(ns.Annotations, constants.AnnotationEnableYuniKorn),
 		generateAppID:  getAnnotationValue(ns.Annotations, constants.AnnotationGenerateAppID),
+		autoGang:       getAnnotationValue(ns.Annotations, constants.AnnotationAutoGang),
+		audit:          getAnnotationValue(ns.Annotations, constants.AnnotationAdmissionAudit),
 	}
 }
 
@@ -52,6 +52,19 @@ func TestFlags(t *testing.T) {
 	cache.nameSpaces["generate-set"] = nsFlags{
 		enableYuniKorn: UNSET,
 		generateAppID:  TRUE,
+		autoGang:       UNSET,
+	}
+	cache.nameSpaces["auto-gang-set"] = nsFlags{
+		enableYuniKorn: UNSET,
+		generateAppID:  UNSET,
+		autoGang:       TRUE,
+		audit:          UNSET,
+	}
+	cache.nameSpaces["audit-set"] = nsFlags{
+		enableYuniKorn: UNSET,
+		generateAppID:  UNSET,
+		autoGang:       UNSET,
+		audit:          TRUE,
 	}
 
 	assert.Equal(t, UNSET, cache.enableYuniKorn(""), "not in cache")
@@ -64,6 +77,12 @@ func TestFlags(t *testing.T) {
 	assert.Equal(t, TRUE, cache.generateAppID("exist-set"), "exist generate set")
 	assert.Equal(t, UNSET, cache.enableYuniKorn("generate-set"), "only generate set")
 	assert.Equal(t, TRUE, cache.generateAppID("generate-set"), "generate should be set")
+	assert.Equal(t, UNSET, cache.autoGang(""), "not in cache")
+	assert.Equal(t, UNSET, cache.autoGang("generate-set"), "only generate set")
+	assert.Equal(t, TRUE, cache.autoGang("auto-gang-set"), "auto gang should be set")
+	assert.Equal(t, UNSET, cache.audit(""), "not in cache")
+	assert.Equal(t, UNSET, cache.audit("auto-gang-set"), "only auto gang set")
+	assert.Equal(t, TRUE, cache.audit("audit-set"), "audit should be set")
 }
 
 func TestNamespaceHandlers(t *testing.T) {
@@ -141,7 +160,7 @@ func TestGetAnnotations(t *testing.T) {
 	}{
 		"nil ns": {
 			ns: nil,
-			f:  nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET},
+			f:  nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET, autoGang: UNSET, audit: UNSET},
 		},
 		"empty annotations": {
 			ns: &v1.Namespace{
@@ -149,7 +168,7 @@ func TestGetAnnotations(t *testing.T) {
 					Name: testNS,
 				},
 			},
-			f: nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET},
+			f: nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET, autoGang: UNSET, audit: UNSET},
 		},
 		"invalid values": {
 			ns: &v1.Namespace{
@@ -161,7 +180,7 @@ func TestGetAnnotations(t *testing.T) {
 					},
 				},
 			},
-			f: nsFlags{enableYuniKorn: FALSE, generateAppID: FALSE},
+			f: nsFlags{enableYuniKorn: FALSE, generateAppID: FALSE, autoGang: UNSET, audit: UNSET},
 		},
 		"true values": {
 			ns: &v1.Namespace{
@@ -173,7 +192,7 @@ func TestGetAnnotations(t *testing.T) {
 					},
 				},
 			},
-			f: nsFlags{enableYuniKorn: TRUE, generateAppID: TRUE},
+			f: nsFlags{enableYuniKorn: TRUE, generateAppID: TRUE, autoGang: UNSET, audit: UNSET},
 		},
 		"distinct values": {
 			ns: &v1.Namespace{
@@ -185,7 +204,29 @@ func TestGetAnnotations(t *testing.T) {
 					},
 				},
 			},
-			f: nsFlags{enableYuniKorn: FALSE, generateAppID: TRUE},
+			f: nsFlags{enableYuniKorn: FALSE, generateAppID: TRUE, autoGang: UNSET, audit: UNSET},
+		},
+		"auto gang": {
+			ns: &v1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNS,
+					Annotations: map[string]string{
+						constants.AnnotationAutoGang: "true",
+					},
+				},
+			},
+			f: nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET, autoGang: TRUE, audit: UNSET},
+		},
+		"audit set": {
+			ns: &v1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNS,
+					Annotations: map[string]string{
+						constants.AnnotationAdmissionAudit: "false",
+					},
+				},
+			},
+			f: nsFlags{enableYuniKorn: UNSET, generateAppID: UNSET, autoGang: UNSET, audit: FALSE},
 		},
 	}
 	for name, test := range tests {
@@ -193,6 +234,8 @@ func TestGetAnnotations(t *testing.T) {
 			f := getAnnotationValues(test.ns)
 			assert.Equal(t, f.enableYuniKorn, test.f.enableYuniKorn, "enable value incorrect")
 			assert.Equal(t, f.generateAppID, test.f.generateAppID, "enable value incorrect")
+			assert.Equal(t, f.autoGang, test.f.autoGang, "auto gang value incorrect")
+			assert.Equal(t, f.audit, test.f.audit, "audit value incorrect")
 		})
 	}
 }
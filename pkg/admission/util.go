@@ -23,13 +23,15 @@ import (
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/apache/yunikorn-k8shim/pkg/admission/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/yunikorn-k8shim/pkg/log"
 )
 
-func updatePodLabel(pod *v1.Pod, namespace string, generateUniqueAppIds bool) map[string]string {
+func updatePodLabel(pod *v1.Pod, namespace string, generateUniqueAppIds bool, enablePodGroups bool, rule *conf.AdmissionRule) map[string]string {
 	result := make(map[string]string)
 	for k, v := range pod.Labels {
 		result[k] = v
@@ -39,7 +41,26 @@ func updatePodLabel(pod *v1.Pod, namespace string, generateUniqueAppIds bool) ma
 	sparkAppID := utils.GetPodLabelValue(pod, constants.SparkLabelAppID)
 	labelAppID := utils.GetPodLabelValue(pod, constants.LabelApplicationID)
 	annotationAppID := utils.GetPodAnnotationValue(pod, constants.AnnotationApplicationID)
-	if canonicalAppID == "" && sparkAppID == "" && labelAppID == "" && annotationAppID == "" {
+	var podGroup string
+	if enablePodGroups {
+		podGroup = utils.GetPodLabelValue(pod, constants.LabelPodGroup)
+	}
+	controller := metav1.GetControllerOf(pod)
+	if canonicalAppID == "" && sparkAppID == "" && labelAppID == "" && annotationAppID == "" && podGroup != "" {
+		// members of a PodGroup form one application
+		generatedID := utils.GeneratePodGroupApplicationID(namespace, podGroup)
+		result[constants.CanonicalLabelApplicationID] = generatedID
+		// Deprecated: After 1.7.0, admission controller will only add canonical label if application ID was not set
+		result[constants.LabelApplicationID] = generatedID
+	} else if canonicalAppID == "" && sparkAppID == "" && labelAppID == "" && annotationAppID == "" &&
+		rule != nil && rule.ApplicationID == conf.ApplicationIDFromController && controller != nil && controller.UID != "" {
+		// a rule groups the pods of the immediate controller in one application:
+		// application ID convention: ${CONTROLLER_NAME}-${CONTROLLER_UID}
+		generatedID := utils.GenerateOwnerApplicationID(controller.Name, string(controller.UID))
+		result[constants.CanonicalLabelApplicationID] = generatedID
+		// Deprecated: After 1.7.0, admission controller will only add canonical label if application ID was not set
+		result[constants.LabelApplicationID] = generatedID
+	} else if canonicalAppID == "" && sparkAppID == "" && labelAppID == "" && annotationAppID == "" {
 		// if app id not exist, generate one
 		// for each namespace, we group unnamed pods to one single app - if GenerateUniqueAppId is not set
 		// if GenerateUniqueAppId:
@@ -57,6 +78,11 @@ func updatePodLabel(pod *v1.Pod, namespace string, generateUniqueAppIds bool) ma
 	}
 
 	canonicalQueueName := utils.GetPodLabelValue(pod, constants.CanonicalLabelQueueName)
+	if utils.GetQueueNameFromPod(pod) == "" && rule != nil && rule.Queue != "" {
+		// the queue of the rule is only set if the pod does not define one
+		canonicalQueueName = rule.Queue
+		result[constants.CanonicalLabelQueueName] = canonicalQueueName
+	}
 	if canonicalQueueName != "" {
 		// Deprecated: Added in 1.6.0 for backward compatibility, in case the prior shim version can't handle canonical label
 		result[constants.LabelQueueName] = canonicalQueueName
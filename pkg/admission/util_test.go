@@ -110,7 +110,7 @@ func TestUpdatePodLabelForAdmissionController(t *testing.T) {
 	// verify when appId/queue are not given,
 	// we generate new appId/queue labels
 	pod := createTestingPodWithMeta()
-	if result := updatePodLabel(pod, "default", false); result != nil {
+	if result := updatePodLabel(pod, "default", false, false, nil); result != nil {
 		assert.Equal(t, len(result), 3)
 		assert.Equal(t, result["random"], "random")
 		assert.Equal(t, strings.HasPrefix(result[constants.CanonicalLabelApplicationID], constants.AutoGenAppPrefix), true)
@@ -122,7 +122,7 @@ func TestUpdatePodLabelForAdmissionController(t *testing.T) {
 	// verify if appId/queue is given in the canonical labels
 	// we won't modify the value and will add it to non-canonical label for backward compatibility
 	pod = createTestingPodWithLabels(dummyAppId, dummyQueueName)
-	if result := updatePodLabel(pod, "default", false); result != nil {
+	if result := updatePodLabel(pod, "default", false, false, nil); result != nil {
 		assert.Equal(t, len(result), 5)
 		assert.Equal(t, result["random"], "random")
 		assert.Equal(t, result[constants.CanonicalLabelApplicationID], dummyAppId)
@@ -136,7 +136,7 @@ func TestUpdatePodLabelForAdmissionController(t *testing.T) {
 	// verify if applicationId and queue is given in the annotations,
 	// we won't generate new labels
 	pod = createTestingPodWithAnnotations(dummyAppId, dummyQueueName)
-	if result := updatePodLabel(pod, "default", false); result != nil {
+	if result := updatePodLabel(pod, "default", false, false, nil); result != nil {
 		t.Log(result)
 		assert.Equal(t, len(result), 1)
 		assert.Equal(t, result["random"], "random")
@@ -148,7 +148,7 @@ func TestUpdatePodLabelForAdmissionController(t *testing.T) {
 	// labels might be empty
 	pod = createTestingPodNoNamespaceAndLabels()
 
-	if result := updatePodLabel(pod, "default", false); result != nil {
+	if result := updatePodLabel(pod, "default", false, false, nil); result != nil {
 		assert.Equal(t, len(result), 2)
 		assert.Equal(t, strings.HasPrefix(result[constants.CanonicalLabelApplicationID], constants.AutoGenAppPrefix), true)
 		assert.Equal(t, strings.HasPrefix(result[constants.LabelApplicationID], constants.AutoGenAppPrefix), true)
@@ -158,7 +158,7 @@ func TestUpdatePodLabelForAdmissionController(t *testing.T) {
 
 	// pod name might be empty, it can comes from generatedName
 	pod = createTestingPodWithGenerateName()
-	if result := updatePodLabel(pod, "default", false); result != nil {
+	if result := updatePodLabel(pod, "default", false, false, nil); result != nil {
 		assert.Equal(t, len(result), 2)
 		assert.Equal(t, strings.HasPrefix(result[constants.CanonicalLabelApplicationID], constants.AutoGenAppPrefix), true)
 		assert.Equal(t, strings.HasPrefix(result[constants.LabelApplicationID], constants.AutoGenAppPrefix), true)
@@ -167,7 +167,7 @@ func TestUpdatePodLabelForAdmissionController(t *testing.T) {
 	}
 
 	pod = createMinimalTestingPod()
-	if result := updatePodLabel(pod, "default", false); result != nil {
+	if result := updatePodLabel(pod, "default", false, false, nil); result != nil {
 		assert.Equal(t, len(result), 2)
 		assert.Equal(t, strings.HasPrefix(result[constants.CanonicalLabelApplicationID], constants.AutoGenAppPrefix), true)
 		assert.Equal(t, strings.HasPrefix(result[constants.LabelApplicationID], constants.AutoGenAppPrefix), true)
@@ -176,10 +176,55 @@ func TestUpdatePodLabelForAdmissionController(t *testing.T) {
 	}
 }
 
+func TestUpdatePodLabelPodGroup(t *testing.T) {
+	// members of a PodGroup get the same appId
+	pod := createMinimalTestingPod()
+	pod.Labels = map[string]string{constants.LabelPodGroup: "pg-1"}
+	result := updatePodLabel(pod, "default", true, true, nil)
+	assert.Equal(t, len(result), 3)
+	assert.Equal(t, result[constants.CanonicalLabelApplicationID], "pg-default-pg-1")
+	assert.Equal(t, result[constants.LabelApplicationID], "pg-default-pg-1")
+
+	// PodGroups disabled: the label is ignored
+	result = updatePodLabel(pod, "default", false, false, nil)
+	assert.Equal(t, result[constants.CanonicalLabelApplicationID], "yunikorn-default-autogen")
+
+	// appId given in the labels takes precedence
+	pod.Labels[constants.CanonicalLabelApplicationID] = "app-0001"
+	result = updatePodLabel(pod, "default", true, true, nil)
+	assert.Equal(t, result[constants.CanonicalLabelApplicationID], "app-0001")
+	assert.Equal(t, result[constants.LabelApplicationID], "app-0001")
+}
+
+func TestUpdatePodLabelRule(t *testing.T) {
+	controller := true
+	rule := &conf.AdmissionRule{Name: "jobs", Queue: "root.jobs", ApplicationID: conf.ApplicationIDFromController}
+	pod := createMinimalTestingPod()
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "Job", Name: "job", UID: "job-uid", Controller: &controller}}
+	result := updatePodLabel(pod, "default", false, false, rule)
+	assert.Equal(t, result[constants.CanonicalLabelApplicationID], "job-job-uid")
+	assert.Equal(t, result[constants.LabelApplicationID], "job-job-uid")
+	assert.Equal(t, result[constants.CanonicalLabelQueueName], "root.jobs")
+	assert.Equal(t, result[constants.LabelQueueName], "root.jobs")
+
+	// values set on the pod take precedence
+	pod.Labels = map[string]string{constants.CanonicalLabelApplicationID: "app-0001"}
+	pod.Annotations = map[string]string{constants.AnnotationQueueName: "root.other"}
+	result = updatePodLabel(pod, "default", false, false, rule)
+	assert.Equal(t, result[constants.CanonicalLabelApplicationID], "app-0001")
+	_, ok := result[constants.CanonicalLabelQueueName]
+	assert.Assert(t, !ok, "queue of the rule should not be set")
+
+	// no controller: the application ID is generated
+	pod = createMinimalTestingPod()
+	result = updatePodLabel(pod, "default", false, false, rule)
+	assert.Equal(t, result[constants.CanonicalLabelApplicationID], "yunikorn-default-autogen")
+}
+
 func TestDefaultQueueName(t *testing.T) {
 	defaultConf := createConfig()
 	pod := createTestingPodWithMeta()
-	if result := updatePodLabel(pod, defaultConf.GetNamespace(), defaultConf.GetGenerateUniqueAppIds()); result != nil {
+	if result := updatePodLabel(pod, defaultConf.GetNamespace(), defaultConf.GetGenerateUniqueAppIds(), false, nil); result != nil {
 		assert.Equal(t, len(result), 3)
 		assert.Equal(t, result["random"], "random")
 		assert.Equal(t, result[constants.CanonicalLabelApplicationID], "yunikorn-default-autogen")
@@ -25,10 +25,13 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -51,6 +54,8 @@ const (
 	caPrivateKey1Path = "cakey1.pem"
 	caPrivateKey2Path = "cakey2.pem"
 	webhookLabel      = "yunikorn"
+	jobSetGroup       = "jobset.x-k8s.io"
+	jobSetVersion     = "v1alpha2"
 )
 
 // WebhookManager is used to handle all registration requirements for the webhook, including certificates
@@ -378,7 +383,6 @@ func (wm *webhookManagerImpl) installMutatingWebhook() (bool, error) {
 }
 
 func (wm *webhookManagerImpl) checkValidatingWebhook(webhook *v1.ValidatingWebhookConfiguration) error {
-	ignore := v1.Ignore
 	none := v1.SideEffectClassNone
 	path := "/validate-conf"
 
@@ -441,8 +445,16 @@ func (wm *webhookManagerImpl) checkValidatingWebhook(webhook *v1.ValidatingWebho
 		return errors.New("webhook: wrong resources")
 	}
 
-	if hook.FailurePolicy == nil || *hook.FailurePolicy != ignore {
-		return errors.New("webhook: wrong failure policy")
+	if err = wm.checkWebhookSettings(hook.FailurePolicy, hook.TimeoutSeconds); err != nil {
+		return err
+	}
+
+	if !equalSelectors(hook.NamespaceSelector, wm.getValidatingNamespaceSelector()) {
+		return errors.New("webhook: wrong namespace selector")
+	}
+
+	if !equalSelectors(hook.ObjectSelector, nil) {
+		return errors.New("webhook: wrong object selector")
 	}
 
 	if hook.SideEffects == nil || *hook.SideEffects != none {
@@ -453,7 +465,6 @@ func (wm *webhookManagerImpl) checkValidatingWebhook(webhook *v1.ValidatingWebho
 }
 
 func (wm *webhookManagerImpl) checkMutatingWebhook(webhook *v1.MutatingWebhookConfiguration) error {
-	ignore := v1.Ignore
 	none := v1.SideEffectClassNone
 	path := "/mutate"
 
@@ -494,8 +505,9 @@ func (wm *webhookManagerImpl) checkMutatingWebhook(webhook *v1.MutatingWebhookCo
 		return err
 	}
 
+	customRules := wm.getCustomWorkloadRules()
 	rules := hook.Rules
-	if len(rules) != 1 {
+	if len(rules) != 2+len(customRules) {
 		return errors.New("webhook: wrong rule count")
 	}
 
@@ -520,8 +532,31 @@ func (wm *webhookManagerImpl) checkMutatingWebhook(webhook *v1.MutatingWebhookCo
 		return errors.New("webhook: wrong resources")
 	}
 
-	if hook.FailurePolicy == nil || *hook.FailurePolicy != ignore {
-		return errors.New("webhook: wrong failure policy")
+	rule = rules[1]
+	if len(rule.Operations) != 1 || rule.Operations[0] != v1.Create {
+		return errors.New("webhook: wrong jobset operations")
+	}
+
+	if len(rule.APIGroups) != 1 || rule.APIGroups[0] != jobSetGroup ||
+		len(rule.APIVersions) != 1 || rule.APIVersions[0] != jobSetVersion ||
+		len(rule.Resources) != 1 || rule.Resources[0] != "jobsets" {
+		return errors.New("webhook: wrong jobset resources")
+	}
+
+	if !reflect.DeepEqual(rules[2:], customRules) {
+		return errors.New("webhook: wrong custom workload resources")
+	}
+
+	if err = wm.checkWebhookSettings(hook.FailurePolicy, hook.TimeoutSeconds); err != nil {
+		return err
+	}
+
+	if !equalSelectors(hook.NamespaceSelector, wm.conf.GetWebHookNamespaceSelector()) {
+		return errors.New("webhook: wrong namespace selector")
+	}
+
+	if !equalSelectors(hook.ObjectSelector, wm.conf.GetWebHookObjectSelector()) {
+		return errors.New("webhook: wrong object selector")
 	}
 
 	if hook.SideEffects == nil || *hook.SideEffects != none {
@@ -531,6 +566,30 @@ func (wm *webhookManagerImpl) checkMutatingWebhook(webhook *v1.MutatingWebhookCo
 	return nil
 }
 
+// checkWebhookSettings checks the failure policy and the timeout shared by both webhooks against the configuration
+func (wm *webhookManagerImpl) checkWebhookSettings(failurePolicy *v1.FailurePolicyType, timeoutSeconds *int32) error {
+	if failurePolicy == nil || *failurePolicy != wm.conf.GetWebHookFailurePolicy() {
+		return errors.New("webhook: wrong failure policy")
+	}
+
+	if timeoutSeconds == nil || *timeoutSeconds != wm.conf.GetWebHookTimeoutSeconds() {
+		return errors.New("webhook: wrong timeout")
+	}
+
+	return nil
+}
+
+// equalSelectors returns true if both selectors select the same objects, a missing selector selects all objects
+func equalSelectors(selector1 *metav1.LabelSelector, selector2 *metav1.LabelSelector) bool {
+	if selector1 == nil {
+		selector1 = &metav1.LabelSelector{}
+	}
+	if selector2 == nil {
+		selector2 = &metav1.LabelSelector{}
+	}
+	return equality.Semantic.DeepEqual(selector1, selector2)
+}
+
 func (wm *webhookManagerImpl) validateCaBundle(bundle []byte) error {
 	wm.RLock()
 	defer wm.RUnlock()
@@ -577,7 +636,8 @@ func (wm *webhookManagerImpl) createEmptyValidatingWebhook() *v1.ValidatingWebho
 }
 
 func (wm *webhookManagerImpl) populateValidatingWebhook(webhook *v1.ValidatingWebhookConfiguration, caBundle []byte) {
-	ignore := v1.Ignore
+	failurePolicy := wm.conf.GetWebHookFailurePolicy()
+	timeoutSeconds := wm.conf.GetWebHookTimeoutSeconds()
 	none := v1.SideEffectClassNone
 	path := "/validate-conf"
 
@@ -597,7 +657,10 @@ func (wm *webhookManagerImpl) populateValidatingWebhook(webhook *v1.ValidatingWe
 				Operations: []v1.OperationType{v1.Create, v1.Update},
 				Rule:       v1.Rule{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"configmaps"}},
 			}},
-			FailurePolicy:           &ignore,
+			NamespaceSelector:       wm.getValidatingNamespaceSelector(),
+			ObjectSelector:          &metav1.LabelSelector{},
+			FailurePolicy:           &failurePolicy,
+			TimeoutSeconds:          &timeoutSeconds,
 			AdmissionReviewVersions: []string{"v1"},
 			SideEffects:             &none,
 		},
@@ -612,7 +675,8 @@ func (wm *webhookManagerImpl) createEmptyMutatingWebhook() *v1.MutatingWebhookCo
 }
 
 func (wm *webhookManagerImpl) populateMutatingWebhook(webhook *v1.MutatingWebhookConfiguration, caBundle []byte) {
-	ignore := v1.Ignore
+	failurePolicy := wm.conf.GetWebHookFailurePolicy()
+	timeoutSeconds := wm.conf.GetWebHookTimeoutSeconds()
 	none := v1.SideEffectClassNone
 	path := "/mutate"
 
@@ -632,12 +696,46 @@ func (wm *webhookManagerImpl) populateMutatingWebhook(webhook *v1.MutatingWebhoo
 				Operations: []v1.OperationType{v1.Create, v1.Update},
 				Rule: v1.Rule{APIGroups: []string{"", "apps", "batch"}, APIVersions: []string{"v1"}, Resources: []string{
 					"pods", "deployments", "replicasets", "statefulsets", "daemonsets", "jobs", "cronjobs"}},
+			}, {
+				// JobSets are only mutated to derive the gang
+				Operations: []v1.OperationType{v1.Create},
+				Rule:       v1.Rule{APIGroups: []string{jobSetGroup}, APIVersions: []string{jobSetVersion}, Resources: []string{"jobsets"}},
 			}},
-			FailurePolicy:           &ignore,
+			NamespaceSelector:       wm.conf.GetWebHookNamespaceSelector(),
+			ObjectSelector:          wm.conf.GetWebHookObjectSelector(),
+			FailurePolicy:           &failurePolicy,
+			TimeoutSeconds:          &timeoutSeconds,
 			AdmissionReviewVersions: []string{"v1"},
 			SideEffects:             &none,
 		},
 	}
+	webhook.Webhooks[0].Rules = append(webhook.Webhooks[0].Rules, wm.getCustomWorkloadRules()...)
+}
+
+// getValidatingNamespaceSelector returns the selector of the namespace of the scheduler, only the configmaps of that
+// namespace are validated
+func (wm *webhookManagerImpl) getValidatingNamespaceSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+		Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{wm.conf.GetNamespace()},
+	}}}
+}
+
+// getCustomWorkloadRules returns a rule per group and version of the custom workloads. Custom workloads are only
+// mutated on create. The rules are installed when the admission controller starts.
+func (wm *webhookManagerImpl) getCustomWorkloadRules() []v1.RuleWithOperations {
+	rules := make([]v1.RuleWithOperations, 0)
+	for _, kind := range wm.conf.GetWorkloadKinds() {
+		last := len(rules) - 1
+		if last >= 0 && rules[last].APIGroups[0] == kind.Group && rules[last].APIVersions[0] == kind.Version {
+			rules[last].Resources = append(rules[last].Resources, kind.Resource)
+			continue
+		}
+		rules = append(rules, v1.RuleWithOperations{
+			Operations: []v1.OperationType{v1.Create},
+			Rule:       v1.Rule{APIGroups: []string{kind.Group}, APIVersions: []string{kind.Version}, Resources: []string{kind.Resource}},
+		})
+	}
+	return rules
 }
 
 // gets the best certificate / private key pair to use (one with latest expiration)
@@ -34,6 +34,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 
+	"github.com/apache/yunikorn-k8shim/pkg/admission/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/admission/pki"
 )
 
@@ -285,6 +286,9 @@ func TestCheckValidatingWebhook(t *testing.T) {
 		mutator  func(*arv1.ValidatingWebhookConfiguration)
 	}{
 		{name: "Valid", expected: "", mutator: func(_ *arv1.ValidatingWebhookConfiguration) {}},
+		{name: "MissingObjectSelector", expected: "", mutator: func(h *arv1.ValidatingWebhookConfiguration) {
+			h.Webhooks[0].ObjectSelector = nil
+		}},
 		{name: "MissingLabel", expected: "missing label", mutator: func(h *arv1.ValidatingWebhookConfiguration) {
 			delete(h.Labels, "app")
 		}},
@@ -353,6 +357,19 @@ func TestCheckValidatingWebhook(t *testing.T) {
 			fail := arv1.Fail
 			h.Webhooks[0].FailurePolicy = &fail
 		}},
+		{name: "MissingTimeout", expected: "timeout", mutator: func(h *arv1.ValidatingWebhookConfiguration) {
+			h.Webhooks[0].TimeoutSeconds = nil
+		}},
+		{name: "WrongTimeout", expected: "timeout", mutator: func(h *arv1.ValidatingWebhookConfiguration) {
+			timeout := int32(30)
+			h.Webhooks[0].TimeoutSeconds = &timeout
+		}},
+		{name: "WrongNamespaceSelector", expected: "namespace selector", mutator: func(h *arv1.ValidatingWebhookConfiguration) {
+			h.Webhooks[0].NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"invalid": "label"}}
+		}},
+		{name: "WrongObjectSelector", expected: "object selector", mutator: func(h *arv1.ValidatingWebhookConfiguration) {
+			h.Webhooks[0].ObjectSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"invalid": "label"}}
+		}},
 		{name: "MissingSideEffects", expected: "side effects", mutator: func(h *arv1.ValidatingWebhookConfiguration) {
 			h.Webhooks[0].SideEffects = nil
 		}},
@@ -388,6 +405,9 @@ func TestCheckMutatingWebhook(t *testing.T) {
 		mutator  func(*arv1.MutatingWebhookConfiguration)
 	}{
 		{name: "Valid", expected: "", mutator: func(_ *arv1.MutatingWebhookConfiguration) {}},
+		{name: "MissingObjectSelector", expected: "", mutator: func(h *arv1.MutatingWebhookConfiguration) {
+			h.Webhooks[0].ObjectSelector = nil
+		}},
 		{name: "MissingLabel", expected: "missing label", mutator: func(h *arv1.MutatingWebhookConfiguration) {
 			delete(h.Labels, "app")
 		}},
@@ -446,6 +466,15 @@ func TestCheckMutatingWebhook(t *testing.T) {
 		{name: "WrongResources", expected: "resources", mutator: func(h *arv1.MutatingWebhookConfiguration) {
 			h.Webhooks[0].Rules[0].Resources[0] = "invalid-resource"
 		}},
+		{name: "WrongJobSetOps", expected: "jobset operations", mutator: func(h *arv1.MutatingWebhookConfiguration) {
+			h.Webhooks[0].Rules[1].Operations[0] = arv1.Update
+		}},
+		{name: "WrongJobSetResources", expected: "jobset resources", mutator: func(h *arv1.MutatingWebhookConfiguration) {
+			h.Webhooks[0].Rules[1].APIVersions[0] = "v1"
+		}},
+		{name: "WrongCustomWorkloadResources", expected: "custom workload resources", mutator: func(h *arv1.MutatingWebhookConfiguration) {
+			h.Webhooks[0].Rules[2].Resources = []string{"invalid-resource"}
+		}},
 		{name: "MissingFailurePolicy", expected: "failure policy", mutator: func(h *arv1.MutatingWebhookConfiguration) {
 			h.Webhooks[0].FailurePolicy = nil
 		}},
@@ -453,6 +482,19 @@ func TestCheckMutatingWebhook(t *testing.T) {
 			fail := arv1.Fail
 			h.Webhooks[0].FailurePolicy = &fail
 		}},
+		{name: "MissingTimeout", expected: "timeout", mutator: func(h *arv1.MutatingWebhookConfiguration) {
+			h.Webhooks[0].TimeoutSeconds = nil
+		}},
+		{name: "WrongTimeout", expected: "timeout", mutator: func(h *arv1.MutatingWebhookConfiguration) {
+			timeout := int32(30)
+			h.Webhooks[0].TimeoutSeconds = &timeout
+		}},
+		{name: "WrongNamespaceSelector", expected: "namespace selector", mutator: func(h *arv1.MutatingWebhookConfiguration) {
+			h.Webhooks[0].NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"invalid": "label"}}
+		}},
+		{name: "WrongObjectSelector", expected: "object selector", mutator: func(h *arv1.MutatingWebhookConfiguration) {
+			h.Webhooks[0].ObjectSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"invalid": "label"}}
+		}},
 		{name: "MissingSideEffects", expected: "side effects", mutator: func(h *arv1.MutatingWebhookConfiguration) {
 			h.Webhooks[0].SideEffects = nil
 		}},
@@ -465,6 +507,7 @@ func TestCheckMutatingWebhook(t *testing.T) {
 	testSetupOnce(t)
 	clientset := fake.NewClientset()
 	wm := createPopulatedWm(clientset)
+	wm.conf = createConfigWithOverrides(map[string]string{conf.AMWorkloads: `[{"group": "kubeflow.org", "kind": "MPIJob"}]`})
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -529,3 +572,86 @@ func keyPem(t *testing.T, key *rsa.PrivateKey) []byte {
 	assert.NilError(t, err, "failed to encode certificate")
 	return *pem
 }
+
+func TestGetCustomWorkloadRules(t *testing.T) {
+	wm := newWebhookManagerImpl(createConfig(), fake.NewClientset())
+	assert.Equal(t, len(wm.getCustomWorkloadRules()), 0, "no custom workloads by default")
+
+	wm = newWebhookManagerImpl(createConfigWithOverrides(map[string]string{
+		conf.AMWorkloads: `[{"group": "argoproj.io", "kind": "Workflow"}, {"group": "kubeflow.org", "kind": "PyTorchJob"},
+			{"group": "kubeflow.org", "kind": "MPIJob"}, {"group": "ray.io", "kind": "RayCluster"},
+			{"group": "sparkoperator.k8s.io", "kind": "SparkApplication"}]`,
+	}), fake.NewClientset())
+	rules := wm.getCustomWorkloadRules()
+	resources := make([]string, 0)
+	for _, rule := range rules {
+		assert.DeepEqual(t, rule.Operations, []arv1.OperationType{arv1.Create})
+		for _, resource := range rule.Resources {
+			resources = append(resources, rule.APIGroups[0]+"/"+rule.APIVersions[0]+"/"+resource)
+		}
+	}
+	assert.DeepEqual(t, resources, []string{
+		"argoproj.io/v1alpha1/workflows",
+		"kubeflow.org/v1/pytorchjobs",
+		"kubeflow.org/v2beta1/mpijobs",
+		"ray.io/v1/rayclusters",
+		"sparkoperator.k8s.io/v1beta2/sparkapplications",
+	})
+
+	// resources of the same group and version share a rule
+	wm = newWebhookManagerImpl(createConfigWithOverrides(map[string]string{
+		conf.AMWorkloads: `[{"group": "kubeflow.org", "kind": "PyTorchJob"}, {"group": "kubeflow.org", "version": "v1",
+			"kind": "TFJob", "resource": "tfjobs", "podTemplatePaths": ["/spec/tfReplicaSpecs/*/template/metadata"]}]`,
+	}), fake.NewClientset())
+	rules = wm.getCustomWorkloadRules()
+	assert.Equal(t, len(rules), 1)
+	assert.DeepEqual(t, rules[0].Resources, []string{"pytorchjobs", "tfjobs"})
+}
+
+func TestWebhookSettings(t *testing.T) {
+	testSetupOnce(t)
+
+	// defaults
+	wm := createPopulatedWm(fake.NewClientset())
+	mh := wm.createEmptyMutatingWebhook()
+	wm.populateMutatingWebhook(mh, caBundle)
+	assert.Equal(t, *mh.Webhooks[0].FailurePolicy, arv1.Ignore)
+	assert.Equal(t, *mh.Webhooks[0].TimeoutSeconds, int32(conf.DefaultWebHookTimeoutSeconds))
+	assert.DeepEqual(t, mh.Webhooks[0].NamespaceSelector, &metav1.LabelSelector{})
+	assert.DeepEqual(t, mh.Webhooks[0].ObjectSelector, &metav1.LabelSelector{})
+	vh := wm.createEmptyValidatingWebhook()
+	wm.populateValidatingWebhook(vh, caBundle)
+	assert.Equal(t, *vh.Webhooks[0].FailurePolicy, arv1.Ignore)
+	assert.DeepEqual(t, vh.Webhooks[0].NamespaceSelector, &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+		Key: v1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{wm.conf.GetNamespace()},
+	}}})
+
+	// configured and generated settings
+	configured := newWebhookManagerImpl(createConfigWithOverrides(map[string]string{
+		conf.AMWebHookFailurePolicy:             "Fail",
+		conf.AMWebHookTimeoutSeconds:            "5",
+		conf.AMWebHookNamespaceSelector:         "env in (dev,test)",
+		conf.AMWebHookObjectSelector:            "!skip-yunikorn",
+		conf.AMWebHookGenerateNamespaceSelector: "true",
+		conf.AMFilteringBypassNamespaces:        "^kube-system$,^kube-",
+	}), fake.NewClientset())
+	configured.caCert1, configured.caKey1, configured.caCert2, configured.caKey2 = cacert1, cakey1, cacert2, cakey2
+	cmh := configured.createEmptyMutatingWebhook()
+	configured.populateMutatingWebhook(cmh, caBundle)
+	assert.NilError(t, configured.checkMutatingWebhook(cmh))
+	assert.Equal(t, *cmh.Webhooks[0].FailurePolicy, arv1.Fail)
+	assert.Equal(t, *cmh.Webhooks[0].TimeoutSeconds, int32(5))
+	assert.Equal(t, metav1.FormatLabelSelector(cmh.Webhooks[0].NamespaceSelector), "env in (dev,test),kubernetes.io/metadata.name notin (default,kube-system)")
+	assert.Equal(t, metav1.FormatLabelSelector(cmh.Webhooks[0].ObjectSelector), "!skip-yunikorn")
+	cvh := configured.createEmptyValidatingWebhook()
+	configured.populateValidatingWebhook(cvh, caBundle)
+	assert.NilError(t, configured.checkValidatingWebhook(cvh))
+	assert.Equal(t, *cvh.Webhooks[0].FailurePolicy, arv1.Fail)
+	assert.Equal(t, *cvh.Webhooks[0].TimeoutSeconds, int32(5))
+
+	// webhooks installed with other settings must be updated
+	assert.ErrorContains(t, wm.checkMutatingWebhook(cmh), "failure policy")
+	assert.ErrorContains(t, wm.checkValidatingWebhook(cvh), "failure policy")
+	assert.ErrorContains(t, configured.checkMutatingWebhook(mh), "failure policy")
+	assert.ErrorContains(t, configured.checkValidatingWebhook(vh), "failure policy")
+}
@@ -20,8 +20,9 @@ package cache
 
 import (
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
 )
 
 type AddApplicationRequest struct {
@@ -41,20 +42,13 @@ type ApplicationMetadata struct {
 	TaskGroups                 []TaskGroup
 	OwnerReferences            []metav1.OwnerReference
 	SchedulingPolicyParameters *SchedulingPolicyParameters
+	PodGroupName               string
 	CreationTime               int64
 }
 
-type TaskGroup struct {
-	Name                      string
-	MinMember                 int32
-	Labels                    map[string]string
-	Annotations               map[string]string
-	MinResource               map[string]resource.Quantity
-	NodeSelector              map[string]string
-	Tolerations               []v1.Toleration
-	Affinity                  *v1.Affinity
-	TopologySpreadConstraints []v1.TopologySpreadConstraint
-}
+// TaskGroup and SchedulingPolicyParameters are parsed from the pod annotations, shared with the admission controller
+type TaskGroup = utils.TaskGroup
+type SchedulingPolicyParameters = utils.SchedulingPolicyParameters
 
 type TaskMetadata struct {
 	ApplicationID string
@@ -63,21 +57,3 @@ type TaskMetadata struct {
 	Placeholder   bool
 	TaskGroupName string
 }
-
-type SchedulingPolicyParameters struct {
-	placeholderTimeout  int64
-	gangSchedulingStyle string
-}
-
-func NewSchedulingPolicyParameters(placeholderTimeout int64, gangSchedulingStyle string) *SchedulingPolicyParameters {
-	spp := &SchedulingPolicyParameters{placeholderTimeout: placeholderTimeout, gangSchedulingStyle: gangSchedulingStyle}
-	return spp
-}
-
-func (spp *SchedulingPolicyParameters) GetPlaceholderTimeout() int64 {
-	return spp.placeholderTimeout
-}
-
-func (spp *SchedulingPolicyParameters) GetGangSchedulingStyle() string {
-	return spp.gangSchedulingStyle
-}
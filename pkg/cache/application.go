@@ -21,8 +21,10 @@ package cache
 import (
 	"context"
 	"fmt"
+	"maps"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/looplab/fsm"
 	"go.uber.org/zap"
@@ -32,6 +34,7 @@ import (
 	"github.com/apache/yunikorn-k8shim/pkg/common"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/yunikorn-k8shim/pkg/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/yunikorn-k8shim/pkg/locking"
@@ -59,6 +62,18 @@ type Application struct {
 	placeholderTimeoutInSec    int64
 	schedulingStyle            string
 	originatingTask            *Task // Original Pod which creates the requests
+	podGroupName               string
+	podGroups                  *podGroupManager         // writes the state of the app into the PodGroup status
+	topologyPlans              map[string]*topologyPlan // topology domain of the task groups with a topologyKey
+	taskGroupTimeouts          map[string]int64         // placeholder timeout in seconds of the task groups
+	taskGroupTimers            map[string]*time.Timer   // running placeholder timeouts of the task groups
+	timersStopped              bool                     // the placeholders are cleaned up, no timers are started
+	timeoutRelease             string                   // placeholders released when a task group times out
+	timedOutTaskGroups         map[string]bool          // task groups released after their placeholders timed out
+	gangTimedOut               bool                     // all placeholders are released by the shim after a timeout
+	gangStatus                 *gangStatusManager       // writes the gang progress on the owner of the app
+	gangStatusOwner            *gangStatusOwner         // owner the gang progress is written on, resolved once
+	queueLimits                *queueLimits             // maximum resources of the queues of the core
 }
 
 const transitionErr = "no transition"
@@ -85,6 +100,10 @@ func NewApplication(appID, queueName, user string, groups []string, tags map[str
 		schedulerAPI:            scheduler,
 		placeholderTimeoutInSec: 0,
 		schedulingStyle:         constants.SchedulingPolicyStyleParamDefault,
+		topologyPlans:           make(map[string]*topologyPlan),
+		taskGroupTimers:         make(map[string]*time.Timer),
+		timeoutRelease:          constants.SchedulingPolicyTimeoutReleaseAll,
+		timedOutTaskGroups:      make(map[string]bool),
 	}
 	return app
 }
@@ -163,6 +182,11 @@ func (app *Application) GetSchedulingParamsDefinition() string {
 	return app.schedulingParamsDefinition
 }
 
+// setTaskGroups sets the task groups and the placeholder ask of the application. The placeholder ask sent to the core
+// is the gang minimum: the core runs the application once the minimum members of all task groups are allocated.
+// The interface has no notion of elasticity, the additional placeholders of elastic task groups, up to the maximum
+// members, are regular placeholder requests for the core. They are replaced by the real pods of the task group, the
+// core releases the allocated placeholders that are not replaced when the placeholder timeout expires.
 func (app *Application) setTaskGroups(taskGroups []TaskGroup) {
 	app.lock.Lock()
 	defer app.lock.Unlock()
@@ -196,6 +220,179 @@ func (app *Application) getPlaceholderOwnerReferences() []metav1.OwnerReference
 	return app.placeholderOwnerReferences
 }
 
+// setPodGroup links the application to its PodGroup, must be called before the application is added to the context
+func (app *Application) setPodGroup(name string, podGroups *podGroupManager) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.podGroupName = name
+	app.podGroups = podGroups
+}
+
+// setQueueLimits links the application to the queue limits used to check the gang, must be called before the
+// application is added to the context
+func (app *Application) setQueueLimits(limits *queueLimits) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.queueLimits = limits
+}
+
+// setGangStatus links the application to the gang status writer, must be called before the application is added to
+// the context
+func (app *Application) setGangStatus(gangStatus *gangStatusManager) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.gangStatus = gangStatus
+	app.gangStatusOwner = &gangStatusOwner{}
+}
+
+// updateGangStatus writes the gang phase and the desired and bound placeholders of the task groups on the owner of the
+// application. Must be called with the application lock held.
+func (app *Application) updateGangStatus(phase string) {
+	if app.gangStatus == nil || len(app.taskGroups) == 0 {
+		return
+	}
+	status := &GangStatus{
+		ApplicationID: app.applicationID,
+		Phase:         phase,
+		TaskGroups:    make([]TaskGroupStatus, 0, len(app.taskGroups)),
+	}
+	for _, tg := range app.taskGroups {
+		status.TaskGroups = append(status.TaskGroups, TaskGroupStatus{
+			Name:      tg.Name,
+			MinMember: tg.MinMember,
+			Desired:   tg.GetMaxMember(),
+			Bound:     app.countBoundPlaceholders(tg.Name),
+			TimedOut:  app.timedOutTaskGroups[tg.Name],
+		})
+	}
+	update := &gangStatusUpdate{
+		ownerRefs: app.placeholderOwnerReferences,
+		namespace: app.tags[constants.AppTagNamespace],
+		status:    status,
+		owner:     app.gangStatusOwner,
+	}
+	if app.originatingTask != nil {
+		update.pod = app.originatingTask.GetTaskPod()
+	}
+	app.gangStatus.setStatus(update)
+}
+
+// onPodGroupStateChange writes the phase matching the new state into the PodGroup of the application.
+// Called from the state machine with the application lock held, the PodGroup is not changed after creation.
+func (app *Application) onPodGroupStateChange(state string) {
+	if app.podGroups == nil || app.podGroupName == "" {
+		return
+	}
+	if phase := getPodGroupPhase(state); phase != "" {
+		app.podGroups.setPhase(app.tags[constants.AppTagNamespace], app.podGroupName, phase)
+	}
+}
+
+// getTopologyDomain returns the topology domain planned for the task group, empty if there is no plan
+func (app *Application) getTopologyDomain(taskGroupName string) string {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	if plan, ok := app.topologyPlans[taskGroupName]; ok {
+		return plan.domain
+	}
+	return ""
+}
+
+// setTopologyDomain plans the placeholders of the task group in the topology domain, the domain is added to the tried
+// domains of the task group and will not be selected again when re-planning.
+func (app *Application) setTopologyDomain(taskGroupName string, domain string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	plan, ok := app.topologyPlans[taskGroupName]
+	if !ok {
+		plan = &topologyPlan{tried: make(map[string]bool)}
+		app.topologyPlans[taskGroupName] = plan
+	}
+	plan.domain = domain
+	plan.tried[domain] = true
+}
+
+// setTopologyReplanTimer sets the timer that re-plans the topology domain of the task group, a previous timer is
+// stopped. The timer is stopped directly if the placeholders of the application have been cleaned up.
+func (app *Application) setTopologyReplanTimer(taskGroupName string, timer *time.Timer) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	plan, ok := app.topologyPlans[taskGroupName]
+	if !ok || app.timersStopped {
+		timer.Stop()
+		return
+	}
+	if plan.replanTimer != nil {
+		plan.replanTimer.Stop()
+	}
+	plan.replanTimer = timer
+}
+
+// stopTimers stops the topology re-plan timers and the placeholder timeouts of the task groups. No new timers are
+// started after this call.
+func (app *Application) stopTimers() {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.timersStopped = true
+	for _, plan := range app.topologyPlans {
+		if plan.replanTimer != nil {
+			plan.replanTimer.Stop()
+			plan.replanTimer = nil
+		}
+	}
+	for name, timer := range app.taskGroupTimers {
+		timer.Stop()
+		delete(app.taskGroupTimers, name)
+	}
+}
+
+// getTriedTopologyDomains returns a copy of the topology domains already tried for the task group
+func (app *Application) getTriedTopologyDomains(taskGroupName string) map[string]bool {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	if plan, ok := app.topologyPlans[taskGroupName]; ok {
+		return maps.Clone(plan.tried)
+	}
+	return nil
+}
+
+// inTopologyDomain returns true if the placeholder was placed in the planned topology domain of its task group, or
+// if the task group has no planned domain. The caller must hold the application lock.
+func (app *Application) inTopologyDomain(task *Task) bool {
+	plan, ok := app.topologyPlans[task.GetTaskGroupName()]
+	if !ok {
+		return true
+	}
+	return utils.GetPodAnnotationValue(task.GetTaskPod(), constants.AnnotationTopologyDomain) == plan.domain
+}
+
+// getBoundPlaceholderCount returns the number of bound placeholders of the task group in the planned topology domain
+func (app *Application) getBoundPlaceholderCount(taskGroupName string) int32 {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.countBoundPlaceholders(taskGroupName)
+}
+
+// countBoundPlaceholders returns the number of bound placeholders of the task group in the planned topology domain.
+// The caller must hold the application lock.
+func (app *Application) countBoundPlaceholders(taskGroupName string) int32 {
+	var count int32
+	for _, t := range app.getTasks(TaskStates().Bound) {
+		if t.placeholder && t.GetTaskGroupName() == taskGroupName && app.inTopologyDomain(t) {
+			count++
+		}
+	}
+	return count
+}
+
+// setTaskGroupTimeouts sets the placeholder timeouts of the task groups and what is released when one expires
+func (app *Application) setTaskGroupTimeouts(timeouts map[string]int64, release string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.taskGroupTimeouts = timeouts
+	app.timeoutRelease = release
+}
+
 func (app *Application) setSchedulingStyle(schedulingStyle string) {
 	app.lock.Lock()
 	defer app.lock.Unlock()
@@ -222,6 +419,9 @@ func (app *Application) addTask(task *Task) {
 		return
 	}
 	app.taskMap[task.taskID] = task
+	if app.gangTimedOut || app.timedOutTaskGroups[task.GetTaskGroupName()] {
+		task.releaseTaskGroup()
+	}
 }
 
 func (app *Application) RemoveTask(taskID string) {
@@ -347,7 +547,9 @@ func (app *Application) Schedule() bool {
 	switch app.GetApplicationState() {
 	case ApplicationStates().New:
 		ev := NewSubmitApplicationEvent(app.GetApplicationID())
-		if err := app.handle(ev); err != nil {
+		err := app.handle(ev)
+		dispatcher.RecordEvent(ev, err)
+		if err != nil {
 			log.Log(log.ShimCacheApplication).Warn("failed to handle SUBMIT app event",
 				zap.Error(err))
 		}
@@ -394,8 +596,10 @@ func (app *Application) scheduleTasks(taskScheduleCondition func(t *Task) bool)
 			if err := task.sanityCheckBeforeScheduling(); err == nil {
 				// note, if we directly trigger submit task event, it may spawn too many duplicate
 				// events, because a task might be submitted multiple times before its state transits to PENDING.
-				if handleErr := task.handle(
-					NewSimpleTaskEvent(task.applicationID, task.taskID, InitTask)); handleErr != nil {
+				ev := NewSimpleTaskEvent(task.applicationID, task.taskID, InitTask)
+				handleErr := task.handle(ev)
+				dispatcher.RecordEvent(ev, handleErr)
+				if handleErr != nil {
 					// something goes wrong when transit task to PENDING state,
 					// this should not happen because we already checked the state
 					// before calling the transition. Nowhere to go, just log the error.
@@ -469,6 +673,19 @@ func (app *Application) skipReservationStage() bool {
 	return false
 }
 
+// checkGangFitsQueue returns an error if the placeholders of the gang exceed the maximum resources of the queue the
+// core placed the application in: the application would wait for the placeholder timeout and fail. The check can be
+// disabled for clusters where the queue maximum or the cluster size changes while applications wait, for example
+// with autoscaling.
+func (app *Application) checkGangFitsQueue() error {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	if app.queueLimits == nil || conf.GetSchedulerConf().DisableGangQueueCheck {
+		return nil
+	}
+	return app.queueLimits.checkFits(app.applicationID, app.placeholderAsk)
+}
+
 func (app *Application) postAppAccepted() {
 	// if app has taskGroups defined, and it has no allocated tasks,
 	// it goes to the Reserving state before getting to Running.
@@ -483,6 +700,16 @@ func (app *Application) postAppAccepted() {
 		ev = NewRunApplicationEvent(app.applicationID)
 		log.Log(log.ShimCacheApplication).Info("Skip the reservation stage",
 			zap.String("appID", app.applicationID))
+	} else if err := app.checkGangFitsQueue(); err != nil {
+		ev = NewFailApplicationEvent(app.applicationID,
+			fmt.Sprintf("%s: %s", constants.ApplicationGangExceedsQueueFailure, err.Error()))
+		log.Log(log.ShimCacheApplication).Info("gang can never fit in the queue, failing the application",
+			zap.String("appID", app.applicationID),
+			zap.Error(err))
+		if task := app.GetOriginatingTask(); task != nil {
+			events.GetRecorder().Eventf(task.GetTaskPod().DeepCopy(), nil, v1.EventTypeWarning, "GangScheduling",
+				"GangExceedsQueueMaxResources", "Application %s %s", app.applicationID, err.Error())
+		}
 	} else {
 		ev = NewSimpleApplicationEvent(app.applicationID, TryReserve)
 		log.Log(log.ShimCacheApplication).Info("app has taskGroups defined, trying to reserve resources for gang members",
@@ -493,11 +720,24 @@ func (app *Application) postAppAccepted() {
 
 // onResuming triggered when entering the resuming state which is triggered by the time out of the gang placeholders
 // if SOFT gang scheduling is configured.
+// The core releases the placeholders when the application times out. When the timeout of a task group expired in the
+// shim, the shim releases the placeholders: the application runs once all placeholders are gone.
 func (app *Application) onResuming() {
 	if app.originatingTask != nil {
 		events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeWarning, "GangScheduling",
 			"GangSchedulingFailed", "Application %s resuming as non-gang application (SOFT)", app.applicationID)
 	}
+	app.updateGangStatus("Resuming")
+	if !app.gangTimedOut {
+		return
+	}
+	if len(app.getPlaceHolderTasks()) == 0 {
+		dispatcher.Dispatch(NewRunApplicationEvent(app.applicationID))
+		return
+	}
+	go func() {
+		getPlaceholderManager().cleanUp(app)
+	}()
 }
 
 // onReserving triggered when entering the reserving state.
@@ -514,6 +754,7 @@ func (app *Application) onReserving() {
 		events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeNormal, "GangScheduling",
 			"CreatingPlaceholders", "Application %s creating placeholders", app.applicationID)
 	}
+	app.updateGangStatus("CreatingPlaceholders")
 
 	go func() {
 		// while doing reserving
@@ -528,28 +769,152 @@ func (app *Application) onReserving() {
 				events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeWarning, "GangScheduling",
 					"PlaceholderCreateFailed", "Application %s fall back to normal scheduling", app.applicationID)
 			}
+			app.lock.RLock()
+			app.updateGangStatus("PlaceholderCreateFailed")
+			app.lock.RUnlock()
 		}
 	}()
+	app.scheduleTaskGroupTimeouts()
+}
+
+// scheduleTaskGroupTimeouts starts the placeholder timeout of the task groups that have their own timeout. The
+// timeout of the other task groups is the application timeout handled by the core. The timers are stopped when the
+// placeholders of the application are cleaned up.
+func (app *Application) scheduleTaskGroupTimeouts() {
+	if app.timersStopped {
+		return
+	}
+	for _, tg := range app.taskGroups {
+		timeout, ok := app.taskGroupTimeouts[tg.Name]
+		if !ok {
+			continue
+		}
+		if timer, ok := app.taskGroupTimers[tg.Name]; ok {
+			timer.Stop()
+		}
+		appID := app.applicationID
+		taskGroupName := tg.Name
+		app.taskGroupTimers[taskGroupName] = time.AfterFunc(time.Duration(timeout)*time.Second, func() {
+			if app.GetApplicationState() == ApplicationStates().Reserving {
+				dispatcher.Dispatch(NewTaskGroupTimeoutEvent(appID, taskGroupName))
+			}
+		})
+	}
+}
+
+// handleTaskGroupTimeoutEvent is called when the placeholder timeout of a task group expires while reserving.
+// Nothing happens if the task group is satisfied. Otherwise, if only the unsatisfied task groups are released, the
+// placeholders of the task group are removed and the gang is checked without it: the pods of a released task group
+// are scheduled without placeholders. If all placeholders are released, or no other task group is left, the gang
+// timed out: the application fails for HARD gang scheduling and resumes as a non-gang application for SOFT, all pods
+// are scheduled without placeholders.
+func (app *Application) handleTaskGroupTimeoutEvent(taskGroupName string) {
+	var taskGroup *TaskGroup
+	for i := range app.taskGroups {
+		if app.taskGroups[i].Name == taskGroupName {
+			taskGroup = &app.taskGroups[i]
+			break
+		}
+	}
+	if taskGroup == nil || app.timedOutTaskGroups[taskGroupName] {
+		return
+	}
+	if app.countBoundPlaceholders(taskGroupName) >= taskGroup.MinMember {
+		log.Log(log.ShimCacheApplication).Debug("task group satisfied before the placeholder timeout",
+			zap.String("appID", app.applicationID),
+			zap.String("taskGroup", taskGroupName))
+		return
+	}
+	if app.timeoutRelease == constants.SchedulingPolicyTimeoutReleaseUnsatisfied && len(app.timedOutTaskGroups)+1 < len(app.taskGroups) {
+		log.Log(log.ShimCacheApplication).Info("task group placeholders timed out, releasing the task group",
+			zap.String("appID", app.applicationID),
+			zap.String("taskGroup", taskGroupName))
+		app.timedOutTaskGroups[taskGroupName] = true
+		if app.originatingTask != nil {
+			events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeWarning, "GangScheduling",
+				"TaskGroupPlaceholderTimeout", "Application %s task group %s placeholders timed out, releasing the unsatisfied task group",
+				app.applicationID, taskGroupName)
+		}
+		app.updateGangStatus("TaskGroupPlaceholderTimeout")
+		app.releaseTaskGroupTasks(taskGroupName)
+		go func() {
+			getPlaceholderManager().deleteTaskGroupPlaceholders(app, taskGroupName, "")
+		}()
+		// the remaining task groups might already be satisfied
+		app.checkGangReservation()
+		return
+	}
+	log.Log(log.ShimCacheApplication).Info("task group placeholders timed out, releasing the gang",
+		zap.String("appID", app.applicationID),
+		zap.String("taskGroup", taskGroupName),
+		zap.String("style", app.schedulingStyle))
+	if app.originatingTask != nil {
+		events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeWarning, "GangScheduling",
+			"GangPlaceholderTimeout", "Application %s task group %s placeholders timed out, releasing all placeholders",
+			app.applicationID, taskGroupName)
+	}
+	app.updateGangStatus("GangPlaceholderTimeout")
+	if app.schedulingStyle == constants.SchedulingPolicyStyleParamValues["Hard"] {
+		dispatcher.Dispatch(NewFailApplicationEvent(app.applicationID,
+			fmt.Sprintf("%s: task group %s placeholders timed out", constants.ApplicationInsufficientResourcesFailure, taskGroupName)))
+		return
+	}
+	app.gangTimedOut = true
+	app.releaseTaskGroupTasks("")
+	dispatcher.Dispatch(NewResumingApplicationEvent(app.applicationID))
+}
+
+// releaseTaskGroupTasks schedules the pods of the task group without placeholders, the pods of all task groups if the
+// name is empty. The caller must hold the application lock.
+func (app *Application) releaseTaskGroupTasks(taskGroupName string) {
+	for _, task := range app.taskMap {
+		if taskGroupName == "" || task.GetTaskGroupName() == taskGroupName {
+			task.releaseTaskGroup()
+		}
+	}
 }
 
 // onReservationStateChange is called when there is an add or a release of a placeholder
 // If we have all the required placeholders progress the application status, otherwise nothing happens
+// The gang is satisfied when the minimum members of all task groups are bound. The additional placeholders of elastic
+// task groups can be bound after the application is running, they are replaced by the real pods of the task group or
+// released by the core on the placeholder timeout.
 func (app *Application) onReservationStateChange() {
+	if app.sm.Current() == ApplicationStates().Running {
+		log.Log(log.ShimCacheApplication).Info("elastic placeholder allocated for running application",
+			zap.String("appID", app.applicationID))
+		if app.originatingTask != nil {
+			events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeNormal, "GangScheduling",
+				"ElasticPlaceholderAllocated", "Application %s additional placeholder has been allocated.", app.applicationID)
+		}
+		app.updateGangStatus("ElasticPlaceholderAllocated")
+		return
+	}
 	if app.originatingTask != nil {
 		events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeNormal, "GangScheduling",
 			"PlaceholderAllocated", "Application %s placeholder has been allocated.", app.applicationID)
 	}
+	app.updateGangStatus("PlaceholderAllocated")
+	app.checkGangReservation()
+}
+
+// checkGangReservation progresses the application to running if the minimum members of all task groups are bound.
+// Task groups released after their placeholders timed out are not part of the gang anymore.
+func (app *Application) checkGangReservation() {
 	desireCounts := make(map[string]int32, len(app.taskGroups))
 	for _, tg := range app.taskGroups {
-		desireCounts[tg.Name] = tg.MinMember
+		if !app.timedOutTaskGroups[tg.Name] {
+			desireCounts[tg.Name] = tg.MinMember
+		}
 	}
 
 	for _, t := range app.getTasks(TaskStates().Bound) {
-		if t.placeholder {
+		// placeholders left behind in a previous topology domain do not count towards the gang
+		if t.placeholder && app.inTopologyDomain(t) {
 			taskGroupName := t.GetTaskGroupName()
 			if _, ok := desireCounts[taskGroupName]; ok {
 				desireCounts[taskGroupName]--
-			} else {
+			} else if !app.timedOutTaskGroups[taskGroupName] {
 				log.Log(log.ShimCacheApplication).Debug("placeholder taskGroupName set on pod is unknown for application",
 					zap.String("application", app.applicationID),
 					zap.String("podName", t.GetTaskPod().Name),
@@ -565,10 +930,20 @@ func (app *Application) onReservationStateChange() {
 		}
 	}
 
-	if app.originatingTask != nil {
-		// Now that all placeholders has been allocated, send a final conclusion message
-		events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeNormal, "GangScheduling",
-			"GangReservationComplete", "Application %s all placeholders are allocated. Transitioning to running state.", app.applicationID)
+	if len(app.timedOutTaskGroups) > 0 {
+		app.updateGangStatus("PartialGangReservationComplete")
+		if app.originatingTask != nil {
+			events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeNormal, "GangScheduling",
+				"PartialGangReservationComplete", "Application %s placeholders of the satisfied task groups are allocated, "+
+					"timed out task groups are scheduled without placeholders. Transitioning to running state.", app.applicationID)
+		}
+	} else {
+		app.updateGangStatus("GangReservationComplete")
+		if app.originatingTask != nil {
+			// Now that all placeholders has been allocated, send a final conclusion message
+			events.GetRecorder().Eventf(app.originatingTask.GetTaskPod().DeepCopy(), nil, v1.EventTypeNormal, "GangScheduling",
+				"GangReservationComplete", "Application %s all placeholders are allocated. Transitioning to running state.", app.applicationID)
+		}
 	}
 	dispatcher.Dispatch(NewRunApplicationEvent(app.applicationID))
 }
@@ -614,6 +989,7 @@ func (app *Application) handleFailApplicationEvent(errMsg string) {
 
 	timeout := strings.Contains(errMsg, constants.ApplicationInsufficientResourcesFailure)
 	rejected := strings.Contains(errMsg, constants.ApplicationRejectedFailure)
+	exceedsQueue := strings.Contains(errMsg, constants.ApplicationGangExceedsQueueFailure)
 	// publish pod level event to unallocated pods
 	for _, task := range unalloc {
 		// Only need to fail the non-placeholder pod(s)
@@ -622,6 +998,9 @@ func (app *Application) handleFailApplicationEvent(errMsg string) {
 		} else if rejected {
 			errMsgArr := strings.Split(errMsg, ":")
 			failTaskPodWithReasonAndMsg(task, constants.ApplicationRejectedFailure, errMsgArr[1])
+		} else if exceedsQueue {
+			failTaskPodWithReasonAndMsg(task, constants.ApplicationGangExceedsQueueFailure,
+				strings.TrimSpace(strings.TrimPrefix(errMsg, constants.ApplicationGangExceedsQueueFailure+":")))
 		}
 		events.GetRecorder().Eventf(task.GetTaskPod().DeepCopy(), nil, v1.EventTypeWarning, "ApplicationFailed", "ApplicationFailed",
 			"Application %s scheduling failed, reason: %s", app.applicationID, errMsg)
@@ -678,6 +1057,23 @@ func (app *Application) SetPlaceholderTimeout(timeout int64) {
 	app.placeholderTimeoutInSec = timeout
 }
 
+// isPlaceholderInUse returns true if the placeholders of the application can still be used: the application is not
+// finished and has pods, other than placeholders, that are not terminated.
+func (app *Application) isPlaceholderInUse() bool {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	switch app.sm.Current() {
+	case ApplicationStates().Completed, ApplicationStates().Failed, ApplicationStates().Killed, ApplicationStates().Rejected:
+		return false
+	}
+	for _, task := range app.taskMap {
+		if !task.placeholder && !task.isTerminated() {
+			return true
+		}
+	}
+	return false
+}
+
 func (app *Application) removeCompletedTasks() {
 	app.lock.Lock()
 	defer app.lock.Unlock()
@@ -51,10 +51,11 @@ const (
 	ReleaseAppAllocation
 	ResumingApplication
 	AppTaskCompleted
+	TaskGroupTimeout
 )
 
 func (ae ApplicationEventType) String() string {
-	return [...]string{"SubmitApplication", "AcceptApplication", "TryReserve", "UpdateReservation", "RunApplication", "RejectApplication", "CompleteApplication", "FailApplication", "KillApplication", "KilledApplication", "ReleaseAppAllocation", "ResumingApplication", "AppTaskCompleted"}[ae]
+	return [...]string{"SubmitApplication", "AcceptApplication", "TryReserve", "UpdateReservation", "RunApplication", "RejectApplication", "CompleteApplication", "FailApplication", "KillApplication", "KilledApplication", "ReleaseAppAllocation", "ResumingApplication", "AppTaskCompleted", "TaskGroupTimeout"}[ae]
 }
 
 // ------------------------
@@ -321,6 +322,37 @@ func (re ResumingApplicationEvent) GetApplicationID() string {
 	return re.applicationID
 }
 
+// ------------------------
+// Task group placeholder timeout
+// ------------------------
+type TaskGroupTimeoutEvent struct {
+	applicationID string
+	taskGroupName string
+	event         ApplicationEventType
+}
+
+func NewTaskGroupTimeoutEvent(appID string, taskGroupName string) TaskGroupTimeoutEvent {
+	return TaskGroupTimeoutEvent{
+		applicationID: appID,
+		taskGroupName: taskGroupName,
+		event:         TaskGroupTimeout,
+	}
+}
+
+func (te TaskGroupTimeoutEvent) GetEvent() string {
+	return te.event.String()
+}
+
+func (te TaskGroupTimeoutEvent) GetArgs() []interface{} {
+	args := make([]interface{}, 1)
+	args[0] = te.taskGroupName
+	return args
+}
+
+func (te TaskGroupTimeoutEvent) GetApplicationID() string {
+	return te.applicationID
+}
+
 // ----------------------------------
 // Application states
 // ----------------------------------
@@ -385,6 +417,16 @@ func newAppState() *fsm.FSM { //nolint:funlen
 				Src:  []string{states.Reserving},
 				Dst:  states.Reserving,
 			},
+			{
+				Name: UpdateReservation.String(),
+				Src:  []string{states.Running},
+				Dst:  states.Running,
+			},
+			{
+				Name: TaskGroupTimeout.String(),
+				Src:  []string{states.Reserving},
+				Dst:  states.Reserving,
+			},
 			{
 				Name: ResumingApplication.String(),
 				Src:  []string{states.Reserving},
@@ -454,6 +496,7 @@ func newAppState() *fsm.FSM { //nolint:funlen
 					zap.String("source", event.Src),
 					zap.String("destination", event.Dst),
 					zap.String("event", event.Event))
+				app.onPodGroupStateChange(event.Dst)
 			},
 			states.Reserving: func(_ context.Context, event *fsm.Event) {
 				app := event.Args[0].(*Application) //nolint:errcheck
@@ -509,6 +552,16 @@ func newAppState() *fsm.FSM { //nolint:funlen
 				terminationType := eventArgs[1]
 				app.handleReleaseAppAllocationEvent(taskID, terminationType)
 			},
+			TaskGroupTimeout.String(): func(_ context.Context, event *fsm.Event) {
+				app := event.Args[0].(*Application) //nolint:errcheck
+				eventArgs := make([]string, 1)
+				generic := event.Args[1].([]interface{}) //nolint:errcheck
+				if err := events.GetEventArgsAsStrings(eventArgs, generic); err != nil {
+					log.Log(log.ShimFSM).Error("fail to parse event arg", zap.Error(err))
+					return
+				}
+				app.handleTaskGroupTimeoutEvent(eventArgs[0])
+			},
 			AppTaskCompleted.String(): func(_ context.Context, event *fsm.Event) {
 				app := event.Args[0].(*Application) //nolint:errcheck
 				app.handleAppTaskCompletedEvent()
@@ -791,6 +791,13 @@ func TestNewResumingApplicationEvent(t *testing.T) {
 	}
 }
 
+func TestNewTaskGroupTimeoutEvent(t *testing.T) {
+	instance := NewTaskGroupTimeoutEvent("testAppId001", "workers")
+	assert.Equal(t, instance.GetApplicationID(), "testAppId001")
+	assert.Equal(t, instance.GetEvent(), TaskGroupTimeout.String())
+	assert.DeepEqual(t, instance.GetArgs(), []interface{}{"workers"})
+}
+
 func TestApplicationEventsAsString(t *testing.T) {
 	assert.Equal(t, SubmitApplication.String(), "SubmitApplication")
 	assert.Equal(t, AcceptApplication.String(), "AcceptApplication")
@@ -805,4 +812,5 @@ func TestApplicationEventsAsString(t *testing.T) {
 	assert.Equal(t, ReleaseAppAllocation.String(), "ReleaseAppAllocation")
 	assert.Equal(t, ResumingApplication.String(), "ResumingApplication")
 	assert.Equal(t, AppTaskCompleted.String(), "AppTaskCompleted")
+	assert.Equal(t, TaskGroupTimeout.String(), "TaskGroupTimeout")
 }
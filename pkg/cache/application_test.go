@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -38,6 +39,7 @@ import (
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/yunikorn-k8shim/pkg/locking"
 	"github.com/apache/yunikorn-scheduler-interface/lib/go/api"
@@ -651,6 +653,33 @@ func TestSetTaskGroupsAndSchedulingPolicy(t *testing.T) {
 	assert.DeepEqual(t, actualPlaceholderAsk, expectedPlaceholderAsk, cmpopts.IgnoreUnexported(si.Resource{}, si.Quantity{}))
 }
 
+func TestSetTaskGroupsElastic(t *testing.T) {
+	app := NewApplication("app01", "root.a", "test-user", testGroups, map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]TaskGroup{
+		{
+			Name:      "test-group-1",
+			MinMember: 2,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("500m"),
+			},
+		},
+		{
+			Name:      "test-group-2",
+			MinMember: 2,
+			MaxMember: 5,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("1000m"),
+			},
+		},
+	})
+	assert.Equal(t, app.getTaskGroups()[0].GetMaxMember(), int32(2))
+	assert.Equal(t, app.getTaskGroups()[1].GetMaxMember(), int32(5))
+
+	// the placeholder ask is the gang minimum, the additional members are regular placeholders
+	expectedPlaceholderAsk := common.NewResourceBuilder().AddResource("pods", 4).AddResource(siCommon.CPU, 3000).Build()
+	assert.DeepEqual(t, app.getPlaceholderAsk(), expectedPlaceholderAsk, cmpopts.IgnoreUnexported(si.Resource{}, si.Quantity{}))
+}
+
 type threadSafePodsMap struct {
 	pods map[string]*v1.Pod
 	locking.RWMutex
@@ -740,6 +769,49 @@ func TestTryReserve(t *testing.T) {
 	assert.NilError(t, err, "placeholders are not created")
 }
 
+func TestGangExceedsQueue(t *testing.T) {
+	context := initContextForTest()
+	dispatcher.RegisterEventHandler("TestAppHandler", dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	context.queueLimits = newTestQueueLimits(t)
+	NewPlaceholderManager(client.NewMockedAPIProvider(false).GetAPIs())
+
+	newGangApp := func(appID, queue string) *Application {
+		app := NewApplication(appID, queue, "test-user", testGroups, map[string]string{}, newMockSchedulerAPI())
+		context.addApplicationToContext(app)
+		app.setQueueLimits(context.queueLimits)
+		if queue != "" {
+			addCoreApplication(t, context.queueLimits, appID, queue, nil)
+		}
+		app.setTaskGroups([]TaskGroup{{
+			Name:        "test-group-1",
+			MinMember:   3,
+			MinResource: map[string]resource.Quantity{v1.ResourceCPU.String(): resource.MustParse("1")},
+		}})
+		app.sm.SetState(ApplicationStates().Accepted)
+		return app
+	}
+
+	// the gang needs 3 vcore, the queue maximum is 2 vcore: the app fails without reserving
+	app := newGangApp("app00001", "root.small")
+	app.Schedule()
+	assertAppState(t, app, ApplicationStates().Failing, 3*time.Second)
+
+	// the core does not know the app: nothing to check
+	app = newGangApp("app00003", "")
+	assert.NilError(t, app.checkGangFitsQueue())
+
+	// opt-out: the app tries to reserve
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{conf.CMSvcDisableGangQueueCheck: "true"}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		assert.NilError(t, conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true), "failed to reset config")
+	}()
+	app = newGangApp("app00002", "root.small")
+	assert.NilError(t, app.checkGangFitsQueue())
+}
+
 //nolint:funlen
 func TestTryReservePostRestart(t *testing.T) {
 	context := initContextForTest()
@@ -1305,6 +1377,208 @@ func TestApplication_onReservationStateChange(t *testing.T) {
 	assert.NilError(t, err, "event should have been emitted")
 }
 
+func TestApplication_onReservationStateChangeElastic(t *testing.T) {
+	recorder := k8sEvents.NewFakeRecorder(1024)
+	events.SetRecorder(recorder)
+	defer events.SetRecorder(events.NewMockedRecorder())
+
+	context := initContextForTest()
+	app := NewApplication(appID, "root.a", "testuser", testGroups, map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]TaskGroup{
+		{
+			Name:      "test-group-1",
+			MinMember: 1,
+			MaxMember: 3,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("500m"),
+			},
+		},
+	})
+	task := NewTask("task0001", app, context, &v1.Pod{})
+	task.setTaskGroupName("test-group-1")
+	task.placeholder = true
+	app.addTask(task)
+	app.setOriginatingTask(task)
+
+	// an additional placeholder bound after the gang is satisfied keeps the app running
+	app.sm.SetState(ApplicationStates().Running)
+	err := app.handle(NewUpdateApplicationReservationEvent(appID))
+	assert.NilError(t, err, "update reservation not allowed for a running app")
+	assert.Equal(t, app.GetApplicationState(), ApplicationStates().Running)
+	err = utils.WaitForCondition(func() bool {
+		for {
+			select {
+			case event := <-recorder.Events:
+				if strings.Contains(event, "additional placeholder has been allocated") {
+					return true
+				}
+			default:
+				return false
+			}
+		}
+	}, 5*time.Millisecond, time.Second)
+	assert.NilError(t, err, "elastic placeholder event should have been emitted")
+}
+
+func TestApplication_releaseElasticPlaceholders(t *testing.T) {
+	context, apiProvider := initContextAndAPIProviderForTest()
+	dispatcher.RegisterEventHandler("TestAppHandler", dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	var deleted sync.Map
+	apiProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		deleted.Store(pod.Name, true)
+		return nil
+	})
+
+	app := NewApplication(appID, "root.a", "testuser", testGroups, map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]TaskGroup{
+		{
+			Name:      "test-group-1",
+			MinMember: 1,
+			MaxMember: 3,
+			MinResource: map[string]resource.Quantity{
+				v1.ResourceCPU.String(): resource.MustParse("500m"),
+			},
+		},
+	})
+	context.addApplicationToContext(app)
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("placeholder-%d", i)
+		task := NewTaskPlaceholder(name, app, context, &v1.Pod{ObjectMeta: apis.ObjectMeta{Name: name}})
+		task.setTaskGroupName("test-group-1")
+		task.sm.SetState(TaskStates().Bound)
+		app.addTask(task)
+	}
+
+	// the gang runs once the minimum members are bound
+	app.sm.SetState(ApplicationStates().Reserving)
+	assert.NilError(t, app.handle(NewUpdateApplicationReservationEvent(appID)))
+	assertAppState(t, app, ApplicationStates().Running, 3*time.Second)
+
+	// the core releases the placeholders that are not replaced on the placeholder timeout: the pods are deleted
+	assert.NilError(t, app.handle(NewReleaseAppAllocationEvent(appID, si.TerminationType_TIMEOUT, "placeholder-2")))
+	assert.NilError(t, app.handle(NewReleaseAppAllocationEvent(appID, si.TerminationType_TIMEOUT, "placeholder-3")))
+	for _, name := range []string{"placeholder-2", "placeholder-3"} {
+		_, ok := deleted.Load(name)
+		assert.Assert(t, ok, "placeholder %s should have been deleted", name)
+	}
+	_, ok := deleted.Load("placeholder-1")
+	assert.Assert(t, !ok, "placeholder of the minimum member should not have been deleted")
+	assert.Equal(t, app.GetApplicationState(), ApplicationStates().Running)
+}
+
+func TestApplication_stopTaskGroupTimers(t *testing.T) {
+	app := NewApplication(appID, "root.a", "testuser", testGroups, map[string]string{}, newMockSchedulerAPI())
+	app.setTaskGroups([]TaskGroup{
+		{Name: "driver", MinMember: 1, MinResource: map[string]resource.Quantity{"cpu": resource.MustParse("1")}},
+		{Name: "workers", MinMember: 2, MinResource: map[string]resource.Quantity{"cpu": resource.MustParse("1")}},
+	})
+	app.setTaskGroupTimeouts(map[string]int64{"workers": 3600}, constants.SchedulingPolicyTimeoutReleaseAll)
+	app.scheduleTaskGroupTimeouts()
+	timer, ok := app.taskGroupTimers["workers"]
+	assert.Assert(t, ok, "timer of the task group with a timeout should be started")
+	assert.Equal(t, len(app.taskGroupTimers), 1)
+
+	// stopped timers are removed, no new timers are started after that
+	app.stopTimers()
+	assert.Equal(t, len(app.taskGroupTimers), 0)
+	assert.Assert(t, !timer.Stop(), "timer should be stopped")
+	app.scheduleTaskGroupTimeouts()
+	assert.Equal(t, len(app.taskGroupTimers), 0)
+}
+
+func TestApplication_handleTaskGroupTimeout(t *testing.T) {
+	context, apiProvider := initContextAndAPIProviderForTest()
+	dispatcher.RegisterEventHandler("TestAppHandler", dispatcher.EventTypeApp, context.ApplicationEventHandler())
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	recorder := k8sEvents.NewFakeRecorder(1024)
+	events.SetRecorder(recorder)
+	defer events.SetRecorder(events.NewMockedRecorder())
+	var deleted sync.Map
+	apiProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		deleted.Store(pod.Name, true)
+		return nil
+	})
+	NewPlaceholderManager(apiProvider.GetAPIs())
+
+	newApp := func(id string, release string, style string) *Application {
+		app := NewApplication(id, "root.a", "testuser", testGroups, map[string]string{}, newMockSchedulerAPI())
+		app.setTaskGroups([]TaskGroup{
+			{Name: "driver", MinMember: 1, MinResource: map[string]resource.Quantity{"cpu": resource.MustParse("1")}},
+			{Name: "workers", MinMember: 2, MinResource: map[string]resource.Quantity{"cpu": resource.MustParse("1")}},
+		})
+		app.setTaskGroupTimeouts(map[string]int64{"driver": 10, "workers": 30}, release)
+		app.setSchedulingStyle(style)
+		driver := NewTaskPlaceholder(id+"-driver", app, context, &v1.Pod{ObjectMeta: apis.ObjectMeta{Name: id + "-driver"}})
+		driver.setTaskGroupName("driver")
+		driver.sm.SetState(TaskStates().Bound)
+		worker := NewTaskPlaceholder(id+"-worker", app, context, &v1.Pod{ObjectMeta: apis.ObjectMeta{Name: id + "-worker"}})
+		worker.setTaskGroupName("workers")
+		app.addTask(driver)
+		app.addTask(worker)
+		app.setOriginatingTask(driver)
+		context.addApplicationToContext(app)
+		app.sm.SetState(ApplicationStates().Reserving)
+		return app
+	}
+	waitForEvent := func(message string) {
+		err := utils.WaitForCondition(func() bool {
+			for {
+				select {
+				case event := <-recorder.Events:
+					if strings.Contains(event, message) {
+						return true
+					}
+				default:
+					return false
+				}
+			}
+		}, 5*time.Millisecond, time.Second)
+		assert.NilError(t, err, "event %s should have been emitted", message)
+	}
+	waitForDelete := func(name string) {
+		err := utils.WaitForCondition(func() bool {
+			_, ok := deleted.Load(name)
+			return ok
+		}, 5*time.Millisecond, time.Second)
+		assert.NilError(t, err, "placeholder %s should have been deleted", name)
+	}
+
+	// release the unsatisfied task group: the satisfied driver keeps its placeholder and the app runs
+	app := newApp("app-partial", constants.SchedulingPolicyTimeoutReleaseUnsatisfied, "Soft")
+	assert.NilError(t, app.handle(NewTaskGroupTimeoutEvent(app.applicationID, "driver")))
+	assert.Equal(t, len(app.timedOutTaskGroups), 0, "satisfied task group should not time out")
+	assert.NilError(t, app.handle(NewTaskGroupTimeoutEvent(app.applicationID, "workers")))
+	assert.Assert(t, app.timedOutTaskGroups["workers"])
+	waitForEvent("releasing the unsatisfied task group")
+	waitForDelete("app-partial-worker")
+	assertAppState(t, app, ApplicationStates().Running, time.Second)
+	waitForEvent("timed out task groups are scheduled without placeholders")
+	_, ok := deleted.Load("app-partial-driver")
+	assert.Assert(t, !ok, "placeholder of the satisfied task group should be kept")
+
+	// release all placeholders, SOFT: the app resumes as a non-gang application
+	app = newApp("app-soft", constants.SchedulingPolicyTimeoutReleaseAll, "Soft")
+	assert.NilError(t, app.handle(NewTaskGroupTimeoutEvent(app.applicationID, "workers")))
+	waitForEvent("releasing all placeholders")
+	assertAppState(t, app, ApplicationStates().Resuming, time.Second)
+	waitForDelete("app-soft-driver")
+	waitForDelete("app-soft-worker")
+
+	// release all placeholders, HARD: the app fails
+	app = newApp("app-hard", constants.SchedulingPolicyTimeoutReleaseAll, "Hard")
+	assert.NilError(t, app.handle(NewTaskGroupTimeoutEvent(app.applicationID, "workers")))
+	assertAppState(t, app, ApplicationStates().Failing, time.Second)
+
+	// the last unsatisfied task group times out the gang
+	app = newApp("app-last", constants.SchedulingPolicyTimeoutReleaseUnsatisfied, "Soft")
+	app.timedOutTaskGroups["driver"] = true
+	assert.NilError(t, app.handle(NewTaskGroupTimeoutEvent(app.applicationID, "workers")))
+	assertAppState(t, app, ApplicationStates().Resuming, time.Second)
+}
+
 func TestTaskRemoval(t *testing.T) {
 	app := NewApplication(appID, "root.a", "testuser", testGroups, map[string]string{}, newMockSchedulerAPI())
 	context := initContextForTest()
@@ -29,9 +29,11 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	resourcev1beta1 "k8s.io/api/resource/v1beta1"
 	schedulingv1 "k8s.io/api/scheduling/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
@@ -40,6 +42,7 @@ import (
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumebinding"
 
+	"github.com/apache/yunikorn-core/pkg/scheduler"
 	schedulercache "github.com/apache/yunikorn-k8shim/pkg/cache/external"
 	"github.com/apache/yunikorn-k8shim/pkg/client"
 	"github.com/apache/yunikorn-k8shim/pkg/common"
@@ -76,6 +79,11 @@ type Context struct {
 	txnID          atomic.Uint64                  // transaction ID counter
 	klogger        klog.Logger
 	podActivator   atomic.Value
+	bindsInFlight  atomic.Int32       // in-flight bind operations
+	stopping       atomic.Bool        // set once the shim stops, the callbacks of the core are ignored
+	podGroups      *podGroupManager   // nil if PodGroup support is disabled
+	gangStatus     *gangStatusManager // nil if the gang status is not written on the owners
+	queueLimits    *queueLimits       // nil if the core does not run in the same process
 }
 
 // NewContext create a new context for the scheduler using a default (empty) configuration
@@ -103,6 +111,24 @@ func NewContextWithBootstrapConfigMaps(apis client.APIProvider, bootstrapConfigM
 	// create the cache
 	ctx.schedulerCache = schedulercache.NewSchedulerCache(apis.GetAPIs())
 
+	// resolve the devices of the dynamic resource allocation API into resources
+	if clients := apis.GetAPIs(); clients.IsDRAEnabled() {
+		common.SetDeviceResolver(newDeviceResolver(clients.ResourceClaimInformer.Lister(),
+			clients.ResourceSliceInformer.Lister(), clients.DeviceClassInformer.Lister()))
+	} else {
+		common.SetDeviceResolver(nil)
+	}
+
+	// use the PodGroups of the coscheduling API as a gang scheduling source
+	if clients := apis.GetAPIs(); clients.IsPodGroupEnabled() {
+		ctx.podGroups = newPodGroupManager(clients)
+	}
+
+	// write the gang scheduling progress on the owners of the applications
+	if clients := apis.GetAPIs(); clients.DynamicClient != nil && schedulerconf.GetSchedulerConf().IsGangStatusEnabled() {
+		ctx.gangStatus = newGangStatusManager(clients)
+	}
+
 	// create the predicate manager
 	sharedLister := support.NewSharedLister(ctx.schedulerCache)
 	clientSet := apis.GetAPIs().KubeClient.GetClientSet()
@@ -112,6 +138,14 @@ func NewContextWithBootstrapConfigMaps(apis client.APIProvider, bootstrapConfigM
 	return ctx
 }
 
+// SetCoreClusterContext links the context to the core running in the same process, the queues of the core are used
+// to check if the gangs fit. Must be called before the shim is started.
+func (ctx *Context) SetCoreClusterContext(clusterContext *scheduler.ClusterContext) {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+	ctx.queueLimits = newQueueLimits(clusterContext)
+}
+
 // SetPodActivator is used by the plugin mode to add a callback function to reschedule a pod
 func (ctx *Context) SetPodActivator(podActivator func(logger klog.Logger, pod *v1.Pod)) {
 	ctx.podActivator.Store(podActivator)
@@ -167,6 +201,18 @@ func (ctx *Context) AddSchedulingEventHandlers() error {
 		return err
 	}
 
+	if ctx.apiProvider.GetAPIs().IsDRAEnabled() {
+		err = ctx.apiProvider.AddEventHandler(&client.ResourceEventHandlers{
+			Type:     client.ResourceSliceInformerHandlers,
+			AddFn:    ctx.addResourceSlice,
+			UpdateFn: ctx.updateResourceSlice,
+			DeleteFn: ctx.deleteResourceSlice,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -225,17 +271,24 @@ func (ctx *Context) updateNodeInternal(node *v1.Node, register bool) {
 		}
 	} else {
 		// existing node
-		prevCapacity := common.GetNodeResource(&prevNode.Status)
-		newCapacity := common.GetNodeResource(&node.Status)
+		prevCapacity := common.GetSchedulableNodeResource(prevNode)
+		newCapacity := common.GetSchedulableNodeResource(node)
+		attributesChanged := !reflect.DeepEqual(getNodeAttributes(prevNode), getNodeAttributes(node))
 
-		if !common.Equals(prevCapacity, newCapacity) {
-			// update capacity
+		if !common.Equals(prevCapacity, newCapacity) || attributesChanged {
+			// update capacity and attributes
 			if err := ctx.updateNodeResources(node, newCapacity); err != nil {
 				log.Log(log.ShimContext).Warn("Failed to update node capacity", zap.Error(err))
 			} else {
 				log.Log(log.ShimContext).Warn("Failed to update cached node capacity", zap.String("nodeName", node.Name))
 			}
 		}
+
+		if schedulable := isNodeSchedulable(node); schedulable != isNodeSchedulable(prevNode) {
+			if err := ctx.updateNodeSchedulable(node, schedulable); err != nil {
+				log.Log(log.ShimContext).Warn("Failed to update node schedulable state", zap.Error(err))
+			}
+		}
 	}
 }
 
@@ -298,6 +351,8 @@ func (ctx *Context) AddPod(obj interface{}) {
 }
 
 func (ctx *Context) UpdatePod(oldObj, newObj interface{}) {
+	// slow down the informer while the dispatcher is catching up with a burst of events
+	dispatcher.WaitForCapacity()
 	ctx.lock.Lock()
 	defer ctx.lock.Unlock()
 	pod, err := utils.Convert2Pod(newObj)
@@ -367,6 +422,9 @@ func (ctx *Context) ensureAppAndTaskCreated(pod *v1.Pod, app *Application) {
 				zap.String("name", pod.Name))
 			return
 		}
+		if ctx.podGroups != nil {
+			ctx.podGroups.updateAppMetadata(pod, &appMeta)
+		}
 		app = ctx.addApplication(&AddApplicationRequest{
 			Metadata: appMeta,
 		})
@@ -648,14 +706,75 @@ func (ctx *Context) setConfigMap(index int, configMap *v1.ConfigMap) map[string]
 	ctx.lock.Lock()
 	defer ctx.lock.Unlock()
 	ctx.configMaps[index] = configMap
+	oldPolicies := schedulerconf.GetSchedulerConf().NodeResourcePolicies
 	err := schedulerconf.UpdateConfigMaps(ctx.configMaps, false)
 	if err != nil {
 		log.Log(log.ShimContext).Error("Unable to update configmap, ignoring changes", zap.Error(err))
 		return nil
 	}
+	if !reflect.DeepEqual(oldPolicies, schedulerconf.GetSchedulerConf().NodeResourcePolicies) {
+		ctx.updateAllNodeResources()
+	}
 	return schedulerconf.FlattenConfigMaps(ctx.configMaps)
 }
 
+// updateAllNodeResources re-sends the schedulable resources of all cached nodes to the core.
+// This method must be called while holding the Context write lock.
+func (ctx *Context) updateAllNodeResources() {
+	log.Log(log.ShimContext).Info("node resource policies changed, updating all nodes")
+	for _, nodeInfo := range ctx.schedulerCache.GetNodesInfo() {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		if err := ctx.updateNodeResources(node, common.GetSchedulableNodeResource(node)); err != nil {
+			log.Log(log.ShimContext).Warn("Failed to update node capacity",
+				zap.String("nodeName", node.Name),
+				zap.Error(err))
+		}
+	}
+}
+
+func (ctx *Context) addResourceSlice(obj interface{}) {
+	ctx.updateResourceSliceNode(obj)
+}
+
+func (ctx *Context) updateResourceSlice(_, obj interface{}) {
+	ctx.updateResourceSliceNode(obj)
+}
+
+func (ctx *Context) deleteResourceSlice(obj interface{}) {
+	if t, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = t.Obj
+	}
+	ctx.updateResourceSliceNode(obj)
+}
+
+// updateResourceSliceNode re-sends the resources of the node a changed ResourceSlice is published for, slices that
+// are not local to a node do not add to the node resources.
+func (ctx *Context) updateResourceSliceNode(obj interface{}) {
+	slice, ok := obj.(*resourcev1beta1.ResourceSlice)
+	if !ok {
+		log.Log(log.ShimContext).Warn("unable to convert to resourceSlice")
+		return
+	}
+	if slice.Spec.NodeName == "" {
+		return
+	}
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+	nodeInfo := ctx.schedulerCache.GetNode(slice.Spec.NodeName)
+	if nodeInfo == nil || nodeInfo.Node() == nil {
+		return
+	}
+	node := nodeInfo.Node()
+	if err := ctx.updateNodeResources(node, common.GetSchedulableNodeResource(node)); err != nil {
+		log.Log(log.ShimContext).Warn("Failed to update node capacity",
+			zap.String("nodeName", node.Name),
+			zap.Error(err))
+	}
+}
+
 // EventsToRegister returns the Kubernetes events that should be watched for updates which may effect predicate processing
 func (ctx *Context) EventsToRegister(queueingHintFn framework.QueueingHintFn) []framework.ClusterEventWithHint {
 	return ctx.predManager.EventsToRegister(queueingHintFn)
@@ -710,6 +829,49 @@ func (ctx *Context) IsPodFitNodeViaPreemption(name, node string, allocations []s
 	return -1, false
 }
 
+// trackBind registers a bind operation running in the background, the returned function must be called once the
+// bind operation has finished.
+func (ctx *Context) trackBind() func() {
+	ctx.bindsInFlight.Add(1)
+	return func() {
+		ctx.bindsInFlight.Add(-1)
+	}
+}
+
+// WaitForBinds waits until all in-flight bind operations have finished, or the timeout has passed.
+// Returns the number of bind operations still in flight. The counter is polled: unlike a WaitGroup wait, a timed out
+// wait does not leave a goroutine behind.
+func (ctx *Context) WaitForBinds(timeout time.Duration) int {
+	if err := utils.WaitForCondition(func() bool {
+		return ctx.bindsInFlight.Load() == 0
+	}, 10*time.Millisecond, timeout); err != nil {
+		log.Log(log.ShimContext).Warn("bind operations did not finish in time",
+			zap.Int32("inFlight", ctx.bindsInFlight.Load()),
+			zap.Duration("timeout", timeout))
+	}
+	return int(ctx.bindsInFlight.Load())
+}
+
+// StopCoreCallbacks makes the context ignore the allocation, application and node updates of the core. It is called
+// when the shim shuts down, before the queued events are drained, so no new binds are started while draining.
+func (ctx *Context) StopCoreCallbacks() {
+	ctx.stopping.Store(true)
+}
+
+// StopApplicationTimers stops the topology re-plan timers and the task group placeholder timeouts of all applications.
+// It is called when the shim shuts down, the timers would otherwise dispatch events after the dispatcher stopped.
+func (ctx *Context) StopApplicationTimers() {
+	ctx.lock.RLock()
+	defer ctx.lock.RUnlock()
+	for _, app := range ctx.applications {
+		app.stopTimers()
+	}
+}
+
+func (ctx *Context) isStopping() bool {
+	return ctx.stopping.Load()
+}
+
 // call volume binder to bind pod volumes if necessary,
 // internally, volume binder maintains a cache (podBindingCache) for pod volumes,
 // and before calling this, they should have been updated by FindPodVolumes and AssumePodVolumes.
@@ -789,6 +951,21 @@ func (ctx *Context) bindPodVolumes(pod *v1.Pod) error {
 	return nil
 }
 
+// bindPodResources allocates and reserves the resources that were reserved for the pod when it was assumed, like the
+// devices of the resource claims of the pod. This must be done before the pod is bound to the node: the kubelet does
+// not start a pod with resource claims that are not reserved for it.
+func (ctx *Context) bindPodResources(pod *v1.Pod) error {
+	plugin, err := ctx.predManager.PreBindPod(pod)
+	if err != nil {
+		log.Log(log.ShimContext).Error("Failed to bind pod resources",
+			zap.String("podName", pod.Name),
+			zap.String("plugin", plugin),
+			zap.Error(err))
+		return errors.Join(fmt.Errorf("failed plugin: '%s'", plugin), err)
+	}
+	return nil
+}
+
 // assume a pod will be running on a node, in scheduler, we maintain
 // a cache where stores info for each node what pods are supposed to
 // be running on it. And we keep this cache in-sync between core and the shim.
@@ -839,8 +1016,20 @@ func (ctx *Context) AssumePod(name, node string) error {
 					zap.Error(err))
 				return err
 			}
+			// reserve the resources the shim does not bind itself, like the devices of the resource claims
+			// in plugin mode the default scheduler reserves and binds them
+			if !utils.IsPluginMode() {
+				if plugin, reserveErr := ctx.predManager.ReservePod(pod, targetNode); reserveErr != nil {
+					log.Log(log.ShimContext).Error("Failed to reserve pod resources",
+						zap.String("podName", assumedPod.Name),
+						zap.String("plugin", plugin),
+						zap.Error(reserveErr))
+					return errors.Join(fmt.Errorf("failed plugin: '%s'", plugin), reserveErr)
+				}
+			}
 			allBound, err = ctx.apiProvider.GetAPIs().VolumeBinder.AssumePodVolumes(ctx.klogger, pod, node, volumes)
 			if err != nil {
+				ctx.predManager.UnreservePod(pod)
 				return err
 			}
 
@@ -860,6 +1049,7 @@ func (ctx *Context) ForgetPod(name string) {
 	defer ctx.lock.Unlock()
 	if pod := ctx.schedulerCache.GetPod(name); pod != nil {
 		log.Log(log.ShimContext).Debug("forget pod", zap.String("pod", pod.Name))
+		ctx.predManager.UnreservePod(pod)
 		ctx.schedulerCache.ForgetPod(pod)
 		return
 	}
@@ -1004,10 +1194,21 @@ func (ctx *Context) addApplication(request *AddApplicationRequest) *Application
 		app.tags[siCommon.DomainYuniKorn+siCommon.CreationTime] = strconv.FormatInt(request.Metadata.CreationTime, 10)
 	}
 	if request.Metadata.SchedulingPolicyParameters != nil {
-		app.SetPlaceholderTimeout(request.Metadata.SchedulingPolicyParameters.GetPlaceholderTimeout())
+		app.SetPlaceholderTimeout(request.Metadata.SchedulingPolicyParameters.GetGangTimeout())
 		app.setSchedulingStyle(request.Metadata.SchedulingPolicyParameters.GetGangSchedulingStyle())
+		app.setTaskGroupTimeouts(request.Metadata.SchedulingPolicyParameters.GetTaskGroupTimeouts(),
+			request.Metadata.SchedulingPolicyParameters.GetTimeoutRelease())
 	}
 	app.setPlaceholderOwnerReferences(request.Metadata.OwnerReferences)
+	if request.Metadata.PodGroupName != "" && ctx.podGroups != nil {
+		app.setPodGroup(request.Metadata.PodGroupName, ctx.podGroups)
+	}
+	if ctx.gangStatus != nil {
+		app.setGangStatus(ctx.gangStatus)
+	}
+	if ctx.queueLimits != nil {
+		app.setQueueLimits(ctx.queueLimits)
+	}
 
 	// add into cache
 	ctx.applications[app.applicationID] = app
@@ -1343,7 +1544,11 @@ func (ctx *Context) GetStateDump() (string, error) {
 	log.Log(log.ShimContext).Info("State dump requested")
 
 	dump := map[string]interface{}{
-		"cache": ctx.schedulerCache.GetSchedulerCacheDao(),
+		"cache":      ctx.schedulerCache.GetSchedulerCacheDao(),
+		"dispatcher": dispatcher.GetState(),
+	}
+	if mgr := getPlaceholderManager(); mgr != nil {
+		dump["placeholders"] = mgr.getState()
 	}
 
 	bytes, err := json.Marshal(dump)
@@ -1443,6 +1648,43 @@ func (ctx *Context) InitializeState() error {
 		return err
 	}
 
+	// Step 9: Collect orphan placeholders. Placeholders that could not be deleted before a restart are not tracked
+	// anymore: hand the placeholders that are not used by an application to the placeholder manager for deletion.
+	err = ctx.collectOrphanPlaceholders()
+	if err != nil {
+		log.Log(log.ShimContext).Error("failed to collect orphan placeholders", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// collectOrphanPlaceholders hands the placeholder pods that are not used by an application to the placeholder
+// manager. A placeholder is not used if its application is unknown or finished, or has no other pods left that could
+// replace the placeholder.
+func (ctx *Context) collectOrphanPlaceholders() error {
+	mgr := getPlaceholderManager()
+	if mgr == nil {
+		return nil
+	}
+	pods, err := ctx.apiProvider.GetAPIs().PodInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	orphans := make([]*v1.Pod, 0)
+	for _, pod := range pods {
+		if !utils.GetPlaceholderFlagFromPodSpec(pod) {
+			continue
+		}
+		if app := ctx.GetApplication(utils.GetApplicationIDFromPod(pod)); app == nil || !app.isPlaceholderInUse() {
+			orphans = append(orphans, pod)
+		}
+	}
+	if len(orphans) > 0 {
+		log.Log(log.ShimContext).Info("found orphan placeholders after restart",
+			zap.Int("count", len(orphans)))
+		mgr.addOrphanPlaceholders(orphans)
+	}
 	return nil
 }
 
@@ -1522,15 +1764,11 @@ func (ctx *Context) registerNodes(nodes []*v1.Node) ([]*v1.Node, error) {
 	// Generate a NodeInfo object for each node and add to the registration request
 	for _, node := range nodes {
 		log.Log(log.ShimContext).Info("Registering node", zap.String("name", node.Name))
-		nodeStatus := node.Status
 		nodesToRegister = append(nodesToRegister, &si.NodeInfo{
-			NodeID: node.Name,
-			Action: si.NodeInfo_CREATE_DRAIN,
-			Attributes: map[string]string{
-				constants.DefaultNodeAttributeHostNameKey: node.Name,
-				constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
-			},
-			SchedulableResource: common.GetNodeResource(&nodeStatus),
+			NodeID:              node.Name,
+			Action:              si.NodeInfo_CREATE_DRAIN,
+			Attributes:          getNodeAttributes(node),
+			SchedulableResource: common.GetSchedulableNodeResource(node),
 		})
 		pendingNodes[node.Name] = node
 	}
@@ -1555,6 +1793,27 @@ func (ctx *Context) registerNodes(nodes []*v1.Node) ([]*v1.Node, error) {
 	return acceptedNodes, nil
 }
 
+// getNodeAttributes returns the attributes that are sent to the core for a node.
+// The host and rack name are always set, node labels listed in the service.nodeAttributeLabelKeys
+// configuration are copied as-is and can override the default rack name.
+func getNodeAttributes(node *v1.Node) map[string]string {
+	attributes := map[string]string{
+		constants.DefaultNodeAttributeHostNameKey: node.Name,
+		constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
+	}
+	for _, key := range schedulerconf.GetSchedulerConf().GetNodeAttributeLabelKeys() {
+		if value, ok := node.Labels[key]; ok {
+			attributes[key] = value
+		}
+	}
+	return attributes
+}
+
+// isNodeSchedulable checks the cordon state and the taints configured in service.nodeDrainTaintKeys
+func isNodeSchedulable(node *v1.Node) bool {
+	return utils.IsNodeSchedulable(node, schedulerconf.GetSchedulerConf().GetNodeDrainTaintKeys())
+}
+
 func (ctx *Context) registerNodesInternal(nodesToRegister []*si.NodeInfo, pendingNodes map[string]*v1.Node) ([]*v1.Node, []*v1.Node, error) {
 	acceptedNodes := make([]*v1.Node, 0)
 	rejectedNodes := make([]*v1.Node, 0)
@@ -1613,10 +1872,30 @@ func (ctx *Context) decommissionNode(node *v1.Node) error {
 }
 
 func (ctx *Context) updateNodeResources(node *v1.Node, capacity *si.Resource) error {
-	request := common.CreateUpdateRequestForUpdatedNode(node.Name, capacity)
+	request := common.CreateUpdateRequestForUpdatedNode(node.Name, capacity, getNodeAttributes(node))
 	return ctx.apiProvider.GetAPIs().SchedulerAPI.UpdateNode(request)
 }
 
+// updateNodeSchedulable drains or restores the node in the core after a cordon, uncordon or taint change
+func (ctx *Context) updateNodeSchedulable(node *v1.Node, schedulable bool) error {
+	action := si.NodeInfo_DRAIN_NODE
+	reason := "NodeUnschedulable"
+	if schedulable {
+		action = si.NodeInfo_DRAIN_TO_SCHEDULABLE
+		reason = "NodeSchedulable"
+	}
+	log.Log(log.ShimContext).Info("Node schedulable state changed",
+		zap.String("nodeName", node.Name),
+		zap.Bool("schedulable", schedulable))
+	request := common.CreateUpdateRequestForDeleteOrRestoreNode(node.Name, action)
+	if err := ctx.apiProvider.GetAPIs().SchedulerAPI.UpdateNode(request); err != nil {
+		return err
+	}
+	events.GetRecorder().Eventf(node.DeepCopy(), nil, v1.EventTypeNormal, reason, reason,
+		"node %s schedulable state changed to %t in the scheduler", node.Name, schedulable)
+	return nil
+}
+
 func (ctx *Context) enableNode(node *v1.Node) error {
 	return ctx.enableNodes([]*v1.Node{node})
 }
@@ -1625,7 +1904,12 @@ func (ctx *Context) enableNodes(nodes []*v1.Node) error {
 	nodesToEnable := make([]*si.NodeInfo, 0)
 
 	// Generate a NodeInfo object for each node and add to the enablement request
+	// cordoned nodes stay drained until they become schedulable
 	for _, node := range nodes {
+		if !isNodeSchedulable(node) {
+			log.Log(log.ShimContext).Info("Not enabling unschedulable node", zap.String("name", node.Name))
+			continue
+		}
 		log.Log(log.ShimContext).Info("Enabling node", zap.String("name", node.Name))
 		nodesToEnable = append(nodesToEnable, &si.NodeInfo{
 			NodeID:     node.Name,
@@ -1633,6 +1917,9 @@ func (ctx *Context) enableNodes(nodes []*v1.Node) error {
 			Attributes: map[string]string{},
 		})
 	}
+	if len(nodesToEnable) == 0 {
+		return nil
+	}
 
 	// enable scheduling on all nodes
 	if err := ctx.apiProvider.GetAPIs().SchedulerAPI.UpdateNode(&si.NodeRequest{
@@ -41,6 +41,7 @@ import (
 	"github.com/apache/yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/yunikorn-k8shim/pkg/common/test"
 	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/dispatcher"
 	"github.com/apache/yunikorn-k8shim/pkg/log"
 	siCommon "github.com/apache/yunikorn-scheduler-interface/lib/go/common"
@@ -57,6 +58,8 @@ const (
 	appID4             = "app00004"
 	appID5             = "app00005"
 	non_existing_appID = "app-none-exist"
+	appID              = "app01"
+	app2ID             = "app02"
 
 	uid1 = "uid_0001"
 	uid2 = "uid_0002"
@@ -212,6 +215,198 @@ func TestUpdateNodes(t *testing.T) {
 	ctx.updateNode(&oldNode, &newNode)
 }
 
+func TestNodeAttributes(t *testing.T) {
+	ctx, apiProvider := initContextAndAPIProviderForTest()
+	dispatcher.Start()
+	defer dispatcher.UnregisterAllEventHandlers()
+	defer dispatcher.Stop()
+
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcNodeAttributeLabelKeys: "topology.kubernetes.io/zone,topology.kubernetes.io/region",
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+
+	var attributes map[string]string
+	apiProvider.MockSchedulerAPIUpdateNodeFn(func(request *si.NodeRequest) error {
+		for _, node := range request.Nodes {
+			if node.Action == si.NodeInfo_CREATE_DRAIN || node.Action == si.NodeInfo_UPDATE {
+				attributes = node.Attributes
+			}
+			dispatcher.Dispatch(CachedSchedulerNodeEvent{
+				NodeID: node.NodeID,
+				Event:  NodeAccepted,
+			})
+		}
+		return nil
+	})
+
+	node := v1.Node{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      Host1,
+			Namespace: "default",
+			UID:       uid1,
+			Labels: map[string]string{
+				"topology.kubernetes.io/zone": "zone-a",
+				"unrelated":                   "value",
+			},
+		},
+	}
+	ctx.addNode(&node)
+	assert.DeepEqual(t, attributes, map[string]string{
+		constants.DefaultNodeAttributeHostNameKey: Host1,
+		constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
+		"topology.kubernetes.io/zone":             "zone-a",
+	})
+
+	// label change must be re-sent even when the capacity does not change
+	attributes = nil
+	newNode := node.DeepCopy()
+	newNode.Labels["topology.kubernetes.io/zone"] = "zone-b"
+	newNode.Labels["topology.kubernetes.io/region"] = "region-1"
+	ctx.updateNode(&node, newNode)
+	assert.DeepEqual(t, attributes, map[string]string{
+		constants.DefaultNodeAttributeHostNameKey: Host1,
+		constants.DefaultNodeAttributeRackNameKey: constants.DefaultRackName,
+		"topology.kubernetes.io/zone":             "zone-b",
+		"topology.kubernetes.io/region":           "region-1",
+	})
+
+	// changes to labels which are not mapped are not sent
+	attributes = nil
+	unrelated := newNode.DeepCopy()
+	unrelated.Labels["unrelated"] = "other"
+	ctx.updateNode(newNode, unrelated)
+	assert.Assert(t, attributes == nil, "unexpected node update")
+}
+
+func TestNodeSchedulableChange(t *testing.T) {
+	ctx, apiProvider := initContextAndAPIProviderForTest()
+	dispatcher.Start()
+	defer dispatcher.UnregisterAllEventHandlers()
+	defer dispatcher.Stop()
+
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcNodeDrainTaintKeys: "maintenance",
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+
+	var actions []si.NodeInfo_ActionFromRM
+	apiProvider.MockSchedulerAPIUpdateNodeFn(func(request *si.NodeRequest) error {
+		for _, node := range request.Nodes {
+			actions = append(actions, node.Action)
+			if node.Action == si.NodeInfo_CREATE_DRAIN {
+				dispatcher.Dispatch(CachedSchedulerNodeEvent{
+					NodeID: node.NodeID,
+					Event:  NodeAccepted,
+				})
+			}
+		}
+		return nil
+	})
+
+	// a cordoned node is registered but not enabled
+	node := &v1.Node{
+		ObjectMeta: apis.ObjectMeta{
+			Name:      Host1,
+			Namespace: "default",
+			UID:       uid1,
+		},
+		Spec: v1.NodeSpec{Unschedulable: true},
+	}
+	ctx.addNode(node)
+	assert.DeepEqual(t, actions, []si.NodeInfo_ActionFromRM{si.NodeInfo_CREATE_DRAIN})
+
+	// uncordon
+	actions = nil
+	uncordoned := node.DeepCopy()
+	uncordoned.Spec.Unschedulable = false
+	ctx.updateNode(node, uncordoned)
+	assert.DeepEqual(t, actions, []si.NodeInfo_ActionFromRM{si.NodeInfo_DRAIN_TO_SCHEDULABLE})
+
+	// unrelated update does not change state
+	actions = nil
+	ctx.updateNode(uncordoned, uncordoned.DeepCopy())
+	assert.Equal(t, len(actions), 0)
+
+	// configured NoSchedule taint drains the node
+	tainted := uncordoned.DeepCopy()
+	tainted.Spec.Taints = []v1.Taint{{Key: "maintenance", Effect: v1.TaintEffectNoSchedule}}
+	ctx.updateNode(uncordoned, tainted)
+	assert.DeepEqual(t, actions, []si.NodeInfo_ActionFromRM{si.NodeInfo_DRAIN_NODE})
+
+	// other taints are ignored
+	actions = nil
+	otherTaint := uncordoned.DeepCopy()
+	otherTaint.Spec.Taints = []v1.Taint{{Key: "other", Effect: v1.TaintEffectNoSchedule}}
+	ctx.updateNode(tainted, otherTaint)
+	assert.DeepEqual(t, actions, []si.NodeInfo_ActionFromRM{si.NodeInfo_DRAIN_TO_SCHEDULABLE})
+}
+
+func TestNodeResourcePolicyReload(t *testing.T) {
+	ctx, apiProvider := initContextAndAPIProviderForTest()
+	dispatcher.Start()
+	defer dispatcher.UnregisterAllEventHandlers()
+	defer dispatcher.Stop()
+	defer func() {
+		err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+
+	updates := make(map[string]*si.Resource)
+	apiProvider.MockSchedulerAPIUpdateNodeFn(func(request *si.NodeRequest) error {
+		for _, node := range request.Nodes {
+			if node.Action == si.NodeInfo_CREATE_DRAIN || node.Action == si.NodeInfo_UPDATE {
+				updates[node.NodeID] = node.SchedulableResource
+			}
+			dispatcher.Dispatch(CachedSchedulerNodeEvent{
+				NodeID: node.NodeID,
+				Event:  NodeAccepted,
+			})
+		}
+		return nil
+	})
+
+	node := &v1.Node{
+		ObjectMeta: apis.ObjectMeta{
+			Name:   Host1,
+			UID:    uid1,
+			Labels: map[string]string{"pool": "batch"},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("8G"),
+			},
+		},
+	}
+	ctx.addNode(node)
+	assert.Equal(t, updates[Host1].Resources[siCommon.CPU].GetValue(), int64(4000))
+
+	// a policy change re-sends all registered nodes
+	delete(updates, Host1)
+	ctx.triggerReloadConfig(1, &v1.ConfigMap{Data: map[string]string{
+		conf.CMSvcNodeResourcePolicies: `[{"nodeSelector": "pool=batch", "factors": {"cpu": 2}}]`,
+	}})
+	assert.Assert(t, updates[Host1] != nil, "node was not updated")
+	assert.Equal(t, updates[Host1].Resources[siCommon.CPU].GetValue(), int64(8000))
+
+	// reload without a policy change does not update the nodes
+	delete(updates, Host1)
+	ctx.triggerReloadConfig(1, &v1.ConfigMap{Data: map[string]string{
+		conf.CMSvcNodeResourcePolicies:   `[{"nodeSelector": "pool=batch", "factors": {"cpu": 2}}]`,
+		conf.CMSvcNodeAttributeLabelKeys: "pool",
+	}})
+	assert.Assert(t, updates[Host1] == nil, "node was updated")
+}
+
 func TestDeleteNodes(t *testing.T) {
 	ctx, apiProvider := initContextAndAPIProviderForTest()
 	dispatcher.Start()
@@ -1704,6 +1899,57 @@ func TestGetStateDump(t *testing.T) {
 	assert.Assert(t, ok, "Unable to cast uid")
 
 	assert.Equal(t, string(pod1.UID), uid, "wrong uid")
+
+	dispatcherObj, ok := stateDump["dispatcher"]
+	assert.Assert(t, ok, "dispatcher not found")
+	dispatcherState, ok := dispatcherObj.(map[string]interface{})
+	assert.Assert(t, ok, "unable to cast dispatcher")
+	assert.Equal(t, dispatcherState["degraded"], false, "dispatcher should not be degraded")
+	_, ok = dispatcherState["queueDepth"]
+	assert.Assert(t, ok, "queue depth not found")
+	_, ok = dispatcherState["oldestEventAgeMs"]
+	assert.Assert(t, ok, "oldest event age not found")
+}
+
+func TestCollectOrphanPlaceholders(t *testing.T) {
+	context, apiProvider := initContextAndAPIProviderForTest()
+	mgr := NewPlaceholderManager(apiProvider.GetAPIs())
+	newPlaceholderPod := func(name, uid, appID string) *v1.Pod {
+		pod := newPodHelper(name, "default", uid, "", appID, v1.PodPending)
+		pod.Annotations = map[string]string{constants.AnnotationPlaceholderFlag: constants.True}
+		return pod
+	}
+	pods := []*v1.Pod{
+		// app with a running pod: placeholder in use
+		newPodHelper("driver", "default", "uid-driver", "", appID1, v1.PodPending),
+		newPlaceholderPod("ph-1", "uid-ph-1", appID1),
+		// app without other pods
+		newPlaceholderPod("ph-2", "uid-ph-2", appID2),
+	}
+	for _, pod := range pods {
+		context.AddPod(pod)
+		apiProvider.GetPodListerMock().AddPod(pod)
+	}
+	// unknown app: the pod is not known to the context
+	unknown := newPlaceholderPod("ph-3", "uid-ph-3", "unknown-app")
+	apiProvider.GetPodListerMock().AddPod(unknown)
+
+	assert.NilError(t, context.collectOrphanPlaceholders())
+	assert.Equal(t, mgr.getOrphanPodsLength(), 2)
+	_, ok := mgr.orphanPods["uid-ph-2"]
+	assert.Assert(t, ok, "placeholder of an app without pods should be an orphan")
+	_, ok = mgr.orphanPods["uid-ph-3"]
+	assert.Assert(t, ok, "placeholder of an unknown app should be an orphan")
+
+	// the orphans are part of the state dump
+	stateDumpStr, err := context.GetStateDump()
+	assert.NilError(t, err, "error during state dump")
+	var stateDump struct {
+		Placeholders PlaceholderState `json:"placeholders"`
+	}
+	assert.NilError(t, json.Unmarshal([]byte(stateDumpStr), &stateDump), "unable to parse state dump")
+	assert.Equal(t, stateDump.Placeholders.OrphanCount, 2)
+	assert.Equal(t, len(stateDump.Placeholders.Orphans), 2)
 }
 
 func TestFilterPriorityClasses(t *testing.T) {
@@ -2530,3 +2776,19 @@ func assertListerPods(pods []*v1.Pod, count int) bool {
 	}
 	return count == counted
 }
+
+func TestWaitForBinds(t *testing.T) {
+	context := initContextForTest()
+	assert.Equal(t, context.WaitForBinds(time.Second), 0)
+
+	done1 := context.trackBind()
+	done2 := context.trackBind()
+	done1()
+	assert.Equal(t, context.WaitForBinds(10*time.Millisecond), 1, "one bind should still be in flight")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		done2()
+	}()
+	assert.Equal(t, context.WaitForBinds(time.Second), 0, "all binds should have finished")
+}
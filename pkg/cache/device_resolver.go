@@ -0,0 +1,180 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	resourcev1beta1 "k8s.io/api/resource/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	resourcelisters "k8s.io/client-go/listers/resource/v1beta1"
+	"k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/dynamic-resource-allocation/resourceclaim"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+)
+
+// number of compiled device class selectors that are cached
+const celCacheSize = 10
+
+// deviceResolver resolves ResourceClaims and ResourceSlices into device counts per device class using the listers
+// of the dynamic resource allocation informers.
+type deviceResolver struct {
+	claimLister resourcelisters.ResourceClaimLister
+	sliceLister resourcelisters.ResourceSliceLister
+	classLister resourcelisters.DeviceClassLister
+	celCache    *cel.Cache
+}
+
+var _ common.DeviceResolver = &deviceResolver{}
+
+func newDeviceResolver(claimLister resourcelisters.ResourceClaimLister, sliceLister resourcelisters.ResourceSliceLister, classLister resourcelisters.DeviceClassLister) *deviceResolver {
+	return &deviceResolver{
+		claimLister: claimLister,
+		sliceLister: sliceLister,
+		classLister: classLister,
+		celCache:    cel.NewCache(celCacheSize),
+	}
+}
+
+// GetPodDevices returns the devices requested by the ResourceClaims of the pod. Claims that do not exist yet, like
+// claims that still need to be generated from a template, are skipped: tasks are only submitted once all their claims
+// exist, and the resource of the task is recalculated at that point. A claim shared by multiple pods is counted for
+// each pod.
+func (r *deviceResolver) GetPodDevices(pod *v1.Pod) map[string]int64 {
+	devices := make(map[string]int64)
+	for i := range pod.Spec.ResourceClaims {
+		claimName, _, err := resourceclaim.Name(pod, &pod.Spec.ResourceClaims[i])
+		if err != nil || claimName == nil {
+			log.Log(log.ShimContext).Debug("resource claim of pod not resolved",
+				zap.String("namespace", pod.Namespace),
+				zap.String("podName", pod.Name),
+				zap.String("claim", pod.Spec.ResourceClaims[i].Name),
+				zap.Error(err))
+			continue
+		}
+		claim, err := r.claimLister.ResourceClaims(pod.Namespace).Get(*claimName)
+		if err != nil {
+			log.Log(log.ShimContext).Debug("resource claim of pod not found",
+				zap.String("namespace", pod.Namespace),
+				zap.String("podName", pod.Name),
+				zap.String("claimName", *claimName),
+				zap.Error(err))
+			continue
+		}
+		for idx := range claim.Spec.Devices.Requests {
+			request := &claim.Spec.Devices.Requests[idx]
+			if count := requestedDevices(claim, request); count > 0 {
+				devices[request.DeviceClassName] += count
+			}
+		}
+	}
+	return devices
+}
+
+// requestedDevices returns the number of devices for the request. For requests that ask for all devices the number
+// is only known after the claim is allocated.
+func requestedDevices(claim *resourcev1beta1.ResourceClaim, request *resourcev1beta1.DeviceRequest) int64 {
+	if request.AllocationMode == resourcev1beta1.DeviceAllocationModeAll {
+		if claim.Status.Allocation == nil {
+			return 0
+		}
+		var count int64
+		for _, result := range claim.Status.Allocation.Devices.Results {
+			if result.Request == request.Name {
+				count++
+			}
+		}
+		return count
+	}
+	if request.Count <= 0 {
+		return 1
+	}
+	return request.Count
+}
+
+// GetNodeDevices returns the devices published for the node, counted for each device class that selects them. Only
+// slices that are local to the node and part of the latest generation of their pool are taken into account.
+func (r *deviceResolver) GetNodeDevices(nodeName string) map[string]int64 {
+	devices := make(map[string]int64)
+	slices, err := r.sliceLister.List(labels.Everything())
+	if err != nil {
+		log.Log(log.ShimContext).Warn("failed to list resource slices", zap.Error(err))
+		return devices
+	}
+	classes, err := r.classLister.List(labels.Everything())
+	if err != nil {
+		log.Log(log.ShimContext).Warn("failed to list device classes", zap.Error(err))
+		return devices
+	}
+	generations := make(map[string]int64)
+	nodeSlices := make([]*resourcev1beta1.ResourceSlice, 0)
+	for _, slice := range slices {
+		if slice.Spec.NodeName != nodeName {
+			continue
+		}
+		pool := slice.Spec.Driver + "/" + slice.Spec.Pool.Name
+		generations[pool] = max(generations[pool], slice.Spec.Pool.Generation)
+		nodeSlices = append(nodeSlices, slice)
+	}
+	for _, slice := range nodeSlices {
+		if slice.Spec.Pool.Generation < generations[slice.Spec.Driver+"/"+slice.Spec.Pool.Name] {
+			continue
+		}
+		for idx := range slice.Spec.Devices {
+			for _, class := range classes {
+				if r.deviceMatches(class, slice.Spec.Driver, &slice.Spec.Devices[idx]) {
+					devices[class.Name]++
+				}
+			}
+		}
+	}
+	return devices
+}
+
+// deviceMatches returns true if the device is selected by all selectors of the device class
+func (r *deviceResolver) deviceMatches(class *resourcev1beta1.DeviceClass, driver string, device *resourcev1beta1.Device) bool {
+	if device.Basic == nil {
+		return false
+	}
+	for _, selector := range class.Spec.Selectors {
+		if selector.CEL == nil {
+			continue
+		}
+		expr := r.celCache.GetOrCompile(selector.CEL.Expression)
+		if expr.Error != nil {
+			log.Log(log.ShimContext).Warn("invalid device class selector",
+				zap.String("deviceClass", class.Name),
+				zap.Error(expr.Error))
+			return false
+		}
+		matches, _, err := expr.DeviceMatches(context.Background(), cel.Device{
+			Driver:     driver,
+			Attributes: device.Basic.Attributes,
+			Capacity:   device.Basic.Capacity,
+		})
+		if err != nil || !matches {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+	resourcev1beta1 "k8s.io/api/resource/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	resourcelisters "k8s.io/client-go/listers/resource/v1beta1"
+	k8scache "k8s.io/client-go/tools/cache"
+)
+
+func newTestDeviceResolver(t *testing.T, objs ...interface{}) *deviceResolver {
+	claims := k8scache.NewIndexer(k8scache.MetaNamespaceKeyFunc, k8scache.Indexers{k8scache.NamespaceIndex: k8scache.MetaNamespaceIndexFunc})
+	slices := k8scache.NewIndexer(k8scache.MetaNamespaceKeyFunc, k8scache.Indexers{})
+	classes := k8scache.NewIndexer(k8scache.MetaNamespaceKeyFunc, k8scache.Indexers{})
+	for _, obj := range objs {
+		var err error
+		switch obj.(type) {
+		case *resourcev1beta1.ResourceClaim:
+			err = claims.Add(obj)
+		case *resourcev1beta1.ResourceSlice:
+			err = slices.Add(obj)
+		case *resourcev1beta1.DeviceClass:
+			err = classes.Add(obj)
+		}
+		assert.NilError(t, err, "failed to add object to indexer")
+	}
+	return newDeviceResolver(resourcelisters.NewResourceClaimLister(claims),
+		resourcelisters.NewResourceSliceLister(slices), resourcelisters.NewDeviceClassLister(classes))
+}
+
+func TestGetPodDevices(t *testing.T) {
+	claimName := "gpu-claim"
+	exact := &resourcev1beta1.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: claimName, Namespace: "default"},
+		Spec: resourcev1beta1.ResourceClaimSpec{
+			Devices: resourcev1beta1.DeviceClaim{
+				Requests: []resourcev1beta1.DeviceRequest{
+					{Name: "gpus", DeviceClassName: "gpu.example.com", AllocationMode: resourcev1beta1.DeviceAllocationModeExactCount, Count: 2},
+					{Name: "nic", DeviceClassName: "nic.example.com"},
+				},
+			},
+		},
+	}
+	all := &resourcev1beta1.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "generated-claim", Namespace: "default"},
+		Spec: resourcev1beta1.ResourceClaimSpec{
+			Devices: resourcev1beta1.DeviceClaim{
+				Requests: []resourcev1beta1.DeviceRequest{
+					{Name: "all", DeviceClassName: "gpu.example.com", AllocationMode: resourcev1beta1.DeviceAllocationModeAll},
+				},
+			},
+		},
+		Status: resourcev1beta1.ResourceClaimStatus{
+			Allocation: &resourcev1beta1.AllocationResult{
+				Devices: resourcev1beta1.DeviceAllocationResult{
+					Results: []resourcev1beta1.DeviceRequestAllocationResult{
+						{Request: "all", Driver: "gpu.example.com", Pool: "node-1", Device: "gpu-0"},
+						{Request: "all", Driver: "gpu.example.com", Pool: "node-1", Device: "gpu-1"},
+						{Request: "all", Driver: "gpu.example.com", Pool: "node-1", Device: "gpu-2"},
+					},
+				},
+			},
+		},
+	}
+	resolver := newTestDeviceResolver(t, exact, all)
+
+	templateName := "template"
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec: v1.PodSpec{
+			ResourceClaims: []v1.PodResourceClaim{
+				{Name: "direct", ResourceClaimName: &claimName},
+				{Name: "template", ResourceClaimTemplateName: &templateName},
+			},
+		},
+	}
+	// generated claim not created yet
+	devices := resolver.GetPodDevices(pod)
+	assert.DeepEqual(t, devices, map[string]int64{"gpu.example.com": 2, "nic.example.com": 1})
+
+	generated := "generated-claim"
+	pod.Status.ResourceClaimStatuses = []v1.PodResourceClaimStatus{{Name: "template", ResourceClaimName: &generated}}
+	devices = resolver.GetPodDevices(pod)
+	assert.DeepEqual(t, devices, map[string]int64{"gpu.example.com": 5, "nic.example.com": 1})
+
+	// claim does not exist
+	missing := "missing"
+	pod.Spec.ResourceClaims = []v1.PodResourceClaim{{Name: "missing", ResourceClaimName: &missing}}
+	devices = resolver.GetPodDevices(pod)
+	assert.Equal(t, len(devices), 0)
+}
+
+func TestGetNodeDevices(t *testing.T) {
+	driver := "gpu.example.com"
+	gpuClass := &resourcev1beta1.DeviceClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu.example.com"},
+		Spec: resourcev1beta1.DeviceClassSpec{
+			Selectors: []resourcev1beta1.DeviceSelector{
+				{CEL: &resourcev1beta1.CELDeviceSelector{Expression: `device.driver == "gpu.example.com"`}},
+			},
+		},
+	}
+	otherClass := &resourcev1beta1.DeviceClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "other.example.com"},
+		Spec: resourcev1beta1.DeviceClassSpec{
+			Selectors: []resourcev1beta1.DeviceSelector{
+				{CEL: &resourcev1beta1.CELDeviceSelector{Expression: `device.driver == "other.example.com"`}},
+			},
+		},
+	}
+	devices := []resourcev1beta1.Device{
+		{Name: "gpu-0", Basic: &resourcev1beta1.BasicDevice{}},
+		{Name: "gpu-1", Basic: &resourcev1beta1.BasicDevice{}},
+	}
+	current := &resourcev1beta1.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1-current"},
+		Spec: resourcev1beta1.ResourceSliceSpec{
+			Driver:   driver,
+			NodeName: "node-1",
+			Pool:     resourcev1beta1.ResourcePool{Name: "node-1", Generation: 2, ResourceSliceCount: 1},
+			Devices:  devices,
+		},
+	}
+	outdated := &resourcev1beta1.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1-outdated"},
+		Spec: resourcev1beta1.ResourceSliceSpec{
+			Driver:   driver,
+			NodeName: "node-1",
+			Pool:     resourcev1beta1.ResourcePool{Name: "node-1", Generation: 1, ResourceSliceCount: 1},
+			Devices:  devices,
+		},
+	}
+	otherNode := &resourcev1beta1.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+		Spec: resourcev1beta1.ResourceSliceSpec{
+			Driver:   driver,
+			NodeName: "node-2",
+			Pool:     resourcev1beta1.ResourcePool{Name: "node-2", Generation: 1, ResourceSliceCount: 1},
+			Devices:  devices[:1],
+		},
+	}
+	resolver := newTestDeviceResolver(t, gpuClass, otherClass, current, outdated, otherNode)
+
+	assert.DeepEqual(t, resolver.GetNodeDevices("node-1"), map[string]int64{"gpu.example.com": 2})
+	assert.DeepEqual(t, resolver.GetNodeDevices("node-2"), map[string]int64{"gpu.example.com": 1})
+	assert.Equal(t, len(resolver.GetNodeDevices("node-3")), 0)
+}
@@ -0,0 +1,216 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+)
+
+// maxOwnerDepth limits the owner references followed to find the top-level owner of a pod
+const maxOwnerDepth = 5
+
+// GangStatus is the gang scheduling progress of an application, written as a JSON annotation on the workload owner
+type GangStatus struct {
+	ApplicationID string            `json:"applicationId"`
+	Phase         string            `json:"phase"`
+	TaskGroups    []TaskGroupStatus `json:"taskGroups"`
+}
+
+// TaskGroupStatus is the number of desired and bound placeholders of a task group
+type TaskGroupStatus struct {
+	Name      string `json:"name"`
+	MinMember int32  `json:"minMember"`
+	Desired   int32  `json:"desired"`
+	Bound     int32  `json:"bound"`
+	TimedOut  bool   `json:"timedOut,omitempty"`
+}
+
+type gangStatusUpdate struct {
+	ownerRefs []metav1.OwnerReference
+	pod       *v1.Pod // originating pod, used when the owner reference is the pod itself
+	namespace string
+	status    *GangStatus
+	owner     *gangStatusOwner
+}
+
+// gangStatusOwner is the top-level owner of an application, resolved on the first write of the gang status and again
+// when the owner is not found. Only used by the writer of the application, which runs one write at a time.
+type gangStatusOwner struct {
+	resolved bool
+	gvr      schema.GroupVersionResource
+	name     string // empty if the application has no owner to write the status on
+	status   string // gang status annotation on the owner, unchanged statuses are not written
+}
+
+// gangStatusManager writes the gang status of the applications on the top-level owner of their originating pod, for
+// example the Job or the SparkApplication. Job owners and dashboards look at the owner, not at the events of the pod.
+type gangStatusManager struct {
+	clients  *client.Clients
+	statuses *keyedWriter[*gangStatusUpdate]
+}
+
+func newGangStatusManager(clients *client.Clients) *gangStatusManager {
+	m := &gangStatusManager{
+		clients: clients,
+	}
+	m.statuses = newKeyedWriter(m.writeStatusUpdate)
+	return m
+}
+
+// setStatus writes the gang status on the owner asynchronously. Updates of the same application are written in order,
+// an update that is not written yet is replaced by a newer one.
+func (m *gangStatusManager) setStatus(update *gangStatusUpdate) {
+	m.statuses.set(update.status.ApplicationID, update)
+}
+
+func (m *gangStatusManager) writeStatusUpdate(key string, update *gangStatusUpdate) {
+	if err := m.writeStatus(update); err != nil {
+		log.Log(log.ShimCacheApplication).Warn("failed to update the gang status of the owner",
+			zap.String("appID", key),
+			zap.String("phase", update.status.Phase),
+			zap.Error(err))
+	}
+}
+
+func (m *gangStatusManager) writeStatus(update *gangStatusUpdate) error {
+	value, err := json.Marshal(update.status)
+	if err != nil {
+		return fmt.Errorf("unable to marshal the gang status: %w", err)
+	}
+	owner := update.owner
+	if !owner.resolved {
+		if err = m.resolveOwner(update); err != nil {
+			return err
+		}
+	}
+	if owner.name == "" || owner.status == string(value) {
+		return nil
+	}
+	err = m.patchOwner(owner, update.namespace, value)
+	if apierrors.IsNotFound(err) {
+		// the owner was deleted or replaced since it was resolved
+		if err = m.resolveOwner(update); err != nil || owner.name == "" || owner.status == string(value) {
+			return err
+		}
+		err = m.patchOwner(owner, update.namespace, value)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	owner.status = string(value)
+	return nil
+}
+
+func (m *gangStatusManager) patchOwner(owner *gangStatusOwner, namespace string, value []byte) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{constants.AnnotationGangStatus: string(value)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal the gang status patch: %w", err)
+	}
+	_, err = m.clients.DynamicClient.Resource(owner.gvr).Namespace(namespace).
+		Patch(context.Background(), owner.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// resolveOwner finds the top-level owner of the application and stores it in the owner of the update
+func (m *gangStatusManager) resolveOwner(update *gangStatusUpdate) error {
+	obj, gvr, err := m.getTopLevelOwner(update)
+	if err != nil {
+		return err
+	}
+	*update.owner = gangStatusOwner{resolved: true}
+	if obj != nil {
+		update.owner.gvr = gvr
+		update.owner.name = obj.GetName()
+		update.owner.status = obj.GetAnnotations()[constants.AnnotationGangStatus]
+	}
+	return nil
+}
+
+// getTopLevelOwner follows the controller references, starting from the owner references of the placeholders, up to
+// the owner without a controller. An owner that cannot be read ends the walk at the last owner that could be read.
+// Returns nil if the originating pod has no owner: the events of the pod describe the gang progress.
+func (m *gangStatusManager) getTopLevelOwner(update *gangStatusUpdate) (*unstructured.Unstructured, schema.GroupVersionResource, error) {
+	ref := getControllerReference(update.ownerRefs)
+	if ref != nil && ref.APIVersion == "v1" && ref.Kind == "Pod" {
+		ref = nil
+		if update.pod != nil {
+			ref = metav1.GetControllerOf(update.pod)
+		}
+	}
+	var owner *unstructured.Unstructured
+	var gvr schema.GroupVersionResource
+	for depth := 0; ref != nil && depth < maxOwnerDepth; depth++ {
+		refGVR, _ := meta.UnsafeGuessKindToResource(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		obj, err := m.clients.DynamicClient.Resource(refGVR).Namespace(update.namespace).
+			Get(context.Background(), ref.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			if owner == nil {
+				return nil, gvr, fmt.Errorf("unable to get owner %s %s/%s: %w", ref.Kind, update.namespace, ref.Name, err)
+			}
+			// the owner cannot be read, for example the RBAC does not cover it: use the last owner that can be read
+			log.Log(log.ShimCacheApplication).Debug("unable to get owner, using the last owner that could be read",
+				zap.String("owner", ref.Kind+" "+update.namespace+"/"+ref.Name),
+				zap.String("lastOwner", owner.GetKind()+" "+owner.GetNamespace()+"/"+owner.GetName()),
+				zap.Error(err))
+			break
+		}
+		owner = obj
+		gvr = refGVR
+		ref = metav1.GetControllerOfNoCopy(obj)
+	}
+	return owner, gvr, nil
+}
+
+// getControllerReference returns the controller in the owner references, the first reference if none is the controller
+func getControllerReference(refs []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
+		}
+	}
+	if len(refs) > 0 {
+		return &refs[0]
+	}
+	return nil
+}
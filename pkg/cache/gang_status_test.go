@@ -0,0 +1,225 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
+)
+
+var (
+	jobResource        = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	deploymentResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+)
+
+func newTestOwner(apiVersion, kind, name string, owner *metav1.OwnerReference) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+	}}
+	if owner != nil {
+		obj.SetOwnerReferences([]metav1.OwnerReference{*owner})
+	}
+	return obj
+}
+
+func newControllerRef(apiVersion, kind, name string) *metav1.OwnerReference {
+	controller := true
+	return &metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, Controller: &controller}
+}
+
+func newTestGangStatusManager(owners ...*unstructured.Unstructured) *gangStatusManager {
+	objects := make([]runtime.Object, len(owners))
+	for i, owner := range owners {
+		objects[i] = owner
+	}
+	return newGangStatusManager(&client.Clients{
+		DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...),
+	})
+}
+
+func getTestGangStatus(t *testing.T, m *gangStatusManager, gvr schema.GroupVersionResource, name string) *GangStatus {
+	obj, err := m.clients.DynamicClient.Resource(gvr).Namespace("default").Get(context.Background(), name, metav1.GetOptions{})
+	assert.NilError(t, err, "failed to get owner")
+	value, ok := obj.GetAnnotations()[constants.AnnotationGangStatus]
+	if !ok {
+		return nil
+	}
+	status := &GangStatus{}
+	assert.NilError(t, json.Unmarshal([]byte(value), status), "failed to parse gang status")
+	return status
+}
+
+func TestGangStatusTopLevelOwner(t *testing.T) {
+	m := newTestGangStatusManager(
+		newTestOwner("apps/v1", "Deployment", "deploy", nil),
+		newTestOwner("apps/v1", "ReplicaSet", "rs", newControllerRef("apps/v1", "Deployment", "deploy")),
+		newTestOwner("batch/v1", "Job", "job", nil),
+	)
+	status := &GangStatus{ApplicationID: "app-1", Phase: "CreatingPlaceholders",
+		TaskGroups: []TaskGroupStatus{{Name: "tg", MinMember: 2, Desired: 3}}}
+
+	// the owner reference of the placeholders is the originating pod: the controller of the pod is followed up
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default",
+		OwnerReferences: []metav1.OwnerReference{*newControllerRef("apps/v1", "ReplicaSet", "rs")}}}
+	update := &gangStatusUpdate{
+		ownerRefs: getOwnerReference(pod),
+		pod:       pod,
+		namespace: "default",
+		status:    status,
+		owner:     &gangStatusOwner{},
+	}
+	assert.NilError(t, m.writeStatus(update))
+	assert.DeepEqual(t, getTestGangStatus(t, m, deploymentResource, "deploy"), status)
+	assert.Assert(t, getTestGangStatus(t, m, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}, "rs") == nil,
+		"only the top-level owner should be updated")
+
+	// owner references set explicitly
+	update.ownerRefs = []metav1.OwnerReference{*newControllerRef("batch/v1", "Job", "job")}
+	update.owner = &gangStatusOwner{}
+	assert.NilError(t, m.writeStatus(update))
+	assert.DeepEqual(t, getTestGangStatus(t, m, jobResource, "job"), status)
+
+	// no owner or a deleted owner: nothing to write
+	update.pod = &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	update.ownerRefs = getOwnerReference(update.pod)
+	update.owner = &gangStatusOwner{}
+	assert.NilError(t, m.writeStatus(update))
+	update.ownerRefs = []metav1.OwnerReference{*newControllerRef("batch/v1", "Job", "deleted")}
+	update.owner = &gangStatusOwner{}
+	assert.NilError(t, m.writeStatus(update))
+}
+
+func TestGangStatusOwnerCached(t *testing.T) {
+	m := newTestGangStatusManager(newTestOwner("batch/v1", "Job", "job", nil))
+	fakeClient, ok := m.clients.DynamicClient.(*dynamicfake.FakeDynamicClient)
+	assert.Assert(t, ok, "unexpected dynamic client type")
+	gets := 0
+	fakeClient.PrependReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		return false, nil, nil
+	})
+	status := &GangStatus{ApplicationID: "app-1", Phase: "CreatingPlaceholders",
+		TaskGroups: []TaskGroupStatus{{Name: "tg", MinMember: 2, Desired: 3}}}
+	update := &gangStatusUpdate{
+		ownerRefs: []metav1.OwnerReference{*newControllerRef("batch/v1", "Job", "job")},
+		namespace: "default",
+		status:    status,
+		owner:     &gangStatusOwner{},
+	}
+	assert.NilError(t, m.writeStatus(update))
+	assert.Equal(t, gets, 1, "owner should be resolved on the first write")
+
+	// the owner is resolved once per application
+	status.Phase = "PlaceholderAllocated"
+	assert.NilError(t, m.writeStatus(update))
+	assert.Equal(t, gets, 1, "resolved owner should be reused")
+	assert.DeepEqual(t, getTestGangStatus(t, m, jobResource, "job"), status)
+
+	// the owner is resolved again when it is not found
+	err := m.clients.DynamicClient.Resource(jobResource).Namespace("default").Delete(context.Background(), "job", metav1.DeleteOptions{})
+	assert.NilError(t, err, "failed to delete owner")
+	gets = 0
+	status.Phase = "GangReservationComplete"
+	assert.NilError(t, m.writeStatus(update))
+	assert.Equal(t, gets, 1, "deleted owner should be resolved again")
+	assert.Equal(t, update.owner.name, "", "deleted owner should not be used")
+}
+
+func TestGangStatusUnreadableOwner(t *testing.T) {
+	m := newTestGangStatusManager(
+		newTestOwner("apps/v1", "Deployment", "deploy", nil),
+		newTestOwner("apps/v1", "ReplicaSet", "rs", newControllerRef("apps/v1", "Deployment", "deploy")),
+	)
+	fakeClient, ok := m.clients.DynamicClient.(*dynamicfake.FakeDynamicClient)
+	assert.Assert(t, ok, "unexpected dynamic client type")
+	forbidden := map[string]bool{"deployments": true}
+	fakeClient.PrependReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		resource := action.GetResource()
+		if forbidden[resource.Resource] {
+			return true, nil, apierrors.NewForbidden(resource.GroupResource(), "", nil)
+		}
+		return false, nil, nil
+	})
+	status := &GangStatus{ApplicationID: "app-1", Phase: "CreatingPlaceholders",
+		TaskGroups: []TaskGroupStatus{{Name: "tg", MinMember: 2, Desired: 3}}}
+	update := &gangStatusUpdate{
+		ownerRefs: []metav1.OwnerReference{*newControllerRef("apps/v1", "ReplicaSet", "rs")},
+		namespace: "default",
+		status:    status,
+		owner:     &gangStatusOwner{},
+	}
+	// the top-level owner cannot be read: the last owner that could be read is updated
+	assert.NilError(t, m.writeStatus(update))
+	assert.DeepEqual(t, getTestGangStatus(t, m, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}, "rs"), status)
+
+	// the first owner cannot be read: nothing to write
+	forbidden["replicasets"] = true
+	update.owner = &gangStatusOwner{}
+	assert.ErrorContains(t, m.writeStatus(update), "unable to get owner")
+}
+
+func TestApplicationGangStatus(t *testing.T) {
+	m := newTestGangStatusManager(newTestOwner("batch/v1", "Job", "job", nil))
+	app := NewApplication(appID1, "root.a", "testuser", testGroups, map[string]string{constants.AppTagNamespace: "default"}, newMockSchedulerAPI())
+	app.setTaskGroups([]TaskGroup{{Name: "tg-1", MinMember: 2, MaxMember: 3}, {Name: "tg-2", MinMember: 1}})
+	app.setPlaceholderOwnerReferences([]metav1.OwnerReference{*newControllerRef("batch/v1", "Job", "job")})
+
+	// not linked to the writer: nothing written
+	app.updateGangStatus("CreatingPlaceholders")
+	assert.Assert(t, getTestGangStatus(t, m, jobResource, "job") == nil)
+
+	app.setGangStatus(m)
+	app.timedOutTaskGroups["tg-2"] = true
+	app.updateGangStatus("TaskGroupPlaceholderTimeout")
+	expected := &GangStatus{
+		ApplicationID: appID1,
+		Phase:         "TaskGroupPlaceholderTimeout",
+		TaskGroups: []TaskGroupStatus{
+			{Name: "tg-1", MinMember: 2, Desired: 3},
+			{Name: "tg-2", MinMember: 1, Desired: 1, TimedOut: true},
+		},
+	}
+	err := utils.WaitForCondition(func() bool {
+		status := getTestGangStatus(t, m, jobResource, "job")
+		return status != nil && status.Phase == expected.Phase
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "gang status not written on the owner")
+	assert.DeepEqual(t, getTestGangStatus(t, m, jobResource, "job"), expected)
+}
@@ -21,8 +21,6 @@ package cache
 import (
 	"fmt"
 	"math/rand"
-	"strconv"
-	"strings"
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
@@ -92,35 +90,12 @@ func GetPlaceholderResourceRequests(resources map[string]resource.Quantity) v1.R
 }
 
 func GetSchedulingPolicyParam(pod *v1.Pod) *SchedulingPolicyParameters {
-	timeout := int64(0)
-	style := constants.SchedulingPolicyStyleParamDefault
-	schedulingPolicyParams := NewSchedulingPolicyParameters(timeout, style)
-	param := utils.GetPodAnnotationValue(pod, constants.AnnotationSchedulingPolicyParam)
-	if param == "" {
-		return schedulingPolicyParams
+	params, errs := utils.ParseSchedulingPolicyParam(utils.GetPodAnnotationValue(pod, constants.AnnotationSchedulingPolicyParam))
+	for _, err := range errs {
+		log.Log(log.ShimUtils).Warn("Ignoring invalid scheduling policy parameter",
+			zap.String("namespace", pod.Namespace),
+			zap.String("name", pod.Name),
+			zap.Error(err))
 	}
-	params := strings.Split(param, constants.SchedulingPolicyParamDelimiter)
-	var err error
-	for _, p := range params {
-		param := strings.Split(p, "=")
-		if len(param) != 2 {
-			log.Log(log.ShimUtils).Warn("Skipping malformed scheduling policy parameter: ", zap.String("namespace", pod.Namespace), zap.String("name", pod.Name), zap.String("Scheduling Policy parameters passed in annotation: ", p))
-			continue
-		}
-		if param[0] == constants.SchedulingPolicyTimeoutParam {
-			timeout, err = strconv.ParseInt(param[1], 10, 64)
-			if err != nil {
-				log.Log(log.ShimUtils).Warn("Failed to parse timeout value from annotation", zap.String("namespace", pod.Namespace), zap.String("name", pod.Name), zap.Int64("Using Placeholder timeout: ", timeout), zap.String("Placeholder timeout passed in annotation: ", p))
-			}
-		} else if param[0] == constants.SchedulingPolicyStyleParam {
-			style = constants.SchedulingPolicyStyleParamValues[param[1]]
-			if style == "" {
-				style = constants.SchedulingPolicyStyleParamDefault
-				log.Log(log.ShimUtils).Warn("Unknown gang scheduling style, using "+constants.SchedulingPolicyStyleParamDefault+" style as default",
-					zap.String("namespace", pod.Namespace), zap.String("name", pod.Name), zap.String("Gang scheduling style passed in annotation: ", p))
-			}
-		}
-	}
-	schedulingPolicyParams = NewSchedulingPolicyParameters(timeout, style)
-	return schedulingPolicyParams
+	return params
 }
@@ -29,6 +29,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
 )
 
 func TestFindAppTaskGroup(t *testing.T) {
@@ -139,6 +140,31 @@ func TestGetSchedulingPolicyParams(t *testing.T) {
 	}
 }
 
+func TestGetSchedulingPolicyParamsTaskGroupTimeouts(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-pod",
+		Namespace: "test",
+		Annotations: map[string]string{constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds=60 " +
+			"placeholderTimeoutInSeconds.driver=30 placeholderTimeoutInSeconds.workers=120 placeholderTimeoutInSeconds.bad=-1 " +
+			"placeholderTimeoutInSeconds.=10 placeholderTimeoutRelease=Unsatisfied"},
+	}}
+	params := GetSchedulingPolicyParam(pod)
+	assert.Equal(t, params.GetPlaceholderTimeout(), int64(60))
+	assert.DeepEqual(t, params.GetTaskGroupTimeouts(), map[string]int64{"driver": 30, "workers": 120})
+	assert.Equal(t, params.GetTimeoutRelease(), constants.SchedulingPolicyTimeoutReleaseUnsatisfied)
+	assert.Equal(t, params.GetGangTimeout(), int64(120), "application timeout should cover the task group timeouts")
+
+	// unknown release falls back to releasing all placeholders, the longest task group timeout replaces the default
+	pod.Annotations[constants.AnnotationSchedulingPolicyParam] = "placeholderTimeoutInSeconds.driver=30 placeholderTimeoutInSeconds.workers=120 placeholderTimeoutRelease=Some"
+	params = GetSchedulingPolicyParam(pod)
+	assert.Equal(t, params.GetTimeoutRelease(), constants.SchedulingPolicyTimeoutReleaseAll)
+	assert.Equal(t, params.GetGangTimeout(), int64(120))
+
+	// no timeouts: the default timeout of the core is used
+	assert.Equal(t, utils.NewSchedulingPolicyParameters(0, "Soft").GetGangTimeout(), int64(0))
+	assert.Equal(t, utils.NewSchedulingPolicyParameters(0, "Soft").GetTimeoutRelease(), constants.SchedulingPolicyTimeoutReleaseAll)
+}
+
 func Test_GetPlaceholderResourceRequest(t *testing.T) {
 	tests := []struct {
 		name   string
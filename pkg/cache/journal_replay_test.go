@@ -0,0 +1,225 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+)
+
+func TestReplayJournal(t *testing.T) {
+	app := dispatcher.EventTypeApp.String()
+	task := dispatcher.EventTypeTask.String()
+	entries := []dispatcher.JournalEntry{
+		{Type: app, Event: SubmitApplication.String(), ApplicationID: appID1, Result: dispatcher.JournalResultHandled},
+		{Type: app, Event: AcceptApplication.String(), ApplicationID: appID1, Result: dispatcher.JournalResultHandled},
+		{Type: app, Event: RunApplication.String(), ApplicationID: appID1, Result: dispatcher.JournalResultHandled},
+		{Type: task, Event: InitTask.String(), ApplicationID: appID1, TaskID: taskUID1, Result: dispatcher.JournalResultHandled},
+		{Type: task, Event: InitTask.String(), ApplicationID: appID1, TaskID: taskUID2, Result: dispatcher.JournalResultHandled},
+		{Type: task, Event: SubmitTask.String(), ApplicationID: appID1, TaskID: taskUID1, Result: dispatcher.JournalResultHandled},
+		{Type: task, Event: SubmitTask.String(), ApplicationID: appID1, TaskID: taskUID2, Result: dispatcher.JournalResultHandled},
+		{Type: "node", Event: NodeAccepted.String(), NodeID: fakeNodeName, Result: dispatcher.JournalResultHandled},
+		// events which were not handled are skipped
+		{Type: task, Event: TaskRejected.String(), ApplicationID: appID1, TaskID: taskUID1, Args: journalArgs("rejected"), Result: dispatcher.JournalResultDiscarded},
+		{Type: task, Event: TaskRejected.String(), ApplicationID: appID1, TaskID: taskUID2, Args: journalArgs("rejected"), Result: dispatcher.JournalResultCoalesced},
+		{Type: task, Event: TaskAllocated.String(), ApplicationID: appID1, TaskID: taskUID1, Args: journalArgs(taskUID1, fakeNodeName), Result: dispatcher.JournalResultHandled},
+		{Type: task, Event: TaskRejected.String(), ApplicationID: appID1, TaskID: taskUID2, Args: journalArgs("rejected"), Result: dispatcher.JournalResultHandled},
+	}
+
+	context, _ := replayJournal(entries)
+	application := context.GetApplication(appID1)
+	assert.Assert(t, application != nil, "application should have been created")
+	assert.Equal(t, application.GetApplicationState(), ApplicationStates().Running)
+
+	task1 := application.GetTask(taskUID1)
+	assert.Assert(t, task1 != nil, "task should have been created")
+	assert.Equal(t, task1.GetTaskState(), TaskStates().Allocated)
+	assert.Equal(t, task1.GetNodeName(), fakeNodeName)
+	assert.Equal(t, context.WaitForBinds(time.Second), 0)
+
+	task2 := application.GetTask(taskUID2)
+	assert.Assert(t, task2 != nil, "task should have been created")
+	assert.Equal(t, task2.GetTaskState(), TaskStates().Rejected)
+}
+
+const (
+	replayQueue     = "root.default"
+	replayUser      = "replay"
+	replayNamespace = "default"
+)
+
+// ReplayJournal replays the application and task events recorded in the dispatcher journal into a new context backed
+// by the mocked API provider, to reproduce the state transitions of the applications and tasks.
+// Applications and tasks are created the first time they are referenced by an event. The events are handled in the
+// recorded order, without the dispatcher: the dispatcher must not be running, the events dispatched by the state
+// machines are part of the journal and must not be handled twice.
+// Node events, events which were not handled, e.g. discarded or coalesced events, and events with arguments that
+// cannot be decoded are skipped.
+func replayJournal(entries []dispatcher.JournalEntry) (*Context, *client.MockedAPIProvider) {
+	apiProvider := client.NewMockedAPIProvider(false)
+	ctx := NewContext(apiProvider)
+	appHandler := ctx.ApplicationEventHandler()
+	taskHandler := ctx.TaskEventHandler()
+
+	for _, entry := range entries {
+		if entry.Result != dispatcher.JournalResultHandled && entry.Result != dispatcher.JournalResultFailed {
+			continue
+		}
+		args, err := replayArgs(entry.Args)
+		if err != nil {
+			log.Log(log.ShimContext).Warn("skipping journal entry",
+				zap.String("type", entry.Type),
+				zap.String("event", entry.Event),
+				zap.Error(err))
+			continue
+		}
+		switch entry.Type {
+		case dispatcher.EventTypeApp.String():
+			ctx.replayApplication(entry.ApplicationID)
+			appHandler(replayApplicationEvent{
+				applicationID: entry.ApplicationID,
+				event:         entry.Event,
+				args:          args,
+			})
+		case dispatcher.EventTypeTask.String():
+			ctx.replayApplication(entry.ApplicationID)
+			ctx.replayTask(entry.ApplicationID, entry.TaskID)
+			taskHandler(replayTaskEvent{
+				applicationID: entry.ApplicationID,
+				taskID:        entry.TaskID,
+				event:         entry.Event,
+				args:          args,
+			})
+		default:
+			log.Log(log.ShimContext).Debug("skipping journal entry",
+				zap.String("type", entry.Type),
+				zap.String("event", entry.Event))
+		}
+	}
+	return ctx, apiProvider
+}
+
+// replayArgs decodes the recorded arguments of an event
+func replayArgs(recorded []dispatcher.JournalArg) ([]interface{}, error) {
+	args := make([]interface{}, len(recorded))
+	for i, arg := range recorded {
+		value, err := arg.Decode()
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+// journalArgs records the arguments as they are recorded by the dispatcher
+func journalArgs(args ...interface{}) []dispatcher.JournalArg {
+	recorded := make([]dispatcher.JournalArg, len(args))
+	for i, arg := range args {
+		recorded[i] = dispatcher.NewJournalArg(arg)
+	}
+	return recorded
+}
+
+// replayApplication adds the application to the context if it does not exist yet
+func (ctx *Context) replayApplication(appID string) {
+	ctx.AddApplication(&AddApplicationRequest{
+		Metadata: ApplicationMetadata{
+			ApplicationID: appID,
+			QueueName:     replayQueue,
+			User:          replayUser,
+			Tags:          map[string]string{constants.AppTagNamespace: replayNamespace},
+		},
+	})
+}
+
+// replayTask adds the task, backed by a minimal pod, to the context if it does not exist yet
+func (ctx *Context) replayTask(appID, taskID string) {
+	ctx.AddTask(&AddTaskRequest{
+		Metadata: TaskMetadata{
+			ApplicationID: appID,
+			TaskID:        taskID,
+			Pod: &v1.Pod{
+				ObjectMeta: apis.ObjectMeta{
+					Name:      taskID,
+					Namespace: replayNamespace,
+					UID:       types.UID(taskID),
+					Labels:    map[string]string{constants.LabelApplicationID: appID},
+				},
+				Spec: v1.PodSpec{
+					SchedulerName: constants.SchedulerName,
+				},
+			},
+		},
+	})
+}
+
+// replayApplicationEvent is an application event rebuilt from a journal entry
+type replayApplicationEvent struct {
+	applicationID string
+	event         string
+	args          []interface{}
+}
+
+func (re replayApplicationEvent) GetApplicationID() string {
+	return re.applicationID
+}
+
+func (re replayApplicationEvent) GetEvent() string {
+	return re.event
+}
+
+func (re replayApplicationEvent) GetArgs() []interface{} {
+	return re.args
+}
+
+// replayTaskEvent is a task event rebuilt from a journal entry
+type replayTaskEvent struct {
+	applicationID string
+	taskID        string
+	event         string
+	args          []interface{}
+}
+
+func (re replayTaskEvent) GetApplicationID() string {
+	return re.applicationID
+}
+
+func (re replayTaskEvent) GetTaskID() string {
+	return re.taskID
+}
+
+func (re replayTaskEvent) GetEvent() string {
+	return re.event
+}
+
+func (re replayTaskEvent) GetArgs() []interface{} {
+	return re.args
+}
@@ -0,0 +1,67 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"github.com/apache/yunikorn-k8shim/pkg/locking"
+)
+
+// keyedWriter writes values to the API server asynchronously without blocking the caller. Values of the same key are
+// written in order by a single goroutine, a value that is not written yet is replaced by a newer one.
+type keyedWriter[T any] struct {
+	write func(key string, value T)
+	// latest value to write per key, and the keys with a running writer
+	pending map[string]T
+	writing map[string]bool
+	lock    locking.Mutex
+}
+
+func newKeyedWriter[T any](write func(key string, value T)) *keyedWriter[T] {
+	return &keyedWriter[T]{
+		write:   write,
+		pending: make(map[string]T),
+		writing: make(map[string]bool),
+	}
+}
+
+// set queues the value for the key, a writer is started if none is running for the key
+func (w *keyedWriter[T]) set(key string, value T) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.pending[key] = value
+	if !w.writing[key] {
+		w.writing[key] = true
+		go w.run(key)
+	}
+}
+
+func (w *keyedWriter[T]) run(key string) {
+	for {
+		w.lock.Lock()
+		value, ok := w.pending[key]
+		if !ok {
+			delete(w.writing, key)
+			w.lock.Unlock()
+			return
+		}
+		delete(w.pending, key)
+		w.lock.Unlock()
+		w.write(key, value)
+	}
+}
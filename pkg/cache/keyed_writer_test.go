@@ -0,0 +1,70 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/yunikorn-k8shim/pkg/locking"
+)
+
+func TestKeyedWriter(t *testing.T) {
+	var lock locking.Mutex
+	written := make(map[string][]int)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	w := newKeyedWriter(func(key string, value int) {
+		if value == 1 {
+			close(started)
+			<-release
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		written[key] = append(written[key], value)
+	})
+	getWritten := func(key string) []int {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]int(nil), written[key]...)
+	}
+
+	// the first write blocks: the values set meanwhile are replaced by the latest one
+	w.set("a", 1)
+	<-started
+	w.set("a", 2)
+	w.set("a", 3)
+	// other keys are not blocked
+	w.set("b", 4)
+	err := utils.WaitForCondition(func() bool {
+		return len(getWritten("b")) == 1
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "value of key b not written")
+	assert.Equal(t, len(getWritten("a")), 0)
+
+	close(release)
+	err = utils.WaitForCondition(func() bool {
+		return len(getWritten("a")) == 2
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "values of key a not written")
+	assert.DeepEqual(t, getWritten("a"), []int{1, 3})
+}
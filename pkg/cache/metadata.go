@@ -108,7 +108,7 @@ func getAppMetadata(pod *v1.Pod) (ApplicationMetadata, bool) {
 	var taskGroups []TaskGroup = nil
 	var err error = nil
 	if !conf.GetSchedulerConf().DisableGangScheduling {
-		taskGroups, err = GetTaskGroupsFromAnnotation(pod)
+		taskGroups, err = utils.GetTaskGroupsFromAnnotation(pod)
 		if err != nil {
 			log.Log(log.ShimCacheApplication).Error("unable to get taskGroups for pod",
 				zap.String("namespace", pod.Namespace),
@@ -118,6 +118,9 @@ func getAppMetadata(pod *v1.Pod) (ApplicationMetadata, bool) {
 				"unable to get taskGroups for pod, reason: %s", err.Error())
 		}
 		tags[constants.AnnotationTaskGroups] = pod.Annotations[constants.AnnotationTaskGroups]
+		if template, ok := pod.Annotations[constants.AnnotationPlaceholderTemplate]; ok {
+			tags[constants.AnnotationPlaceholderTemplate] = template
+		}
 	}
 
 	var ownerReferences []metav1.OwnerReference
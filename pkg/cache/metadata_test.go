@@ -128,6 +128,7 @@ func TestGetAppMetadata(t *testing.T) { //nolint:funlen
 			Annotations: map[string]string{
 				constants.AnnotationTaskGroups:            taskGroupInfo,
 				constants.AnnotationSchedulingPolicyParam: "gangSchedulingStyle=Soft",
+				constants.AnnotationPlaceholderTemplate:   `{"image": "pause"}`,
 			},
 		},
 		Spec: v1.PodSpec{
@@ -149,6 +150,7 @@ func TestGetAppMetadata(t *testing.T) { //nolint:funlen
 	assert.Equal(t, app.Tags[constants.AppTagImagePullSecrets], "secret1,secret2")
 	assert.Equal(t, app.Tags[common.AppTagCreateForce], "false")
 	assert.Assert(t, app.Tags[constants.AnnotationTaskGroups] != "")
+	assert.Equal(t, app.Tags[constants.AnnotationPlaceholderTemplate], `{"image": "pause"}`)
 	assert.Equal(t, app.TaskGroups[0].Name, "test-group-1")
 	assert.Equal(t, app.TaskGroups[0].MinMember, int32(3))
 	assert.Equal(t, app.TaskGroups[0].MinResource["cpu"], resource.MustParse("2"))
@@ -25,6 +25,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/apache/yunikorn-k8shim/pkg/client"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
 	"github.com/apache/yunikorn-k8shim/pkg/conf"
@@ -46,7 +47,9 @@ type Placeholder struct {
 	pod           *v1.Pod
 }
 
-func newPlaceholder(placeholderName string, app *Application, taskGroup TaskGroup) *Placeholder {
+// newPlaceholder creates the placeholder pod for the task group. The template, which can be nil, overrides the
+// image, runtime class, service account, priority class, labels and limits of the placeholder.
+func newPlaceholder(placeholderName string, app *Application, taskGroup TaskGroup, template *conf.PlaceholderTemplate) *Placeholder {
 	// Here the owner reference is always the originator pod
 	ownerRefs := app.getPlaceholderOwnerReferences()
 	annotations := utils.MergeMaps(taskGroup.Annotations, map[string]string{
@@ -85,12 +88,31 @@ func newPlaceholder(placeholderName string, app *Application, taskGroup TaskGrou
 
 	// prepare the resource lists
 	requests := GetPlaceholderResourceRequests(taskGroup.MinResource)
+	limits := getPlaceholderResourceLimits(requests, template)
+
+	image := conf.GetSchedulerConf().PlaceHolderImage
+	var runtimeClassName *string
+	var serviceAccountName string
+	var templateLabels map[string]string
+	if template != nil {
+		if template.Image != "" {
+			image = template.Image
+		}
+		if template.RuntimeClassName != "" {
+			runtimeClassName = &template.RuntimeClassName
+		}
+		if template.PriorityClassName != "" {
+			priorityClassName = template.PriorityClassName
+		}
+		serviceAccountName = template.ServiceAccountName
+		templateLabels = template.Labels
+	}
 	var zeroSeconds int64 = 0
 	placeholderPod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      placeholderName,
 			Namespace: app.tags[constants.AppTagNamespace],
-			Labels: utils.MergeMaps(taskGroup.Labels, map[string]string{
+			Labels: utils.MergeMaps(utils.MergeMaps(templateLabels, taskGroup.Labels), map[string]string{
 				constants.CanonicalLabelApplicationID: app.GetApplicationID(),
 				constants.CanonicalLabelQueueName:     app.GetQueue(),
 			}),
@@ -106,11 +128,11 @@ func newPlaceholder(placeholderName string, app *Application, taskGroup TaskGrou
 			Containers: []v1.Container{
 				{
 					Name:            constants.PlaceholderContainerName,
-					Image:           conf.GetSchedulerConf().PlaceHolderImage,
+					Image:           image,
 					ImagePullPolicy: v1.PullIfNotPresent,
 					Resources: v1.ResourceRequirements{
 						Requests: requests,
-						Limits:   requests,
+						Limits:   limits,
 					},
 				},
 			},
@@ -121,6 +143,8 @@ func newPlaceholder(placeholderName string, app *Application, taskGroup TaskGrou
 			Affinity:                      taskGroup.Affinity,
 			TopologySpreadConstraints:     taskGroup.TopologySpreadConstraints,
 			PriorityClassName:             priorityClassName,
+			RuntimeClassName:              runtimeClassName,
+			ServiceAccountName:            serviceAccountName,
 			TerminationGracePeriodSeconds: &zeroSeconds,
 		},
 	}
@@ -132,6 +156,89 @@ func newPlaceholder(placeholderName string, app *Application, taskGroup TaskGrou
 	}
 }
 
+// getPlaceholderResourceLimits returns the limits of the placeholder: the requests, overridden by the limits of the
+// template. Limits of resources that are not requested are ignored, Kubernetes would set the request to the limit.
+func getPlaceholderResourceLimits(requests v1.ResourceList, template *conf.PlaceholderTemplate) v1.ResourceList {
+	limits := requests.DeepCopy()
+	for name, quantity := range template.GetLimits() {
+		if _, ok := requests[v1.ResourceName(name)]; ok {
+			limits[v1.ResourceName(name)] = quantity
+		}
+	}
+	return limits
+}
+
+// getPlaceholderTemplate returns the placeholder template of the task group: the cluster default from the
+// configuration, overridden by the template of the application and the template of the task group.
+// A PodTemplate is applied at the level that references it: the values of the PodTemplate are used for the fields
+// that are not set at that level, the more specific levels override them.
+// The template is validated against the task group: a limit cannot be lower than the request of the placeholder.
+func getPlaceholderTemplate(kubeClient client.KubeClient, app *Application, taskGroup TaskGroup) (*conf.PlaceholderTemplate, error) {
+	appTemplate, err := conf.ParsePlaceholderTemplate(app.tags[constants.AnnotationPlaceholderTemplate])
+	if err != nil {
+		return nil, fmt.Errorf("application %s has an invalid placeholder template: %w", app.GetApplicationID(), err)
+	}
+	var template *conf.PlaceholderTemplate
+	for _, level := range []*conf.PlaceholderTemplate{conf.GetSchedulerConf().PlaceholderTemplate, appTemplate, taskGroup.PlaceholderTemplate} {
+		if level, err = resolvePodTemplate(kubeClient, app, level); err != nil {
+			return nil, err
+		}
+		template = template.Merge(level)
+	}
+	if template == nil {
+		return nil, nil
+	}
+	if err = template.Validate(); err != nil {
+		return nil, fmt.Errorf("task group %s has an invalid placeholder template: %w", taskGroup.Name, err)
+	}
+	requests := GetPlaceholderResourceRequests(taskGroup.MinResource)
+	for name, limit := range template.GetLimits() {
+		if request, ok := requests[v1.ResourceName(name)]; ok && limit.Cmp(request) < 0 {
+			return nil, fmt.Errorf("task group %s has a placeholder limit for %s lower than the minResource: %s < %s",
+				taskGroup.Name, name, limit.String(), request.String())
+		}
+	}
+	return template, nil
+}
+
+// resolvePodTemplate applies the PodTemplate referenced by the template, the fields set in the template override the
+// values of the PodTemplate. The PodTemplate is read from the namespace of the application.
+func resolvePodTemplate(kubeClient client.KubeClient, app *Application, template *conf.PlaceholderTemplate) (*conf.PlaceholderTemplate, error) {
+	if template == nil || template.PodTemplate == "" {
+		return template, nil
+	}
+	namespace := app.tags[constants.AppTagNamespace]
+	podTemplate, err := kubeClient.GetPodTemplate(namespace, template.PodTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get placeholder pod template %s/%s: %w", namespace, template.PodTemplate, err)
+	}
+	return newTemplateFromPodTemplate(podTemplate).Merge(template), nil
+}
+
+// newTemplateFromPodTemplate converts a PodTemplate object into a placeholder template, the image and limits are
+// taken from the first container.
+func newTemplateFromPodTemplate(podTemplate *v1.PodTemplate) *conf.PlaceholderTemplate {
+	spec := podTemplate.Template.Spec
+	template := &conf.PlaceholderTemplate{
+		ServiceAccountName: spec.ServiceAccountName,
+		PriorityClassName:  spec.PriorityClassName,
+		Labels:             podTemplate.Template.Labels,
+	}
+	if spec.RuntimeClassName != nil {
+		template.RuntimeClassName = *spec.RuntimeClassName
+	}
+	if len(spec.Containers) > 0 {
+		template.Image = spec.Containers[0].Image
+		if len(spec.Containers[0].Resources.Limits) > 0 {
+			template.Limits = make(map[string]string, len(spec.Containers[0].Resources.Limits))
+			for name, quantity := range spec.Containers[0].Resources.Limits {
+				template.Limits[string(name)] = quantity.String()
+			}
+		}
+	}
+	return template
+}
+
 func (p *Placeholder) String() string {
 	return fmt.Sprintf("appID: %s, taskGroup: %s, podName: %s/%s",
 		p.appID, p.taskGroupName, p.pod.Namespace, p.pod.Name)
@@ -19,14 +19,23 @@
 package cache
 
 import (
+	"errors"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/locking"
 	"github.com/apache/yunikorn-k8shim/pkg/log"
 )
@@ -39,27 +48,55 @@ type PlaceholderManager struct {
 	// this pod becomes to be an "orphan" pod. We add them to a map
 	// and keep retrying deleting them in order to avoid wasting resources.
 	orphanPods  map[string]*v1.Pod
+	orphanSince map[string]time.Time // time the pod became an orphan, keyed like the orphanPods
 	stopChan    chan struct{}
 	running     atomic.Bool
 	cleanupTime time.Duration
+	// client-side rate limit shared by all placeholder creations
+	createLimiter    flowcontrol.RateLimiter
+	createLimiterQPS int
+	// placeholders that failed to be created within the failure threshold, keyed by application ID. The creation is
+	// retried on every cleanup tick while the application still reserves.
+	retryPlaceholders map[string]*placeholderRetry
 	// a simple mutex will do we do not have separate read and write paths
 	locking.RWMutex
 }
 
+// placeholderRetry are the placeholders of an application of which the creation is retried
+type placeholderRetry struct {
+	app          *Application
+	placeholders []*Placeholder
+}
+
+// errPlaceholderNotWanted is returned if the application moved past the reservation before the placeholder was created
+var errPlaceholderNotWanted = errors.New("application does not reserve placeholders anymore")
+
 var (
 	placeholderMgr *PlaceholderManager
 	mu             locking.Mutex
+
+	// backoff between the attempts to create a placeholder after a transient error, the number of steps is set
+	// from the configured number of retries
+	placeholderCreateBackoff = wait.Backoff{
+		Duration: 500 * time.Millisecond,
+		Factor:   2.0,
+		Jitter:   0.1,
+		Cap:      10 * time.Second,
+	}
 )
 
 func NewPlaceholderManager(clients *client.Clients) *PlaceholderManager {
 	mu.Lock()
 	defer mu.Unlock()
 	placeholderMgr = &PlaceholderManager{
-		clients:     clients,
-		orphanPods:  make(map[string]*v1.Pod),
-		stopChan:    make(chan struct{}),
-		cleanupTime: 5 * time.Second,
+		clients:           clients,
+		orphanPods:        make(map[string]*v1.Pod),
+		orphanSince:       make(map[string]time.Time),
+		retryPlaceholders: make(map[string]*placeholderRetry),
+		stopChan:          make(chan struct{}),
+		cleanupTime:       5 * time.Second,
 	}
+	initPlaceholderMetrics()
 	return placeholderMgr
 }
 
@@ -69,44 +106,298 @@ func getPlaceholderManager() *PlaceholderManager {
 	return placeholderMgr
 }
 
+// createAppPlaceholders creates the missing placeholders of the application. The placeholders are created concurrently
+// by a bounded pool of workers, rate limited, and transient API errors are retried with backoff.
+// The placeholders of a task group with a topologyKey are pinned to one topology domain.
+// An error is returned if the percentage of placeholders that could not be created exceeds the configured failure
+// threshold: the application should then fall back to normal scheduling. The creation of the placeholders that failed
+// within the threshold is retried until the application stops reserving.
 func (mgr *PlaceholderManager) createAppPlaceholders(app *Application) error {
-	mgr.Lock()
-	defer mgr.Unlock()
-
 	// map task group to count of already created placeholders
 	tgCounts := make(map[string]int32)
 	for _, ph := range app.getPlaceHolderTasks() {
 		tgCounts[ph.GetTaskGroupName()]++
 	}
 
-	// iterate all task groups, create placeholders for all the min members
+	// iterate all task groups, collect the missing placeholders for all the members: elastic task groups get
+	// placeholders up to the max members, the additional placeholders stay pending after the gang is satisfied
+	// the templates are resolved and validated, and the topology domains planned, before any placeholder is created
+	placeholders := make([]*Placeholder, 0)
+	topologyGroups := make([]string, 0)
 	for _, tg := range app.getTaskGroups() {
-		count := tgCounts[tg.Name]
-		// only create missing pods for each task group
-		for i := count; i < tg.MinMember; i++ {
+		if tgCounts[tg.Name] >= tg.GetMaxMember() {
+			continue
+		}
+		template, err := getPlaceholderTemplate(mgr.clients.KubeClient, app, tg)
+		if err != nil {
+			log.Log(log.ShimCachePlaceholder).Error("invalid placeholder template",
+				zap.String("appID", app.GetApplicationID()),
+				zap.String("taskGroup", tg.Name),
+				zap.Error(err))
+			return err
+		}
+		domain, err := mgr.getTopologyDomain(app, tg)
+		if err != nil {
+			log.Log(log.ShimCachePlaceholder).Error("unable to place task group in a topology domain",
+				zap.String("appID", app.GetApplicationID()),
+				zap.String("taskGroup", tg.Name),
+				zap.String("topologyKey", tg.TopologyKey),
+				zap.Error(err))
+			return err
+		}
+		for i := tgCounts[tg.Name]; i < tg.GetMaxMember(); i++ {
 			placeholderName := GeneratePlaceholderName(tg.Name, app.GetApplicationID())
-			placeholder := newPlaceholder(placeholderName, app, tg)
-			// create the placeholder on K8s
-			_, err := mgr.clients.KubeClient.Create(placeholder.pod)
-			if err != nil {
-				log.Log(log.ShimCachePlaceholder).Error("failed to create placeholder pod",
-					zap.Error(err))
-				return err
+			placeholder := newPlaceholder(placeholderName, app, tg, template)
+			if domain != "" {
+				pinTopologyDomain(placeholder.pod, tg.TopologyKey, domain)
 			}
-			log.Log(log.ShimCachePlaceholder).Info("placeholder created",
-				zap.Stringer("placeholder", placeholder))
+			placeholders = append(placeholders, placeholder)
 		}
+		if domain != "" {
+			topologyGroups = append(topologyGroups, tg.Name)
+		}
+	}
+	if len(placeholders) == 0 {
+		return nil
 	}
+	err := mgr.createPlaceholders(app, placeholders)
+	if err == nil {
+		for _, tgName := range topologyGroups {
+			mgr.scheduleTopologyReplan(app, tgName)
+		}
+	}
+	return err
+}
 
+// createPlaceholders creates the placeholders of the application using a bounded pool of workers. An error is
+// returned if the percentage of placeholders that could not be created exceeds the configured failure threshold,
+// otherwise the placeholders that could not be created are queued for retry.
+func (mgr *PlaceholderManager) createPlaceholders(app *Application, placeholders []*Placeholder) error {
+	schedulerConf := conf.GetSchedulerConf()
+	failed, firstErr := mgr.createPlaceholderPods(app, placeholders, false)
+	numFailed := len(failed)
+	if numFailed == 0 {
+		return nil
+	}
+	if numFailed*100 > schedulerConf.PlaceholderFailThreshold*len(placeholders) {
+		log.Log(log.ShimCachePlaceholder).Error("failed to create placeholders, exceeds failure threshold",
+			zap.String("appID", app.GetApplicationID()),
+			zap.Int("failed", numFailed),
+			zap.Int("total", len(placeholders)),
+			zap.Int("thresholdPercentage", schedulerConf.PlaceholderFailThreshold))
+		return firstErr
+	}
+	log.Log(log.ShimCachePlaceholder).Warn("failed to create some placeholders, within failure threshold, retrying",
+		zap.String("appID", app.GetApplicationID()),
+		zap.Int("failed", numFailed),
+		zap.Int("total", len(placeholders)),
+		zap.Int("thresholdPercentage", schedulerConf.PlaceholderFailThreshold),
+		zap.Error(firstErr))
+	mgr.queueRetry(app, failed)
 	return nil
 }
 
+// createPlaceholderPods creates the placeholder pods concurrently, rate limited. Returns the placeholders that could
+// not be created and the first error. Placeholders are not created, and not reported as failed, once the application
+// stops reserving. Placeholders that were attempted before, and already exist, are reported as created.
+func (mgr *PlaceholderManager) createPlaceholderPods(app *Application, placeholders []*Placeholder, attempted bool) ([]*Placeholder, error) {
+	schedulerConf := conf.GetSchedulerConf()
+	numWorkers := min(max(1, schedulerConf.PlaceholderCreateWorkers), len(placeholders))
+	limiter := mgr.getCreateLimiter(schedulerConf.PlaceholderCreateQPS, numWorkers)
+	backoff := placeholderCreateBackoff
+	backoff.Steps = max(0, schedulerConf.PlaceholderCreateRetries) + 1
+
+	var failed []*Placeholder
+	var firstErr error
+	var skipped atomic.Int32
+	var failedLock locking.Mutex
+	var wg sync.WaitGroup
+	work := make(chan *Placeholder)
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for placeholder := range work {
+				err := mgr.createPlaceholder(app, placeholder, limiter, backoff, attempted)
+				if errors.Is(err, errPlaceholderNotWanted) {
+					skipped.Add(1)
+					continue
+				}
+				if err != nil {
+					failedLock.Lock()
+					failed = append(failed, placeholder)
+					if firstErr == nil {
+						firstErr = err
+					}
+					failedLock.Unlock()
+				}
+			}
+		}()
+	}
+	for _, placeholder := range placeholders {
+		work <- placeholder
+	}
+	close(work)
+	wg.Wait()
+	if numSkipped := skipped.Load(); numSkipped > 0 {
+		log.Log(log.ShimCachePlaceholder).Info("application stopped reserving, placeholders not created",
+			zap.String("appID", app.GetApplicationID()),
+			zap.Int32("skipped", numSkipped))
+	}
+	return failed, firstErr
+}
+
+// createPlaceholder creates the placeholder pod on K8s, transient errors are retried using the backoff.
+// If the placeholder was attempted before the pod might exist already.
+func (mgr *PlaceholderManager) createPlaceholder(app *Application, placeholder *Placeholder, limiter flowcontrol.RateLimiter, backoff wait.Backoff, attempted bool) error {
+	attempt := 0
+	err := retry.OnError(backoff, isTransientCreateError, func() error {
+		attempt++
+		if limiter != nil {
+			limiter.Accept()
+		}
+		err := mgr.createPlaceholderPod(app, placeholder)
+		// an earlier attempt might have created the pod even if the call failed
+		if (attempted || attempt > 1) && apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		if errors.Is(err, errPlaceholderNotWanted) {
+			return err
+		}
+		if err != nil && isTransientCreateError(err) {
+			log.Log(log.ShimCachePlaceholder).Debug("transient error creating placeholder pod",
+				zap.Stringer("placeholder", placeholder),
+				zap.Int("attempt", attempt),
+				zap.Error(err))
+		}
+		return err
+	})
+	if err != nil {
+		log.Log(log.ShimCachePlaceholder).Error("failed to create placeholder pod",
+			zap.Stringer("placeholder", placeholder),
+			zap.Int("attempts", attempt),
+			zap.Error(err))
+		return err
+	}
+	log.Log(log.ShimCachePlaceholder).Info("placeholder created",
+		zap.Stringer("placeholder", placeholder))
+	return nil
+}
+
+// createPlaceholderPod creates the pod if the application still reserves. The pod is created under the read lock:
+// cleanUp takes the write lock, the placeholders of the application cannot be created while it is cleaned up.
+func (mgr *PlaceholderManager) createPlaceholderPod(app *Application, placeholder *Placeholder) error {
+	mgr.RLock()
+	defer mgr.RUnlock()
+	if !reservesPlaceholders(app) {
+		return errPlaceholderNotWanted
+	}
+	_, err := mgr.clients.KubeClient.Create(placeholder.pod)
+	return err
+}
+
+// reservesPlaceholders returns true if the application has not moved past the reservation of its placeholders
+func reservesPlaceholders(app *Application) bool {
+	switch app.GetApplicationState() {
+	case ApplicationStates().New, ApplicationStates().Submitted, ApplicationStates().Accepted, ApplicationStates().Reserving:
+		return true
+	default:
+		return false
+	}
+}
+
+// queueRetry adds the placeholders to the placeholders of the application of which the creation is retried
+func (mgr *PlaceholderManager) queueRetry(app *Application, placeholders []*Placeholder) {
+	mgr.Lock()
+	defer mgr.Unlock()
+	appID := app.GetApplicationID()
+	if pending, ok := mgr.retryPlaceholders[appID]; ok {
+		pending.placeholders = append(pending.placeholders, placeholders...)
+		return
+	}
+	mgr.retryPlaceholders[appID] = &placeholderRetry{
+		app:          app,
+		placeholders: placeholders,
+	}
+}
+
+// retryFailedPlaceholders retries the creation of the queued placeholders, the placeholders of applications that
+// stopped reserving are dropped. Placeholders that still cannot be created are queued again.
+func (mgr *PlaceholderManager) retryFailedPlaceholders() {
+	mgr.Lock()
+	retries := mgr.retryPlaceholders
+	mgr.retryPlaceholders = make(map[string]*placeholderRetry)
+	mgr.Unlock()
+	for _, pending := range retries {
+		if !reservesPlaceholders(pending.app) {
+			continue
+		}
+		failed, err := mgr.createPlaceholderPods(pending.app, pending.placeholders, true)
+		log.Log(log.ShimCachePlaceholder).Info("retried creating placeholders",
+			zap.String("appID", pending.app.GetApplicationID()),
+			zap.Int("total", len(pending.placeholders)),
+			zap.Int("failed", len(failed)),
+			zap.Error(err))
+		if len(failed) > 0 {
+			mgr.queueRetry(pending.app, failed)
+		}
+	}
+}
+
+// dropRetries removes the queued placeholders of the application that match the filter. The caller must hold the lock.
+func (mgr *PlaceholderManager) dropRetries(appID string, drop func(placeholder *Placeholder) bool) {
+	pending, ok := mgr.retryPlaceholders[appID]
+	if !ok {
+		return
+	}
+	kept := pending.placeholders[:0]
+	for _, placeholder := range pending.placeholders {
+		if !drop(placeholder) {
+			kept = append(kept, placeholder)
+		}
+	}
+	pending.placeholders = kept
+	if len(kept) == 0 {
+		delete(mgr.retryPlaceholders, appID)
+	}
+}
+
+// isTransientCreateError returns true if creating the pod could succeed when retried: the API server is throttling,
+// unavailable or returned a server error, or there was a conflict.
+func isTransientCreateError(err error) bool {
+	if apierrors.IsTooManyRequests(err) || apierrors.IsConflict(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) || apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) {
+		return true
+	}
+	var status apierrors.APIStatus
+	return errors.As(err, &status) && status.Status().Code >= 500
+}
+
+// getCreateLimiter returns the rate limiter for the placeholder creation, nil if the creation is not rate limited.
+// The limiter is shared by all applications and replaced when the configured rate changes.
+func (mgr *PlaceholderManager) getCreateLimiter(qps int, burst int) flowcontrol.RateLimiter {
+	mgr.Lock()
+	defer mgr.Unlock()
+	if qps <= 0 {
+		mgr.createLimiter = nil
+		mgr.createLimiterQPS = 0
+		return nil
+	}
+	if mgr.createLimiter == nil || mgr.createLimiterQPS != qps {
+		mgr.createLimiter = flowcontrol.NewTokenBucketRateLimiter(float32(qps), max(1, burst))
+		mgr.createLimiterQPS = qps
+	}
+	return mgr.createLimiter
+}
+
 // clean up all the placeholders for an application
 func (mgr *PlaceholderManager) cleanUp(app *Application) {
 	mgr.Lock()
 	defer mgr.Unlock()
 	log.Log(log.ShimCachePlaceholder).Info("start to clean up app placeholders",
 		zap.String("appID", app.GetApplicationID()))
+	delete(mgr.retryPlaceholders, app.GetApplicationID())
+	app.stopTimers()
 	for _, task := range app.GetPlaceHolderTasks() {
 		// remove pod
 		err := mgr.clients.KubeClient.Delete(task.GetTaskPod())
@@ -114,7 +405,7 @@ func (mgr *PlaceholderManager) cleanUp(app *Application) {
 			log.Log(log.ShimCachePlaceholder).Warn("failed to clean up placeholder pod",
 				zap.Error(err))
 			if !strings.Contains(err.Error(), "not found") {
-				mgr.orphanPods[task.GetTaskID()] = task.GetTaskPod()
+				mgr.addOrphan(task.GetTaskID(), task.GetTaskPod())
 			}
 		}
 	}
@@ -130,12 +421,90 @@ func (mgr *PlaceholderManager) cleanOrphanPlaceholders() {
 			zap.String("taskID", taskID),
 			zap.String("podName", pod.Name))
 		err := mgr.clients.KubeClient.Delete(pod)
-		if err != nil {
+		if err != nil && !apierrors.IsNotFound(err) {
 			log.Log(log.ShimCachePlaceholder).Warn("failed to clean up orphan pod", zap.Error(err))
 		} else {
 			delete(mgr.orphanPods, taskID)
+			delete(mgr.orphanSince, taskID)
+		}
+	}
+}
+
+// addOrphanPlaceholders adds placeholder pods left behind by an earlier run of the scheduler to the orphan pods
+func (mgr *PlaceholderManager) addOrphanPlaceholders(pods []*v1.Pod) {
+	mgr.Lock()
+	defer mgr.Unlock()
+	for _, pod := range pods {
+		mgr.addOrphan(string(pod.UID), pod)
+	}
+}
+
+// addOrphan adds the pod to the orphan pods, the time the pod became an orphan is kept if the pod is already an orphan.
+// The caller must hold the lock.
+func (mgr *PlaceholderManager) addOrphan(key string, pod *v1.Pod) {
+	mgr.orphanPods[key] = pod
+	if _, ok := mgr.orphanSince[key]; !ok {
+		mgr.orphanSince[key] = time.Now()
+	}
+}
+
+// OrphanPlaceholder is a placeholder pod that could not be deleted
+type OrphanPlaceholder struct {
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	ApplicationID string `json:"applicationId"`
+	// Age is the time in milliseconds since the pod became an orphan
+	Age int64 `json:"ageMs"`
+}
+
+// PlaceholderState is the state of the placeholder manager in the state dump
+type PlaceholderState struct {
+	OrphanCount int `json:"orphanCount"`
+	// OldestOrphanAge is the time in milliseconds the oldest orphan pod has been waiting for deletion
+	OldestOrphanAge int64               `json:"oldestOrphanAgeMs"`
+	Orphans         []OrphanPlaceholder `json:"orphans,omitempty"`
+}
+
+// getState returns the orphan pods of the placeholder manager, oldest first
+func (mgr *PlaceholderManager) getState() *PlaceholderState {
+	mgr.RLock()
+	defer mgr.RUnlock()
+	now := time.Now()
+	state := &PlaceholderState{
+		OrphanCount: len(mgr.orphanPods),
+		Orphans:     make([]OrphanPlaceholder, 0, len(mgr.orphanPods)),
+	}
+	for key, pod := range mgr.orphanPods {
+		var age int64
+		if since, ok := mgr.orphanSince[key]; ok {
+			age = now.Sub(since).Milliseconds()
+		}
+		state.Orphans = append(state.Orphans, OrphanPlaceholder{
+			Namespace:     pod.Namespace,
+			Name:          pod.Name,
+			ApplicationID: utils.GetApplicationIDFromPod(pod),
+			Age:           age,
+		})
+		state.OldestOrphanAge = max(state.OldestOrphanAge, age)
+	}
+	sort.Slice(state.Orphans, func(i, j int) bool {
+		return state.Orphans[i].Age > state.Orphans[j].Age
+	})
+	return state
+}
+
+// oldestOrphanAge returns the time the oldest orphan pod has been waiting for deletion, 0 if there are no orphans
+func (mgr *PlaceholderManager) oldestOrphanAge() time.Duration {
+	mgr.RLock()
+	defer mgr.RUnlock()
+	var oldest time.Duration
+	now := time.Now()
+	for key := range mgr.orphanPods {
+		if since, ok := mgr.orphanSince[key]; ok {
+			oldest = max(oldest, now.Sub(since))
 		}
 	}
+	return oldest
 }
 
 func (mgr *PlaceholderManager) Start() {
@@ -146,7 +515,7 @@ func (mgr *PlaceholderManager) Start() {
 	log.Log(log.ShimCachePlaceholder).Info("starting the PlaceholderManager")
 	mgr.setRunning(true)
 	go func() {
-		// clean orphan placeholders approximately every 5 seconds
+		// clean orphan placeholders, and retry the failed placeholders, approximately every 5 seconds
 		for {
 			select {
 			case <-mgr.stopChan:
@@ -155,6 +524,7 @@ func (mgr *PlaceholderManager) Start() {
 				return
 			case <-time.After(mgr.getCleanupTime()):
 				mgr.cleanOrphanPlaceholders()
+				mgr.retryFailedPlaceholders()
 			}
 		}
 	}()
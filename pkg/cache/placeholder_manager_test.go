@@ -27,11 +27,15 @@ import (
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/apache/yunikorn-k8shim/pkg/client"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
+	"github.com/apache/yunikorn-k8shim/pkg/locking"
 )
 
 const (
@@ -94,6 +98,276 @@ func TestCreateAppPlaceholdersWithExistingPods(t *testing.T) {
 	}
 }
 
+func TestCreateAppPlaceholdersRetry(t *testing.T) {
+	defer setPlaceholderCreateBackoff(time.Millisecond)()
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider(false)
+	placeholderMgr = NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+
+	// every pod is throttled on the first attempt, one pod keeps failing with a server error
+	attempts := make(map[string]int)
+	createdPods := make(map[string]*v1.Pod)
+	var failed string
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		attempts[pod.Name]++
+		if failed == "" || failed == pod.Name {
+			failed = pod.Name
+			return nil, apierrors.NewInternalError(fmt.Errorf("failed to create pod %s", pod.Name))
+		}
+		if attempts[pod.Name] == 1 {
+			return nil, apierrors.NewTooManyRequests("throttled", 0)
+		}
+		createdPods[pod.Name] = pod
+		return pod, nil
+	})
+	err := placeholderMgr.createAppPlaceholders(app)
+	assert.ErrorContains(t, err, fmt.Sprintf("failed to create pod %s", failed))
+	assert.Equal(t, len(createdPods), 29)
+	assert.Equal(t, attempts[failed], conf.DefaultPlaceholderCreateRetries+1, "failed pod should have been retried")
+
+	// failures within the threshold do not fail the creation
+	err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcPlaceholderFailureThreshold: "10",
+		conf.CMSvcPlaceholderCreateRetries:    "0",
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+	failed = ""
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		if len(failed) < 3*len(pod.Name) {
+			failed += pod.Name
+			return nil, apierrors.NewTooManyRequests("throttled", 0)
+		}
+		return pod, nil
+	})
+	err = placeholderMgr.createAppPlaceholders(app)
+	assert.NilError(t, err, "3 out of 30 failed placeholders should be within the threshold")
+
+	failed = ""
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		if len(failed) < 4*len(pod.Name) {
+			failed += pod.Name
+			return nil, apierrors.NewTooManyRequests("throttled", 0)
+		}
+		return pod, nil
+	})
+	err = placeholderMgr.createAppPlaceholders(app)
+	assert.Assert(t, apierrors.IsTooManyRequests(err), "4 out of 30 failed placeholders should exceed the threshold")
+}
+
+func TestCreateAppPlaceholdersAlreadyExists(t *testing.T) {
+	defer setPlaceholderCreateBackoff(time.Millisecond)()
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider(false)
+	placeholderMgr = NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+
+	// the first attempt times out after creating the pod, the retry finds the pod
+	createdPods := make(map[string]*v1.Pod)
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		if _, ok := createdPods[pod.Name]; ok {
+			return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "pods"}, pod.Name)
+		}
+		createdPods[pod.Name] = pod
+		return nil, apierrors.NewServerTimeout(schema.GroupResource{Resource: "pods"}, "create", 0)
+	})
+	err := placeholderMgr.createAppPlaceholders(app)
+	assert.NilError(t, err)
+	assert.Equal(t, len(createdPods), 30)
+
+	// a pod that already exists on the first attempt is a failure
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "pods"}, pod.Name)
+	})
+	err = placeholderMgr.createAppPlaceholders(app)
+	assert.Assert(t, apierrors.IsAlreadyExists(err))
+}
+
+func TestRetryFailedPlaceholders(t *testing.T) {
+	defer setPlaceholderCreateBackoff(time.Millisecond)()
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider(false)
+	placeholderMgr = NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcPlaceholderFailureThreshold: "10",
+		conf.CMSvcPlaceholderCreateRetries:    "0",
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+
+	// 3 placeholders fail within the threshold and are queued for retry
+	createdPods := make(map[string]*v1.Pod)
+	failed := make(map[string]bool)
+	var createLock locking.Mutex
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		createLock.Lock()
+		defer createLock.Unlock()
+		if len(failed) < 3 || failed[pod.Name] {
+			failed[pod.Name] = true
+			return nil, apierrors.NewTooManyRequests("throttled", 0)
+		}
+		createdPods[pod.Name] = pod
+		return pod, nil
+	})
+	err = placeholderMgr.createAppPlaceholders(app)
+	assert.NilError(t, err, "3 out of 30 failed placeholders should be within the threshold")
+	assert.Equal(t, len(createdPods), 27)
+	assert.Equal(t, len(placeholderMgr.retryPlaceholders[pmAppID].placeholders), 3)
+
+	// still failing: queued again
+	placeholderMgr.retryFailedPlaceholders()
+	assert.Equal(t, len(createdPods), 27)
+	assert.Equal(t, len(placeholderMgr.retryPlaceholders[pmAppID].placeholders), 3)
+
+	// the first retried placeholder was created by an earlier attempt, the others are created now
+	var exists string
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		createLock.Lock()
+		defer createLock.Unlock()
+		if exists == "" {
+			exists = pod.Name
+			return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "pods"}, pod.Name)
+		}
+		createdPods[pod.Name] = pod
+		return pod, nil
+	})
+	placeholderMgr.retryFailedPlaceholders()
+	assert.Equal(t, len(createdPods), 29)
+	assert.Equal(t, len(placeholderMgr.retryPlaceholders), 0, "all placeholders should have been created")
+}
+
+func TestCreateAppPlaceholdersNotReserving(t *testing.T) {
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider(false)
+	placeholderMgr = NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	var created int
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		created++
+		return pod, nil
+	})
+
+	// queued placeholders are dropped once the application stops reserving
+	placeholderMgr.queueRetry(app, []*Placeholder{newPlaceholder("ph-1", app, app.getTaskGroups()[0], nil)})
+	app.sm.SetState(ApplicationStates().Failing)
+	placeholderMgr.retryFailedPlaceholders()
+	assert.Equal(t, created, 0)
+	assert.Equal(t, len(placeholderMgr.retryPlaceholders), 0)
+
+	// no placeholders are created for an application which is not reserving, without an error
+	err := placeholderMgr.createAppPlaceholders(app)
+	assert.NilError(t, err)
+	assert.Equal(t, created, 0)
+
+	// cleaning up the application drops the queued placeholders
+	app.sm.SetState(ApplicationStates().Reserving)
+	placeholderMgr.queueRetry(app, []*Placeholder{newPlaceholder("ph-2", app, app.getTaskGroups()[0], nil)})
+	placeholderMgr.cleanUp(app)
+	assert.Equal(t, len(placeholderMgr.retryPlaceholders), 0)
+
+	// deleting the placeholders of a task group drops the queued placeholders of the task group
+	placeholderMgr.queueRetry(app, []*Placeholder{
+		newPlaceholder("ph-3", app, app.getTaskGroups()[0], nil),
+		newPlaceholder("ph-4", app, app.getTaskGroups()[1], nil),
+	})
+	placeholderMgr.deleteTaskGroupPlaceholders(app, "test-group-1", "")
+	assert.Equal(t, len(placeholderMgr.retryPlaceholders[pmAppID].placeholders), 1)
+	assert.Equal(t, placeholderMgr.retryPlaceholders[pmAppID].placeholders[0].taskGroupName, "test-group-2")
+}
+
+func TestCreateAppPlaceholdersElastic(t *testing.T) {
+	app := createAppWIthTaskGroupForTest()
+	taskGroups := app.getTaskGroups()
+	taskGroups[0].MaxMember = 15
+	app.setTaskGroups(taskGroups)
+	createdPods := make(map[string]*v1.Pod)
+	mockedAPIProvider := client.NewMockedAPIProvider(false)
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		createdPods[pod.Name] = pod
+		return pod, nil
+	})
+	placeholderMgr = NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	err := placeholderMgr.createAppPlaceholders(app)
+	assert.NilError(t, err, "create app placeholders should be successful")
+	assert.Equal(t, len(createdPods), 35)
+	var tg1Count int
+	for _, pod := range createdPods {
+		if pod.Annotations[constants.AnnotationTaskGroupName] == "test-group-1" {
+			tg1Count++
+		}
+	}
+	assert.Equal(t, tg1Count, 15, "placeholders not created up to the max members")
+}
+
+func TestCreateAppPlaceholdersInvalidTemplate(t *testing.T) {
+	app := createAppWIthTaskGroupForTest()
+	mockedAPIProvider := client.NewMockedAPIProvider(false)
+	placeholderMgr = NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	var created int
+	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		created++
+		return pod, nil
+	})
+
+	// the template of the second task group is invalid: no placeholder must be created
+	taskGroups := app.getTaskGroups()
+	taskGroups[1].PlaceholderTemplate = &conf.PlaceholderTemplate{PodTemplate: "missing"}
+	app.setTaskGroups(taskGroups)
+	err := placeholderMgr.createAppPlaceholders(app)
+	assert.ErrorContains(t, err, "unable to get placeholder pod template")
+	assert.Equal(t, 0, created)
+}
+
+func TestIsTransientCreateError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+	testCases := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"too many requests", apierrors.NewTooManyRequests("throttled", 1), true},
+		{"conflict", apierrors.NewConflict(gr, "pod", fmt.Errorf("conflict")), true},
+		{"server timeout", apierrors.NewServerTimeout(gr, "create", 1), true},
+		{"timeout", apierrors.NewTimeoutError("timeout", 1), true},
+		{"service unavailable", apierrors.NewServiceUnavailable("unavailable"), true},
+		{"internal error", apierrors.NewInternalError(fmt.Errorf("internal")), true},
+		{"bad gateway", apierrors.NewGenericServerResponse(502, "POST", gr, "pod", "bad gateway", 0, false), true},
+		{"forbidden", apierrors.NewForbidden(gr, "pod", fmt.Errorf("quota exceeded")), false},
+		{"invalid", apierrors.NewBadRequest("invalid"), false},
+		{"already exists", apierrors.NewAlreadyExists(gr, "pod"), false},
+		{"plain error", fmt.Errorf("failed"), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, isTransientCreateError(tc.err), tc.transient)
+		})
+	}
+}
+
+func TestGetCreateLimiter(t *testing.T) {
+	mgr := NewPlaceholderManager(client.NewMockedAPIProvider(false).GetAPIs())
+	assert.Assert(t, mgr.getCreateLimiter(0, 10) == nil, "creation should not be rate limited")
+	limiter := mgr.getCreateLimiter(50, 10)
+	assert.Assert(t, limiter != nil, "creation should be rate limited")
+	assert.Equal(t, limiter.QPS(), float32(50))
+	assert.Equal(t, mgr.getCreateLimiter(50, 5), limiter, "limiter should be shared")
+	limiter = mgr.getCreateLimiter(20, 10)
+	assert.Equal(t, limiter.QPS(), float32(20), "limiter should be replaced when the rate changes")
+}
+
+// setPlaceholderCreateBackoff shortens the backoff for testing, returns a function restoring the default
+func setPlaceholderCreateBackoff(duration time.Duration) func() {
+	backoff := placeholderCreateBackoff
+	placeholderCreateBackoff.Duration = duration
+	return func() {
+		placeholderCreateBackoff = backoff
+	}
+}
+
 func createAndCheckPlaceholderCreate(mockedAPIProvider *client.MockedAPIProvider, app *Application, t *testing.T) map[string]*v1.Pod {
 	createdPods := make(map[string]*v1.Pod)
 	mockedAPIProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
@@ -346,6 +620,36 @@ func TestCleanOrphanPlaceholders(t *testing.T) {
 	assert.Equal(t, len(placeholderMgr.orphanPods), 1)
 }
 
+func TestOrphanPlaceholderState(t *testing.T) {
+	mockedAPIProvider := client.NewMockedAPIProvider(false)
+	mockedAPIProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, pod.Name)
+	})
+	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
+	assert.Equal(t, mgr.oldestOrphanAge(), time.Duration(0))
+	mgr.addOrphanPlaceholders([]*v1.Pod{
+		{ObjectMeta: apis.ObjectMeta{Name: "ph-1", Namespace: namespace, UID: "UID-01",
+			Labels: map[string]string{constants.CanonicalLabelApplicationID: "app-1"}},
+			Spec: v1.PodSpec{SchedulerName: constants.SchedulerName}},
+		{ObjectMeta: apis.ObjectMeta{Name: "ph-2", Namespace: namespace, UID: "UID-02"}},
+	})
+	mgr.orphanSince["UID-02"] = time.Now().Add(-time.Minute)
+	// adding the orphan again keeps the age
+	mgr.addOrphanPlaceholders([]*v1.Pod{{ObjectMeta: apis.ObjectMeta{Name: "ph-2", Namespace: namespace, UID: "UID-02"}}})
+	assert.Assert(t, mgr.oldestOrphanAge() >= time.Minute)
+
+	state := mgr.getState()
+	assert.Equal(t, state.OrphanCount, 2)
+	assert.Assert(t, state.OldestOrphanAge >= time.Minute.Milliseconds())
+	assert.Equal(t, state.Orphans[0].Name, "ph-2", "oldest orphan should be first")
+	assert.Equal(t, state.Orphans[1].ApplicationID, "app-1")
+
+	// pods that are already gone are removed
+	mgr.cleanOrphanPlaceholders()
+	assert.Equal(t, mgr.getOrphanPodsLength(), 0)
+	assert.Equal(t, len(mgr.orphanSince), 0)
+}
+
 func TestPlaceholderManagerStartStop(t *testing.T) {
 	mockedAPIProvider := client.NewMockedAPIProvider(false)
 	mgr := NewPlaceholderManager(mockedAPIProvider.GetAPIs())
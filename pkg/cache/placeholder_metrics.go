@@ -0,0 +1,71 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/apache/yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+)
+
+// PlaceholderSubsystem - subsystem name used by the placeholder manager
+const PlaceholderSubsystem = "k8shim_placeholder"
+
+var placeholderMetricsOnce sync.Once
+
+// initPlaceholderMetrics registers the metrics of the placeholder manager on the default prometheus registry, the
+// metrics always report on the current placeholder manager
+func initPlaceholderMetrics() {
+	placeholderMetricsOnce.Do(func() {
+		orphans := prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace: dispatcher.Namespace,
+				Subsystem: PlaceholderSubsystem,
+				Name:      "orphan_pods",
+				Help:      "Number of placeholder pods that could not be deleted and are waiting for the orphan cleaner.",
+			}, func() float64 {
+				if mgr := getPlaceholderManager(); mgr != nil {
+					return float64(mgr.getOrphanPodsLength())
+				}
+				return 0
+			})
+		oldestOrphanAge := prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace: dispatcher.Namespace,
+				Subsystem: PlaceholderSubsystem,
+				Name:      "oldest_orphan_pod_age_seconds",
+				Help:      "Time the oldest orphan placeholder pod has been waiting for deletion, 0 if there are no orphans.",
+			}, func() float64 {
+				if mgr := getPlaceholderManager(); mgr != nil {
+					return mgr.oldestOrphanAge().Seconds()
+				}
+				return 0
+			})
+		for _, collector := range []prometheus.Collector{orphans, oldestOrphanAge} {
+			if err := prometheus.Register(collector); err != nil {
+				log.Log(log.ShimCachePlaceholder).Warn("failed to register placeholder metrics",
+					zap.Error(err))
+			}
+		}
+	})
+}
@@ -19,6 +19,7 @@
 package cache
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -27,8 +28,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/apache/yunikorn-k8shim/pkg/client"
 	"github.com/apache/yunikorn-k8shim/pkg/common"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
 	siCommon "github.com/apache/yunikorn-scheduler-interface/lib/go/common"
 )
 
@@ -119,7 +122,7 @@ func TestNewPlaceholder(t *testing.T) {
 	assert.Equal(t, app.placeholderAsk.Resources[siCommon.Memory].Value, int64(10*1024*1000*1000))
 	assert.Equal(t, app.placeholderAsk.Resources["pods"].Value, int64(10))
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, holder.appID, appID)
 	assert.Equal(t, holder.taskGroupName, app.taskGroups[0].Name)
 	assert.Equal(t, holder.pod.Spec.SchedulerName, constants.SchedulerName)
@@ -163,7 +166,7 @@ func TestNewPlaceholderWithNodeSelectors(t *testing.T) {
 		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
 	app.setTaskGroups(taskGroups)
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, len(holder.pod.Spec.NodeSelector), 2)
 	assert.Equal(t, holder.pod.Spec.NodeSelector["nodeType"], "test")
 	assert.Equal(t, holder.pod.Spec.NodeSelector["nodeState"], "healthy")
@@ -178,7 +181,7 @@ func TestNewPlaceholderWithTolerations(t *testing.T) {
 		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
 	app.setTaskGroups(taskGroups)
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, len(holder.pod.Spec.Tolerations), 1)
 	tlr := holder.pod.Spec.Tolerations[0]
 	assert.Equal(t, tlr.Key, "key1")
@@ -196,7 +199,7 @@ func TestNewPlaceholderWithAffinity(t *testing.T) {
 		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
 	app.setTaskGroups(taskGroups)
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, len(holder.pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution), 1)
 	term := holder.pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution
 	assert.Equal(t, term[0].TopologyKey, "topologyKey")
@@ -215,14 +218,14 @@ func TestNewPlaceholderTaskGroupsDefinition(t *testing.T) {
 	app := NewApplication(appID, queue,
 		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
 	app.setTaskGroups(taskGroups)
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, "", holder.pod.Annotations[constants.AnnotationTaskGroups])
 
 	app = NewApplication(appID, queue,
 		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
 	app.setTaskGroups(taskGroups)
 	app.setTaskGroupsDefinition("taskGroupsDef")
-	holder = newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder = newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, "taskGroupsDef", holder.pod.Annotations[constants.AnnotationTaskGroups])
 	var priority *int32
 	assert.Equal(t, priority, holder.pod.Spec.Priority)
@@ -234,7 +237,7 @@ func TestNewPlaceholderExtendedResources(t *testing.T) {
 	app := NewApplication(appID, queue,
 		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
 	app.setTaskGroups(taskGroups)
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, len(holder.pod.Spec.Containers[0].Resources.Requests), 5, "expected requests not found")
 	assert.Equal(t, len(holder.pod.Spec.Containers[0].Resources.Limits), 5, "expected limits not found")
 	assert.Equal(t, holder.pod.Spec.Containers[0].Resources.Limits[gpu], holder.pod.Spec.Containers[0].Resources.Requests[gpu], "gpu: expected same value for request and limit")
@@ -271,7 +274,7 @@ func TestNewPlaceholderWithPriorityClassName(t *testing.T) {
 	app.taskMap[taskID1] = task1
 	app.setOriginatingTask(task1)
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, len(holder.pod.Spec.Containers[0].Resources.Requests), 5, "expected requests not found")
 	assert.Equal(t, len(holder.pod.Spec.Containers[0].Resources.Limits), 5, "expected limits not found")
 	assert.Equal(t, holder.pod.Spec.Containers[0].Resources.Limits[gpu], holder.pod.Spec.Containers[0].Resources.Requests[gpu], "gpu: expected same value for request and limit")
@@ -287,7 +290,7 @@ func TestNewPlaceholderWithTopologySpreadConstraints(t *testing.T) {
 		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
 	app.setTaskGroups(taskGroups)
 
-	holder := newPlaceholder("ph-name", app, app.taskGroups[0])
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], nil)
 	assert.Equal(t, len(holder.pod.Spec.TopologySpreadConstraints), 1)
 	assert.Equal(t, holder.pod.Spec.TopologySpreadConstraints[0].MaxSkew, int32(1))
 	assert.Equal(t, holder.pod.Spec.TopologySpreadConstraints[0].TopologyKey, v1.LabelTopologyZone)
@@ -297,3 +300,138 @@ func TestNewPlaceholderWithTopologySpreadConstraints(t *testing.T) {
 		"labelKey1": "labelKeyValue1",
 	})
 }
+
+func TestNewPlaceholderWithTemplate(t *testing.T) {
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	app := NewApplication(appID, queue,
+		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
+	app.setTaskGroups(taskGroups)
+
+	template := &conf.PlaceholderTemplate{
+		Image:              "registry.local/pause:3.9",
+		RuntimeClassName:   "gvisor",
+		ServiceAccountName: "placeholder",
+		PriorityClassName:  "placeholder-priority",
+		Labels:             map[string]string{"team": "ml", "labelKey0": "template"},
+		Limits:             map[string]string{"memory": "2G", "pods": "2"},
+	}
+	holder := newPlaceholder("ph-name", app, app.taskGroups[0], template)
+	assert.Equal(t, "registry.local/pause:3.9", holder.pod.Spec.Containers[0].Image)
+	assert.Equal(t, "gvisor", *holder.pod.Spec.RuntimeClassName)
+	assert.Equal(t, "placeholder", holder.pod.Spec.ServiceAccountName)
+	assert.Equal(t, "placeholder-priority", holder.pod.Spec.PriorityClassName)
+	assert.DeepEqual(t, holder.pod.Labels, map[string]string{
+		constants.CanonicalLabelApplicationID: appID,
+		constants.CanonicalLabelQueueName:     queue,
+		"labelKey0":                           "labelKeyValue0",
+		"labelKey1":                           "labelKeyValue1",
+		"team":                                "ml",
+	})
+	resources := holder.pod.Spec.Containers[0].Resources
+	assert.Equal(t, resources.Limits.Memory().String(), "2G")
+	assert.Equal(t, resources.Requests.Memory().String(), "1024M")
+	assert.Equal(t, resources.Limits.Cpu().String(), "500m")
+	_, ok := resources.Limits[v1.ResourcePods]
+	assert.Assert(t, !ok, "limit set for a resource that is not requested")
+
+	// no template: the defaults are used
+	holder = newPlaceholder("ph-name", app, app.taskGroups[0], nil)
+	assert.Equal(t, conf.GetSchedulerConf().PlaceHolderImage, holder.pod.Spec.Containers[0].Image)
+	assert.Assert(t, holder.pod.Spec.RuntimeClassName == nil, "runtime class set")
+	assert.Equal(t, "", holder.pod.Spec.ServiceAccountName)
+	assert.DeepEqual(t, holder.pod.Spec.Containers[0].Resources.Limits, holder.pod.Spec.Containers[0].Resources.Requests)
+}
+
+func TestGetPlaceholderTemplate(t *testing.T) {
+	defer func() {
+		err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset configmap")
+	}()
+	kubeClient := client.NewKubeClientMock(false)
+	mockedSchedulerAPI := newMockSchedulerAPI()
+	app := NewApplication(appID, queue,
+		"bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, mockedSchedulerAPI)
+	taskGroup := TaskGroup{
+		Name:        "test-group",
+		MinMember:   1,
+		MinResource: map[string]resource.Quantity{"memory": resource.MustParse("1Gi")},
+	}
+
+	// nothing configured
+	template, err := getPlaceholderTemplate(kubeClient, app, taskGroup)
+	assert.NilError(t, err)
+	assert.Assert(t, template == nil, "template set without configuration")
+
+	// cluster default, overridden by the application and the task group
+	err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcPlaceholderTemplate: `{"image": "cluster", "serviceAccountName": "cluster", "labels": {"a": "cluster"}}`,
+	}}}, true)
+	assert.NilError(t, err, "failed to set configmap")
+	app.tags[constants.AnnotationPlaceholderTemplate] = `{"image": "app", "labels": {"b": "app"}}`
+	taskGroup.PlaceholderTemplate = &conf.PlaceholderTemplate{Labels: map[string]string{"a": "tg"}}
+	template, err = getPlaceholderTemplate(kubeClient, app, taskGroup)
+	assert.NilError(t, err)
+	assert.Equal(t, "app", template.Image)
+	assert.Equal(t, "cluster", template.ServiceAccountName)
+	assert.DeepEqual(t, map[string]string{"a": "tg", "b": "app"}, template.Labels)
+
+	// referenced pod template, explicit fields override the pod template
+	runtimeClass := "kata"
+	_, err = kubeClient.GetClientSet().CoreV1().PodTemplates(namespace).Create(context.Background(), &v1.PodTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "ph-template", Namespace: namespace},
+		Template: v1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"c": "podtemplate"}},
+			Spec: v1.PodSpec{
+				RuntimeClassName:  &runtimeClass,
+				PriorityClassName: "podtemplate",
+				Containers: []v1.Container{{
+					Name:      "pause",
+					Image:     "podtemplate",
+					Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")}},
+				}},
+			},
+		},
+	}, metav1.CreateOptions{})
+	assert.NilError(t, err, "failed to create pod template")
+	// referenced by the task group: overrides the application and the cluster default
+	taskGroup.PlaceholderTemplate.PodTemplate = "ph-template"
+	template, err = getPlaceholderTemplate(kubeClient, app, taskGroup)
+	assert.NilError(t, err)
+	assert.Equal(t, "podtemplate", template.Image)
+	assert.Equal(t, "cluster", template.ServiceAccountName)
+	assert.Equal(t, "kata", template.RuntimeClassName)
+	assert.Equal(t, "podtemplate", template.PriorityClassName)
+	assert.DeepEqual(t, map[string]string{"a": "tg", "b": "app", "c": "podtemplate"}, template.Labels)
+	assert.DeepEqual(t, map[string]string{"memory": "2Gi"}, template.Limits)
+
+	// referenced by the cluster default: overridden by the application and the task group
+	err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcPlaceholderTemplate: `{"podTemplate": "ph-template", "serviceAccountName": "cluster", "labels": {"c": "cluster"}}`,
+	}}}, true)
+	assert.NilError(t, err, "failed to set configmap")
+	taskGroup.PlaceholderTemplate = &conf.PlaceholderTemplate{PriorityClassName: "tg"}
+	template, err = getPlaceholderTemplate(kubeClient, app, taskGroup)
+	assert.NilError(t, err)
+	assert.Equal(t, "app", template.Image)
+	assert.Equal(t, "cluster", template.ServiceAccountName)
+	assert.Equal(t, "kata", template.RuntimeClassName)
+	assert.Equal(t, "tg", template.PriorityClassName)
+	assert.DeepEqual(t, map[string]string{"b": "app", "c": "cluster"}, template.Labels)
+	assert.DeepEqual(t, map[string]string{"memory": "2Gi"}, template.Limits)
+
+	// missing pod template
+	taskGroup.PlaceholderTemplate = &conf.PlaceholderTemplate{PodTemplate: "unknown"}
+	_, err = getPlaceholderTemplate(kubeClient, app, taskGroup)
+	assert.ErrorContains(t, err, "unable to get placeholder pod template test/unknown")
+
+	// limit lower than the request
+	taskGroup.PlaceholderTemplate = &conf.PlaceholderTemplate{Limits: map[string]string{"memory": "512Mi"}}
+	_, err = getPlaceholderTemplate(kubeClient, app, taskGroup)
+	assert.ErrorContains(t, err, "placeholder limit for memory lower than the minResource")
+
+	// invalid application template
+	taskGroup.PlaceholderTemplate = nil
+	app.tags[constants.AnnotationPlaceholderTemplate] = `{"priorityClassName": "High"}`
+	_, err = getPlaceholderTemplate(kubeClient, app, taskGroup)
+	assert.ErrorContains(t, err, "application app01 has an invalid placeholder template")
+}
@@ -0,0 +1,312 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+	"github.com/apache/yunikorn-scheduler-interface/lib/go/si"
+)
+
+// topologyPlan is the topology domain the placeholders of a task group are placed in
+type topologyPlan struct {
+	domain      string
+	tried       map[string]bool // domains already used for the task group, not selected again when re-planning
+	replanTimer *time.Timer     // re-plans the domain if the task group is not bound in time
+}
+
+// getTopologyDomain returns the topology domain for the placeholders of the task group, empty if the task group has no
+// topologyKey. A domain is planned if the task group has none: after a restart the domain is recovered from the
+// existing placeholders. An error is returned if no schedulable node has the topologyKey.
+func (mgr *PlaceholderManager) getTopologyDomain(app *Application, taskGroup TaskGroup) (string, error) {
+	if taskGroup.TopologyKey == "" {
+		return "", nil
+	}
+	if domain := app.getTopologyDomain(taskGroup.Name); domain != "" {
+		return domain, nil
+	}
+	for _, ph := range app.getPlaceHolderTasks() {
+		if ph.GetTaskGroupName() != taskGroup.Name {
+			continue
+		}
+		if domain := utils.GetPodAnnotationValue(ph.GetTaskPod(), constants.AnnotationTopologyDomain); domain != "" {
+			app.setTopologyDomain(taskGroup.Name, domain)
+			return domain, nil
+		}
+	}
+	domain, fits, err := mgr.selectTopologyDomain(app, taskGroup)
+	if err != nil {
+		publishTopologyEvent(app, v1.EventTypeWarning, "TopologyDomainUnavailable",
+			"Application %s task group %s: %v", app.GetApplicationID(), taskGroup.Name, err)
+		return "", err
+	}
+	app.setTopologyDomain(taskGroup.Name, domain)
+	if !fits {
+		publishTopologyEvent(app, v1.EventTypeWarning, "TopologyDomainInsufficient",
+			"Application %s task group %s: no %s domain has enough free capacity, using %s with the most headroom",
+			app.GetApplicationID(), taskGroup.Name, taskGroup.TopologyKey, domain)
+	}
+	publishTopologyEvent(app, v1.EventTypeNormal, "TopologyDomainSelected",
+		"Application %s task group %s placeholders placed in %s=%s",
+		app.GetApplicationID(), taskGroup.Name, taskGroup.TopologyKey, domain)
+	return domain, nil
+}
+
+// selectTopologyDomain returns the topology domain with the most headroom for the minimum members of the task group,
+// skipping the domains already tried for the task group. Returns an empty domain if all domains have been tried, fits
+// is false if the selected domain does not have enough free capacity for the gang.
+func (mgr *PlaceholderManager) selectTopologyDomain(app *Application, taskGroup TaskGroup) (string, bool, error) {
+	capacity, err := mgr.getTopologyDomainCapacity(taskGroup)
+	if err != nil {
+		return "", false, err
+	}
+	if len(capacity) == 0 {
+		return "", false, fmt.Errorf("no schedulable node has the topology key %s", taskGroup.TopologyKey)
+	}
+	tried := app.getTriedTopologyDomains(taskGroup.Name)
+	required := common.GetTGResource(taskGroup.MinResource, int64(taskGroup.MinMember))
+	domains := make([]string, 0, len(capacity))
+	for domain := range capacity {
+		domains = append(domains, domain)
+	}
+	// sort to break ties between domains with the same headroom consistently
+	slices.Sort(domains)
+	selected := ""
+	best := -math.MaxFloat64
+	for _, domain := range domains {
+		if tried[domain] {
+			continue
+		}
+		if headroom := getTopologyHeadroom(capacity[domain], required); headroom > best {
+			selected = domain
+			best = headroom
+		}
+	}
+	return selected, best >= 1, nil
+}
+
+// getTopologyDomainCapacity returns the free capacity of each domain of the topologyKey of the task group: the
+// schedulable resources of the nodes minus the requests of the pods assigned to them. Only schedulable nodes that match
+// the node selector of the task group are included.
+func (mgr *PlaceholderManager) getTopologyDomainCapacity(taskGroup TaskGroup) (map[string]*si.Resource, error) {
+	nodes, err := mgr.clients.NodeInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	selector := labels.SelectorFromSet(taskGroup.NodeSelector)
+	nodeDomains := make(map[string]string)
+	capacity := make(map[string]*si.Resource)
+	for _, node := range nodes {
+		domain, ok := node.Labels[taskGroup.TopologyKey]
+		if !ok || node.Spec.Unschedulable || !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		nodeDomains[node.Name] = domain
+		capacity[domain] = common.Add(capacity[domain], common.GetSchedulableNodeResource(node))
+	}
+	if len(nodeDomains) == 0 {
+		return capacity, nil
+	}
+	pods, err := mgr.clients.PodInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if domain, ok := nodeDomains[pod.Spec.NodeName]; ok && !utils.IsPodTerminated(pod) {
+			capacity[domain] = common.Sub(capacity[domain], common.GetPodResource(pod))
+		}
+	}
+	return capacity, nil
+}
+
+// getTopologyHeadroom returns how many times the required resources fit in the free resources of a domain, limited by
+// the scarcest resource. A value of at least 1 means the gang fits in the domain.
+func getTopologyHeadroom(free *si.Resource, required *si.Resource) float64 {
+	headroom := math.MaxFloat64
+	for name, quantity := range required.Resources {
+		if quantity.Value <= 0 {
+			continue
+		}
+		var available int64
+		if q, ok := free.Resources[name]; ok {
+			available = q.Value
+		}
+		headroom = min(headroom, float64(available)/float64(quantity.Value))
+	}
+	return headroom
+}
+
+// pinTopologyDomain restricts the placeholder pod to the nodes of the topology domain: the domain is added as a
+// required node affinity to all node selector terms. The domain is recorded on the placeholder to recognise
+// placeholders of an earlier plan and to recover the plan after a restart.
+func pinTopologyDomain(pod *v1.Pod, topologyKey string, domain string) {
+	requirement := v1.NodeSelectorRequirement{
+		Key:      topologyKey,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{domain},
+	}
+	// the affinity of the task group is shared by all its placeholders
+	affinity := pod.Spec.Affinity.DeepCopy()
+	if affinity == nil {
+		affinity = &v1.Affinity{}
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{}
+	}
+	nodeSelector := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []v1.NodeSelectorTerm{{}}
+	}
+	for i := range nodeSelector.NodeSelectorTerms {
+		term := &nodeSelector.NodeSelectorTerms[i]
+		term.MatchExpressions = append(term.MatchExpressions, requirement)
+	}
+	pod.Spec.Affinity = affinity
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[constants.AnnotationTopologyDomain] = domain
+}
+
+// scheduleTopologyReplan re-plans the topology domain of the task group if the minimum members of the task group are
+// not bound within the configured timeout. A timeout of zero disables re-planning.
+func (mgr *PlaceholderManager) scheduleTopologyReplan(app *Application, taskGroupName string) {
+	timeout := conf.GetSchedulerConf().TopologyReplanTimeout
+	if timeout <= 0 {
+		return
+	}
+	app.setTopologyReplanTimer(taskGroupName, time.AfterFunc(timeout, func() {
+		mgr.replanTopology(app, taskGroupName)
+	}))
+}
+
+// replanTopology moves the placeholders of the task group into another topology domain if the application is still
+// reserving and the minimum members of the task group are not bound in the planned domain. The placeholders of the
+// current plan are deleted and new placeholders are created in the domain with the most headroom that has not been
+// tried yet. If all domains have been tried the placeholders stay in the current domain.
+func (mgr *PlaceholderManager) replanTopology(app *Application, taskGroupName string) {
+	if app.GetApplicationState() != ApplicationStates().Reserving {
+		return
+	}
+	var taskGroup TaskGroup
+	found := false
+	for _, tg := range app.getTaskGroups() {
+		if tg.Name == taskGroupName {
+			taskGroup = tg
+			found = true
+			break
+		}
+	}
+	current := app.getTopologyDomain(taskGroupName)
+	if !found || current == "" || app.getBoundPlaceholderCount(taskGroupName) >= taskGroup.MinMember {
+		return
+	}
+	domain, fits, err := mgr.selectTopologyDomain(app, taskGroup)
+	if err != nil || domain == "" {
+		log.Log(log.ShimCachePlaceholder).Info("no other topology domain available for task group",
+			zap.String("appID", app.GetApplicationID()),
+			zap.String("taskGroup", taskGroupName),
+			zap.String("domain", current),
+			zap.Error(err))
+		publishTopologyEvent(app, v1.EventTypeWarning, "TopologyReplanFailed",
+			"Application %s task group %s not satisfied in %s=%s, no other domain available",
+			app.GetApplicationID(), taskGroupName, taskGroup.TopologyKey, current)
+		return
+	}
+	log.Log(log.ShimCachePlaceholder).Info("re-planning topology domain of task group",
+		zap.String("appID", app.GetApplicationID()),
+		zap.String("taskGroup", taskGroupName),
+		zap.String("from", current),
+		zap.String("to", domain),
+		zap.Bool("fits", fits))
+	app.setTopologyDomain(taskGroupName, domain)
+	publishTopologyEvent(app, v1.EventTypeNormal, "TopologyReplanned",
+		"Application %s task group %s not satisfied in %s=%s, placeholders moved to %s",
+		app.GetApplicationID(), taskGroupName, taskGroup.TopologyKey, current, domain)
+
+	// remove the placeholders of the previous plan, the task group is recreated completely in the new domain
+	mgr.deleteTaskGroupPlaceholders(app, taskGroupName, domain)
+	template, err := getPlaceholderTemplate(mgr.clients.KubeClient, app, taskGroup)
+	if err == nil {
+		placeholders := make([]*Placeholder, 0, taskGroup.GetMaxMember())
+		for i := int32(0); i < taskGroup.GetMaxMember(); i++ {
+			placeholder := newPlaceholder(GeneratePlaceholderName(taskGroupName, app.GetApplicationID()), app, taskGroup, template)
+			pinTopologyDomain(placeholder.pod, taskGroup.TopologyKey, domain)
+			placeholders = append(placeholders, placeholder)
+		}
+		err = mgr.createPlaceholders(app, placeholders)
+	}
+	if err != nil {
+		publishTopologyEvent(app, v1.EventTypeWarning, "TopologyReplanFailed",
+			"Application %s task group %s failed to create placeholders in %s=%s: %v",
+			app.GetApplicationID(), taskGroupName, taskGroup.TopologyKey, domain, err)
+		return
+	}
+	mgr.scheduleTopologyReplan(app, taskGroupName)
+}
+
+// deleteTaskGroupPlaceholders deletes the placeholders of the task group that are not placed in the topology domain,
+// all placeholders of the task group if the domain is empty. Placeholders that cannot be deleted are retried as orphans.
+// The placeholders of the task group queued for retry outside the domain are not created anymore.
+func (mgr *PlaceholderManager) deleteTaskGroupPlaceholders(app *Application, taskGroupName string, domain string) {
+	mgr.Lock()
+	defer mgr.Unlock()
+	mgr.dropRetries(app.GetApplicationID(), func(placeholder *Placeholder) bool {
+		return placeholder.taskGroupName == taskGroupName &&
+			(domain == "" || utils.GetPodAnnotationValue(placeholder.pod, constants.AnnotationTopologyDomain) != domain)
+	})
+	for _, task := range app.GetPlaceHolderTasks() {
+		pod := task.GetTaskPod()
+		if task.GetTaskGroupName() != taskGroupName || (domain != "" && utils.GetPodAnnotationValue(pod, constants.AnnotationTopologyDomain) == domain) {
+			continue
+		}
+		if err := mgr.clients.KubeClient.Delete(pod); err != nil {
+			log.Log(log.ShimCachePlaceholder).Warn("failed to delete placeholder pod of previous topology domain",
+				zap.String("podName", pod.Name),
+				zap.Error(err))
+			if !strings.Contains(err.Error(), "not found") {
+				mgr.addOrphan(task.GetTaskID(), pod)
+			}
+		}
+	}
+}
+
+// publishTopologyEvent records an event about the topology placement on the originating pod of the application
+func publishTopologyEvent(app *Application, eventType string, action string, note string, args ...interface{}) {
+	if task := app.GetOriginatingTask(); task != nil {
+		events.GetRecorder().Eventf(task.GetTaskPod().DeepCopy(), nil, eventType, "GangScheduling", action, note, args...)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
+)
+
+const zoneKey = "topology.kubernetes.io/zone"
+
+func newTopologyNode(name string, zone string, cpu string, unschedulable bool) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: apis.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{zoneKey: zone, "pool": "gpu"},
+		},
+		Spec: v1.NodeSpec{Unschedulable: unschedulable},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse(cpu),
+				v1.ResourceMemory: resource.MustParse("16Gi"),
+				v1.ResourcePods:   resource.MustParse("110"),
+			},
+		},
+	}
+}
+
+func newTopologyTestProvider() *client.MockedAPIProvider {
+	provider := client.NewMockedAPIProvider(false)
+	nodes := provider.GetNodeListerMock()
+	nodes.AddNode(newTopologyNode("node-a1", "zone-a", "4", false))
+	nodes.AddNode(newTopologyNode("node-a2", "zone-a", "4", false))
+	nodes.AddNode(newTopologyNode("node-b1", "zone-b", "8", false))
+	nodes.AddNode(newTopologyNode("node-c1", "zone-c", "64", true))
+	unlabelled := newTopologyNode("node-x", "", "64", false)
+	delete(unlabelled.Labels, zoneKey)
+	nodes.AddNode(unlabelled)
+	// 3 cpu used in zone-a: 5 cpu free
+	provider.GetPodListerMock().AddPod(&v1.Pod{
+		ObjectMeta: apis.ObjectMeta{Name: "running", Namespace: namespace},
+		Spec: v1.PodSpec{
+			NodeName: "node-a1",
+			Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("3"),
+			}}}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	})
+	return provider
+}
+
+func newTopologyTestApp(minMember int32) *Application {
+	app := NewApplication(pmAppID, queue, "bob", testGroups, map[string]string{constants.AppTagNamespace: namespace}, newMockSchedulerAPI())
+	app.setTaskGroups([]TaskGroup{{
+		Name:         "workers",
+		MinMember:    minMember,
+		MinResource:  map[string]resource.Quantity{"cpu": resource.MustParse("1")},
+		NodeSelector: map[string]string{"pool": "gpu"},
+		TopologyKey:  zoneKey,
+	}})
+	return app
+}
+
+func TestSelectTopologyDomain(t *testing.T) {
+	mgr := NewPlaceholderManager(newTopologyTestProvider().GetAPIs())
+	app := newTopologyTestApp(6)
+	tg := app.getTaskGroups()[0]
+
+	capacity, err := mgr.getTopologyDomainCapacity(tg)
+	assert.NilError(t, err)
+	assert.Equal(t, len(capacity), 2, "unschedulable and unlabelled nodes should be skipped")
+	assert.Equal(t, capacity["zone-a"].Resources["vcore"].Value, int64(5000))
+	assert.Equal(t, capacity["zone-b"].Resources["vcore"].Value, int64(8000))
+
+	domain, fits, err := mgr.selectTopologyDomain(app, tg)
+	assert.NilError(t, err)
+	assert.Equal(t, domain, "zone-b")
+	assert.Assert(t, fits)
+
+	// tried domains are skipped, zone-a cannot fit the gang
+	app.setTopologyDomain(tg.Name, "zone-b")
+	domain, fits, err = mgr.selectTopologyDomain(app, tg)
+	assert.NilError(t, err)
+	assert.Equal(t, domain, "zone-a")
+	assert.Assert(t, !fits)
+	app.setTopologyDomain(tg.Name, "zone-a")
+	domain, _, err = mgr.selectTopologyDomain(app, tg)
+	assert.NilError(t, err)
+	assert.Equal(t, domain, "")
+
+	// no node matches the node selector
+	tg.NodeSelector = map[string]string{"pool": "cpu"}
+	_, _, err = mgr.selectTopologyDomain(app, tg)
+	assert.ErrorContains(t, err, "no schedulable node has the topology key "+zoneKey)
+}
+
+func TestPinTopologyDomain(t *testing.T) {
+	requirement := v1.NodeSelectorRequirement{Key: zoneKey, Operator: v1.NodeSelectorOpIn, Values: []string{"zone-a"}}
+	pod := &v1.Pod{}
+	pinTopologyDomain(pod, zoneKey, "zone-a")
+	assert.Equal(t, pod.Annotations[constants.AnnotationTopologyDomain], "zone-a")
+	terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	assert.Equal(t, len(terms), 1)
+	assert.DeepEqual(t, terms[0].MatchExpressions, []v1.NodeSelectorRequirement{requirement})
+
+	// the requirement is added to all terms, the shared affinity is not modified
+	existing := v1.NodeSelectorRequirement{Key: "pool", Operator: v1.NodeSelectorOpExists}
+	affinity := &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{
+			{MatchExpressions: []v1.NodeSelectorRequirement{existing}},
+			{MatchFields: []v1.NodeSelectorRequirement{{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: []string{"node-a1"}}}},
+		}},
+	}}
+	pod = &v1.Pod{Spec: v1.PodSpec{Affinity: affinity}}
+	pinTopologyDomain(pod, zoneKey, "zone-a")
+	terms = pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	assert.DeepEqual(t, terms[0].MatchExpressions, []v1.NodeSelectorRequirement{existing, requirement})
+	assert.DeepEqual(t, terms[1].MatchExpressions, []v1.NodeSelectorRequirement{requirement})
+	assert.Equal(t, len(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions), 1)
+}
+
+func TestCreateAppPlaceholdersTopology(t *testing.T) {
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{conf.CMSvcTopologyReplanTimeout: "0s"}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		assert.NilError(t, conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true), "failed to reset config")
+	}()
+
+	provider := newTopologyTestProvider()
+	createdPods := make(map[string]*v1.Pod)
+	provider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		createdPods[pod.Name] = pod
+		return pod, nil
+	})
+	mgr := NewPlaceholderManager(provider.GetAPIs())
+	app := newTopologyTestApp(4)
+	assert.NilError(t, mgr.createAppPlaceholders(app))
+	assert.Equal(t, len(createdPods), 4)
+	for _, pod := range createdPods {
+		assert.Equal(t, pod.Annotations[constants.AnnotationTopologyDomain], "zone-b")
+		terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		assert.Equal(t, terms[0].MatchExpressions[0].Values[0], "zone-b")
+	}
+	assert.Equal(t, app.getTopologyDomain("workers"), "zone-b")
+
+	// no domain: the application falls back to normal scheduling
+	mgr = NewPlaceholderManager(client.NewMockedAPIProvider(false).GetAPIs())
+	err = mgr.createAppPlaceholders(newTopologyTestApp(4))
+	assert.ErrorContains(t, err, "no schedulable node has the topology key")
+}
+
+func TestReplanTopology(t *testing.T) {
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{conf.CMSvcTopologyReplanTimeout: "0s"}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		assert.NilError(t, conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true), "failed to reset config")
+	}()
+
+	provider := newTopologyTestProvider()
+	createdPods := make(map[string]*v1.Pod)
+	provider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		createdPods[pod.Name] = pod
+		return pod, nil
+	})
+	deletedPods := make(map[string]bool)
+	provider.MockDeleteFn(func(pod *v1.Pod) error {
+		deletedPods[pod.Name] = true
+		return nil
+	})
+	mgr := NewPlaceholderManager(provider.GetAPIs())
+	app := newTopologyTestApp(2)
+	app.setTopologyDomain("workers", "zone-b")
+	pod := &v1.Pod{ObjectMeta: apis.ObjectMeta{
+		Name:        "ph-zone-b",
+		Annotations: map[string]string{constants.AnnotationTaskGroupName: "workers"},
+	}}
+	pinTopologyDomain(pod, zoneKey, "zone-b")
+	app.addTask(NewTaskPlaceholder("ph-zone-b", app, initContextForTest(), pod))
+
+	// not reserving: nothing changes
+	mgr.replanTopology(app, "workers")
+	assert.Equal(t, app.getTopologyDomain("workers"), "zone-b")
+
+	app.sm.SetState(ApplicationStates().Reserving)
+	mgr.replanTopology(app, "workers")
+	assert.Equal(t, app.getTopologyDomain("workers"), "zone-a")
+	assert.Assert(t, deletedPods["ph-zone-b"], "placeholder of the previous domain should be deleted")
+	assert.Equal(t, len(createdPods), 2)
+	for _, created := range createdPods {
+		assert.Equal(t, created.Annotations[constants.AnnotationTopologyDomain], "zone-a")
+	}
+	assert.Equal(t, app.getBoundPlaceholderCount("workers"), int32(0))
+
+	// all domains tried: the placeholders stay in the current domain
+	mgr.replanTopology(app, "workers")
+	assert.Equal(t, app.getTopologyDomain("workers"), "zone-a")
+	assert.Equal(t, len(createdPods), 2)
+}
+
+func TestStopTopologyReplanTimer(t *testing.T) {
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{conf.CMSvcTopologyReplanTimeout: "1h"}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		assert.NilError(t, conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true), "failed to reset config")
+	}()
+
+	mgr := NewPlaceholderManager(client.NewMockedAPIProvider(false).GetAPIs())
+	app := newTopologyTestApp(2)
+	app.setTopologyDomain("workers", "zone-a")
+	mgr.scheduleTopologyReplan(app, "workers")
+	timer := app.topologyPlans["workers"].replanTimer
+	assert.Assert(t, timer != nil, "re-plan timer should be set")
+
+	// the cleanup of the placeholders stops the timer, no new timer is started after that
+	mgr.cleanUp(app)
+	assert.Assert(t, app.topologyPlans["workers"].replanTimer == nil, "re-plan timer should be removed")
+	assert.Assert(t, !timer.Stop(), "re-plan timer should be stopped")
+	mgr.scheduleTopologyReplan(app, "workers")
+	assert.Assert(t, app.topologyPlans["workers"].replanTimer == nil, "re-plan timer should not be started")
+}
@@ -0,0 +1,233 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+)
+
+// phases of a PodGroup, as defined by the scheduler-plugins coscheduling API
+const (
+	PodGroupPending    = "Pending"
+	PodGroupScheduling = "Scheduling"
+	PodGroupScheduled  = "Scheduled"
+	PodGroupFinished   = "Finished"
+	PodGroupFailed     = "Failed"
+)
+
+// podGroup contains the fields of a scheduling.x-k8s.io/v1alpha1 PodGroup used by the shim
+type podGroup struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              podGroupSpec `json:"spec,omitempty"`
+}
+
+type podGroupSpec struct {
+	MinMember              int32           `json:"minMember,omitempty"`
+	MinResources           v1.ResourceList `json:"minResources,omitempty"`
+	ScheduleTimeoutSeconds *int32          `json:"scheduleTimeoutSeconds,omitempty"`
+}
+
+// podGroupManager maps the PodGroups of the pods onto the task groups of the applications and writes the scheduling
+// phase of the applications back into the PodGroup status.
+type podGroupManager struct {
+	clients *client.Clients
+	phases  *keyedWriter[podGroupPhase]
+}
+
+// podGroupPhase is the phase to write into the status of a PodGroup
+type podGroupPhase struct {
+	namespace string
+	name      string
+	phase     string
+}
+
+func newPodGroupManager(clients *client.Clients) *podGroupManager {
+	m := &podGroupManager{
+		clients: clients,
+	}
+	m.phases = newKeyedWriter(m.writePhaseUpdate)
+	return m
+}
+
+// getPodGroup returns the PodGroup from the informer cache, nil if it does not exist or cannot be converted
+func (m *podGroupManager) getPodGroup(namespace, name string) *podGroup {
+	obj, err := m.clients.PodGroupInformer.Lister().ByNamespace(namespace).Get(name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Log(log.ShimCacheApplication).Warn("failed to get PodGroup",
+				zap.String("namespace", namespace),
+				zap.String("name", name),
+				zap.Error(err))
+		}
+		return nil
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	pg := &podGroup{}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), pg); err != nil {
+		log.Log(log.ShimCacheApplication).Warn("failed to convert PodGroup",
+			zap.String("namespace", namespace),
+			zap.String("name", name),
+			zap.Error(err))
+		return nil
+	}
+	return pg
+}
+
+// updateAppMetadata sets the task group and scheduling policy of the application from the PodGroup of the pod.
+// The task groups annotation takes precedence: the PodGroup is only used if the pod has no task groups defined.
+func (m *podGroupManager) updateAppMetadata(pod *v1.Pod, meta *ApplicationMetadata) {
+	name := utils.GetPodLabelValue(pod, constants.LabelPodGroup)
+	if name == "" || conf.GetSchedulerConf().DisableGangScheduling || len(meta.TaskGroups) > 0 {
+		return
+	}
+	pg := m.getPodGroup(pod.Namespace, name)
+	if pg == nil {
+		log.Log(log.ShimCacheApplication).Info("PodGroup of the pod not found, scheduling without gang",
+			zap.String("namespace", pod.Namespace),
+			zap.String("pod", pod.Name),
+			zap.String("podGroup", name))
+		return
+	}
+	meta.PodGroupName = name
+	if pg.Spec.MinMember <= 0 {
+		return
+	}
+	taskGroups := []TaskGroup{newTaskGroupFromPodGroup(pg, pod)}
+	meta.TaskGroups = taskGroups
+	// placeholders carry the definition for recovery
+	if def, err := json.Marshal(taskGroups); err == nil {
+		meta.Tags[constants.AnnotationTaskGroups] = string(def)
+	}
+	params := meta.SchedulingPolicyParameters
+	if params == nil {
+		params = utils.NewSchedulingPolicyParameters(0, constants.SchedulingPolicyStyleParamDefault)
+	}
+	if pg.Spec.ScheduleTimeoutSeconds != nil && params.GetPlaceholderTimeout() == 0 {
+		params = utils.NewSchedulingPolicyParameters(int64(*pg.Spec.ScheduleTimeoutSeconds), params.GetGangSchedulingStyle())
+	}
+	meta.SchedulingPolicyParameters = params
+}
+
+// newTaskGroupFromPodGroup creates the task group named after the PodGroup. The minResources of the PodGroup are the
+// total for the group, the resources per member are rounded up. If the PodGroup has no minResources the requests of
+// the pod are used. The placeholders are placed like the pod: the node selector, tolerations and affinity of the pod
+// are copied.
+func newTaskGroupFromPodGroup(pg *podGroup, pod *v1.Pod) TaskGroup {
+	minResource := make(map[string]resource.Quantity)
+	if len(pg.Spec.MinResources) > 0 {
+		members := int64(pg.Spec.MinMember)
+		for name, quantity := range pg.Spec.MinResources {
+			perMember := (quantity.MilliValue() + members - 1) / members
+			minResource[string(name)] = *resource.NewMilliQuantity(perMember, quantity.Format)
+		}
+	} else {
+		for _, container := range pod.Spec.Containers {
+			for name, quantity := range container.Resources.Requests {
+				total := minResource[string(name)]
+				total.Add(quantity)
+				minResource[string(name)] = total
+			}
+		}
+	}
+	return TaskGroup{
+		Name:         pg.Name,
+		MinMember:    pg.Spec.MinMember,
+		MinResource:  minResource,
+		NodeSelector: pod.Spec.NodeSelector,
+		Tolerations:  pod.Spec.Tolerations,
+		Affinity:     pod.Spec.Affinity,
+	}
+}
+
+// getPodGroupPhase returns the PodGroup phase for the application state, empty if the phase should not change
+func getPodGroupPhase(appState string) string {
+	states := ApplicationStates()
+	switch appState {
+	case states.New, states.Submitted, states.Accepted:
+		return PodGroupPending
+	case states.Reserving, states.Resuming:
+		return PodGroupScheduling
+	case states.Running:
+		return PodGroupScheduled
+	case states.Completed:
+		return PodGroupFinished
+	case states.Rejected, states.Failed, states.Killed:
+		return PodGroupFailed
+	default:
+		return ""
+	}
+}
+
+// setPhase writes the phase into the status of the PodGroup asynchronously. Updates of the same PodGroup are written
+// in order, an update that is not written yet is replaced by a newer one.
+func (m *podGroupManager) setPhase(namespace, name, phase string) {
+	m.phases.set(namespace+"/"+name, podGroupPhase{namespace: namespace, name: name, phase: phase})
+}
+
+func (m *podGroupManager) writePhaseUpdate(_ string, update podGroupPhase) {
+	if err := m.writePhase(update.namespace, update.name, update.phase); err != nil {
+		log.Log(log.ShimCacheApplication).Warn("failed to update PodGroup status",
+			zap.String("namespace", update.namespace),
+			zap.String("name", update.name),
+			zap.String("phase", update.phase),
+			zap.Error(err))
+	}
+}
+
+func (m *podGroupManager) writePhase(namespace, name, phase string) error {
+	podGroups := m.clients.DynamicClient.Resource(client.PodGroupResource).Namespace(namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pg, err := podGroups.Get(context.Background(), name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		current, _, err := unstructured.NestedString(pg.Object, "status", "phase")
+		if err == nil && current == phase {
+			return nil
+		}
+		if err = unstructured.SetNestedField(pg.Object, phase, "status", "phase"); err != nil {
+			return fmt.Errorf("unable to set the PodGroup phase: %w", err)
+		}
+		_, err = podGroups.UpdateStatus(context.Background(), pg, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,225 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
+)
+
+func newTestPodGroup(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "scheduling.x-k8s.io/v1alpha1",
+		"kind":       "PodGroup",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+		"spec": spec,
+	}}
+}
+
+func newTestPodGroupManager(t *testing.T, podGroups ...*unstructured.Unstructured) *podGroupManager {
+	objects := make([]runtime.Object, len(podGroups))
+	for i, pg := range podGroups {
+		objects[i] = pg
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{client.PodGroupResource: "PodGroupList"}, objects...)
+	informer := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0).ForResource(client.PodGroupResource)
+	for _, pg := range podGroups {
+		assert.NilError(t, informer.Informer().GetIndexer().Add(pg), "failed to add PodGroup to indexer")
+	}
+	return newPodGroupManager(&client.Clients{
+		PodGroupInformer: informer,
+		DynamicClient:    dynamicClient,
+	})
+}
+
+func newTestPodGroupPod(podGroup string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			Labels:    map[string]string{constants.LabelPodGroup: podGroup},
+		},
+		Spec: v1.PodSpec{
+			NodeSelector: map[string]string{"zone": "a"},
+			Containers: []v1.Container{
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("500m"),
+					v1.ResourceMemory: resource.MustParse("1Gi"),
+				}}},
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					v1.ResourceCPU: resource.MustParse("250m"),
+				}}},
+			},
+		},
+	}
+}
+
+func TestPodGroupUpdateAppMetadata(t *testing.T) {
+	manager := newTestPodGroupManager(t,
+		newTestPodGroup("pg-gang", map[string]interface{}{
+			"minMember":              int64(3),
+			"minResources":           map[string]interface{}{"cpu": "1", "memory": "3Gi"},
+			"scheduleTimeoutSeconds": int64(120),
+		}),
+		newTestPodGroup("pg-no-min", map[string]interface{}{}))
+
+	// PodGroup with a gang
+	meta := &ApplicationMetadata{Tags: map[string]string{}}
+	manager.updateAppMetadata(newTestPodGroupPod("pg-gang"), meta)
+	assert.Equal(t, meta.PodGroupName, "pg-gang")
+	assert.Equal(t, len(meta.TaskGroups), 1)
+	tg := meta.TaskGroups[0]
+	assert.Equal(t, tg.Name, "pg-gang")
+	assert.Equal(t, tg.MinMember, int32(3))
+	cpu := tg.MinResource["cpu"]
+	assert.Equal(t, cpu.MilliValue(), int64(334), "cpu per member should be rounded up")
+	memory := tg.MinResource["memory"]
+	assert.Equal(t, memory.Value(), int64(1024*1024*1024))
+	assert.DeepEqual(t, tg.NodeSelector, map[string]string{"zone": "a"})
+	assert.Equal(t, meta.SchedulingPolicyParameters.GetPlaceholderTimeout(), int64(120))
+	assert.Equal(t, meta.SchedulingPolicyParameters.GetGangSchedulingStyle(), constants.SchedulingPolicyStyleParamDefault)
+	taskGroups, err := utils.GetTaskGroupsFromAnnotation(&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{constants.AnnotationTaskGroups: meta.Tags[constants.AnnotationTaskGroups]},
+	}})
+	assert.NilError(t, err, "task groups tag should be valid")
+	assert.Equal(t, len(taskGroups), 1)
+
+	// timeout of the scheduling policy takes precedence
+	meta = &ApplicationMetadata{
+		Tags:                       map[string]string{},
+		SchedulingPolicyParameters: utils.NewSchedulingPolicyParameters(30, constants.SchedulingPolicyStyleParamValues["Hard"]),
+	}
+	manager.updateAppMetadata(newTestPodGroupPod("pg-gang"), meta)
+	assert.Equal(t, meta.SchedulingPolicyParameters.GetPlaceholderTimeout(), int64(30))
+	assert.Equal(t, meta.SchedulingPolicyParameters.GetGangSchedulingStyle(), constants.SchedulingPolicyStyleParamValues["Hard"])
+
+	// task groups annotation takes precedence
+	meta = &ApplicationMetadata{Tags: map[string]string{}, TaskGroups: []TaskGroup{{Name: "tg", MinMember: 1}}}
+	manager.updateAppMetadata(newTestPodGroupPod("pg-gang"), meta)
+	assert.Equal(t, meta.PodGroupName, "")
+	assert.Equal(t, meta.TaskGroups[0].Name, "tg")
+
+	// PodGroup without minMember: status is updated but no gang
+	meta = &ApplicationMetadata{Tags: map[string]string{}}
+	manager.updateAppMetadata(newTestPodGroupPod("pg-no-min"), meta)
+	assert.Equal(t, meta.PodGroupName, "pg-no-min")
+	assert.Equal(t, len(meta.TaskGroups), 0)
+	assert.Assert(t, meta.SchedulingPolicyParameters == nil)
+
+	// unknown PodGroup
+	meta = &ApplicationMetadata{Tags: map[string]string{}}
+	manager.updateAppMetadata(newTestPodGroupPod("pg-unknown"), meta)
+	assert.Equal(t, meta.PodGroupName, "")
+	assert.Equal(t, len(meta.TaskGroups), 0)
+
+	// gang scheduling disabled
+	err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{conf.CMSvcDisableGangScheduling: "true"}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		assert.NilError(t, conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true), "failed to reset config")
+	}()
+	meta = &ApplicationMetadata{Tags: map[string]string{}}
+	manager.updateAppMetadata(newTestPodGroupPod("pg-gang"), meta)
+	assert.Equal(t, meta.PodGroupName, "")
+	assert.Equal(t, len(meta.TaskGroups), 0)
+}
+
+func TestNewTaskGroupFromPodGroupRequests(t *testing.T) {
+	pg := &podGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "pg"},
+		Spec:       podGroupSpec{MinMember: 2},
+	}
+	tg := newTaskGroupFromPodGroup(pg, newTestPodGroupPod("pg"))
+	assert.Equal(t, tg.MinMember, int32(2))
+	cpu := tg.MinResource["cpu"]
+	assert.Equal(t, cpu.MilliValue(), int64(750), "requests of all containers should be summed")
+	memory := tg.MinResource["memory"]
+	assert.Equal(t, memory.Value(), int64(1024*1024*1024))
+}
+
+func TestGetPodGroupPhase(t *testing.T) {
+	states := ApplicationStates()
+	tests := map[string]string{
+		states.New:       PodGroupPending,
+		states.Submitted: PodGroupPending,
+		states.Accepted:  PodGroupPending,
+		states.Reserving: PodGroupScheduling,
+		states.Resuming:  PodGroupScheduling,
+		states.Running:   PodGroupScheduled,
+		states.Completed: PodGroupFinished,
+		states.Rejected:  PodGroupFailed,
+		states.Failed:    PodGroupFailed,
+		states.Killed:    PodGroupFailed,
+		states.Failing:   "",
+		states.Killing:   "",
+	}
+	for state, phase := range tests {
+		assert.Equal(t, getPodGroupPhase(state), phase, "unexpected phase for state %s", state)
+	}
+}
+
+func TestPodGroupSetPhase(t *testing.T) {
+	manager := newTestPodGroupManager(t, newTestPodGroup("pg", map[string]interface{}{"minMember": int64(1)}))
+	podGroups := manager.clients.DynamicClient.Resource(client.PodGroupResource).Namespace("default")
+	waitForPhase := func(phase string) {
+		err := utils.WaitForCondition(func() bool {
+			pg, err := podGroups.Get(context.Background(), "pg", metav1.GetOptions{})
+			if err != nil {
+				return false
+			}
+			current, _, err := unstructured.NestedString(pg.Object, "status", "phase")
+			return err == nil && current == phase
+		}, 10*time.Millisecond, time.Second)
+		assert.NilError(t, err, "PodGroup phase %s not set", phase)
+	}
+
+	manager.setPhase("default", "pg", PodGroupScheduling)
+	waitForPhase(PodGroupScheduling)
+	manager.setPhase("default", "pg", PodGroupScheduled)
+	waitForPhase(PodGroupScheduled)
+
+	// the state of the application is written into the PodGroup
+	app := NewApplication("app-1", "root.default", "user", []string{}, map[string]string{constants.AppTagNamespace: "default"}, nil)
+	app.setPodGroup("pg", manager)
+	app.onPodGroupStateChange(ApplicationStates().Completed)
+	waitForPhase(PodGroupFinished)
+
+	// missing PodGroup is ignored
+	assert.NilError(t, manager.writePhase("default", "unknown", PodGroupFailed))
+}
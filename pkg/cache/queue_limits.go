@@ -0,0 +1,71 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	coreCommon "github.com/apache/yunikorn-core/pkg/common"
+	"github.com/apache/yunikorn-core/pkg/common/resources"
+	"github.com/apache/yunikorn-core/pkg/scheduler"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
+	"github.com/apache/yunikorn-scheduler-interface/lib/go/si"
+)
+
+// queueLimits reads the maximum resources of the queue the core placed an application in. The scheduler interface does
+// not expose the queues: the cluster context of the core running in the same process is used.
+type queueLimits struct {
+	clusterContext *scheduler.ClusterContext
+}
+
+func newQueueLimits(clusterContext *scheduler.ClusterContext) *queueLimits {
+	return &queueLimits{
+		clusterContext: clusterContext,
+	}
+}
+
+// checkFits returns an error if the resources do not fit in the maximum resources of the queue of the application.
+// The maximum includes the limits of the parent queues, the namespace quota set on a dynamic leaf queue and the size
+// of the cluster. Resource types without a maximum are not limited. Nothing is checked if the core does not know the
+// application.
+func (l *queueLimits) checkFits(appID string, request *si.Resource) error {
+	if request == nil {
+		return nil
+	}
+	partition := l.clusterContext.GetPartition(coreCommon.GetNormalizedPartitionName(constants.DefaultPartition,
+		conf.GetSchedulerConf().ClusterID))
+	if partition == nil {
+		return nil
+	}
+	app := partition.GetApplication(appID)
+	if app == nil {
+		return nil
+	}
+	queue := partition.GetQueue(app.GetQueuePath())
+	if queue == nil {
+		return nil
+	}
+	ask := resources.NewResourceFromProto(request)
+	if maxResource := queue.GetMaxResource(); maxResource != nil && !maxResource.FitInMaxUndef(ask) {
+		return fmt.Errorf("gang request %s exceeds the maximum resources %s of queue %s",
+			ask.String(), maxResource.String(), queue.GetQueuePath())
+	}
+	return nil
+}
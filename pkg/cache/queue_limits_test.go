@@ -0,0 +1,119 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	coreCommon "github.com/apache/yunikorn-core/pkg/common"
+	"github.com/apache/yunikorn-core/pkg/common/security"
+	"github.com/apache/yunikorn-core/pkg/scheduler"
+	"github.com/apache/yunikorn-core/pkg/scheduler/objects"
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
+	siCommon "github.com/apache/yunikorn-scheduler-interface/lib/go/common"
+	"github.com/apache/yunikorn-scheduler-interface/lib/go/si"
+)
+
+const queueLimitsConfig = `
+partitions:
+  - name: default
+    placementrules:
+      - name: provided
+        create: true
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: small
+            resources:
+              max:
+                vcore: 2
+                memory: 2G
+          - name: parent
+            parent: true
+            resources:
+              max:
+                vcore: 10
+            childtemplate:
+              resources:
+                max:
+                  vcore: 4
+            queues:
+              - name: unlimited
+`
+
+func newTestAsk(vcore int64, memory int64) *si.Resource {
+	return &si.Resource{Resources: map[string]*si.Quantity{
+		"vcore":  {Value: vcore},
+		"memory": {Value: memory},
+	}}
+}
+
+// newTestQueueLimits returns the queue limits of a core cluster context using the queue configuration
+func newTestQueueLimits(t *testing.T) *queueLimits {
+	clusterContext, err := scheduler.NewClusterContext(conf.GetSchedulerConf().ClusterID, "test", []byte(queueLimitsConfig))
+	assert.NilError(t, err, "failed to create the core cluster context")
+	return newQueueLimits(clusterContext)
+}
+
+// addCoreApplication adds the application to the core, the core places it in the queue
+func addCoreApplication(t *testing.T, limits *queueLimits, appID, queue string, tags map[string]string) {
+	rmID := conf.GetSchedulerConf().ClusterID
+	partition := limits.clusterContext.GetPartition(coreCommon.GetNormalizedPartitionName(constants.DefaultPartition, rmID))
+	assert.Assert(t, partition != nil, "partition not found")
+	app := objects.NewApplication(&si.AddApplicationRequest{
+		ApplicationID: appID,
+		QueueName:     queue,
+		PartitionName: partition.Name,
+		Tags:          tags,
+	}, security.UserGroup{User: "testuser"}, nil, rmID)
+	assert.NilError(t, partition.AddApplication(app), "failed to add application to the core")
+}
+
+func TestQueueLimitsCheckFits(t *testing.T) {
+	limits := newTestQueueLimits(t)
+	addCoreApplication(t, limits, "app-small", "root.small", nil)
+	addCoreApplication(t, limits, "app-unlimited", "root.parent.unlimited", nil)
+	addCoreApplication(t, limits, "app-dynamic", "root.parent.dynamic", nil)
+	addCoreApplication(t, limits, "app-quota", "root.parent.quota", map[string]string{
+		siCommon.AppTagNamespaceResourceQuota: `{"resources":{"vcore":{"value":1000}}}`,
+	})
+	addCoreApplication(t, limits, "app-other", "root.other", nil)
+
+	// queue maximum
+	assert.NilError(t, limits.checkFits("app-small", newTestAsk(2000, 1000*1000*1000)))
+	assert.ErrorContains(t, limits.checkFits("app-small", newTestAsk(3000, 0)), "of queue root.small")
+	assert.ErrorContains(t, limits.checkFits("app-small", newTestAsk(0, 3*1000*1000*1000)), "of queue root.small")
+
+	// the maximum of the parent applies to the children
+	assert.NilError(t, limits.checkFits("app-unlimited", newTestAsk(8000, 0)))
+	assert.ErrorContains(t, limits.checkFits("app-unlimited", newTestAsk(12000, 0)), "of queue root.parent.unlimited")
+
+	// dynamic queues use the child template of the parent, or the namespace quota
+	assert.ErrorContains(t, limits.checkFits("app-dynamic", newTestAsk(8000, 0)), "of queue root.parent.dynamic")
+	assert.ErrorContains(t, limits.checkFits("app-quota", newTestAsk(2000, 0)), "of queue root.parent.quota")
+	assert.NilError(t, limits.checkFits("app-other", newTestAsk(100000, 0)))
+
+	// unknown application or no request: nothing to check
+	assert.NilError(t, limits.checkFits("unknown", newTestAsk(100000, 0)))
+	assert.NilError(t, limits.checkFits("app-small", nil))
+}
@@ -46,6 +46,10 @@ func NewAsyncRMCallback(ctx *Context) *AsyncRMCallback {
 func (callback *AsyncRMCallback) UpdateAllocation(response *si.AllocationResponse) error {
 	log.Log(log.ShimRMCallback).Debug("UpdateAllocation callback received",
 		zap.Stringer("UpdateAllocationResponse", response))
+	if callback.context.isStopping() {
+		log.Log(log.ShimRMCallback).Info("scheduler is stopping, ignoring UpdateAllocation callback")
+		return nil
+	}
 	// handle new allocations
 	for _, alloc := range response.New {
 		// got allocation for pod, bind pod to the scheduled node
@@ -107,6 +111,10 @@ func (callback *AsyncRMCallback) UpdateAllocation(response *si.AllocationRespons
 func (callback *AsyncRMCallback) UpdateApplication(response *si.ApplicationResponse) error {
 	log.Log(log.ShimRMCallback).Debug("UpdateApplication callback received",
 		zap.Stringer("UpdateApplicationResponse", response))
+	if callback.context.isStopping() {
+		log.Log(log.ShimRMCallback).Info("scheduler is stopping, ignoring UpdateApplication callback")
+		return nil
+	}
 
 	// handle new accepted apps
 	for _, app := range response.Accepted {
@@ -157,6 +165,10 @@ func (callback *AsyncRMCallback) UpdateApplication(response *si.ApplicationRespo
 func (callback *AsyncRMCallback) UpdateNode(response *si.NodeResponse) error {
 	log.Log(log.ShimRMCallback).Debug("UpdateNode callback received",
 		zap.Stringer("UpdateNodeResponse", response))
+	if callback.context.isStopping() {
+		log.Log(log.ShimRMCallback).Info("scheduler is stopping, ignoring UpdateNode callback")
+		return nil
+	}
 	// handle new accepted nodes
 	for _, node := range response.Accepted {
 		log.Log(log.ShimRMCallback).Debug("callback: response to accepted node",
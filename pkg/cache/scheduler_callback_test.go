@@ -64,6 +64,26 @@ func TestUpdateAllocation_NewTask(t *testing.T) {
 	assert.NilError(t, err, "task has not transitioned to Bound state")
 }
 
+func TestUpdateAllocation_Stopping(t *testing.T) {
+	callback, context := initCallbackTest(t, false, false)
+	defer dispatcher.UnregisterAllEventHandlers()
+	defer dispatcher.Stop()
+
+	context.StopCoreCallbacks()
+	err := callback.UpdateAllocation(&si.AllocationResponse{
+		New: []*si.Allocation{
+			{
+				ApplicationID: appID,
+				AllocationKey: taskUID1,
+				NodeID:        fakeNodeName,
+			},
+		},
+	})
+	assert.NilError(t, err, "error updating allocation")
+	assert.Assert(t, !context.schedulerCache.IsAssumedPod(taskUID1), "pod should not be assumed while stopping")
+	assert.Equal(t, context.getTask(appID, taskUID1).GetTaskState(), TaskStates().Scheduling)
+}
+
 func TestUpdateAllocation_NewTask_TaskNotFound(t *testing.T) {
 	callback, context := initCallbackTest(t, false, false)
 	defer dispatcher.UnregisterAllEventHandlers()
@@ -528,6 +548,16 @@ func (m *mockPredicateManager) Predicates(_ *v1.Pod, _ *framework.NodeInfo, _ bo
 	return "", nil
 }
 
+func (m *mockPredicateManager) ReservePod(_ *v1.Pod, _ *framework.NodeInfo) (plugin string, error error) {
+	return "", nil
+}
+
+func (m *mockPredicateManager) PreBindPod(_ *v1.Pod) (plugin string, error error) {
+	return "", nil
+}
+
+func (m *mockPredicateManager) UnreservePod(_ *v1.Pod) {}
+
 func (m *mockPredicateManager) PreemptionPredicates(_ *v1.Pod, _ *framework.NodeInfo, _ []*v1.Pod, _ int) (index int) {
 	return 0
 }
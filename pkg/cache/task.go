@@ -26,6 +26,7 @@ import (
 	"github.com/looplab/fsm"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/dynamic-resource-allocation/resourceclaim"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 
 	"github.com/apache/yunikorn-k8shim/pkg/common"
@@ -58,6 +59,8 @@ type Task struct {
 	schedulingState TaskSchedulingState
 	resource        *si.Resource
 	pod             *v1.Pod
+	// the shim released the placeholders of the task group, the task is scheduled without placeholders
+	taskGroupReleased bool
 
 	lock *locking.RWMutex
 }
@@ -288,6 +291,12 @@ func (task *Task) handleSubmitTaskEvent() {
 	log.Log(log.ShimCacheTask).Debug("scheduling pod",
 		zap.String("podName", task.pod.Name))
 
+	// the resource claims of the pod exist now: the resource calculated when the task was created can be missing the
+	// devices of claims generated from a template
+	if len(task.pod.Spec.ResourceClaims) > 0 {
+		task.resource = common.GetPodResource(task.pod)
+	}
+
 	// send update allocation event to core
 	task.updateAllocation()
 
@@ -314,6 +323,11 @@ func (task *Task) updateAllocation() {
 		AllowPreemptOther: task.isPreemptOtherAllowed(),
 	}
 
+	// a request with a task group waits for a placeholder to replace
+	taskGroupName := task.taskGroupName
+	if task.taskGroupReleased {
+		taskGroupName = ""
+	}
 	// submit allocation
 	rr := common.CreateAllocationForTask(
 		task.applicationID,
@@ -321,7 +335,7 @@ func (task *Task) updateAllocation() {
 		task.pod.Spec.NodeName,
 		task.resource,
 		task.placeholder,
-		task.taskGroupName,
+		taskGroupName,
 		task.pod,
 		task.originator,
 		preemptionPolicy)
@@ -332,6 +346,32 @@ func (task *Task) updateAllocation() {
 	}
 }
 
+// releaseTaskGroup schedules the task without placeholders after the shim released the placeholders of its task group.
+// The core only accounts for the placeholders it timed out itself: a request with the task group would wait for a
+// placeholder to replace forever. A request already sent to the core is removed and sent again without the task group.
+func (task *Task) releaseTaskGroup() {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	if task.placeholder || task.taskGroupName == "" || task.taskGroupReleased {
+		return
+	}
+	task.taskGroupReleased = true
+	if task.sm.Current() != TaskStates().Scheduling {
+		return
+	}
+	log.Log(log.ShimCacheTask).Info("placeholders of the task group released, scheduling the task without placeholders",
+		zap.String("appID", task.applicationID),
+		zap.String("taskID", task.taskID),
+		zap.String("taskGroupName", task.taskGroupName))
+	releaseRequest := common.CreateReleaseRequestForTask(task.applicationID, task.taskID, task.application.partition,
+		si.TerminationType_STOPPED_BY_RM)
+	if err := task.context.apiProvider.GetAPIs().SchedulerAPI.UpdateAllocation(releaseRequest); err != nil {
+		log.Log(log.ShimCacheTask).Debug("failed to send release request to scheduler", zap.Error(err))
+		return
+	}
+	task.updateAllocation()
+}
+
 // this is called after task reaches PENDING state,
 // submit the resource asks from this task to the scheduler core
 func (task *Task) postTaskPending() {
@@ -345,7 +385,9 @@ func (task *Task) postTaskPending() {
 // The result of the binding is tracked and failures are properly handled.
 // If successful, we move task to next state BOUND, otherwise we fail the task
 func (task *Task) postTaskAllocated() {
+	bindDone := task.context.trackBind()
 	go func() {
+		defer bindDone()
 		// we need to obtain task's lock first,
 		// this ensures no other threads modifying task state at the time being
 		task.lock.Lock()
@@ -379,6 +421,15 @@ func (task *Task) postTaskAllocated() {
 				return
 			}
 
+			log.Log(log.ShimCacheTask).Debug("bind pod resource claims",
+				zap.String("podName", task.pod.Name),
+				zap.String("podUID", string(task.pod.UID)))
+			if err := task.context.bindPodResources(task.pod); err != nil {
+				log.Log(log.ShimCacheTask).Error("bind resource claims to pod failed", zap.String("taskID", task.taskID), zap.Error(err))
+				task.failWithEvent(fmt.Sprintf("bind resource claims to pod failed, name: %s, %s", task.alias, err.Error()), "PodResourceClaimsBindFailure")
+				return
+			}
+
 			log.Log(log.ShimCacheTask).Debug("bind pod",
 				zap.String("podName", task.pod.Name),
 				zap.String("podUID", string(task.pod.UID)))
@@ -554,7 +605,37 @@ func (task *Task) sanityCheckBeforeScheduling() error {
 				nil, v1.EventTypeWarning, "Scheduling", "Scheduling", fmt.Sprintf("Pod has inconsistent queue metadata and may be rejected in a future YuniKorn release: %s", err.Error()))
 		}
 	}
-	return task.checkPodPVCs()
+	if err := task.checkPodPVCs(); err != nil {
+		return err
+	}
+	return task.checkPodResourceClaims()
+}
+
+// checkPodResourceClaims checks that the resource claims of the pod exist. Claims generated from a template are only
+// created after the pod: the devices requested by the pod are not known before that.
+func (task *Task) checkPodResourceClaims() error {
+	clients := task.context.apiProvider.GetAPIs()
+	if !clients.IsDRAEnabled() {
+		return nil
+	}
+	task.lock.RLock()
+	pod := task.pod
+	task.lock.RUnlock()
+	for i := range pod.Spec.ResourceClaims {
+		claimName, _, err := resourceclaim.Name(pod, &pod.Spec.ResourceClaims[i])
+		if err != nil {
+			return err
+		}
+		if claimName == nil {
+			// the claim is not needed by the pod
+			continue
+		}
+		log.Log(log.ShimCacheTask).Debug("checking resource claim", zap.String("name", *claimName))
+		if _, err = clients.ResourceClaimInformer.Lister().ResourceClaims(pod.Namespace).Get(*claimName); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (task *Task) checkPodPVCs() error {
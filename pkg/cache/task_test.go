@@ -24,11 +24,15 @@ import (
 
 	"gotest.tools/v3/assert"
 	v1 "k8s.io/api/core/v1"
+	resourcev1beta1 "k8s.io/api/resource/v1beta1"
 	schedulingv1 "k8s.io/api/scheduling/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	k8fake "k8s.io/client-go/kubernetes/fake"
 
 	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/yunikorn-k8shim/pkg/common/utils"
@@ -763,3 +767,58 @@ func TestUpdatePodCondition(t *testing.T) {
 	assert.Equal(t, v1.PodPending, podCopy.Status.Phase)
 	assert.Equal(t, v1.PodReasonUnschedulable, podCopy.Status.Conditions[0].Reason)
 }
+
+func TestSubmitTaskWithTemplateResourceClaims(t *testing.T) {
+	mockedContext, mockedAPIProvider := initContextAndAPIProviderForTest()
+	var allocRequest *si.AllocationRequest
+	mockedAPIProvider.MockSchedulerAPIUpdateAllocationFn(func(request *si.AllocationRequest) error {
+		allocRequest = request
+		return nil
+	})
+	clients := mockedAPIProvider.GetAPIs()
+	informerFactory := informers.NewSharedInformerFactory(k8fake.NewClientset(), 0)
+	clients.ResourceClaimInformer = informerFactory.Resource().V1beta1().ResourceClaims()
+	clients.ResourceSliceInformer = informerFactory.Resource().V1beta1().ResourceSlices()
+	clients.DeviceClassInformer = informerFactory.Resource().V1beta1().DeviceClasses()
+	common.SetDeviceResolver(newDeviceResolver(clients.ResourceClaimInformer.Lister(),
+		clients.ResourceSliceInformer.Lister(), clients.DeviceClassInformer.Lister()))
+	defer common.SetDeviceResolver(nil)
+
+	template := "gpu-template"
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-dra", Namespace: "default", UID: "UID-DRA"},
+		Spec: v1.PodSpec{
+			Containers:     []v1.Container{{Name: "container-01"}},
+			ResourceClaims: []v1.PodResourceClaim{{Name: "gpu", ResourceClaimTemplateName: &template}},
+		},
+	}
+	app := NewApplication("app-dra", "root.abc", "testuser", testGroups, map[string]string{}, clients.SchedulerAPI)
+	task := NewTask("UID-DRA", app, mockedContext, pod)
+
+	// the claim is not generated from the template yet
+	assert.Assert(t, task.sanityCheckBeforeScheduling() != nil, "task must wait for the claim to be generated")
+
+	// the claim is generated but not seen by the informer yet
+	claimName := "pod-dra-gpu-x7k2p"
+	withStatus := pod.DeepCopy()
+	withStatus.Status.ResourceClaimStatuses = []v1.PodResourceClaimStatus{{Name: "gpu", ResourceClaimName: &claimName}}
+	task.SetTaskPod(withStatus)
+	assert.ErrorContains(t, task.sanityCheckBeforeScheduling(), "not found")
+
+	err := clients.ResourceClaimInformer.Informer().GetIndexer().Add(&resourcev1beta1.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: claimName, Namespace: "default"},
+		Spec: resourcev1beta1.ResourceClaimSpec{Devices: resourcev1beta1.DeviceClaim{
+			Requests: []resourcev1beta1.DeviceRequest{{Name: "gpus", DeviceClassName: "gpu.example.com", Count: 2}},
+		}},
+	})
+	assert.NilError(t, err, "failed to add claim")
+	assert.NilError(t, task.sanityCheckBeforeScheduling())
+
+	// the ask includes the devices of the generated claim
+	task.sm.SetState(TaskStates().Pending)
+	err = task.handle(NewSubmitTaskEvent(app.applicationID, task.taskID))
+	assert.NilError(t, err, "failed to handle SubmitTask event")
+	assert.Assert(t, allocRequest != nil)
+	assert.Equal(t, len(allocRequest.Allocations), 1)
+	assert.Equal(t, allocRequest.Allocations[0].ResourcePerAlloc.Resources["gpu.example.com"].Value, int64(2))
+}
@@ -23,6 +23,8 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -36,7 +38,7 @@ import (
 
 type Type int
 
-var informerTypes = [...]string{"Pod", "Node", "ConfigMap", "PV", "PVC", "Storage", "CSINode", "CSIDriver", "CSIStorageCapacity", "Namespace", "PriorityClass", "Service", "ReplicationController", "ReplicaSet", "StatefulSet", "VolumeAttachment"}
+var informerTypes = [...]string{"Pod", "Node", "ConfigMap", "PV", "PVC", "Storage", "CSINode", "CSIDriver", "CSIStorageCapacity", "Namespace", "PriorityClass", "Service", "ReplicationController", "ReplicaSet", "StatefulSet", "VolumeAttachment", "ResourceSlice"}
 
 const (
 	PodInformerHandlers Type = iota
@@ -55,6 +57,7 @@ const (
 	ReplicaSetInformerHandlers
 	StatefulSetInformerHandlers
 	VolumeAttachmentInformerHandlers
+	ResourceSliceInformerHandlers
 )
 
 func (t Type) String() string {
@@ -129,29 +132,52 @@ func NewAPIFactory(scheduler api.SchedulerAPI, informerFactory informers.SharedI
 		capacityCheck,
 		configs.VolumeBindTimeout)
 
+	clients := &Clients{
+		KubeClient:                    kubeClient,
+		SchedulerAPI:                  scheduler,
+		InformerFactory:               informerFactory,
+		PodInformer:                   podInformer,
+		NodeInformer:                  nodeInformer,
+		ConfigMapInformer:             configMapInformer,
+		PVInformer:                    pvInformer,
+		PVCInformer:                   pvcInformer,
+		StorageClassInformer:          storageInformer,
+		CSINodeInformer:               csiNodeInformer,
+		CSIDriverInformer:             csiDriverInformer,
+		CSIStorageCapacityInformer:    csiStorageCapacityInformer,
+		NamespaceInformer:             namespaceInformer,
+		PriorityClassInformer:         priorityClassInformer,
+		ServiceInformer:               serviceInformer,
+		ReplicationControllerInformer: replicationControllerInformer,
+		ReplicaSetInformer:            replicaSetInformer,
+		StatefulSetInformer:           statefulSetInformer,
+		VolumeAttachmentInformer:      volumeAttachmentInformer,
+		VolumeBinder:                  volumeBinder,
+	}
+
+	// the resource.k8s.io API is not served by all clusters: only watch it when explicitly enabled
+	if configs.IsDRAEnabled() {
+		clients.ResourceClaimInformer = informerFactory.Resource().V1beta1().ResourceClaims()
+		clients.ResourceSliceInformer = informerFactory.Resource().V1beta1().ResourceSlices()
+		clients.DeviceClassInformer = informerFactory.Resource().V1beta1().DeviceClasses()
+	}
+
+	// the PodGroup CRD is not installed in all clusters: only watch it when explicitly enabled
+	// the gang status is written on owners of any kind: also uses the dynamic client
+	if configs.IsPodGroupEnabled() || configs.IsGangStatusEnabled() {
+		dynamicClient, err := dynamic.NewForConfig(kubeClient.GetConfigs())
+		if err != nil {
+			log.Log(log.ShimClient).Error("failed to create dynamic client, PodGroups and gang status are disabled", zap.Error(err))
+		} else {
+			clients.DynamicClient = dynamicClient
+			if configs.IsPodGroupEnabled() {
+				clients.PodGroupInformer = dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0).ForResource(PodGroupResource)
+			}
+		}
+	}
+
 	return &APIFactory{
-		clients: &Clients{
-			KubeClient:                    kubeClient,
-			SchedulerAPI:                  scheduler,
-			InformerFactory:               informerFactory,
-			PodInformer:                   podInformer,
-			NodeInformer:                  nodeInformer,
-			ConfigMapInformer:             configMapInformer,
-			PVInformer:                    pvInformer,
-			PVCInformer:                   pvcInformer,
-			StorageClassInformer:          storageInformer,
-			CSINodeInformer:               csiNodeInformer,
-			CSIDriverInformer:             csiDriverInformer,
-			CSIStorageCapacityInformer:    csiStorageCapacityInformer,
-			NamespaceInformer:             namespaceInformer,
-			PriorityClassInformer:         priorityClassInformer,
-			ServiceInformer:               serviceInformer,
-			ReplicationControllerInformer: replicationControllerInformer,
-			ReplicaSetInformer:            replicaSetInformer,
-			StatefulSetInformer:           statefulSetInformer,
-			VolumeAttachmentInformer:      volumeAttachmentInformer,
-			VolumeBinder:                  volumeBinder,
-		},
+		clients:  clients,
 		testMode: testMode,
 		stopChan: make(chan struct{}),
 		lock:     &locking.RWMutex{},
@@ -220,6 +246,12 @@ func (s *APIFactory) addEventHandlers(
 	case PriorityClassInformerHandlers:
 		_, err = s.GetAPIs().PriorityClassInformer.Informer().
 			AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	case ResourceSliceInformerHandlers:
+		if !s.GetAPIs().IsDRAEnabled() {
+			return errors.New("dynamic resource allocation is not enabled")
+		}
+		_, err = s.GetAPIs().ResourceSliceInformer.Informer().
+			AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
 	}
 
 	if err != nil {
@@ -246,6 +278,11 @@ func (s *APIFactory) Start() {
 
 func (s *APIFactory) Stop() {
 	if !s.IsTestingMode() {
-		close(s.stopChan)
+		select {
+		case <-s.stopChan:
+			// already stopped
+		default:
+			close(s.stopChan)
+		}
 	}
 }
@@ -24,6 +24,7 @@ import (
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	resourcev1beta1 "k8s.io/api/resource/v1beta1"
 	schedv1 "k8s.io/api/scheduling/v1"
 	"k8s.io/client-go/informers"
 	k8fake "k8s.io/client-go/kubernetes/fake"
@@ -289,7 +290,12 @@ func (m *MockedAPIProvider) Start() {
 func (m *MockedAPIProvider) Stop() {
 	m.Lock()
 	defer m.Unlock()
-	close(m.stop)
+	select {
+	case <-m.stop:
+		// already stopped
+	default:
+		close(m.stop)
+	}
 	m.running = false
 }
 
@@ -397,6 +403,31 @@ func (m *MockedAPIProvider) UpdatePriorityClass(oldObj *schedv1.PriorityClass, n
 	}
 }
 
+func (m *MockedAPIProvider) AddResourceSlice(obj *resourcev1beta1.ResourceSlice) {
+	m.events <- informerEvent{
+		obj:         obj,
+		op:          Add,
+		handlerType: ResourceSliceInformerHandlers,
+	}
+}
+
+func (m *MockedAPIProvider) DeleteResourceSlice(obj *resourcev1beta1.ResourceSlice) {
+	m.events <- informerEvent{
+		obj:         obj,
+		op:          Delete,
+		handlerType: ResourceSliceInformerHandlers,
+	}
+}
+
+func (m *MockedAPIProvider) UpdateResourceSlice(oldObj *resourcev1beta1.ResourceSlice, newObj *resourcev1beta1.ResourceSlice) {
+	m.events <- informerEvent{
+		obj:         newObj,
+		oldObj:      oldObj,
+		op:          Update,
+		handlerType: ResourceSliceInformerHandlers,
+	}
+}
+
 func (m *MockedAPIProvider) GetPodBindStats() *BindStats {
 	kubeClient, ok := m.clients.KubeClient.(*KubeClientMock)
 	if !ok {
@@ -23,9 +23,12 @@ import (
 
 	"go.uber.org/zap"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	appsInformerV1 "k8s.io/client-go/informers/apps/v1"
 	coreInformerV1 "k8s.io/client-go/informers/core/v1"
+	resourceInformerV1beta1 "k8s.io/client-go/informers/resource/v1beta1"
 	schedulingInformerV1 "k8s.io/client-go/informers/scheduling/v1"
 	storageInformerV1 "k8s.io/client-go/informers/storage/v1"
 	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumebinding"
@@ -34,6 +37,9 @@ import (
 	"github.com/apache/yunikorn-scheduler-interface/lib/go/api"
 )
 
+// PodGroupResource is the PodGroup resource of the scheduler-plugins coscheduling API
+var PodGroupResource = schema.GroupVersionResource{Group: "scheduling.x-k8s.io", Version: "v1alpha1", Resource: "podgroups"}
+
 // clients encapsulates a set of useful client APIs
 // that can be shared by callers when talking to K8s api-server,
 // or the scheduler core.
@@ -63,6 +69,16 @@ type Clients struct {
 	ReplicationControllerInformer coreInformerV1.ReplicationControllerInformer
 	VolumeAttachmentInformer      storageInformerV1.VolumeAttachmentInformer
 
+	// dynamic resource allocation informers, only set when DRA is enabled
+	ResourceClaimInformer resourceInformerV1beta1.ResourceClaimInformer
+	ResourceSliceInformer resourceInformerV1beta1.ResourceSliceInformer
+	DeviceClassInformer   resourceInformerV1beta1.DeviceClassInformer
+
+	// PodGroup informer, only set when PodGroups are enabled, and the dynamic client to update the PodGroup status
+	// and the gang status of the owners, only set when PodGroups or the gang status are enabled
+	PodGroupInformer informers.GenericInformer
+	DynamicClient    dynamic.Interface
+
 	// volume binder handles PV/PVC related operations
 	VolumeBinder volumebinding.SchedulerVolumeBinder
 }
@@ -86,7 +102,9 @@ func (c *Clients) WaitForSync() {
 			c.ServiceInformer.Informer().HasSynced() &&
 			c.StatefulSetInformer.Informer().HasSynced() &&
 			c.StorageClassInformer.Informer().HasSynced() &&
-			c.VolumeAttachmentInformer.Informer().HasSynced() {
+			c.VolumeAttachmentInformer.Informer().HasSynced() &&
+			c.draInformersSynced() &&
+			c.podGroupInformerSynced() {
 			return
 		}
 		time.Sleep(time.Second)
@@ -115,4 +133,38 @@ func (c *Clients) Run(stopCh <-chan struct{}) {
 	go c.StatefulSetInformer.Informer().Run(stopCh)
 	go c.StorageClassInformer.Informer().Run(stopCh)
 	go c.VolumeAttachmentInformer.Informer().Run(stopCh)
+	if c.IsDRAEnabled() {
+		go c.ResourceClaimInformer.Informer().Run(stopCh)
+		go c.ResourceSliceInformer.Informer().Run(stopCh)
+		go c.DeviceClassInformer.Informer().Run(stopCh)
+	}
+	if c.IsPodGroupEnabled() {
+		go c.PodGroupInformer.Informer().Run(stopCh)
+	}
+}
+
+// IsDRAEnabled returns true if the dynamic resource allocation informers are set
+func (c *Clients) IsDRAEnabled() bool {
+	return c.ResourceClaimInformer != nil && c.ResourceSliceInformer != nil && c.DeviceClassInformer != nil
+}
+
+func (c *Clients) draInformersSynced() bool {
+	if !c.IsDRAEnabled() {
+		return true
+	}
+	return c.ResourceClaimInformer.Informer().HasSynced() &&
+		c.ResourceSliceInformer.Informer().HasSynced() &&
+		c.DeviceClassInformer.Informer().HasSynced()
+}
+
+// IsPodGroupEnabled returns true if the PodGroup informer and the dynamic client are set
+func (c *Clients) IsPodGroupEnabled() bool {
+	return c.PodGroupInformer != nil && c.DynamicClient != nil
+}
+
+func (c *Clients) podGroupInformerSynced() bool {
+	if !c.IsPodGroupEnabled() {
+		return true
+	}
+	return c.PodGroupInformer.Informer().HasSynced()
 }
@@ -49,6 +49,8 @@ type KubeClient interface {
 	GetConfigs() *rest.Config
 
 	GetConfigMap(namespace string, name string) (*v1.ConfigMap, error)
+
+	GetPodTemplate(namespace string, name string) (*v1.PodTemplate, error)
 }
 
 func NewKubeClient(kc string) KubeClient {
@@ -160,6 +160,10 @@ func (nc SchedulerKubeClient) GetConfigMap(namespace string, name string) (*v1.C
 	return configmap, nil
 }
 
+func (nc SchedulerKubeClient) GetPodTemplate(namespace string, name string) (*v1.PodTemplate, error) {
+	return nc.clientSet.CoreV1().PodTemplates(namespace).Get(context.Background(), name, apis.GetOptions{})
+}
+
 func (nc SchedulerKubeClient) Get(podNamespace string, podName string) (*v1.Pod, error) {
 	pod, err := nc.clientSet.CoreV1().Pods(podNamespace).Get(context.Background(), podName, apis.GetOptions{})
 	if err != nil {
@@ -19,11 +19,13 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	apis "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
@@ -216,6 +218,12 @@ func (c *KubeClientMock) GetConfigMap(namespace string, name string) (*v1.Config
 	return nil, nil
 }
 
+func (c *KubeClientMock) GetPodTemplate(namespace string, name string) (*v1.PodTemplate, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.clientSet.CoreV1().PodTemplates(namespace).Get(context.Background(), name, apis.GetOptions{})
+}
+
 func (c *KubeClientMock) GetBindStats() BindStats {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
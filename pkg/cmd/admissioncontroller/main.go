@@ -29,11 +29,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8events "k8s.io/client-go/tools/events"
 
 	"github.com/apache/yunikorn-k8shim/pkg/admission"
 	"github.com/apache/yunikorn-k8shim/pkg/admission/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/client"
+	"github.com/apache/yunikorn-k8shim/pkg/common/events"
 	"github.com/apache/yunikorn-k8shim/pkg/locking"
 	"github.com/apache/yunikorn-k8shim/pkg/log"
 )
@@ -43,6 +47,9 @@ const (
 	healthURL       = "/health"
 	mutateURL       = "/mutate"
 	validateConfURL = "/validate-conf"
+	metricsURL      = "/metrics"
+
+	eventReporter = "yunikorn-admission-controller"
 )
 
 type WebHook struct {
@@ -80,6 +87,15 @@ func main() {
 	}
 	informers.Start()
 
+	// events record the decisions of the audit mode
+	eventBroadcaster := k8events.NewBroadcaster(&k8events.EventSinkImpl{
+		Interface: kubeClient.GetClientSet().EventsV1()})
+	if err = eventBroadcaster.StartRecordingToSinkWithContext(context.Background()); err != nil {
+		log.Log(log.Admission).Error("Could not create event broadcaster", zap.Error(err))
+	} else {
+		events.SetRecorder(eventBroadcaster.NewRecorder(scheme.Scheme, eventReporter))
+	}
+
 	wm, err := admission.NewWebhookManager(amConf)
 	if err != nil {
 		log.Log(log.Admission).Fatal("Failed to initialize webhook manager", zap.Error(err))
@@ -152,6 +168,7 @@ func (wh *WebHook) Startup(certs *tls.Certificate) {
 	mux.HandleFunc(healthURL, wh.ac.Health)
 	mux.HandleFunc(mutateURL, wh.ac.Serve)
 	mux.HandleFunc(validateConfURL, wh.ac.Serve)
+	mux.Handle(metricsURL, promhttp.Handler())
 
 	wh.server = &http.Server{
 		Addr: fmt.Sprintf(":%v", wh.port),
@@ -177,7 +194,7 @@ func (wh *WebHook) Startup(certs *tls.Certificate) {
 
 	log.Log(log.Admission).Info("the admission controller started",
 		zap.Int("port", HTTPPort),
-		zap.Strings("listeningOn", []string{healthURL, mutateURL, validateConfURL}))
+		zap.Strings("listeningOn", []string{healthURL, mutateURL, validateConfURL, metricsURL}))
 }
 
 func (wh *WebHook) Shutdown() {
@@ -50,11 +50,16 @@ func main() {
 		log.Log(log.Shim).Fatal("Unable to load initial configmaps", zap.Error(err))
 	}
 
+	if conf.GetSchedulerConf().IsDRAEnabled() {
+		predicates.EnableDynamicResourceAllocationFeatureGate()
+	}
+
 	log.Log(log.Shim).Info("Starting scheduler", zap.String("name", constants.SchedulerName))
 	serviceContext := entrypoint.StartAllServicesWithLogger(log.RootLogger(), log.GetZapConfigs())
 
 	if serviceContext.RMProxy != nil {
 		ss := shim.NewShimScheduler(serviceContext.RMProxy, conf.GetSchedulerConf(), configMaps)
+		ss.GetContext().SetCoreClusterContext(serviceContext.Scheduler.GetClusterContext())
 		if err := ss.Run(); err != nil {
 			log.Log(log.Shim).Fatal("Unable to start scheduler", zap.Error(err))
 		}
@@ -63,11 +63,18 @@ const DefaultUser = "nobody"
 // Spark
 const SparkLabelAppID = "spark-app-selector"
 
+// PodGroup of the scheduler-plugins coscheduling API
+const LabelPodGroup = "scheduling.x-k8s.io/pod-group"
+const PodGroupAppPrefix = "pg"
+
 // Configuration
 const ConfigMapName = "yunikorn-configs"
 const DefaultConfigMapName = "yunikorn-defaults"
 const SchedulerName = "yunikorn"
 
+// Metrics
+const MetricsNamespace = "yunikorn"
+
 // OwnerReferences
 const DaemonSetType = "DaemonSet"
 const NodeKind = "Node"
@@ -86,6 +93,9 @@ const AnnotationPlaceholderFlag = DomainYuniKornInternal + "placeholder"
 const AnnotationTaskGroupName = DomainYuniKorn + "task-group-name"
 const AnnotationTaskGroups = DomainYuniKorn + "task-groups"
 const AnnotationSchedulingPolicyParam = DomainYuniKorn + "schedulingPolicyParameters"
+const AnnotationPlaceholderTemplate = DomainYuniKorn + "placeholder-template"
+const AnnotationTopologyDomain = DomainYuniKornInternal + "topology-domain"
+const AnnotationGangStatus = DomainYuniKorn + "gang-status"
 const SchedulingPolicyTimeoutParam = "placeholderTimeoutInSeconds"
 const SchedulingPolicyParamDelimiter = " "
 const SchedulingPolicyStyleParam = "gangSchedulingStyle"
@@ -93,8 +103,17 @@ const SchedulingPolicyStyleParamDefault = "Soft"
 
 var SchedulingPolicyStyleParamValues = map[string]string{"Hard": "Hard", "Soft": "Soft"}
 
+// SchedulingPolicyTimeoutReleaseParam sets what is released when the placeholders of a task group time out:
+// all placeholders of the gang, or only the placeholders of the unsatisfied task groups.
+const SchedulingPolicyTimeoutReleaseParam = "placeholderTimeoutRelease"
+const SchedulingPolicyTimeoutReleaseAll = "All"
+const SchedulingPolicyTimeoutReleaseUnsatisfied = "Unsatisfied"
+
+var SchedulingPolicyTimeoutReleaseValues = map[string]string{"All": "All", "Unsatisfied": "Unsatisfied"}
+
 const ApplicationInsufficientResourcesFailure = "ResourceReservationTimeout"
 const ApplicationRejectedFailure = "ApplicationRejected"
+const ApplicationGangExceedsQueueFailure = "GangExceedsQueueMaxResources"
 
 // namespace.max.* (Retaining for backwards compatibility. Need to be removed in next major release)
 const CPUQuota = DomainYuniKorn + "namespace.max.cpu"
@@ -127,6 +146,18 @@ const AnnotationGenerateAppID = DomainYuniKorn + "namespace.generateAppId"
 // false: do not do anything
 const AnnotationEnableYuniKorn = DomainYuniKorn + "namespace.enableYuniKorn"
 
+// AnnotationAutoGang derives the gang of Indexed Jobs and JobSets in the namespace even if not set in the admission config.
+// Overrides the admission config if set.
+// true: add the task groups to the pod templates
+// false: do not derive the gang
+const AnnotationAutoGang = DomainYuniKorn + "namespace.autoGang"
+
+// AnnotationAdmissionAudit records the decisions of the admission controller for the namespace without applying them.
+// Overrides the admission config if set.
+// true: record the patch or the rejection, admit the object unchanged
+// false: apply the decisions
+const AnnotationAdmissionAudit = DomainYuniKorn + "namespace.admissionAudit"
+
 // Admission Controller pod label update constants
 const AutoGenAppPrefix = "yunikorn"
 const AutoGenAppSuffix = "autogen"
@@ -0,0 +1,72 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package common
+
+import (
+	"sync/atomic"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/apache/yunikorn-scheduler-interface/lib/go/si"
+)
+
+// DeviceResolver translates the devices managed through dynamic resource allocation into countable resources.
+// The device class name is used as the resource name, subject to the configured resource mappings.
+type DeviceResolver interface {
+	// GetPodDevices returns the number of devices per device class requested by the ResourceClaims of the pod
+	GetPodDevices(pod *v1.Pod) map[string]int64
+	// GetNodeDevices returns the number of devices per device class published in the ResourceSlices of the node
+	GetNodeDevices(nodeName string) map[string]int64
+}
+
+type deviceResolverHolder struct {
+	resolver DeviceResolver
+}
+
+var deviceResolver atomic.Pointer[deviceResolverHolder]
+
+// SetDeviceResolver sets the resolver used when building pod and node resources, nil disables device accounting.
+func SetDeviceResolver(resolver DeviceResolver) {
+	deviceResolver.Store(&deviceResolverHolder{resolver: resolver})
+}
+
+func getDeviceResolver() DeviceResolver {
+	if holder := deviceResolver.Load(); holder != nil {
+		return holder.resolver
+	}
+	return nil
+}
+
+// addDeviceResources adds the device counts to the resource, applying the configured resource mappings
+func addDeviceResources(res *si.Resource, devices map[string]int64) {
+	if len(devices) == 0 {
+		return
+	}
+	builder := newMappedResourceBuilder()
+	for className, count := range devices {
+		builder.addMappedResource(className, count)
+	}
+	for name, value := range builder.Build().Resources {
+		if current, ok := res.Resources[name]; ok {
+			current.Value += value.Value
+		} else {
+			res.Resources[name] = value
+		}
+	}
+}
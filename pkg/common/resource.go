@@ -19,12 +19,16 @@
 package common
 
 import (
+	"maps"
+	"slices"
+
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	helpers "k8s.io/component-helpers/resource"
 
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/log"
 	siCommon "github.com/apache/yunikorn-scheduler-interface/lib/go/common"
 	"github.com/apache/yunikorn-scheduler-interface/lib/go/si"
@@ -33,6 +37,7 @@ import (
 // resource builder is a helper struct to construct si resources
 type ResourceBuilder struct {
 	resourceMap map[string]*si.Quantity
+	mappings    map[string]string
 }
 
 func NewResourceBuilder() *ResourceBuilder {
@@ -41,11 +46,41 @@ func NewResourceBuilder() *ResourceBuilder {
 	}
 }
 
+// newMappedResourceBuilder creates a builder that applies the configured resource name mappings
+func newMappedResourceBuilder() *ResourceBuilder {
+	return &ResourceBuilder{
+		resourceMap: make(map[string]*si.Quantity),
+		mappings:    conf.GetSchedulerConf().GetResourceMappings(),
+	}
+}
+
 func (w *ResourceBuilder) AddResource(name string, value int64) *ResourceBuilder {
 	w.resourceMap[name] = &si.Quantity{Value: value}
 	return w
 }
 
+// addMappedResource renames or drops the Kubernetes resource based on the mappings of the builder.
+// Values of resources mapped onto the same name are summed.
+func (w *ResourceBuilder) addMappedResource(name string, value int64) {
+	name, ok := mapResourceName(w.mappings, name)
+	if !ok {
+		return
+	}
+	if current, exists := w.resourceMap[name]; exists {
+		current.Value += value
+		return
+	}
+	w.resourceMap[name] = &si.Quantity{Value: value}
+}
+
+// mapResourceName returns the name of the resource as sent to the core, false is returned if the resource is dropped
+func mapResourceName(mappings map[string]string, name string) (string, bool) {
+	if mapped, ok := mappings[name]; ok {
+		return mapped, mapped != ""
+	}
+	return name, true
+}
+
 func (w *ResourceBuilder) Build() *si.Resource {
 	return &si.Resource{Resources: w.resourceMap}
 }
@@ -105,6 +140,11 @@ func GetPodResource(pod *v1.Pod) (resource *si.Resource) {
 			zap.Stringer("overheadSize", podOverHeadResource))
 	}
 
+	// devices requested through ResourceClaims are shared by all containers of the pod and added once
+	if resolver := getDeviceResolver(); resolver != nil && len(pod.Spec.ResourceClaims) > 0 {
+		addDeviceResources(podResource, resolver.GetPodDevices(pod))
+	}
+
 	return podResource
 }
 
@@ -208,6 +248,61 @@ func GetNodeResource(nodeStatus *v1.NodeStatus) *si.Resource {
 	return getResource(nodeStatus.Allocatable)
 }
 
+// GetSchedulableNodeResource returns the node resources that can be used for scheduling. The allocatable resources,
+// including the devices published for the node through ResourceSlices, are adjusted by the first node resource policy
+// that matches the node labels, if any.
+func GetSchedulableNodeResource(node *v1.Node) *si.Resource {
+	nodeResource := GetNodeResource(&node.Status)
+	if resolver := getDeviceResolver(); resolver != nil {
+		addDeviceResources(nodeResource, resolver.GetNodeDevices(node.Name))
+	}
+	policy := conf.GetSchedulerConf().GetNodeResourcePolicy(node.Labels)
+	if policy == nil {
+		return nodeResource
+	}
+	reserved := make(v1.ResourceList, len(policy.GetReserved()))
+	for name, quantity := range policy.GetReserved() {
+		reserved[v1.ResourceName(name)] = quantity
+	}
+	for name, value := range getResource(reserved).Resources {
+		if current, ok := nodeResource.Resources[name]; ok {
+			current.Value = max(current.Value-value.Value, 0)
+		}
+	}
+	for name, factor := range getMappedFactors(policy.Factors) {
+		if current, ok := nodeResource.Resources[name]; ok {
+			current.Value = int64(float64(current.Value) * factor)
+		}
+	}
+	log.Log(log.ShimResources).Debug("node resource policy applied",
+		zap.String("nodeName", node.Name),
+		zap.String("policy", policy.Name),
+		zap.Stringer("schedulable", nodeResource))
+	return nodeResource
+}
+
+// getMappedFactors returns the factors by the name of the resource after mapping. One factor applies per resource: the
+// factor set on the resulting name wins over the factors of the sources merged into it, otherwise the factor of the
+// first source in name order is used.
+func getMappedFactors(factors map[string]float64) map[string]float64 {
+	mappings := conf.GetSchedulerConf().GetResourceMappings()
+	result := make(map[string]float64, len(factors))
+	for _, name := range slices.Sorted(maps.Keys(factors)) {
+		target := siCommon.CPU
+		if name != v1.ResourceCPU.String() {
+			mapped, ok := mapResourceName(mappings, name)
+			if !ok {
+				continue
+			}
+			target = mapped
+		}
+		if _, ok := result[target]; !ok || name == target {
+			result[target] = factors[name]
+		}
+	}
+	return result
+}
+
 // parse cpu and memory from string to si.Resource, both of them are optional
 // if parse failed with some errors, log the error and return a nil
 func ParseResource(cpuStr, memStr string) *si.Resource {
@@ -242,7 +337,7 @@ func ParseResource(cpuStr, memStr string) *si.Resource {
 }
 
 func GetResource(resMap map[string]string) *si.Resource {
-	result := NewResourceBuilder()
+	result := newMappedResourceBuilder()
 	for resName, resValue := range resMap {
 		switch resName {
 		case v1.ResourceCPU.String():
@@ -257,7 +352,7 @@ func GetResource(resMap map[string]string) *si.Resource {
 			}
 		default:
 			if actualValue, err := resource.ParseQuantity(resValue); err == nil {
-				result.AddResource(resName, actualValue.Value())
+				result.addMappedResource(resName, actualValue.Value())
 			} else {
 				log.Log(log.ShimResources).Error("failed to parse resource",
 					zap.String("res name", resName),
@@ -271,35 +366,35 @@ func GetResource(resMap map[string]string) *si.Resource {
 }
 
 func GetTGResource(resMap map[string]resource.Quantity, members int64) *si.Resource {
-	result := NewResourceBuilder()
+	result := newMappedResourceBuilder()
 	result.AddResource("pods", members)
 	for resName, resValue := range resMap {
 		switch resName {
 		case v1.ResourceCPU.String():
 			result.AddResource(siCommon.CPU, members*resValue.MilliValue())
 		default:
-			result.AddResource(resName, members*resValue.Value())
+			result.addMappedResource(resName, members*resValue.Value())
 		}
 	}
 	return result.Build()
 }
 
 func getResource(resourceList v1.ResourceList) *si.Resource {
-	resources := NewResourceBuilder()
+	resources := newMappedResourceBuilder()
 	for name, value := range resourceList {
 		switch name {
 		case v1.ResourceCPU:
 			vcore := value.MilliValue()
 			resources.AddResource(siCommon.CPU, vcore)
 		default:
-			resources.AddResource(string(name), value.Value())
+			resources.addMappedResource(string(name), value.Value())
 		}
 	}
 	return resources.Build()
 }
 
 func getPodLevelResource(resourceList v1.ResourceList) *si.Resource {
-	resources := NewResourceBuilder()
+	resources := newMappedResourceBuilder()
 	for name, value := range resourceList {
 		if helpers.IsSupportedPodLevelResource(name) {
 			switch name {
@@ -307,7 +402,7 @@ func getPodLevelResource(resourceList v1.ResourceList) *si.Resource {
 				vcore := value.MilliValue()
 				resources.AddResource(siCommon.CPU, vcore)
 			default:
-				resources.AddResource(string(name), value.Value())
+				resources.addMappedResource(string(name), value.Value())
 			}
 		}
 	}
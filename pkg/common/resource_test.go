@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/v3/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -28,6 +29,7 @@ import (
 	k8res "k8s.io/component-helpers/resource"
 
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
 	"github.com/apache/yunikorn-k8shim/pkg/plugin/predicates"
 	siCommon "github.com/apache/yunikorn-scheduler-interface/lib/go/common"
 	"github.com/apache/yunikorn-scheduler-interface/lib/go/si"
@@ -657,6 +659,186 @@ func TestNodeResource(t *testing.T) {
 	assert.Equal(t, result.Resources[siCommon.CPU].GetValue(), int64(14500))
 }
 
+func TestGetSchedulableNodeResource(t *testing.T) {
+	defer func() {
+		err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcNodeResourcePolicies: `[{"nodeSelector": "pool=batch", "reserved": {"memory": "1G", "cpu": "8"}, "factors": {"cpu": 2}}]`,
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+
+	node := &v1.Node{
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("10G"),
+				"nvidia.com/gpu":  resource.MustParse("1"),
+			},
+		},
+	}
+	// no policy matches: allocatable is returned
+	result := GetSchedulableNodeResource(node)
+	assert.Equal(t, result.Resources[siCommon.CPU].GetValue(), int64(4000))
+	assert.Equal(t, result.Resources[siCommon.Memory].GetValue(), int64(10*1000*1000*1000))
+
+	// reserved is subtracted (never below zero), factor applied afterwards
+	node.Labels = map[string]string{"pool": "batch"}
+	result = GetSchedulableNodeResource(node)
+	assert.Equal(t, result.Resources[siCommon.CPU].GetValue(), int64(0))
+	assert.Equal(t, result.Resources[siCommon.Memory].GetValue(), int64(9*1000*1000*1000))
+	assert.Equal(t, result.Resources["nvidia.com/gpu"].GetValue(), int64(1))
+
+	node.Status.Allocatable[v1.ResourceCPU] = resource.MustParse("16")
+	result = GetSchedulableNodeResource(node)
+	assert.Equal(t, result.Resources[siCommon.CPU].GetValue(), int64(16000))
+}
+
+func TestGetSchedulableNodeResourceMergedFactors(t *testing.T) {
+	defer func() {
+		err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+	node := &v1.Node{
+		ObjectMeta: apis.ObjectMeta{Labels: map[string]string{"pool": "batch"}},
+		Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+			"a.example.com/gpu": resource.MustParse("4"),
+			"b.example.com/gpu": resource.MustParse("4"),
+		}},
+	}
+
+	// factors of merged sources: the factor of the first source applies once
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcResourceMappings:     "a.example.com/gpu=gpu,b.example.com/gpu=gpu",
+		conf.CMSvcNodeResourcePolicies: `[{"nodeSelector": "pool=batch", "factors": {"a.example.com/gpu": 2, "b.example.com/gpu": 3}}]`,
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	assert.Equal(t, GetSchedulableNodeResource(node).Resources["gpu"].GetValue(), int64(16))
+
+	// a factor on the target wins over the factors of the sources
+	err = conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcResourceMappings:     "a.example.com/gpu=gpu,b.example.com/gpu=gpu",
+		conf.CMSvcNodeResourcePolicies: `[{"nodeSelector": "pool=batch", "factors": {"a.example.com/gpu": 2, "gpu": 0.5}}]`,
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	assert.Equal(t, GetSchedulableNodeResource(node).Resources["gpu"].GetValue(), int64(4))
+}
+
+type testDeviceResolver struct {
+	podDevices  map[string]int64
+	nodeDevices map[string]int64
+}
+
+func (r *testDeviceResolver) GetPodDevices(_ *v1.Pod) map[string]int64 {
+	return r.podDevices
+}
+
+func (r *testDeviceResolver) GetNodeDevices(_ string) map[string]int64 {
+	return r.nodeDevices
+}
+
+func TestDeviceResources(t *testing.T) {
+	defer func() {
+		SetDeviceResolver(nil)
+		err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcResourceMappings: "gpu.example.com=gpu,nvidia.com/gpu=gpu",
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+
+	claimName := "claim"
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					v1.ResourceCPU:   resource.MustParse("1"),
+					"nvidia.com/gpu": resource.MustParse("1"),
+				}}},
+			},
+			ResourceClaims: []v1.PodResourceClaim{{Name: "gpu", ResourceClaimName: &claimName}},
+		},
+	}
+	// no resolver: claims are ignored
+	res := GetPodResource(pod)
+	assert.Equal(t, res.Resources["gpu"].GetValue(), int64(1))
+
+	SetDeviceResolver(&testDeviceResolver{
+		podDevices:  map[string]int64{"gpu.example.com": 2, "nic.example.com": 1},
+		nodeDevices: map[string]int64{"gpu.example.com": 8},
+	})
+	res = GetPodResource(pod)
+	assert.Equal(t, res.Resources[siCommon.CPU].GetValue(), int64(1000))
+	assert.Equal(t, res.Resources["gpu"].GetValue(), int64(3))
+	assert.Equal(t, res.Resources["nic.example.com"].GetValue(), int64(1))
+
+	// pods without claims do not call the resolver
+	pod.Spec.ResourceClaims = nil
+	res = GetPodResource(pod)
+	assert.Equal(t, res.Resources["gpu"].GetValue(), int64(1))
+	assert.Assert(t, res.Resources["nic.example.com"] == nil, "unexpected device resource")
+
+	node := &v1.Node{
+		ObjectMeta: apis.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+			v1.ResourceCPU:  resource.MustParse("4"),
+			v1.ResourcePods: resource.MustParse("10"),
+		}},
+	}
+	res = GetSchedulableNodeResource(node)
+	assert.Equal(t, res.Resources[siCommon.CPU].GetValue(), int64(4000))
+	assert.Equal(t, res.Resources["gpu"].GetValue(), int64(8))
+}
+
+func TestResourceMappings(t *testing.T) {
+	defer func() {
+		err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset config")
+	}()
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{
+		conf.CMSvcResourceMappings: "nvidia.com/gpu=gpu,amd.com/gpu=gpu,ephemeral-storage=",
+	}}}, true)
+	assert.NilError(t, err, "failed to update config")
+
+	// pod: vendor names merged, dropped resource removed
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					v1.ResourceCPU:              resource.MustParse("1"),
+					"nvidia.com/gpu":            resource.MustParse("1"),
+					v1.ResourceEphemeralStorage: resource.MustParse("1G"),
+				}}},
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					"amd.com/gpu": resource.MustParse("2"),
+				}}},
+			},
+		},
+	}
+	res := GetPodResource(pod)
+	assert.Equal(t, res.Resources[siCommon.CPU].GetValue(), int64(1000))
+	assert.Equal(t, res.Resources["gpu"].GetValue(), int64(3))
+	assert.Assert(t, res.Resources["nvidia.com/gpu"] == nil, "nvidia.com/gpu not renamed")
+	assert.Assert(t, res.Resources[string(v1.ResourceEphemeralStorage)] == nil, "ephemeral-storage not dropped")
+
+	// node
+	res = GetNodeResource(&v1.NodeStatus{Allocatable: v1.ResourceList{
+		"nvidia.com/gpu":            resource.MustParse("4"),
+		"amd.com/gpu":               resource.MustParse("2"),
+		v1.ResourceEphemeralStorage: resource.MustParse("100G"),
+	}})
+	assert.DeepEqual(t, res.Resources, map[string]*si.Quantity{"gpu": {Value: 6}}, cmpopts.IgnoreUnexported(si.Quantity{}))
+
+	// task group and namespace annotations
+	res = GetTGResource(map[string]resource.Quantity{"nvidia.com/gpu": resource.MustParse("2")}, 3)
+	assert.Equal(t, res.Resources["gpu"].GetValue(), int64(6))
+	assert.Equal(t, res.Resources["pods"].GetValue(), int64(3))
+	res = GetResource(map[string]string{"amd.com/gpu": "8", "ephemeral-storage": "1G"})
+	assert.DeepEqual(t, res.Resources, map[string]*si.Quantity{"gpu": {Value: 8}}, cmpopts.IgnoreUnexported(si.Quantity{}))
+}
+
 func TestIsZero(t *testing.T) {
 	r := NewResourceBuilder().
 		AddResource(siCommon.Memory, 1).
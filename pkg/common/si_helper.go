@@ -186,11 +186,14 @@ func CreateReleaseRequestForForeignPod(uid, partition string) *si.AllocationRequ
 	}
 }
 
-// CreateUpdateRequestForUpdatedNode builds a NodeRequest for capacity updates
-func CreateUpdateRequestForUpdatedNode(nodeID string, capacity *si.Resource) *si.NodeRequest {
+// CreateUpdateRequestForUpdatedNode builds a NodeRequest for capacity and attribute updates
+func CreateUpdateRequestForUpdatedNode(nodeID string, capacity *si.Resource, attributes map[string]string) *si.NodeRequest {
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
 	nodeInfo := &si.NodeInfo{
 		NodeID:              nodeID,
-		Attributes:          map[string]string{},
+		Attributes:          attributes,
 		SchedulableResource: capacity,
 		Action:              si.NodeInfo_UPDATE,
 	}
@@ -211,11 +211,17 @@ func TestCreateTagsForTask(t *testing.T) {
 
 func TestCreateUpdateRequestForUpdatedNode(t *testing.T) {
 	capacity := NewResourceBuilder().AddResource(common.Memory, 200).AddResource(common.CPU, 2).Build()
-	request := CreateUpdateRequestForUpdatedNode(nodeID, capacity)
+	request := CreateUpdateRequestForUpdatedNode(nodeID, capacity, nil)
 	assert.Equal(t, len(request.Nodes), 1)
 	assert.Equal(t, request.Nodes[0].NodeID, nodeID)
 	assert.Equal(t, request.Nodes[0].SchedulableResource, capacity)
 	assert.Equal(t, len(request.Nodes[0].Attributes), 0)
+
+	attributes := map[string]string{"topology.kubernetes.io/zone": "zone-a"}
+	request = CreateUpdateRequestForUpdatedNode(nodeID, capacity, attributes)
+	assert.Equal(t, len(request.Nodes), 1)
+	assert.Equal(t, request.Nodes[0].Action, si.NodeInfo_UPDATE)
+	assert.DeepEqual(t, request.Nodes[0].Attributes, attributes)
 }
 
 func TestCreateUpdateRequestForDeleteNode(t *testing.T) {
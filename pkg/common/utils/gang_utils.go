@@ -0,0 +1,248 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
+)
+
+type TaskGroup struct {
+	Name                      string
+	MinMember                 int32
+	MaxMember                 int32
+	Labels                    map[string]string
+	Annotations               map[string]string
+	MinResource               map[string]resource.Quantity
+	NodeSelector              map[string]string
+	Tolerations               []v1.Toleration
+	Affinity                  *v1.Affinity
+	TopologySpreadConstraints []v1.TopologySpreadConstraint
+	TopologyKey               string // node label: all placeholders of the task group are placed in one domain
+	PlaceholderTemplate       *conf.PlaceholderTemplate
+}
+
+// GetMaxMember returns the maximum number of members of the task group. A task group without a maximum is not
+// elastic: the maximum is the minimum.
+func (tg TaskGroup) GetMaxMember() int32 {
+	return max(tg.MinMember, tg.MaxMember)
+}
+
+type SchedulingPolicyParameters struct {
+	placeholderTimeout  int64
+	gangSchedulingStyle string
+	taskGroupTimeouts   map[string]int64 // placeholder timeout per task group, overrides the placeholderTimeout
+	timeoutRelease      string
+}
+
+func NewSchedulingPolicyParameters(placeholderTimeout int64, gangSchedulingStyle string) *SchedulingPolicyParameters {
+	spp := &SchedulingPolicyParameters{
+		placeholderTimeout:  placeholderTimeout,
+		gangSchedulingStyle: gangSchedulingStyle,
+		timeoutRelease:      constants.SchedulingPolicyTimeoutReleaseAll,
+	}
+	return spp
+}
+
+func (spp *SchedulingPolicyParameters) GetPlaceholderTimeout() int64 {
+	return spp.placeholderTimeout
+}
+
+func (spp *SchedulingPolicyParameters) GetGangSchedulingStyle() string {
+	return spp.gangSchedulingStyle
+}
+
+// GetTaskGroupTimeouts returns the placeholder timeouts in seconds of the task groups that override the application
+// placeholder timeout
+func (spp *SchedulingPolicyParameters) GetTaskGroupTimeouts() map[string]int64 {
+	return spp.taskGroupTimeouts
+}
+
+// GetTimeoutRelease returns what is released when the placeholders of a task group time out
+func (spp *SchedulingPolicyParameters) GetTimeoutRelease() string {
+	return spp.timeoutRelease
+}
+
+// GetGangTimeout returns the placeholder timeout of the application passed to the core. The core times out the
+// placeholders of the whole application: the application timeout is raised to the longest task group timeout. If only
+// task group timeouts are set the longest one replaces the default timeout of the core, the task groups without a
+// timeout time out with it.
+func (spp *SchedulingPolicyParameters) GetGangTimeout() int64 {
+	timeout := max(spp.placeholderTimeout, 0)
+	for _, tgTimeout := range spp.taskGroupTimeouts {
+		timeout = max(timeout, tgTimeout)
+	}
+	return timeout
+}
+
+// GetTaskGroupsFromAnnotation parses and validates the task groups annotation of the pod. Returns nil if the
+// annotation is not set.
+func GetTaskGroupsFromAnnotation(pod *v1.Pod) ([]TaskGroup, error) {
+	taskGroupInfo := GetPodAnnotationValue(pod, constants.AnnotationTaskGroups)
+	if taskGroupInfo == "" {
+		return nil, nil
+	}
+
+	taskGroups := []TaskGroup{}
+	err := json.Unmarshal([]byte(taskGroupInfo), &taskGroups)
+	if err != nil {
+		return nil, err
+	}
+	// json.Unmarshal won't return error if name or MinMember is empty, but will return error if MinResource is empty or error format.
+	for _, taskGroup := range taskGroups {
+		if taskGroup.Name == "" {
+			return nil, fmt.Errorf("can't get taskGroup Name from pod annotation, %s",
+				taskGroupInfo)
+		}
+		if taskGroup.MinResource == nil {
+			return nil, fmt.Errorf("can't get taskGroup MinResource from pod annotation, %s",
+				taskGroupInfo)
+		}
+		if taskGroup.MinMember == int32(0) {
+			return nil, fmt.Errorf("can't get taskGroup MinMember from pod annotation, %s",
+				taskGroupInfo)
+		}
+		if taskGroup.MinMember < int32(0) {
+			return nil, fmt.Errorf("minMember cannot be negative, %s",
+				taskGroupInfo)
+		}
+		if taskGroup.MaxMember < int32(0) {
+			return nil, fmt.Errorf("maxMember cannot be negative, %s",
+				taskGroupInfo)
+		}
+		if taskGroup.MaxMember != int32(0) && taskGroup.MaxMember < taskGroup.MinMember {
+			return nil, fmt.Errorf("maxMember cannot be less than minMember, %s",
+				taskGroupInfo)
+		}
+		if taskGroup.TopologyKey != "" {
+			if errs := validation.IsQualifiedName(taskGroup.TopologyKey); len(errs) > 0 {
+				return nil, fmt.Errorf("taskGroup %s has an invalid topologyKey %s: %s",
+					taskGroup.Name, taskGroup.TopologyKey, strings.Join(errs, ", "))
+			}
+		}
+		if err = taskGroup.PlaceholderTemplate.Validate(); err != nil {
+			return nil, fmt.Errorf("taskGroup %s: %w", taskGroup.Name, err)
+		}
+	}
+	return taskGroups, nil
+}
+
+// ParseSchedulingPolicyParam parses the scheduling policy parameters annotation. Invalid parameters are skipped, or
+// replaced by their default, and returned as errors.
+func ParseSchedulingPolicyParam(param string) (*SchedulingPolicyParameters, []error) {
+	timeout := int64(0)
+	style := constants.SchedulingPolicyStyleParamDefault
+	if param == "" {
+		return NewSchedulingPolicyParameters(timeout, style), nil
+	}
+	var errs []error
+	var taskGroupTimeouts map[string]int64
+	release := constants.SchedulingPolicyTimeoutReleaseAll
+	for _, p := range strings.Split(param, constants.SchedulingPolicyParamDelimiter) {
+		if p == "" {
+			continue
+		}
+		param := strings.Split(p, "=")
+		if len(param) != 2 {
+			errs = append(errs, fmt.Errorf("malformed scheduling policy parameter %q, expected key=value", p))
+			continue
+		}
+		// the timeout of a task group is set using the task group name as a suffix: placeholderTimeoutInSeconds.<name>
+		if taskGroup, ok := strings.CutPrefix(param[0], constants.SchedulingPolicyTimeoutParam+"."); ok {
+			tgTimeout, parseErr := strconv.ParseInt(param[1], 10, 64)
+			if parseErr != nil || taskGroup == "" || tgTimeout <= 0 {
+				errs = append(errs, fmt.Errorf("invalid task group placeholder timeout %q, expected a positive number of seconds", p))
+				continue
+			}
+			if taskGroupTimeouts == nil {
+				taskGroupTimeouts = make(map[string]int64)
+			}
+			taskGroupTimeouts[taskGroup] = tgTimeout
+		} else if param[0] == constants.SchedulingPolicyTimeoutReleaseParam {
+			release = constants.SchedulingPolicyTimeoutReleaseValues[param[1]]
+			if release == "" {
+				release = constants.SchedulingPolicyTimeoutReleaseAll
+				errs = append(errs, fmt.Errorf("unknown placeholder timeout release %q, expected %s or %s", param[1],
+					constants.SchedulingPolicyTimeoutReleaseAll, constants.SchedulingPolicyTimeoutReleaseUnsatisfied))
+			}
+		} else if param[0] == constants.SchedulingPolicyTimeoutParam {
+			var err error
+			timeout, err = strconv.ParseInt(param[1], 10, 64)
+			if err != nil || timeout < 0 {
+				errs = append(errs, fmt.Errorf("invalid placeholder timeout %q, expected a number of seconds", p))
+			}
+		} else if param[0] == constants.SchedulingPolicyStyleParam {
+			style = constants.SchedulingPolicyStyleParamValues[param[1]]
+			if style == "" {
+				style = constants.SchedulingPolicyStyleParamDefault
+				errs = append(errs, fmt.Errorf("unknown gang scheduling style %q, expected Hard or Soft", param[1]))
+			}
+		} else {
+			errs = append(errs, fmt.Errorf("unknown scheduling policy parameter %q", param[0]))
+		}
+	}
+	schedulingPolicyParams := NewSchedulingPolicyParameters(timeout, style)
+	schedulingPolicyParams.taskGroupTimeouts = taskGroupTimeouts
+	schedulingPolicyParams.timeoutRelease = release
+	return schedulingPolicyParams, errs
+}
+
+// ValidateGangAnnotations validates the gang scheduling annotations of a pod or pod template, using the same parsing
+// as the scheduler: the task groups, the task group of the pod and the scheduling policy parameters.
+// Returns nil if the annotations are valid or not set.
+func ValidateGangAnnotations(annotations map[string]string) error {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	taskGroups, err := GetTaskGroupsFromAnnotation(pod)
+	if err != nil {
+		return fmt.Errorf("invalid annotation %s: %w", constants.AnnotationTaskGroups, err)
+	}
+	names := make(map[string]bool, len(taskGroups))
+	for _, tg := range taskGroups {
+		if names[tg.Name] {
+			return fmt.Errorf("invalid annotation %s: taskGroup %s is defined more than once", constants.AnnotationTaskGroups, tg.Name)
+		}
+		names[tg.Name] = true
+	}
+	if name := GetPodAnnotationValue(pod, constants.AnnotationTaskGroupName); name != "" && len(taskGroups) > 0 && !names[name] {
+		return fmt.Errorf("invalid annotation %s: taskGroup %s is not defined in %s", constants.AnnotationTaskGroupName,
+			name, constants.AnnotationTaskGroups)
+	}
+	params, errs := ParseSchedulingPolicyParam(GetPodAnnotationValue(pod, constants.AnnotationSchedulingPolicyParam))
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid annotation %s: %w", constants.AnnotationSchedulingPolicyParam, errors.Join(errs...))
+	}
+	for name := range params.GetTaskGroupTimeouts() {
+		if len(taskGroups) > 0 && !names[name] {
+			return fmt.Errorf("invalid annotation %s: placeholder timeout set for taskGroup %s which is not defined in %s",
+				constants.AnnotationSchedulingPolicyParam, name, constants.AnnotationTaskGroups)
+		}
+	}
+	return nil
+}
@@ -16,7 +16,7 @@
  limitations under the License.
 */
 
-package cache
+package utils
 
 import (
 	"testing"
@@ -29,11 +29,6 @@ import (
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
 )
 
-const (
-	appID  = "app01"
-	app2ID = "app02"
-)
-
 //nolint:funlen
 func TestGetTaskGroupFromAnnotation(t *testing.T) {
 	// correct json
@@ -62,6 +57,7 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 		{
 			"name": "test-group-2",
 			"minMember": 5,
+			"maxMember": 8,
 			"minResource": {
 				"cpu": 2,
 				"memory": "4Gi"
@@ -76,7 +72,8 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 			"minResource": {
 				"cpu": 2,
 				"memory": "1Gi"
-			}
+			},
+			"topologyKey": "topology.kubernetes.io/zone"
 		}
 	]`
 	// Error json
@@ -141,6 +138,55 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 			}
 		}
 	]`
+	// invalid placeholder template
+	testGroupErr7 := `
+	[
+		{
+			"name": "test-group-err-7",
+			"minMember": 1,
+			"minResource": {
+				"cpu": 2
+			},
+			"placeholderTemplate": {
+				"image": "pause 3.9"
+			}
+		}
+	]`
+	// maxMember less than minMember
+	testGroupErr8 := `
+	[
+		{
+			"name": "test-group-err-8",
+			"minMember": 3,
+			"maxMember": 2,
+			"minResource": {
+				"cpu": 2
+			}
+		}
+	]`
+	// negative maxMember
+	testGroupErr9 := `
+	[
+		{
+			"name": "test-group-err-9",
+			"minMember": 3,
+			"maxMember": -1,
+			"minResource": {
+				"cpu": 2
+			}
+		}
+	]`
+	testGroupErr10 := `
+	[
+		{
+			"name": "test-group-err-10",
+			"minMember": 3,
+			"minResource": {
+				"cpu": 2
+			},
+			"topologyKey": "invalid key"
+		}
+	]`
 	// Insert task group info to pod annotation
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -182,6 +228,22 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 	taskGroupErr6, err := GetTaskGroupsFromAnnotation(pod)
 	assert.Assert(t, taskGroupErr6 == nil)
 	assert.Assert(t, err != nil)
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroupErr7}
+	taskGroupErr7, err := GetTaskGroupsFromAnnotation(pod)
+	assert.Assert(t, taskGroupErr7 == nil)
+	assert.ErrorContains(t, err, "taskGroup test-group-err-7: placeholder template has an invalid image")
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroupErr8}
+	taskGroupErr8, err := GetTaskGroupsFromAnnotation(pod)
+	assert.Assert(t, taskGroupErr8 == nil)
+	assert.ErrorContains(t, err, "maxMember cannot be less than minMember")
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroupErr9}
+	taskGroupErr9, err := GetTaskGroupsFromAnnotation(pod)
+	assert.Assert(t, taskGroupErr9 == nil)
+	assert.ErrorContains(t, err, "maxMember cannot be negative")
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroupErr10}
+	taskGroupErr10, err := GetTaskGroupsFromAnnotation(pod)
+	assert.Assert(t, taskGroupErr10 == nil)
+	assert.ErrorContains(t, err, "taskGroup test-group-err-10 has an invalid topologyKey invalid key")
 	// Correct case
 	pod.Annotations = map[string]string{constants.AnnotationTaskGroups: testGroup}
 	taskGroups, err := GetTaskGroupsFromAnnotation(pod)
@@ -193,6 +255,8 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 	assert.Equal(t, taskGroups[0].MinResource["memory"], resource.MustParse("2Gi"))
 	assert.Equal(t, taskGroups[1].Name, "test-group-2")
 	assert.Equal(t, taskGroups[1].MinMember, int32(5))
+	assert.Equal(t, taskGroups[1].MaxMember, int32(8))
+	assert.Equal(t, taskGroups[0].GetMaxMember(), int32(10))
 	assert.Equal(t, taskGroups[1].MinResource["cpu"], resource.MustParse("2"))
 	assert.Equal(t, taskGroups[1].MinResource["memory"], resource.MustParse("4Gi"))
 	// NodeSelector check
@@ -216,4 +280,58 @@ func TestGetTaskGroupFromAnnotation(t *testing.T) {
 	assert.Equal(t, taskGroups2[0].MinMember, int32(3))
 	assert.Equal(t, taskGroups2[0].MinResource["cpu"], resource.MustParse("2"))
 	assert.Equal(t, taskGroups2[0].MinResource["memory"], resource.MustParse("1Gi"))
+	assert.Equal(t, taskGroups2[0].TopologyKey, "topology.kubernetes.io/zone")
+}
+
+func TestValidateGangAnnotations(t *testing.T) {
+	taskGroups := `[{"name":"driver","minMember":1,"minResource":{"cpu":"1"}},{"name":"workers","minMember":2,"minResource":{"cpu":"1"}}]`
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		errContains string
+	}{
+		{"no annotations", nil, ""},
+		{"valid", map[string]string{
+			constants.AnnotationTaskGroups:            taskGroups,
+			constants.AnnotationTaskGroupName:         "driver",
+			constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds=60 placeholderTimeoutInSeconds.workers=30 gangSchedulingStyle=Hard",
+		}, ""},
+		{"policy without task groups", map[string]string{
+			constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds.workers=30",
+		}, ""},
+		{"invalid json", map[string]string{constants.AnnotationTaskGroups: `[{"name":"driver"`}, constants.AnnotationTaskGroups},
+		{"zero minMember", map[string]string{
+			constants.AnnotationTaskGroups: `[{"name":"driver","minMember":0,"minResource":{"cpu":"1"}}]`,
+		}, "MinMember"},
+		{"invalid resource", map[string]string{
+			constants.AnnotationTaskGroups: `[{"name":"driver","minMember":1,"minResource":{"cpu":"one"}}]`,
+		}, constants.AnnotationTaskGroups},
+		{"duplicate name", map[string]string{
+			constants.AnnotationTaskGroups: `[{"name":"driver","minMember":1,"minResource":{"cpu":"1"}},{"name":"driver","minMember":1,"minResource":{"cpu":"1"}}]`,
+		}, "taskGroup driver is defined more than once"},
+		{"undeclared task group name", map[string]string{
+			constants.AnnotationTaskGroups:    taskGroups,
+			constants.AnnotationTaskGroupName: "executor",
+		}, "taskGroup executor is not defined"},
+		{"invalid timeout", map[string]string{
+			constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds=oneSecond",
+		}, constants.AnnotationSchedulingPolicyParam},
+		{"invalid style", map[string]string{
+			constants.AnnotationSchedulingPolicyParam: "gangSchedulingStyle=abc",
+		}, constants.AnnotationSchedulingPolicyParam},
+		{"timeout for undeclared task group", map[string]string{
+			constants.AnnotationTaskGroups:            taskGroups,
+			constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds.executor=30",
+		}, "taskGroup executor which is not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGangAnnotations(tt.annotations)
+			if tt.errContains == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
 }
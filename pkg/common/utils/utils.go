@@ -145,6 +145,17 @@ func GenerateApplicationID(namespace string, generateUniqueAppIds bool, podUID s
 	return fmt.Sprintf("%.63s", generatedID)
 }
 
+// GeneratePodGroupApplicationID generates the appID of the pods that are members of a PodGroup
+func GeneratePodGroupApplicationID(namespace string, podGroup string) string {
+	return fmt.Sprintf("%.63s", fmt.Sprintf("%s-%s-%s", constants.PodGroupAppPrefix, namespace, podGroup))
+}
+
+// GenerateOwnerApplicationID generates the appID of the pods of a controller as <owner-name>-<owner-uid>, the name is
+// capped at 26 chars
+func GenerateOwnerApplicationID(ownerName string, ownerUID string) string {
+	return fmt.Sprintf("%.63s", fmt.Sprintf("%.26s-%s", ownerName, ownerUID))
+}
+
 // GetApplicationIDFromPod returns the Application for a Pod. If a Pod is marked as schedulable by YuniKorn but is
 // missing an ApplicationID, one will be generated here (if YuniKorn is running in standard mode) or an empty string
 // will be returned (if YuniKorn is running in plugin mode).
@@ -209,6 +220,11 @@ func GetApplicationIDFromPod(pod *v1.Pod) string {
 		return appID
 	}
 
+	// members of a PodGroup form one application, if PodGroups are enabled
+	if podGroup := GetPodLabelValue(pod, constants.LabelPodGroup); podGroup != "" && conf.GetSchedulerConf().IsPodGroupEnabled() {
+		return GeneratePodGroupApplicationID(pod.Namespace, podGroup)
+	}
+
 	// Standard deployment mode, so we need a valid Application ID to proceed. Generate one now.
 	return GenerateApplicationID(pod.Namespace, conf.GetSchedulerConf().GenerateUniqueAppIds, string(pod.UID))
 }
@@ -458,8 +474,32 @@ func GetPodLabelValue(pod *v1.Pod, labelKey string) string {
 	return ""
 }
 
+// IsNodeSchedulable returns false if the node is cordoned or carries a NoSchedule taint with one of the given keys
+func IsNodeSchedulable(node *v1.Node, drainTaintKeys []string) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect != v1.TaintEffectNoSchedule {
+			continue
+		}
+		for _, key := range drainTaintKeys {
+			if taint.Key == key {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// GetTaskGroupFromPodSpec returns the task group name of the pod. Members of a PodGroup without a task group
+// annotation belong to the task group named after the PodGroup, if PodGroups are enabled.
 func GetTaskGroupFromPodSpec(pod *v1.Pod) string {
-	return GetPodAnnotationValue(pod, constants.AnnotationTaskGroupName)
+	taskGroupName := GetPodAnnotationValue(pod, constants.AnnotationTaskGroupName)
+	if taskGroupName == "" && conf.GetSchedulerConf().IsPodGroupEnabled() {
+		taskGroupName = GetPodLabelValue(pod, constants.LabelPodGroup)
+	}
+	return taskGroupName
 }
 
 func GetPlaceholderFlagFromPodSpec(pod *v1.Pod) bool {
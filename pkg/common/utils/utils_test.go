@@ -587,6 +587,8 @@ func TestPodUnderCondition(t *testing.T) {
 func TestGetApplicationIDFromPod(t *testing.T) {
 	defer SetPluginMode(false)
 	defer func() { conf.GetSchedulerConf().GenerateUniqueAppIds = false }()
+	conf.GetSchedulerConf().EnablePodGroups = true
+	defer func() { conf.GetSchedulerConf().EnablePodGroups = false }()
 
 	appIDInCanonicalLabel := "CanonicalLabelAppID"
 	appIDInAnnotation := "annotationAppID"
@@ -707,6 +709,23 @@ func TestGetApplicationIDFromPod(t *testing.T) {
 			},
 			Spec: v1.PodSpec{SchedulerName: constants.SchedulerName},
 		}, appIDInAnnotation, appIDInAnnotation, false},
+		{"PodGroup member", &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Labels:    map[string]string{constants.LabelPodGroup: "pg-1"},
+			},
+			Spec: v1.PodSpec{SchedulerName: constants.SchedulerName},
+		}, "pg-ns-pg-1", "", false},
+		{"PodGroup member with AppID defined", &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Labels: map[string]string{
+					constants.LabelPodGroup:      "pg-1",
+					constants.LabelApplicationID: appIDInLabel,
+				},
+			},
+			Spec: v1.PodSpec{SchedulerName: constants.SchedulerName},
+		}, appIDInLabel, appIDInLabel, false},
 	}
 
 	for _, tc := range testCases {
@@ -722,6 +741,18 @@ func TestGetApplicationIDFromPod(t *testing.T) {
 			assert.Equal(t, appID2, tc.expectedAppIDPluginMode, "Wrong appID (plugin mode)")
 		})
 	}
+
+	// PodGroups disabled: the label does not define the application
+	conf.GetSchedulerConf().EnablePodGroups = false
+	SetPluginMode(false)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Labels:    map[string]string{constants.LabelPodGroup: "pg-1"},
+		},
+		Spec: v1.PodSpec{SchedulerName: constants.SchedulerName},
+	}
+	assert.Equal(t, GetApplicationIDFromPod(pod), "yunikorn-ns-autogen")
 }
 
 func TestCheckAppIdInPod(t *testing.T) {
@@ -950,6 +981,22 @@ func TestGenerateApplicationID(t *testing.T) {
 		GenerateApplicationID("namespace", true, ""))
 }
 
+func TestGeneratePodGroupApplicationID(t *testing.T) {
+	assert.Equal(t, "pg-namespace-podgroup",
+		GeneratePodGroupApplicationID("namespace", "podgroup"))
+
+	assert.Equal(t, "pg-namespace-longlonglonglonglonglonglonglonglonglonglonglonglo",
+		GeneratePodGroupApplicationID("namespace", strings.Repeat("long", 100)))
+}
+
+func TestGenerateOwnerApplicationID(t *testing.T) {
+	assert.Equal(t, "job-c2a8c4d4-6f0b-4d2e-9a3f-123456789abc",
+		GenerateOwnerApplicationID("job", "c2a8c4d4-6f0b-4d2e-9a3f-123456789abc"))
+
+	assert.Equal(t, "longlonglonglonglonglonglo-c2a8c4d4-6f0b-4d2e-9a3f-123456789abc",
+		GenerateOwnerApplicationID(strings.Repeat("long", 100), "c2a8c4d4-6f0b-4d2e-9a3f-123456789abc"))
+}
+
 func TestMergeMaps(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -1305,6 +1352,18 @@ func TestGetTaskGroupFromPodSpec(t *testing.T) {
 	}
 
 	assert.Equal(t, GetTaskGroupFromPodSpec(pod), "")
+
+	// PodGroup members use the PodGroup as the task group only if PodGroups are enabled
+	pod.Labels = map[string]string{constants.LabelPodGroup: "test-pod-group"}
+	assert.Equal(t, GetTaskGroupFromPodSpec(pod), "")
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{conf.CMSvcEnablePodGroups: "true"}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		assert.NilError(t, conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true), "failed to reset config")
+	}()
+	assert.Equal(t, GetTaskGroupFromPodSpec(pod), "test-pod-group")
+	pod.Annotations = map[string]string{constants.AnnotationTaskGroupName: "test-task-group"}
+	assert.Equal(t, GetTaskGroupFromPodSpec(pod), "test-task-group")
 }
 
 func TestGetPlaceholderFlagFromPodSpec(t *testing.T) {
@@ -1502,3 +1561,21 @@ func TestWaitForCondition(t *testing.T) {
 		}
 	}
 }
+
+func TestIsNodeSchedulable(t *testing.T) {
+	node := &v1.Node{}
+	assert.Assert(t, IsNodeSchedulable(node, nil), "plain node should be schedulable")
+
+	node.Spec.Unschedulable = true
+	assert.Assert(t, !IsNodeSchedulable(node, nil), "cordoned node should not be schedulable")
+
+	node.Spec.Unschedulable = false
+	node.Spec.Taints = []v1.Taint{
+		{Key: "maintenance", Effect: v1.TaintEffectNoSchedule},
+		{Key: "upgrade", Effect: v1.TaintEffectPreferNoSchedule},
+	}
+	assert.Assert(t, IsNodeSchedulable(node, nil), "taints should be ignored without keys")
+	assert.Assert(t, !IsNodeSchedulable(node, []string{"maintenance"}), "NoSchedule taint should drain the node")
+	assert.Assert(t, IsNodeSchedulable(node, []string{"upgrade"}), "PreferNoSchedule taint should be ignored")
+	assert.Assert(t, IsNodeSchedulable(node, []string{"other"}), "unlisted taint should be ignored")
+}
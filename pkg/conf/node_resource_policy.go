@@ -0,0 +1,93 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NodeResourcePolicy adjusts the allocatable resources of the nodes matching the selector before they are sent to
+// the core. The schedulable amount of a resource is calculated as: (allocatable - reserved) * factor
+// Resource names use the Kubernetes naming (cpu, memory, nvidia.com/gpu, ...).
+type NodeResourcePolicy struct {
+	Name         string             `json:"name,omitempty"`
+	NodeSelector string             `json:"nodeSelector,omitempty"`
+	Reserved     map[string]string  `json:"reserved,omitempty"`
+	Factors      map[string]float64 `json:"factors,omitempty"`
+
+	selector labels.Selector
+	reserved map[string]resource.Quantity
+}
+
+// Matches returns true if the node labels match the selector of the policy, an empty selector matches all nodes.
+func (p *NodeResourcePolicy) Matches(nodeLabels map[string]string) bool {
+	if p.selector == nil {
+		return true
+	}
+	return p.selector.Matches(labels.Set(nodeLabels))
+}
+
+// GetReserved returns the parsed reserved quantities of the policy
+func (p *NodeResourcePolicy) GetReserved() map[string]resource.Quantity {
+	return p.reserved
+}
+
+// parseNodeResourcePolicies parses and validates the JSON list of policies.
+func parseNodeResourcePolicies(value string) ([]*NodeResourcePolicy, error) {
+	policies := make([]*NodeResourcePolicy, 0)
+	if value == "" {
+		return policies, nil
+	}
+	if err := json.Unmarshal([]byte(value), &policies); err != nil {
+		return nil, err
+	}
+	for i, policy := range policies {
+		if policy == nil {
+			return nil, fmt.Errorf("node resource policy %d is empty", i)
+		}
+		if policy.NodeSelector != "" {
+			selector, err := labels.Parse(policy.NodeSelector)
+			if err != nil {
+				return nil, fmt.Errorf("node resource policy %d has an invalid node selector: %w", i, err)
+			}
+			policy.selector = selector
+		}
+		policy.reserved = make(map[string]resource.Quantity, len(policy.Reserved))
+		for name, amount := range policy.Reserved {
+			quantity, err := resource.ParseQuantity(amount)
+			if err != nil {
+				return nil, fmt.Errorf("node resource policy %d has an invalid reserved amount for %s: %w", i, name, err)
+			}
+			if quantity.Sign() < 0 {
+				return nil, fmt.Errorf("node resource policy %d has a negative reserved amount for %s", i, name)
+			}
+			policy.reserved[name] = quantity
+		}
+		for name, factor := range policy.Factors {
+			if factor <= 0 {
+				return nil, fmt.Errorf("node resource policy %d has a non-positive factor for %s", i, name)
+			}
+		}
+	}
+	return policies, nil
+}
@@ -0,0 +1,158 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// PlaceholderTemplate overrides the defaults of the placeholder pods created for gang scheduling.
+// PodTemplate references a PodTemplate object in the namespace of the application: the image and limits of its first
+// container, the runtime class, service account, priority class and labels are used for the placeholders. The other
+// fields of the same template override the values of the referenced PodTemplate, the templates of the more specific
+// levels (cluster, application, task group) override both.
+// Limits use the Kubernetes resource naming (cpu, memory, nvidia.com/gpu, ...), the requests of a placeholder are
+// always the minResource of the task group.
+type PlaceholderTemplate struct {
+	PodTemplate        string            `json:"podTemplate,omitempty"`
+	Image              string            `json:"image,omitempty"`
+	RuntimeClassName   string            `json:"runtimeClassName,omitempty"`
+	ServiceAccountName string            `json:"serviceAccountName,omitempty"`
+	PriorityClassName  string            `json:"priorityClassName,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	Limits             map[string]string `json:"limits,omitempty"`
+}
+
+// ParsePlaceholderTemplate parses and validates a JSON placeholder template, an empty value returns nil.
+func ParsePlaceholderTemplate(value string) (*PlaceholderTemplate, error) {
+	if value == "" {
+		return nil, nil
+	}
+	template := &PlaceholderTemplate{}
+	if err := json.Unmarshal([]byte(value), template); err != nil {
+		return nil, err
+	}
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Validate checks the names, labels and limits of the template. A nil template is valid.
+func (t *PlaceholderTemplate) Validate() error {
+	if t == nil {
+		return nil
+	}
+	if strings.ContainsAny(t.Image, " \t\n") {
+		return fmt.Errorf("placeholder template has an invalid image %q", t.Image)
+	}
+	for _, field := range [][2]string{
+		{"podTemplate", t.PodTemplate},
+		{"runtimeClassName", t.RuntimeClassName},
+		{"serviceAccountName", t.ServiceAccountName},
+		{"priorityClassName", t.PriorityClassName},
+	} {
+		if field[1] == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(field[1]); len(errs) > 0 {
+			return fmt.Errorf("placeholder template has an invalid %s %q: %s", field[0], field[1], strings.Join(errs, ", "))
+		}
+	}
+	for key, value := range t.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("placeholder template has an invalid label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("placeholder template has an invalid label value %q: %s", value, strings.Join(errs, ", "))
+		}
+	}
+	for name, amount := range t.Limits {
+		quantity, err := resource.ParseQuantity(amount)
+		if err != nil {
+			return fmt.Errorf("placeholder template has an invalid limit for %s: %w", name, err)
+		}
+		if quantity.Sign() < 0 {
+			return fmt.Errorf("placeholder template has a negative limit for %s", name)
+		}
+	}
+	return nil
+}
+
+// GetLimits returns the parsed limits of the template, invalid amounts are skipped as they are rejected by Validate.
+func (t *PlaceholderTemplate) GetLimits() map[string]resource.Quantity {
+	if t == nil {
+		return nil
+	}
+	limits := make(map[string]resource.Quantity, len(t.Limits))
+	for name, amount := range t.Limits {
+		if quantity, err := resource.ParseQuantity(amount); err == nil {
+			limits[name] = quantity
+		}
+	}
+	return limits
+}
+
+// Merge returns a new template with the fields set in the override replacing the fields of the template.
+// Labels and limits are merged by key. Both templates can be nil.
+func (t *PlaceholderTemplate) Merge(override *PlaceholderTemplate) *PlaceholderTemplate {
+	if t == nil && override == nil {
+		return nil
+	}
+	merged := &PlaceholderTemplate{}
+	for _, template := range []*PlaceholderTemplate{t, override} {
+		if template == nil {
+			continue
+		}
+		if template.PodTemplate != "" {
+			merged.PodTemplate = template.PodTemplate
+		}
+		if template.Image != "" {
+			merged.Image = template.Image
+		}
+		if template.RuntimeClassName != "" {
+			merged.RuntimeClassName = template.RuntimeClassName
+		}
+		if template.ServiceAccountName != "" {
+			merged.ServiceAccountName = template.ServiceAccountName
+		}
+		if template.PriorityClassName != "" {
+			merged.PriorityClassName = template.PriorityClassName
+		}
+		if len(template.Labels) > 0 {
+			if merged.Labels == nil {
+				merged.Labels = make(map[string]string)
+			}
+			maps.Copy(merged.Labels, template.Labels)
+		}
+		if len(template.Limits) > 0 {
+			if merged.Limits == nil {
+				merged.Limits = make(map[string]string)
+			}
+			maps.Copy(merged.Limits, template.Limits)
+		}
+	}
+	return merged
+}
@@ -26,6 +26,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"strconv"
 	"strings"
@@ -41,6 +42,7 @@ import (
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/yunikorn-k8shim/pkg/locking"
 	"github.com/apache/yunikorn-k8shim/pkg/log"
+	siCommon "github.com/apache/yunikorn-scheduler-interface/lib/go/common"
 )
 
 type SchedulerConfFactory = func() *SchedulerConf
@@ -64,10 +66,31 @@ const (
 	CMSvcVolumeBindTimeout            = PrefixService + "volumeBindTimeout"
 	CMSvcEventChannelCapacity         = PrefixService + "eventChannelCapacity"
 	CMSvcDispatchTimeout              = PrefixService + "dispatchTimeout"
+	CMSvcDispatcherWorkers            = PrefixService + "dispatcherWorkers"
+	CMSvcShutdownDrainTimeout         = PrefixService + "shutdownDrainTimeout"
+	CMSvcEventJournalSize             = PrefixService + "eventJournalSize"
+	CMSvcWebServicePort               = PrefixService + "webServicePort"
 	CMSvcDisableGangScheduling        = PrefixService + "disableGangScheduling"
+	CMSvcDisableGangQueueCheck        = PrefixService + "disableGangQueueCheck"
 	CMSvcEnableConfigHotRefresh       = PrefixService + "enableConfigHotRefresh"
 	CMSvcPlaceholderImage             = PrefixService + "placeholderImage"
+	CMSvcPlaceholderCreateWorkers     = PrefixService + "placeholderCreateWorkers"
+	CMSvcPlaceholderCreateQPS         = PrefixService + "placeholderCreateQPS"
+	CMSvcPlaceholderCreateRetries     = PrefixService + "placeholderCreateRetries"
+	CMSvcPlaceholderFailureThreshold  = PrefixService + "placeholderFailureThreshold"
+	CMSvcPlaceholderTemplate          = PrefixService + "placeholderTemplate"
+	CMSvcTopologyReplanTimeout        = PrefixService + "topologyReplanTimeout"
 	CMSvcNodeInstanceTypeNodeLabelKey = PrefixService + "nodeInstanceTypeNodeLabelKey"
+	CMSvcNodeAttributeLabelKeys       = PrefixService + "nodeAttributeLabelKeys"
+	CMSvcNodeDrainTaintKeys           = PrefixService + "nodeDrainTaintKeys"
+	CMSvcNodeResourcePolicies         = PrefixService + "nodeResourcePolicies"
+	CMSvcResourceMappings             = PrefixService + "resourceMappings"
+	CMSvcEnableDRA                    = PrefixService + "enableDynamicResourceAllocation"
+	CMSvcEnablePodGroups              = PrefixService + "enablePodGroups"
+	// the gang status is patched on the top-level owner of the pods, the scheduler needs get and patch on the owner
+	// kinds: the deployed RBAC covers Jobs, Deployments, ReplicaSets, StatefulSets, SparkApplications and JobSets,
+	// other kinds must be added to it
+	CMSvcEnableGangStatus = PrefixService + "enableGangStatus"
 
 	// kubernetes
 	CMKubeQPS   = PrefixKubernetes + "qps"
@@ -85,9 +108,22 @@ const (
 	DefaultVolumeBindTimeout               = 10 * time.Minute
 	DefaultEventChannelCapacity            = 1024 * 1024
 	DefaultDispatchTimeout                 = 300 * time.Second
+	DefaultDispatcherWorkers               = 1
+	DefaultShutdownDrainTimeout            = 30 * time.Second
+	DefaultEventJournalSize                = 0
+	DefaultWebServicePort                  = 9081
 	DefaultOperatorPlugins                 = "general"
 	DefaultDisableGangScheduling           = false
+	DefaultDisableGangQueueCheck           = false
 	DefaultEnableConfigHotRefresh          = true
+	DefaultEnableDRA                       = false
+	DefaultEnablePodGroups                 = false
+	DefaultEnableGangStatus                = false
+	DefaultPlaceholderCreateWorkers        = 16
+	DefaultPlaceholderCreateQPS            = 50
+	DefaultPlaceholderCreateRetries        = 3
+	DefaultPlaceholderFailureThreshold     = 0
+	DefaultTopologyReplanTimeout           = 5 * time.Minute
 	DefaultKubeQPS                         = 1000
 	DefaultKubeBurst                       = 1000
 	DefaultAMFilteringGenerateUniqueAppIds = false
@@ -110,24 +146,42 @@ var confHolder atomic.Value
 var kubeLoggerOnce sync.Once
 
 type SchedulerConf struct {
-	SchedulerName            string        `json:"schedulerName"`
-	ClusterID                string        `json:"clusterId"`
-	ClusterVersion           string        `json:"clusterVersion"`
-	PolicyGroup              string        `json:"policyGroup"`
-	Interval                 time.Duration `json:"schedulingIntervalSecond"`
-	KubeConfig               string        `json:"absoluteKubeConfigFilePath"`
-	VolumeBindTimeout        time.Duration `json:"volumeBindTimeout"`
-	EventChannelCapacity     int           `json:"eventChannelCapacity"`
-	DispatchTimeout          time.Duration `json:"dispatchTimeout"`
-	KubeQPS                  int           `json:"kubeQPS"`
-	KubeBurst                int           `json:"kubeBurst"`
-	EnableConfigHotRefresh   bool          `json:"enableConfigHotRefresh"`
-	DisableGangScheduling    bool          `json:"disableGangScheduling"`
-	UserLabelKey             string        `json:"userLabelKey"`
-	PlaceHolderImage         string        `json:"placeHolderImage"`
-	InstanceTypeNodeLabelKey string        `json:"instanceTypeNodeLabelKey"`
-	Namespace                string        `json:"namespace"`
-	GenerateUniqueAppIds     bool          `json:"generateUniqueAppIds"`
+	SchedulerName            string                `json:"schedulerName"`
+	ClusterID                string                `json:"clusterId"`
+	ClusterVersion           string                `json:"clusterVersion"`
+	PolicyGroup              string                `json:"policyGroup"`
+	Interval                 time.Duration         `json:"schedulingIntervalSecond"`
+	KubeConfig               string                `json:"absoluteKubeConfigFilePath"`
+	VolumeBindTimeout        time.Duration         `json:"volumeBindTimeout"`
+	EventChannelCapacity     int                   `json:"eventChannelCapacity"`
+	DispatchTimeout          time.Duration         `json:"dispatchTimeout"`
+	DispatcherWorkers        int                   `json:"dispatcherWorkers"`
+	ShutdownDrainTimeout     time.Duration         `json:"shutdownDrainTimeout"`
+	EventJournalSize         int                   `json:"eventJournalSize"`
+	WebServicePort           int                   `json:"webServicePort"`
+	KubeQPS                  int                   `json:"kubeQPS"`
+	KubeBurst                int                   `json:"kubeBurst"`
+	EnableConfigHotRefresh   bool                  `json:"enableConfigHotRefresh"`
+	DisableGangScheduling    bool                  `json:"disableGangScheduling"`
+	DisableGangQueueCheck    bool                  `json:"disableGangQueueCheck"`
+	UserLabelKey             string                `json:"userLabelKey"`
+	PlaceHolderImage         string                `json:"placeHolderImage"`
+	PlaceholderCreateWorkers int                   `json:"placeholderCreateWorkers"`
+	PlaceholderCreateQPS     int                   `json:"placeholderCreateQPS"`
+	PlaceholderCreateRetries int                   `json:"placeholderCreateRetries"`
+	PlaceholderFailThreshold int                   `json:"placeholderFailureThreshold"`
+	PlaceholderTemplate      *PlaceholderTemplate  `json:"placeholderTemplate"`
+	TopologyReplanTimeout    time.Duration         `json:"topologyReplanTimeout"`
+	InstanceTypeNodeLabelKey string                `json:"instanceTypeNodeLabelKey"`
+	NodeAttributeLabelKeys   []string              `json:"nodeAttributeLabelKeys"`
+	NodeDrainTaintKeys       []string              `json:"nodeDrainTaintKeys"`
+	NodeResourcePolicies     []*NodeResourcePolicy `json:"nodeResourcePolicies"`
+	ResourceMappings         map[string]string     `json:"resourceMappings"`
+	EnableDRA                bool                  `json:"enableDynamicResourceAllocation"`
+	EnablePodGroups          bool                  `json:"enablePodGroups"`
+	EnableGangStatus         bool                  `json:"enableGangStatus"`
+	Namespace                string                `json:"namespace"`
+	GenerateUniqueAppIds     bool                  `json:"generateUniqueAppIds"`
 
 	locking.RWMutex
 }
@@ -146,13 +200,31 @@ func (conf *SchedulerConf) Clone() *SchedulerConf {
 		VolumeBindTimeout:        conf.VolumeBindTimeout,
 		EventChannelCapacity:     conf.EventChannelCapacity,
 		DispatchTimeout:          conf.DispatchTimeout,
+		DispatcherWorkers:        conf.DispatcherWorkers,
+		ShutdownDrainTimeout:     conf.ShutdownDrainTimeout,
+		EventJournalSize:         conf.EventJournalSize,
+		WebServicePort:           conf.WebServicePort,
 		KubeQPS:                  conf.KubeQPS,
 		KubeBurst:                conf.KubeBurst,
 		EnableConfigHotRefresh:   conf.EnableConfigHotRefresh,
 		DisableGangScheduling:    conf.DisableGangScheduling,
+		DisableGangQueueCheck:    conf.DisableGangQueueCheck,
 		UserLabelKey:             conf.UserLabelKey,
 		PlaceHolderImage:         conf.PlaceHolderImage,
+		PlaceholderCreateWorkers: conf.PlaceholderCreateWorkers,
+		PlaceholderCreateQPS:     conf.PlaceholderCreateQPS,
+		PlaceholderCreateRetries: conf.PlaceholderCreateRetries,
+		PlaceholderFailThreshold: conf.PlaceholderFailThreshold,
+		PlaceholderTemplate:      conf.PlaceholderTemplate,
+		TopologyReplanTimeout:    conf.TopologyReplanTimeout,
 		InstanceTypeNodeLabelKey: conf.InstanceTypeNodeLabelKey,
+		NodeAttributeLabelKeys:   append([]string(nil), conf.NodeAttributeLabelKeys...),
+		NodeDrainTaintKeys:       append([]string(nil), conf.NodeDrainTaintKeys...),
+		NodeResourcePolicies:     append([]*NodeResourcePolicy(nil), conf.NodeResourcePolicies...),
+		ResourceMappings:         maps.Clone(conf.ResourceMappings),
+		EnableDRA:                conf.EnableDRA,
+		EnablePodGroups:          conf.EnablePodGroups,
+		EnableGangStatus:         conf.EnableGangStatus,
 		Namespace:                conf.Namespace,
 		GenerateUniqueAppIds:     conf.GenerateUniqueAppIds,
 	}
@@ -206,12 +278,19 @@ func handleNonReloadableConfig(old *SchedulerConf, new *SchedulerConf) {
 	checkNonReloadableDuration(CMSvcVolumeBindTimeout, &old.VolumeBindTimeout, &new.VolumeBindTimeout)
 	checkNonReloadableInt(CMSvcEventChannelCapacity, &old.EventChannelCapacity, &new.EventChannelCapacity)
 	checkNonReloadableDuration(CMSvcDispatchTimeout, &old.DispatchTimeout, &new.DispatchTimeout)
+	checkNonReloadableInt(CMSvcDispatcherWorkers, &old.DispatcherWorkers, &new.DispatcherWorkers)
+	checkNonReloadableInt(CMSvcEventJournalSize, &old.EventJournalSize, &new.EventJournalSize)
+	checkNonReloadableInt(CMSvcWebServicePort, &old.WebServicePort, &new.WebServicePort)
 	checkNonReloadableInt(CMKubeQPS, &old.KubeQPS, &new.KubeQPS)
 	checkNonReloadableInt(CMKubeBurst, &old.KubeBurst, &new.KubeBurst)
 	checkNonReloadableBool(CMSvcDisableGangScheduling, &old.DisableGangScheduling, &new.DisableGangScheduling)
 	checkNonReloadableString(CMSvcPlaceholderImage, &old.PlaceHolderImage, &new.PlaceHolderImage)
 	checkNonReloadableString(CMSvcNodeInstanceTypeNodeLabelKey, &old.InstanceTypeNodeLabelKey, &new.InstanceTypeNodeLabelKey)
 	checkNonReloadableBool(AMFilteringGenerateUniqueAppIds, &old.GenerateUniqueAppIds, &new.GenerateUniqueAppIds)
+	checkNonReloadableStringMap(CMSvcResourceMappings, &old.ResourceMappings, &new.ResourceMappings)
+	checkNonReloadableBool(CMSvcEnableDRA, &old.EnableDRA, &new.EnableDRA)
+	checkNonReloadableBool(CMSvcEnablePodGroups, &old.EnablePodGroups, &new.EnablePodGroups)
+	checkNonReloadableBool(CMSvcEnableGangStatus, &old.EnableGangStatus, &new.EnableGangStatus)
 }
 
 const warningNonReloadable = "ignoring non-reloadable configuration change (restart required to update)"
@@ -244,6 +323,13 @@ func checkNonReloadableBool(name string, old *bool, new *bool) {
 	}
 }
 
+func checkNonReloadableStringMap(name string, old *map[string]string, new *map[string]string) {
+	if !maps.Equal(*old, *new) {
+		log.Log(log.ShimConfig).Warn(warningNonReloadable, zap.String("config", name), zap.Any("existing", *old), zap.Any("new", *new))
+		*new = *old
+	}
+}
+
 func GetSchedulerConf() *SchedulerConf {
 	once.Do(createConfigs)
 	return confHolder.Load().(*SchedulerConf) //nolint:errcheck
@@ -267,6 +353,59 @@ func (conf *SchedulerConf) GetSchedulingInterval() time.Duration {
 	return conf.Interval
 }
 
+func (conf *SchedulerConf) GetNodeAttributeLabelKeys() []string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.NodeAttributeLabelKeys
+}
+
+func (conf *SchedulerConf) GetNodeDrainTaintKeys() []string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.NodeDrainTaintKeys
+}
+
+// GetNodeResourcePolicy returns the first node resource policy matching the node labels or nil if none match
+func (conf *SchedulerConf) GetNodeResourcePolicy(nodeLabels map[string]string) *NodeResourcePolicy {
+	conf.RLock()
+	defer conf.RUnlock()
+	for _, policy := range conf.NodeResourcePolicies {
+		if policy.Matches(nodeLabels) {
+			return policy
+		}
+	}
+	return nil
+}
+
+// GetResourceMappings returns the configured resource name mappings, the returned map must not be modified
+func (conf *SchedulerConf) GetResourceMappings() map[string]string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.ResourceMappings
+}
+
+// IsDRAEnabled returns true if ResourceClaims should be taken into account for pods and nodes
+func (conf *SchedulerConf) IsDRAEnabled() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EnableDRA
+}
+
+// IsPodGroupEnabled returns true if the PodGroups of the scheduler-plugins coscheduling API should be used as a
+// source of gang scheduling
+func (conf *SchedulerConf) IsPodGroupEnabled() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EnablePodGroups
+}
+
+// IsGangStatusEnabled returns true if the gang scheduling progress should be written on the owner of the application
+func (conf *SchedulerConf) IsGangStatusEnabled() bool {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EnableGangStatus
+}
+
 func (conf *SchedulerConf) GetKubeConfigPath() string {
 	conf.RLock()
 	defer conf.RUnlock()
@@ -309,12 +448,25 @@ func CreateDefaultConfig() *SchedulerConf {
 		VolumeBindTimeout:        DefaultVolumeBindTimeout,
 		EventChannelCapacity:     DefaultEventChannelCapacity,
 		DispatchTimeout:          DefaultDispatchTimeout,
+		DispatcherWorkers:        DefaultDispatcherWorkers,
+		ShutdownDrainTimeout:     DefaultShutdownDrainTimeout,
+		EventJournalSize:         DefaultEventJournalSize,
+		WebServicePort:           DefaultWebServicePort,
 		KubeQPS:                  DefaultKubeQPS,
 		KubeBurst:                DefaultKubeBurst,
 		EnableConfigHotRefresh:   DefaultEnableConfigHotRefresh,
 		DisableGangScheduling:    DefaultDisableGangScheduling,
+		DisableGangQueueCheck:    DefaultDisableGangQueueCheck,
+		EnableDRA:                DefaultEnableDRA,
+		EnablePodGroups:          DefaultEnablePodGroups,
+		EnableGangStatus:         DefaultEnableGangStatus,
 		UserLabelKey:             constants.DefaultUserLabel,
 		PlaceHolderImage:         constants.PlaceholderContainerImage,
+		PlaceholderCreateWorkers: DefaultPlaceholderCreateWorkers,
+		PlaceholderCreateQPS:     DefaultPlaceholderCreateQPS,
+		PlaceholderCreateRetries: DefaultPlaceholderCreateRetries,
+		PlaceholderFailThreshold: DefaultPlaceholderFailureThreshold,
+		TopologyReplanTimeout:    DefaultTopologyReplanTimeout,
 		InstanceTypeNodeLabelKey: constants.DefaultNodeInstanceTypeNodeLabelKey,
 		GenerateUniqueAppIds:     DefaultAMFilteringGenerateUniqueAppIds,
 	}
@@ -337,10 +489,28 @@ func parseConfig(config map[string]string, prev *SchedulerConf) (*SchedulerConf,
 	parser.durationVar(&conf.VolumeBindTimeout, CMSvcVolumeBindTimeout)
 	parser.intVar(&conf.EventChannelCapacity, CMSvcEventChannelCapacity)
 	parser.durationVar(&conf.DispatchTimeout, CMSvcDispatchTimeout)
+	parser.intVar(&conf.DispatcherWorkers, CMSvcDispatcherWorkers)
+	parser.durationVar(&conf.ShutdownDrainTimeout, CMSvcShutdownDrainTimeout)
+	parser.intVar(&conf.EventJournalSize, CMSvcEventJournalSize)
+	parser.intVar(&conf.WebServicePort, CMSvcWebServicePort)
 	parser.boolVar(&conf.DisableGangScheduling, CMSvcDisableGangScheduling)
+	parser.boolVar(&conf.DisableGangQueueCheck, CMSvcDisableGangQueueCheck)
 	parser.boolVar(&conf.EnableConfigHotRefresh, CMSvcEnableConfigHotRefresh)
 	parser.stringVar(&conf.PlaceHolderImage, CMSvcPlaceholderImage)
+	parser.intVar(&conf.PlaceholderCreateWorkers, CMSvcPlaceholderCreateWorkers)
+	parser.intVar(&conf.PlaceholderCreateQPS, CMSvcPlaceholderCreateQPS)
+	parser.intVar(&conf.PlaceholderCreateRetries, CMSvcPlaceholderCreateRetries)
+	parser.percentageVar(&conf.PlaceholderFailThreshold, CMSvcPlaceholderFailureThreshold)
+	parser.placeholderTemplateVar(&conf.PlaceholderTemplate, CMSvcPlaceholderTemplate)
+	parser.durationVar(&conf.TopologyReplanTimeout, CMSvcTopologyReplanTimeout)
 	parser.stringVar(&conf.InstanceTypeNodeLabelKey, CMSvcNodeInstanceTypeNodeLabelKey)
+	parser.stringSliceVar(&conf.NodeAttributeLabelKeys, CMSvcNodeAttributeLabelKeys)
+	parser.stringSliceVar(&conf.NodeDrainTaintKeys, CMSvcNodeDrainTaintKeys)
+	parser.nodeResourcePoliciesVar(&conf.NodeResourcePolicies, CMSvcNodeResourcePolicies)
+	parser.resourceMappingsVar(&conf.ResourceMappings, CMSvcResourceMappings)
+	parser.boolVar(&conf.EnableDRA, CMSvcEnableDRA)
+	parser.boolVar(&conf.EnablePodGroups, CMSvcEnablePodGroups)
+	parser.boolVar(&conf.EnableGangStatus, CMSvcEnableGangStatus)
 
 	// kubernetes
 	parser.intVar(&conf.KubeQPS, CMKubeQPS)
@@ -373,6 +543,77 @@ func (cp *configParser) stringVar(p *string, name string) {
 	}
 }
 
+// stringSliceVar parses a comma separated list, empty entries and surrounding whitespace are dropped
+func (cp *configParser) stringSliceVar(p *[]string, name string) {
+	if newValue, ok := cp.config[name]; ok {
+		values := make([]string, 0)
+		for _, value := range strings.Split(newValue, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		*p = values
+	}
+}
+
+func (cp *configParser) nodeResourcePoliciesVar(p *[]*NodeResourcePolicy, name string) {
+	if newValue, ok := cp.config[name]; ok {
+		policies, err := parseNodeResourcePolicies(newValue)
+		if err != nil {
+			log.Log(log.ShimConfig).Error("Unable to parse configmap entry", zap.String("key", name), zap.String("value", newValue), zap.Error(err))
+			cp.errors = append(cp.errors, err)
+			return
+		}
+		*p = policies
+	}
+}
+
+// placeholderTemplateVar parses and validates the JSON placeholder template, an empty value removes the template.
+func (cp *configParser) placeholderTemplateVar(p **PlaceholderTemplate, name string) {
+	if newValue, ok := cp.config[name]; ok {
+		template, err := ParsePlaceholderTemplate(newValue)
+		if err != nil {
+			log.Log(log.ShimConfig).Error("Unable to parse configmap entry", zap.String("key", name), zap.String("value", newValue), zap.Error(err))
+			cp.errors = append(cp.errors, err)
+			return
+		}
+		*p = template
+	}
+}
+
+// resourceMappingsVar parses a comma separated list of source=target resource name mappings.
+// Several sources can map to the same target to merge them, an empty target drops the resource. The CPU is always
+// converted to vcore: it cannot be a target.
+func (cp *configParser) resourceMappingsVar(p *map[string]string, name string) {
+	if newValue, ok := cp.config[name]; ok {
+		mappings := make(map[string]string)
+		for _, entry := range strings.Split(newValue, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			source, target, found := strings.Cut(entry, "=")
+			source = strings.TrimSpace(source)
+			target = strings.TrimSpace(target)
+			var err error
+			switch {
+			case !found || source == "":
+				err = fmt.Errorf("invalid resource mapping entry: %s", entry)
+			case source == string(v1.ResourceCPU) || source == string(v1.ResourcePods):
+				err = fmt.Errorf("resource %s cannot be mapped", source)
+			case target == string(v1.ResourceCPU) || target == string(v1.ResourcePods) || target == siCommon.CPU:
+				err = fmt.Errorf("resource %s cannot be used as a mapping target", target)
+			}
+			if err != nil {
+				log.Log(log.ShimConfig).Error("Unable to parse configmap entry", zap.String("key", name), zap.String("value", newValue), zap.Error(err))
+				cp.errors = append(cp.errors, err)
+				return
+			}
+			mappings[source] = target
+		}
+		*p = mappings
+	}
+}
+
 func (cp *configParser) intVar(p *int, name string) {
 	if newValue, ok := cp.config[name]; ok {
 		int64Value, err := strconv.ParseInt(newValue, 10, 32)
@@ -386,6 +627,19 @@ func (cp *configParser) intVar(p *int, name string) {
 	}
 }
 
+// percentageVar parses an integer percentage, values outside 0..100 are rejected
+func (cp *configParser) percentageVar(p *int, name string) {
+	value := *p
+	cp.intVar(&value, name)
+	if value < 0 || value > 100 {
+		err := fmt.Errorf("percentage %d is not between 0 and 100", value)
+		log.Log(log.ShimConfig).Error("Invalid configmap entry", zap.String("key", name), zap.Error(err))
+		cp.errors = append(cp.errors, err)
+		return
+	}
+	*p = value
+}
+
 func (cp *configParser) boolVar(p *bool, name string) {
 	if newValue, ok := cp.config[name]; ok {
 		boolValue, err := strconv.ParseBool(newValue)
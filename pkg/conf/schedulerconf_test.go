@@ -120,10 +120,23 @@ func TestParseConfigMap(t *testing.T) {
 		{CMSvcVolumeBindTimeout, "VolumeBindTimeout", 15 * time.Second},
 		{CMSvcEventChannelCapacity, "EventChannelCapacity", 1234},
 		{CMSvcDispatchTimeout, "DispatchTimeout", 3 * time.Minute},
+		{CMSvcDispatcherWorkers, "DispatcherWorkers", 8},
+		{CMSvcShutdownDrainTimeout, "ShutdownDrainTimeout", 45 * time.Second},
+		{CMSvcEventJournalSize, "EventJournalSize", 500},
+		{CMSvcWebServicePort, "WebServicePort", 9091},
 		{CMSvcDisableGangScheduling, "DisableGangScheduling", true},
+		{CMSvcDisableGangQueueCheck, "DisableGangQueueCheck", true},
 		{CMSvcEnableConfigHotRefresh, "EnableConfigHotRefresh", false},
 		{CMSvcPlaceholderImage, "PlaceHolderImage", "test-image"},
+		{CMSvcPlaceholderCreateWorkers, "PlaceholderCreateWorkers", 4},
+		{CMSvcPlaceholderCreateQPS, "PlaceholderCreateQPS", 20},
+		{CMSvcPlaceholderCreateRetries, "PlaceholderCreateRetries", 5},
+		{CMSvcPlaceholderFailureThreshold, "PlaceholderFailThreshold", 10},
+		{CMSvcTopologyReplanTimeout, "TopologyReplanTimeout", 2 * time.Minute},
 		{CMSvcNodeInstanceTypeNodeLabelKey, "InstanceTypeNodeLabelKey", "node.kubernetes.io/instance-type"},
+		{CMSvcEnableDRA, "EnableDRA", true},
+		{CMSvcEnablePodGroups, "EnablePodGroups", true},
+		{CMSvcEnableGangStatus, "EnableGangStatus", true},
 		{CMKubeQPS, "KubeQPS", 2345},
 		{CMKubeBurst, "KubeBurst", 3456},
 	}
@@ -152,9 +165,22 @@ func TestUpdateConfigMapNonReloadable(t *testing.T) {
 		{CMSvcVolumeBindTimeout, "VolumeBindTimeout", 15 * time.Second, false},
 		{CMSvcEventChannelCapacity, "EventChannelCapacity", 1234, false},
 		{CMSvcDispatchTimeout, "DispatchTimeout", 3 * time.Minute, false},
+		{CMSvcDispatcherWorkers, "DispatcherWorkers", 8, false},
+		{CMSvcShutdownDrainTimeout, "ShutdownDrainTimeout", 45 * time.Second, true},
+		{CMSvcEventJournalSize, "EventJournalSize", 500, false},
+		{CMSvcWebServicePort, "WebServicePort", 9091, false},
 		{CMSvcDisableGangScheduling, "DisableGangScheduling", true, false},
+		{CMSvcDisableGangQueueCheck, "DisableGangQueueCheck", true, true},
 		{CMSvcPlaceholderImage, "PlaceHolderImage", "test-image", false},
+		{CMSvcPlaceholderCreateWorkers, "PlaceholderCreateWorkers", 4, true},
+		{CMSvcPlaceholderCreateQPS, "PlaceholderCreateQPS", 20, true},
+		{CMSvcPlaceholderCreateRetries, "PlaceholderCreateRetries", 5, true},
+		{CMSvcPlaceholderFailureThreshold, "PlaceholderFailThreshold", 10, true},
+		{CMSvcTopologyReplanTimeout, "TopologyReplanTimeout", 2 * time.Minute, true},
 		{CMSvcNodeInstanceTypeNodeLabelKey, "InstanceTypeNodeLabelKey", "node.kubernetes.io/instance-type", false},
+		{CMSvcEnableDRA, "EnableDRA", true, false},
+		{CMSvcEnablePodGroups, "EnablePodGroups", true, false},
+		{CMSvcEnableGangStatus, "EnableGangStatus", true, false},
 		{CMKubeQPS, "KubeQPS", 2345, false},
 		{CMKubeBurst, "KubeBurst", 3456, false},
 	}
@@ -189,6 +215,158 @@ func TestParseConfigMapWithUnknownKeyDoesNotFail(t *testing.T) {
 	assert.Assert(t, errs == nil, errs)
 }
 
+func TestParseNodeAttributeLabelKeys(t *testing.T) {
+	prev := CreateDefaultConfig()
+	assert.Equal(t, 0, len(prev.NodeAttributeLabelKeys), "unexpected default label keys")
+	conf, errs := parseConfig(map[string]string{CMSvcNodeAttributeLabelKeys: " topology.kubernetes.io/zone,,topology.kubernetes.io/region "}, prev)
+	assert.Assert(t, errs == nil, errs)
+	assert.DeepEqual(t, []string{"topology.kubernetes.io/zone", "topology.kubernetes.io/region"}, conf.GetNodeAttributeLabelKeys())
+
+	conf, errs = parseConfig(map[string]string{CMSvcNodeAttributeLabelKeys: ""}, conf)
+	assert.Assert(t, errs == nil, errs)
+	assert.Equal(t, 0, len(conf.GetNodeAttributeLabelKeys()), "label keys not cleared")
+}
+
+func TestParseNodeResourcePolicies(t *testing.T) {
+	prev := CreateDefaultConfig()
+	conf, errs := parseConfig(map[string]string{CMSvcNodeResourcePolicies: `[
+		{"name": "batch", "nodeSelector": "pool=batch", "reserved": {"memory": "1Gi"}, "factors": {"cpu": 1.5}},
+		{"name": "default", "reserved": {"cpu": "500m"}}
+	]`}, prev)
+	assert.Assert(t, errs == nil, errs)
+	assert.Equal(t, 2, len(conf.NodeResourcePolicies))
+
+	policy := conf.GetNodeResourcePolicy(map[string]string{"pool": "batch"})
+	assert.Assert(t, policy != nil, "batch policy not matched")
+	assert.Equal(t, "batch", policy.Name)
+	reserved := policy.GetReserved()["memory"]
+	assert.Equal(t, int64(1024*1024*1024), reserved.Value())
+	policy = conf.GetNodeResourcePolicy(map[string]string{"pool": "online"})
+	assert.Assert(t, policy != nil, "default policy not matched")
+	assert.Equal(t, "default", policy.Name)
+
+	conf, errs = parseConfig(map[string]string{CMSvcNodeResourcePolicies: ""}, conf)
+	assert.Assert(t, errs == nil, errs)
+	assert.Assert(t, conf.GetNodeResourcePolicy(map[string]string{}) == nil, "policies not cleared")
+}
+
+func TestParseNodeResourcePoliciesInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{"json", "{", "unexpected end of JSON input"},
+		{"selector", `[{"nodeSelector": "pool in ("}]`, "invalid node selector"},
+		{"quantity", `[{"reserved": {"memory": "x"}}]`, "invalid reserved amount"},
+		{"negative", `[{"reserved": {"memory": "-1Gi"}}]`, "negative reserved amount"},
+		{"factor", `[{"factors": {"cpu": 0}}]`, "non-positive factor"},
+		{"empty", `[null]`, "is empty"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, errs := parseConfig(map[string]string{CMSvcNodeResourcePolicies: tc.value}, CreateDefaultConfig())
+			assert.Assert(t, conf == nil, "conf exists")
+			assert.Equal(t, 1, len(errs), "wrong error count")
+			assert.ErrorContains(t, errs[0], tc.err)
+		})
+	}
+}
+
+func TestParsePlaceholderTemplate(t *testing.T) {
+	conf, errs := parseConfig(map[string]string{CMSvcPlaceholderTemplate: `{"image": "registry.local/pause:3.9",
+		"priorityClassName": "placeholder", "labels": {"team": "ml"}, "limits": {"memory": "2Gi"}}`}, CreateDefaultConfig())
+	assert.Assert(t, errs == nil, errs)
+	assert.Assert(t, conf.PlaceholderTemplate != nil, "template not set")
+	assert.Equal(t, "registry.local/pause:3.9", conf.PlaceholderTemplate.Image)
+	assert.Equal(t, "placeholder", conf.PlaceholderTemplate.PriorityClassName)
+	assert.DeepEqual(t, map[string]string{"team": "ml"}, conf.PlaceholderTemplate.Labels)
+	limit := conf.PlaceholderTemplate.GetLimits()["memory"]
+	assert.Equal(t, int64(2*1024*1024*1024), limit.Value())
+
+	conf, errs = parseConfig(map[string]string{CMSvcPlaceholderTemplate: ""}, conf)
+	assert.Assert(t, errs == nil, errs)
+	assert.Assert(t, conf.PlaceholderTemplate == nil, "template not cleared")
+}
+
+func TestParsePlaceholderTemplateInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{"json", "{", "unexpected end of JSON input"},
+		{"image", `{"image": "pause 3.9"}`, "invalid image"},
+		{"podTemplate", `{"podTemplate": "Template_1"}`, "invalid podTemplate"},
+		{"runtimeClass", `{"runtimeClassName": "-gvisor"}`, "invalid runtimeClassName"},
+		{"serviceAccount", `{"serviceAccountName": "a b"}`, "invalid serviceAccountName"},
+		{"priorityClass", `{"priorityClassName": "High"}`, "invalid priorityClassName"},
+		{"labelKey", `{"labels": {"a/b/c": "x"}}`, "invalid label key"},
+		{"labelValue", `{"labels": {"team": "a b"}}`, "invalid label value"},
+		{"quantity", `{"limits": {"memory": "x"}}`, "invalid limit"},
+		{"negative", `{"limits": {"memory": "-1Gi"}}`, "negative limit"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, errs := parseConfig(map[string]string{CMSvcPlaceholderTemplate: tc.value}, CreateDefaultConfig())
+			assert.Assert(t, conf == nil, "conf exists")
+			assert.Equal(t, 1, len(errs), "wrong error count")
+			assert.ErrorContains(t, errs[0], tc.err)
+		})
+	}
+}
+
+func TestMergePlaceholderTemplate(t *testing.T) {
+	var nilTemplate *PlaceholderTemplate
+	assert.Assert(t, nilTemplate.Merge(nil) == nil, "merge of nil templates must be nil")
+
+	base := &PlaceholderTemplate{
+		PodTemplate: "base",
+		Image:       "base-image",
+		Labels:      map[string]string{"a": "1", "b": "1"},
+		Limits:      map[string]string{"cpu": "1"},
+	}
+	merged := base.Merge(&PlaceholderTemplate{
+		Image:             "override-image",
+		PriorityClassName: "high",
+		Labels:            map[string]string{"b": "2"},
+		Limits:            map[string]string{"memory": "1Gi"},
+	})
+	assert.DeepEqual(t, &PlaceholderTemplate{
+		PodTemplate:       "base",
+		Image:             "override-image",
+		PriorityClassName: "high",
+		Labels:            map[string]string{"a": "1", "b": "2"},
+		Limits:            map[string]string{"cpu": "1", "memory": "1Gi"},
+	}, merged)
+	assert.Equal(t, "1", base.Labels["b"], "base template modified")
+	assert.DeepEqual(t, base, nilTemplate.Merge(base))
+}
+
+func TestParseResourceMappings(t *testing.T) {
+	conf, errs := parseConfig(map[string]string{CMSvcResourceMappings: "nvidia.com/gpu=gpu, amd.com/gpu = gpu,ephemeral-storage=,"}, CreateDefaultConfig())
+	assert.Assert(t, errs == nil, errs)
+	assert.DeepEqual(t, map[string]string{"nvidia.com/gpu": "gpu", "amd.com/gpu": "gpu", "ephemeral-storage": ""}, conf.GetResourceMappings())
+
+	for _, value := range []string{"nvidia.com/gpu", "=gpu", "cpu=vcore", "pods=", "nvidia.com/gpu=pods", "acme.com/cpu=vcore"} {
+		conf, errs = parseConfig(map[string]string{CMSvcResourceMappings: value}, CreateDefaultConfig())
+		assert.Assert(t, conf == nil, "conf exists for %s", value)
+		assert.Equal(t, 1, len(errs), "wrong error count for %s", value)
+	}
+}
+
+func TestUpdateResourceMappingsNonReloadable(t *testing.T) {
+	defer func() {
+		err := UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true)
+		assert.NilError(t, err, "failed to reset configmap")
+	}()
+	err := UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{CMSvcResourceMappings: "amd.com/gpu=gpu"}}}, true)
+	assert.NilError(t, err, "failed to set configmap")
+	err = UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{CMSvcResourceMappings: "nvidia.com/gpu=gpu"}}}, false)
+	assert.NilError(t, err, "failed to update configmap")
+	assert.DeepEqual(t, map[string]string{"amd.com/gpu": "gpu"}, GetSchedulerConf().GetResourceMappings())
+}
+
 func TestParseConfigMapWithInvalidInt(t *testing.T) {
 	prev := CreateDefaultConfig()
 	conf, errs := parseConfig(map[string]string{CMSvcEventChannelCapacity: "x"}, prev)
@@ -197,6 +375,20 @@ func TestParseConfigMapWithInvalidInt(t *testing.T) {
 	assert.ErrorContains(t, errs[0], "invalid syntax", "wrong error type")
 }
 
+func TestParseConfigMapWithInvalidPercentage(t *testing.T) {
+	prev := CreateDefaultConfig()
+	for _, value := range []string{"-1", "101"} {
+		conf, errs := parseConfig(map[string]string{CMSvcPlaceholderFailureThreshold: value}, prev)
+		assert.Assert(t, conf == nil, "conf exists")
+		assert.Equal(t, 1, len(errs), "wrong error count")
+		assert.ErrorContains(t, errs[0], "is not between 0 and 100", "wrong error type")
+	}
+	conf, errs := parseConfig(map[string]string{CMSvcPlaceholderFailureThreshold: "x"}, prev)
+	assert.Assert(t, conf == nil, "conf exists")
+	assert.Equal(t, 1, len(errs), "wrong error count")
+	assert.ErrorContains(t, errs[0], "invalid syntax", "wrong error type")
+}
+
 func TestParseConfigMapWithInvalidBool(t *testing.T) {
 	prev := CreateDefaultConfig()
 	conf, errs := parseConfig(map[string]string{CMSvcEnableConfigHotRefresh: "x"}, prev)
@@ -25,6 +25,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"gotest.tools/v3/assert"
 
 	"github.com/apache/yunikorn-k8shim/pkg/common/events"
@@ -131,7 +133,7 @@ func TestDispatcherStartStop(t *testing.T) {
 	})
 
 	// wait until all events are handled
-	dispatcher.drain()
+	assert.Assert(t, dispatcher.drain(10*time.Second), "dispatcher did not drain")
 
 	// stop the dispatcher,
 	Stop()
@@ -159,7 +161,7 @@ func TestDispatcherStartStop(t *testing.T) {
 func TestEventWillNotBeLostWhenEventChannelIsFull(t *testing.T) {
 	createDispatcher()
 	defer createDispatcher()
-	dispatcher.eventChan = make(chan events.SchedulingEvent, 1)
+	dispatcher.workers = []*worker{newWorker(1)}
 
 	// thread safe
 	recorder := &appEventsRecorder{
@@ -190,7 +192,7 @@ func TestEventWillNotBeLostWhenEventChannelIsFull(t *testing.T) {
 	assert.Assert(t, asyncDispatchCount.Load() > 0)
 
 	// wait until all events are handled
-	dispatcher.drain()
+	assert.Assert(t, dispatcher.drain(10*time.Second), "dispatcher did not drain")
 
 	// stop the dispatcher
 	Stop()
@@ -203,101 +205,536 @@ func TestEventWillNotBeLostWhenEventChannelIsFull(t *testing.T) {
 	assert.Equal(t, dispatcher.isRunning(), false)
 }
 
-// Test dispatch timeout, verify that Dispatcher#asyncDispatch is called when event channel is full
-// and will disappear after timeout.
+// Test backpressure: producers are blocked while the dispatcher is degraded, until the dispatch timeout passes
+// or the dispatcher has caught up.
 func TestDispatchTimeout(t *testing.T) {
 	createDispatcher()
 	defer createDispatcher()
 	// reset event channel with small capacity for testing
-	dispatcher.eventChan = make(chan events.SchedulingEvent, 1)
+	dispatcher.workers = []*worker{newWorker(1)}
 	AsyncDispatchCheckInterval = 100 * time.Millisecond
 	DispatchTimeout = 500 * time.Millisecond
+	AsyncDispatchLimit = 2
+	defer func() {
+		AsyncDispatchCheckInterval = 3 * time.Second
+	}()
 
 	// start the handler, but waiting on a flag
+	started := make(chan bool, 3)
 	RegisterEventHandler("TestAppHandler", EventTypeApp, func(obj interface{}) {
 		if appEvent, ok := obj.(TestAppEvent); ok {
-			fmt.Printf("handling %s\n", appEvent.appID)
+			started <- true
 			<-appEvent.flag
-			fmt.Printf("handling %s DONE\n", appEvent.appID)
 		}
 	})
 
 	// start the dispatcher
 	Start()
 
-	// dispatch 3 events, the third event will be dispatched asynchronously
+	// 1st event is picked up and stuck at handling, 2nd is added to the channel, 3rd to the overflow queue
 	stop := make(chan bool)
-	for i := 0; i < 3; i++ {
+	Dispatch(TestAppEvent{appID: "test-0", eventType: RunApplication, flag: stop})
+	<-started
+	for i := 1; i < 3; i++ {
 		Dispatch(TestAppEvent{
 			appID:     fmt.Sprintf("test-%d", i),
 			eventType: RunApplication,
 			flag:      stop,
 		})
 	}
-
-	// give it a small amount of time,
-	// 1st event should be picked up and stuck at handling
-	// 2nd one should be added to the channel
-	// 3rd one should be posted as an async request
-	time.Sleep(100 * time.Millisecond)
 	assert.Equal(t, asyncDispatchCount.Load(), int32(1))
+	assert.Assert(t, IsDegraded(), "dispatcher should be degraded")
 
-	// verify Dispatcher#asyncDispatch is called
-	buf := make([]byte, 1<<16)
-	runtime.Stack(buf, true)
-	assert.Assert(t, strings.Contains(string(buf), "asyncDispatch"))
+	// producers wait until the timeout
+	begin := time.Now()
+	WaitForCapacity()
+	assert.Assert(t, time.Since(begin) >= DispatchTimeout, "backpressure not applied")
+	assert.Equal(t, timedOutEventCount.Load(), int64(1))
 
-	// wait until async dispatch routine times out
+	// release the handler: the overflow queue drains and the degraded state is cleared
+	close(stop)
 	err := utils.WaitForCondition(func() bool {
-		return asyncDispatchCount.Load() == int32(0)
-	}, 100*time.Millisecond, DispatchTimeout+AsyncDispatchCheckInterval)
+		return asyncDispatchCount.Load() == int32(0) && !IsDegraded()
+	}, 10*time.Millisecond, time.Second)
 	assert.NilError(t, err)
+	begin = time.Now()
+	WaitForCapacity()
+	assert.Assert(t, time.Since(begin) < AsyncDispatchCheckInterval, "backpressure should not be applied")
 
 	// verify no left-over thread
-	buf = make([]byte, 1<<16)
+	buf := make([]byte, 1<<16)
 	runtime.Stack(buf, true)
 	assert.Assert(t, !strings.Contains(string(buf), "asyncDispatch"))
 
 	// stop the dispatcher
-	close(stop)
 	Stop()
 }
 
-// Test exceeding the async-dispatch limit, should panic immediately.
+// Test exceeding the async-dispatch limit, events are still queued and the dispatcher reports it is degraded.
 func TestExceedAsyncDispatchLimit(t *testing.T) {
 	createDispatcher()
 	defer createDispatcher()
 
 	// reset event channel with small capacity for testing
-	dispatcher.eventChan = make(chan events.SchedulingEvent, 1)
+	dispatcher.workers = []*worker{newWorker(1)}
 	AsyncDispatchLimit = 1
-	// pretend to be an time-consuming event-handler
+	recorder := &appEventsRecorder{
+		apps: make([]string, 0),
+		lock: &locking.RWMutex{},
+	}
+	started := make(chan bool, 4)
+	stop := make(chan bool)
 	RegisterEventHandler("TestAppHandler", EventTypeApp, func(obj interface{}) {
-		if _, ok := obj.(events.ApplicationEvent); ok {
-			time.Sleep(2 * time.Second)
+		if appEvent, ok := obj.(TestAppEvent); ok {
+			started <- true
+			<-appEvent.flag
+			recorder.addApp(appEvent.appID)
 		}
 	})
-	// Handle errors in defer func with recover.
-	defer func() {
-		// stop the dispatcher
-		Stop()
-		// check error
-		if err := recover(); err != nil {
-			errStr, ok := err.(error)
-			assert.Assert(t, ok, "Expected error type from panic, got %T", err)
-			assert.Assert(t, strings.Contains(errStr.Error(), "dispatcher exceeds async-dispatch limit"))
-		} else {
-			t.Error("Panic should be caught here")
-		}
-	}()
 	// start the dispatcher
 	Start()
-	// dispatch 4 events, the third and forth events will be dispatched asynchronously
-	for i := 0; i < 4; i++ {
-		Dispatch(TestAppEvent{
-			appID:     "test",
-			eventType: RunApplication,
-		})
+	defer Stop()
+
+	// dispatch 4 events, the third event is dispatched asynchronously and the fourth exceeds the limit
+	err := dispatcher.dispatch(TestAppEvent{appID: "test-0", eventType: RunApplication, flag: stop})
+	assert.NilError(t, err)
+	<-started
+	for i := 1; i < 3; i++ {
+		err = dispatcher.dispatch(TestAppEvent{appID: fmt.Sprintf("test-%d", i), eventType: RunApplication, flag: stop})
+		assert.NilError(t, err)
+	}
+	err = dispatcher.dispatch(TestAppEvent{appID: "test-3", eventType: RunApplication, flag: stop})
+	assert.NilError(t, err)
+	assert.Equal(t, overLimitEventCount.Load(), int64(1))
+	assert.Assert(t, IsDegraded(), "dispatcher should be degraded")
+	assert.Assert(t, dispatcher.isRunning(), "dispatcher should still be running")
+
+	close(stop)
+	err = utils.WaitForCondition(func() bool {
+		return recorder.size() == 4
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err)
+	assert.Assert(t, recorder.contains("test-3"), "event above the limit should be handled")
+	assert.Assert(t, !IsDegraded(), "dispatcher should have recovered")
+}
+
+// Test the queue introspection in the state and the metrics exposed by the dispatcher
+func TestDispatcherMetrics(t *testing.T) {
+	createDispatcher()
+	defer createDispatcher()
+	dispatcher.workers = []*worker{newWorker(1)}
+	AsyncDispatchLimit = 1
+
+	m := getMetrics()
+	overLimit := testutil.ToFloat64(m.overLimitEvents)
+	handled := handlingLatencyCount(t, "application")
+
+	started := make(chan bool, 3)
+	stop := make(chan bool)
+	RegisterEventHandler("TestAppHandler", EventTypeApp, func(obj interface{}) {
+		if appEvent, ok := obj.(TestAppEvent); ok {
+			started <- true
+			<-appEvent.flag
+		}
+	})
+	Start()
+	defer Stop()
+
+	state := GetState()
+	assert.Equal(t, state.QueueDepth, 0)
+	assert.Equal(t, state.OldestEventAge, int64(0))
+
+	// 1st event is stuck at handling, 2nd is in the channel, 3rd and 4th in the overflow queue, the 4th above the limit
+	Dispatch(TestAppEvent{appID: "test-0", eventType: RunApplication, flag: stop})
+	<-started
+	for i := 1; i < 4; i++ {
+		Dispatch(TestAppEvent{appID: fmt.Sprintf("test-%d", i), eventType: RunApplication, flag: stop})
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	state = GetState()
+	assert.Equal(t, state.QueueDepth, 3)
+	assert.Assert(t, state.OldestEventAge >= 20, "unexpected oldest event age: %d", state.OldestEventAge)
+	assert.Equal(t, state.OverLimitEvents, int64(1))
+	assert.Assert(t, state.Degraded, "dispatcher should be degraded")
+	assert.Equal(t, testutil.ToFloat64(m.channelDepth), float64(1))
+	assert.Equal(t, testutil.ToFloat64(m.asyncDispatchCount), float64(2))
+	assert.Assert(t, testutil.ToFloat64(m.oldestEventAge) >= 0.02)
+	assert.Equal(t, testutil.ToFloat64(m.overLimitEvents), overLimit+1)
+
+	close(stop)
+	err := utils.WaitForCondition(func() bool {
+		return GetState().QueueDepth == 0
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err)
+	err = utils.WaitForCondition(func() bool {
+		return handlingLatencyCount(t, "application") == handled+4
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err)
+	assert.Equal(t, GetState().OldestEventAge, int64(0))
+	assert.Equal(t, testutil.ToFloat64(m.channelDepth), float64(0))
+	assert.Equal(t, testutil.ToFloat64(m.oldestEventAge), float64(0))
+}
+
+// Test the graceful shutdown: queued events are handled until the timeout, the events left are reported
+func TestShutdown(t *testing.T) {
+	createDispatcher()
+	defer createDispatcher()
+	dispatcher.workers = []*worker{newWorker(1)}
+
+	recorder := &appEventsRecorder{
+		apps: make([]string, 0),
+		lock: &locking.RWMutex{},
+	}
+	started := make(chan bool, 4)
+	RegisterEventHandler("TestAppHandler", EventTypeApp, func(obj interface{}) {
+		if appEvent, ok := obj.(TestAppEvent); ok {
+			started <- true
+			<-appEvent.flag
+			recorder.addApp(appEvent.appID)
+		}
+	})
+	RegisterEventHandler("TestNodeHandler", EventTypeNode, func(obj interface{}) {})
+	Start()
+
+	// all events handled before the timeout
+	release := make(chan bool)
+	close(release)
+	for i := 0; i < 3; i++ {
+		Dispatch(TestAppEvent{appID: fmt.Sprintf("test-%d", i), eventType: RunApplication, flag: release})
+	}
+	assert.Assert(t, Drain(time.Second), "dispatcher should have drained")
+	assert.Equal(t, recorder.size(), 3)
+
+	// the 1st event is stuck at handling, the others are left when the timeout passes
+	stop := make(chan bool)
+	Dispatch(TestAppEvent{appID: "stuck", eventType: RunApplication, flag: stop})
+	<-started
+	Dispatch(TestAppEvent{appID: "queued-0", eventType: RunApplication, flag: stop})
+	Dispatch(TestAppEvent{appID: "queued-1", eventType: RunApplication, flag: stop})
+	Dispatch(TestNodeEvent{nodeID: "node-1", eventType: "NodeAccepted"})
+	report := Shutdown(50 * time.Millisecond)
+	assert.Assert(t, !report.Drained, "dispatcher should not have drained")
+	assert.DeepEqual(t, report.Unprocessed, map[string]int{"application": 2, "node": 1})
+	assert.Equal(t, GetState().QueueDepth, 0)
+	assert.Equal(t, asyncDispatchCount.Load(), int32(0))
+	assert.Assert(t, !recorder.contains("queued-0"), "event should not be handled")
+
+	// release the stuck handler and wait for the worker to exit
+	close(stop)
+	dispatcher.stopped.Wait()
+	assert.Assert(t, recorder.contains("stuck"), "in-flight event should be handled")
+}
+
+// Test the journal records the dispatched events with the result, and is exported and loaded from a file
+func TestJournal(t *testing.T) {
+	createDispatcher()
+	defer createDispatcher()
+	assert.Assert(t, GetJournal() == nil, "journal should be disabled by default")
+	dispatcher.workers = []*worker{newWorker(1)}
+	dispatcher.journal = newJournal(6)
+	AsyncDispatchLimit = 1
+
+	started := make(chan bool, 1)
+	stop := make(chan bool)
+	RegisterEventHandler("TestTaskHandler", EventTypeTask, func(obj interface{}) {
+		if taskEvent, ok := obj.(TestTaskEvent); ok && taskEvent.eventType == "Init" {
+			started <- true
+			<-stop
+		}
+	})
+	Start()
+	defer Stop()
+
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-0", eventType: "Init"})
+	<-started
+	// channel, overflow queue, coalesced and above the async-dispatch limit
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-0", eventType: "Submit"})
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-1", eventType: "Allocated", args: []interface{}{"alloc-1", int32(1)}})
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-1", eventType: "Allocated", args: []interface{}{"alloc-1", int32(1)}})
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-2", eventType: "Allocated"})
+	close(stop)
+	assert.Assert(t, dispatcher.drain(time.Second), "dispatcher did not drain")
+	RecordEvent(TestTaskEvent{appID: "app", taskID: "task-3", eventType: "Init"}, fmt.Errorf("no transition"))
+
+	journal := GetJournal()
+	assert.DeepEqual(t, journalResults(journal), []string{
+		"task-1:Allocated:coalesced",
+		"task-0:Init:handled",
+		"task-0:Submit:handled",
+		"task-1:Allocated:handled",
+		"task-2:Allocated:handled",
+		"task-3:Init:failed",
+	})
+	assert.Equal(t, journal[1].Type, "task")
+	assert.Equal(t, journal[1].ApplicationID, "app")
+	assert.Assert(t, journal[1].Duration > 0, "duration of the handling should be recorded")
+	assert.Equal(t, len(journal[3].Args), 2)
+	for i, expected := range []interface{}{"alloc-1", int32(1)} {
+		arg, err := journal[3].Args[i].Decode()
+		assert.NilError(t, err)
+		assert.Equal(t, arg, expected)
+	}
+	assert.Equal(t, journal[5].Error, "no transition")
+	assert.Equal(t, len(GetState().Journal), 6)
+
+	// the oldest entry is overwritten
+	RecordEvent(TestTaskEvent{appID: "app", taskID: "task-3", eventType: "Submit"}, nil)
+	journal = GetJournal()
+	assert.Equal(t, len(journal), 6)
+	assert.Equal(t, journalResults(journal)[0], "task-0:Init:handled")
+	assert.Equal(t, journalResults(journal)[5], "task-3:Submit:handled")
+
+	path := t.TempDir() + "/journal.json"
+	assert.NilError(t, ExportJournal(path))
+	loaded, err := LoadJournal(path)
+	assert.NilError(t, err)
+	assert.Equal(t, len(loaded), 6)
+	for i := range loaded {
+		assert.Assert(t, loaded[i].Time.Equal(journal[i].Time))
+		loaded[i].Time = journal[i].Time
+	}
+	assert.DeepEqual(t, loaded, journal)
+}
+
+// Test the arguments of the recorded events are decoded with their type
+func TestJournalArg(t *testing.T) {
+	type testArg struct {
+		Name string
+	}
+	arg := NewJournalArg(testArg{Name: "test"})
+	_, err := arg.Decode()
+	assert.ErrorContains(t, err, "unregistered type dispatcher.testArg")
+
+	RegisterJournalArgType(testArg{})
+	value, err := arg.Decode()
+	assert.NilError(t, err)
+	assert.Equal(t, value, testArg{Name: "test"})
+
+	arg.Value = []byte(`"test"`)
+	_, err = arg.Decode()
+	assert.ErrorContains(t, err, "cannot be decoded as dispatcher.testArg")
+
+	// not serializable, the string representation is kept
+	arg = NewJournalArg(make(chan int))
+	assert.Equal(t, arg.Type, "chan int")
+	_, err = arg.Decode()
+	assert.ErrorContains(t, err, "unregistered type chan int")
+}
+
+func journalResults(journal []JournalEntry) []string {
+	results := make([]string, 0, len(journal))
+	for _, entry := range journal {
+		results = append(results, entry.TaskID+":"+entry.Event+":"+entry.Result)
+	}
+	return results
+}
+
+// handlingLatencyCount returns the number of observations of the handling latency histogram for the event type
+func handlingLatencyCount(t *testing.T, eventType string) uint64 {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	assert.NilError(t, err)
+	for _, mf := range mfs {
+		if mf.GetName() != "yunikorn_k8shim_dispatcher_event_handling_latency_seconds" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "type" && label.GetValue() == eventType {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// node event for testing
+type TestNodeEvent struct {
+	nodeID    string
+	eventType string
+}
+
+func (t TestNodeEvent) GetNodeID() string {
+	return t.nodeID
+}
+
+func (t TestNodeEvent) GetEvent() string {
+	return t.eventType
+}
+
+func (t TestNodeEvent) GetArgs() []interface{} {
+	return nil
+}
+
+// task event for testing
+type TestTaskEvent struct {
+	appID     string
+	taskID    string
+	eventType string
+	args      []interface{}
+}
+
+func (t TestTaskEvent) GetApplicationID() string {
+	return t.appID
+}
+
+func (t TestTaskEvent) GetTaskID() string {
+	return t.taskID
+}
+
+func (t TestTaskEvent) GetEvent() string {
+	return t.eventType
+}
+
+func (t TestTaskEvent) GetArgs() []interface{} {
+	return t.args
+}
+
+// Test that duplicate task events waiting in the overflow queue are coalesced
+func TestCoalesceTaskEvents(t *testing.T) {
+	createDispatcher()
+	defer createDispatcher()
+	dispatcher.workers = []*worker{newWorker(1)}
+
+	lock := &locking.Mutex{}
+	handled := make([]string, 0)
+	started := make(chan bool, 1)
+	stop := make(chan bool)
+	RegisterEventHandler("TestTaskHandler", EventTypeTask, func(obj interface{}) {
+		if taskEvent, ok := obj.(TestTaskEvent); ok {
+			// block handling the first event
+			if taskEvent.eventType == "Init" {
+				started <- true
+				<-stop
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			handled = append(handled, taskEvent.taskID+":"+taskEvent.eventType)
+		}
+	})
+	Start()
+	defer Stop()
+
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-0", eventType: "Init"})
+	<-started
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-0", eventType: "Submit"})
+	// overflow queue
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-1", eventType: "Allocated", args: []interface{}{"node-1"}})
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-1", eventType: "Allocated", args: []interface{}{"node-1"}})
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-1", eventType: "Allocated", args: []interface{}{"node-2"}})
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-1", eventType: "Bound"})
+	Dispatch(TestTaskEvent{appID: "app", taskID: "task-1", eventType: "Allocated", args: []interface{}{"node-2"}})
+	assert.Equal(t, asyncDispatchCount.Load(), int32(4))
+	assert.Equal(t, coalescedEventCount.Load(), int64(1))
+
+	close(stop)
+	err := utils.WaitForCondition(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(handled) == 6
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, handled, []string{"task-0:Init", "task-0:Submit", "task-1:Allocated", "task-1:Allocated", "task-1:Bound", "task-1:Allocated"})
+}
+
+// Test that events of the same application are handled in order when the events are sharded over multiple workers
+func TestShardedDispatcherOrdering(t *testing.T) {
+	createShardedDispatcher(4)
+	defer createDispatcher()
+
+	lock := &locking.Mutex{}
+	handled := make(map[string][]string)
+	RegisterEventHandler("TestAppHandler", EventTypeApp, func(obj interface{}) {
+		if event, ok := obj.(events.ApplicationEvent); ok {
+			lock.Lock()
+			defer lock.Unlock()
+			handled[event.GetApplicationID()] = append(handled[event.GetApplicationID()], event.GetEvent())
+		}
+	})
+
+	Start()
+	numApps := 10
+	numEvents := 50
+	for i := 0; i < numEvents; i++ {
+		for j := 0; j < numApps; j++ {
+			Dispatch(TestAppEvent{
+				appID:     fmt.Sprintf("test-app-%d", j),
+				eventType: fmt.Sprintf("%d", i),
+			})
+		}
+	}
+	err := utils.WaitForCondition(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		count := 0
+		for _, appEvents := range handled {
+			count += len(appEvents)
+		}
+		return count == numApps*numEvents
+	}, 10*time.Millisecond, 5*time.Second)
+	assert.NilError(t, err, "not all events handled")
+	Stop()
+
+	assert.Equal(t, len(handled), numApps)
+	for appID, appEvents := range handled {
+		for i, eventType := range appEvents {
+			assert.Equal(t, eventType, fmt.Sprintf("%d", i), "event out of order for %s", appID)
+		}
+	}
+}
+
+// Test that a blocked handler only stalls the events of the worker it runs on
+func TestShardedDispatcherBlockedWorker(t *testing.T) {
+	createShardedDispatcher(2)
+	defer createDispatcher()
+
+	// find two applications handled by different workers
+	blockedApp := "test-app-blocked"
+	otherApp := ""
+	for i := 0; otherApp == ""; i++ {
+		appID := fmt.Sprintf("test-app-%d", i)
+		if dispatcher.getWorker(TestAppEvent{appID: appID}) != dispatcher.getWorker(TestAppEvent{appID: blockedApp}) {
+			otherApp = appID
+		}
+	}
+
+	recorder := &appEventsRecorder{
+		apps: make([]string, 0),
+		lock: &locking.RWMutex{},
+	}
+	release := make(chan bool)
+	RegisterEventHandler("TestAppHandler", EventTypeApp, func(obj interface{}) {
+		if appEvent, ok := obj.(TestAppEvent); ok {
+			if appEvent.flag != nil {
+				<-appEvent.flag
+			}
+			recorder.addApp(appEvent.appID)
+		}
+	})
+
+	Start()
+	Dispatch(TestAppEvent{appID: blockedApp, eventType: RunApplication, flag: release})
+	Dispatch(TestAppEvent{appID: otherApp, eventType: RunApplication})
+	err := utils.WaitForCondition(func() bool {
+		return recorder.contains(otherApp)
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "event of other application not handled while a worker is blocked")
+	assert.Assert(t, !recorder.contains(blockedApp), "blocked event should not have been handled")
+
+	close(release)
+	err = utils.WaitForCondition(func() bool {
+		return recorder.contains(blockedApp)
+	}, 10*time.Millisecond, time.Second)
+	assert.NilError(t, err, "blocked event not handled after release")
+	Stop()
+}
+
+func createShardedDispatcher(workers int) {
+	createDispatcher()
+	dispatcher.workers = make([]*worker, workers)
+	for i := range dispatcher.workers {
+		dispatcher.workers[i] = newWorker(1024)
 	}
 }
 
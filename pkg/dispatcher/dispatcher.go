@@ -20,6 +20,7 @@ package dispatcher
 
 import (
 	"fmt"
+	"hash/fnv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -43,37 +44,87 @@ const (
 	EventTypeNode
 )
 
+func (t EventType) String() string {
+	switch t {
+	case EventTypeApp:
+		return "application"
+	case EventTypeTask:
+		return "task"
+	case EventTypeNode:
+		return "node"
+	default:
+		return "unknown"
+	}
+}
+
+// getEventType returns the type of the scheduling event, false for unsupported events
+func getEventType(event events.SchedulingEvent) (EventType, bool) {
+	switch event.(type) {
+	case events.TaskEvent:
+		return EventTypeTask, true
+	case events.ApplicationEvent:
+		return EventTypeApp, true
+	case events.SchedulerNodeEvent:
+		return EventTypeNode, true
+	default:
+		return 0, false
+	}
+}
+
 var (
 	AsyncDispatchLimit         int32
 	AsyncDispatchCheckInterval = 3 * time.Second
 	DispatchTimeout            time.Duration
 	asyncDispatchCount         atomic.Int32 = atomic.Int32{}
+	overLimitEventCount        atomic.Int64 = atomic.Int64{}
+	coalescedEventCount        atomic.Int64 = atomic.Int64{}
+	timedOutEventCount         atomic.Int64 = atomic.Int64{}
 )
 
 // central dispatcher that dispatches scheduling events.
+// Events are sharded over the workers, each worker has its own channel and handles the events in order.
 type Dispatcher struct {
-	eventChan chan events.SchedulingEvent
-	stopChan  chan struct{}
-	handlers  map[EventType]map[string]func(interface{})
-	running   atomic.Bool
-	lock      locking.RWMutex
-	stopped   sync.WaitGroup
+	workers      []*worker
+	stopChan     chan struct{}
+	handlers     map[EventType]map[string]func(interface{})
+	running      atomic.Bool
+	inFlight     atomic.Int32 // number of events being handled
+	degraded     atomic.Bool
+	lock         locking.RWMutex
+	stopped      sync.WaitGroup
+	capacityChan chan struct{} // closed and replaced each time an event leaves an overflow queue
+	capacityLock locking.Mutex
+	journal      *journal // nil if the journal is disabled
 }
 
 func initDispatcher() {
 	eventChannelCapacity := conf.GetSchedulerConf().EventChannelCapacity
+	numWorkers := max(1, conf.GetSchedulerConf().DispatcherWorkers)
+	// the channel capacity is shared by all workers
+	workers := make([]*worker, numWorkers)
+	for i := range workers {
+		workers[i] = newWorker(max(1, eventChannelCapacity/numWorkers))
+	}
 	dispatcher = &Dispatcher{
-		eventChan: make(chan events.SchedulingEvent, eventChannelCapacity),
-		handlers:  make(map[EventType]map[string]func(interface{})),
-		stopChan:  make(chan struct{}),
-		lock:      locking.RWMutex{},
+		workers:      workers,
+		handlers:     make(map[EventType]map[string]func(interface{})),
+		stopChan:     make(chan struct{}),
+		lock:         locking.RWMutex{},
+		capacityChan: make(chan struct{}),
+		journal:      newJournal(conf.GetSchedulerConf().EventJournalSize),
 	}
 	dispatcher.setRunning(false)
 	DispatchTimeout = conf.GetSchedulerConf().DispatchTimeout
 	AsyncDispatchLimit = max(10000, int32(eventChannelCapacity/10)) //nolint:gosec
+	asyncDispatchCount.Store(0)
+	overLimitEventCount.Store(0)
+	coalescedEventCount.Store(0)
+	timedOutEventCount.Store(0)
+	getMetrics()
 
 	log.Log(log.ShimDispatcher).Info("Init dispatcher",
 		zap.Int("EventChannelCapacity", eventChannelCapacity),
+		zap.Int("Workers", numWorkers),
 		zap.Int32("AsyncDispatchLimit", AsyncDispatchLimit),
 		zap.Float64("DispatchTimeoutInSeconds", DispatchTimeout.Seconds()))
 }
@@ -133,8 +184,8 @@ func getDispatcher() *Dispatcher {
 
 // dispatches scheduler events to actual app/task handler,
 // each app/task has its own state machine and maintain their own states.
-// currently all events share same channel, so they are dispatched
-// one by one in order.
+// all events of an application and its tasks, or of a node, share the same
+// channel, so they are dispatched one by one in order.
 func Dispatch(event events.SchedulingEvent) {
 	// currently if dispatch fails, we simply log the error
 	// we may revisit this later, e.g add retry here
@@ -152,57 +203,322 @@ func (p *Dispatcher) setRunning(flag bool) {
 	p.running.Store(flag)
 }
 
+// State is the dispatcher state as reported in the state dump
+type State struct {
+	Running            bool  `json:"running"`
+	Degraded           bool  `json:"degraded"`
+	AsyncDispatchCount int32 `json:"asyncDispatchCount"`
+	AsyncDispatchLimit int32 `json:"asyncDispatchLimit"`
+	OverLimitEvents    int64 `json:"overLimitEvents"`
+	CoalescedEvents    int64 `json:"coalescedEvents"`
+	TimedOutEvents     int64 `json:"timedOutEvents"`
+	// QueueDepth is the number of events waiting in the channels and overflow queues of all workers
+	QueueDepth int `json:"queueDepth"`
+	// OldestEventAge is the time in milliseconds the oldest waiting event has been queued
+	OldestEventAge int64 `json:"oldestEventAgeMs"`
+	// Journal contains the last recorded events, if the journal is enabled
+	Journal []JournalEntry `json:"journal,omitempty"`
+}
+
+// GetState returns the current state of the dispatcher, a degraded dispatcher is reported as unhealthy
+func GetState() *State {
+	p := getDispatcher()
+	return &State{
+		Running:            p.isRunning(),
+		Degraded:           p.degraded.Load(),
+		AsyncDispatchCount: asyncDispatchCount.Load(),
+		AsyncDispatchLimit: AsyncDispatchLimit,
+		OverLimitEvents:    overLimitEventCount.Load(),
+		CoalescedEvents:    coalescedEventCount.Load(),
+		TimedOutEvents:     timedOutEventCount.Load(),
+		QueueDepth:         p.pendingEvents(),
+		OldestEventAge:     p.oldestEventAge().Milliseconds(),
+		Journal:            p.journal.list(),
+	}
+}
+
+// IsDegraded returns true if the dispatcher cannot keep up with the events and applies backpressure
+func IsDegraded() bool {
+	return getDispatcher().degraded.Load()
+}
+
+func (p *Dispatcher) setDegraded(degraded bool) {
+	if !p.degraded.CompareAndSwap(!degraded, degraded) {
+		return
+	}
+	if degraded {
+		log.Log(log.ShimDispatcher).Warn("dispatcher is degraded, applying backpressure",
+			zap.Int32("asyncDispatchCount", asyncDispatchCount.Load()),
+			zap.Int32("asyncDispatchLimit", AsyncDispatchLimit))
+	} else {
+		log.Log(log.ShimDispatcher).Info("dispatcher recovered from degraded mode")
+	}
+}
+
+// backpressureThreshold returns the number of async-dispatched events above which producers are slowed down
+func backpressureThreshold() int32 {
+	return max(1, AsyncDispatchLimit/2)
+}
+
 func (p *Dispatcher) dispatch(event events.SchedulingEvent) error {
 	if !p.isRunning() {
 		return fmt.Errorf("dispatcher is not running")
 	}
-	select {
-	case p.eventChan <- event:
-		return nil
-	default:
-		p.asyncDispatch(event)
+	w := p.getWorker(event)
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.isDuplicate(event) {
+		coalescedEventCount.Add(1)
+		getMetrics().incCoalescedEvents()
+		p.journal.record(event, JournalResultCoalesced, nil, time.Now(), 0)
+		log.Log(log.ShimDispatcher).Debug("coalesced duplicate task event")
 		return nil
 	}
+	if w.overflow.Len() > 0 || len(w.eventChan) == cap(w.eventChan) {
+		// the event ends up in the overflow queue: above the limit it is still queued, losing a state
+		// transition is worse than the memory it takes, the producers are slowed down by the backpressure
+		if asyncDispatchCount.Load() >= AsyncDispatchLimit {
+			p.setDegraded(true)
+			if overLimitEventCount.Add(1)%int64(max(1, AsyncDispatchLimit)) == 1 {
+				log.Log(log.ShimDispatcher).Warn("dispatcher exceeds async-dispatch limit, event queued",
+					zap.Int32("asyncDispatchCount", asyncDispatchCount.Load()),
+					zap.Int32("asyncDispatchLimit", AsyncDispatchLimit),
+					zap.Int64("overLimitEvents", overLimitEventCount.Load()))
+			}
+			getMetrics().incOverLimitEvents()
+		}
+	}
+	if w.push(event) {
+		p.asyncDispatch(w)
+	}
+	return nil
 }
 
-// async-dispatch try to enqueue the event in every 3 seconds util timeout,
+// async-dispatch tracks the events added to the overflow queue of a worker,
 // it's only called when event channel is full.
-func (p *Dispatcher) asyncDispatch(event events.SchedulingEvent) {
+func (p *Dispatcher) asyncDispatch(w *worker) {
 	count := asyncDispatchCount.Add(1)
-	log.Log(log.ShimDispatcher).Warn("event channel is full, transition to async-dispatch mode",
-		zap.Int32("asyncDispatchCount", count))
-	if count > AsyncDispatchLimit {
-		panic(fmt.Errorf("dispatcher exceeds async-dispatch limit"))
-	}
-	go func(beginTime time.Time, stop chan struct{}) {
-		defer asyncDispatchCount.Add(-1)
-		for p.isRunning() {
+	if w.overflow.Len() == 1 {
+		log.Log(log.ShimDispatcher).Warn("event channel is full, transition to async-dispatch mode",
+			zap.Int32("asyncDispatchCount", count))
+	}
+	if count >= backpressureThreshold() {
+		p.setDegraded(true)
+	}
+}
+
+// pump moves the events from the overflow queue of the worker into its channel, in order, until stopped
+func (p *Dispatcher) pump(w *worker, stopChan chan struct{}) {
+	defer p.stopped.Done()
+	for {
+		select {
+		case <-w.signal:
+		case <-stopChan:
+			return
+		}
+		for elem := w.front(); elem != nil; elem = w.front() {
+			event := elem.Value.(events.SchedulingEvent) //nolint:errcheck
 			select {
-			case <-stop:
-				return
-			case p.eventChan <- event:
+			case w.eventChan <- event:
+			case <-stopChan:
 				return
-			case <-time.After(AsyncDispatchCheckInterval):
-				elapseTime := time.Since(beginTime)
-				if elapseTime >= DispatchTimeout {
-					log.Log(log.ShimDispatcher).Error("dispatch timeout",
-						zap.Float64("elapseSeconds", elapseTime.Seconds()))
-					return
-				}
-				log.Log(log.ShimDispatcher).Warn("event channel is full, keep waiting...",
+			}
+			w.remove(elem)
+			if asyncDispatchCount.Add(-1) == 0 {
+				p.setDegraded(false)
+			}
+			p.notifyCapacity()
+		}
+	}
+}
+
+// notifyCapacity wakes up all producers waiting for the dispatcher to catch up
+func (p *Dispatcher) notifyCapacity() {
+	p.capacityLock.Lock()
+	defer p.capacityLock.Unlock()
+	close(p.capacityChan)
+	p.capacityChan = make(chan struct{})
+}
+
+func (p *Dispatcher) getCapacityChan() chan struct{} {
+	p.capacityLock.Lock()
+	defer p.capacityLock.Unlock()
+	return p.capacityChan
+}
+
+// WaitForCapacity applies backpressure to event producers, like the informer handlers. If the number of
+// async-dispatched events is above the threshold it blocks until the dispatcher has caught up, or at most
+// DispatchTimeout. It must not be called from an event handler as that could block the worker itself.
+func WaitForCapacity() {
+	p := getDispatcher()
+	if !p.isRunning() || asyncDispatchCount.Load() < backpressureThreshold() {
+		return
+	}
+	beginTime := time.Now()
+	for asyncDispatchCount.Load() >= backpressureThreshold() {
+		select {
+		case <-p.getCapacityChan():
+		case <-p.stopChan:
+			return
+		case <-time.After(AsyncDispatchCheckInterval):
+			elapseTime := time.Since(beginTime)
+			if elapseTime >= DispatchTimeout {
+				timedOutEventCount.Add(1)
+				getMetrics().incTimedOutEvents()
+				log.Log(log.ShimDispatcher).Warn("backpressure timeout, continuing",
 					zap.Float64("elapseSeconds", elapseTime.Seconds()))
+				return
 			}
+			log.Log(log.ShimDispatcher).Info("dispatcher is degraded, keep waiting...",
+				zap.Float64("elapseSeconds", elapseTime.Seconds()))
 		}
-	}(time.Now(), p.stopChan)
+	}
 }
 
-func (p *Dispatcher) drain() {
-	for len(p.eventChan) > 0 {
-		log.Log(log.ShimDispatcher).Info("wait dispatcher to drain",
-			zap.Int("remaining events", len(p.eventChan)))
-		time.Sleep(1 * time.Second)
+// getWorker returns the worker that handles the event. Application and task events are sharded
+// by application ID, node events by node ID.
+func (p *Dispatcher) getWorker(event events.SchedulingEvent) *worker {
+	if len(p.workers) == 1 {
+		return p.workers[0]
+	}
+	var key string
+	switch v := event.(type) {
+	case events.TaskEvent:
+		key = v.GetApplicationID()
+	case events.ApplicationEvent:
+		key = v.GetApplicationID()
+	case events.SchedulerNodeEvent:
+		key = v.GetNodeID()
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return p.workers[hash.Sum32()%uint32(len(p.workers))] //nolint:gosec
+}
+
+// pendingEvents returns the number of events waiting in the channels and overflow queues of all workers
+func (p *Dispatcher) pendingEvents() int {
+	pending := 0
+	for _, w := range p.workers {
+		pending += w.pending()
+	}
+	return pending
+}
+
+// channelDepth returns the number of events waiting in the channels of all workers
+func (p *Dispatcher) channelDepth() int {
+	depth := 0
+	for _, w := range p.workers {
+		depth += w.depth()
+	}
+	return depth
+}
+
+// oldestEventAge returns how long the oldest event not yet handled has been waiting, 0 if no events are waiting
+func (p *Dispatcher) oldestEventAge() time.Duration {
+	var oldest time.Time
+	for _, w := range p.workers {
+		if enqueued, ok := w.oldestEnqueueTime(); ok && (oldest.IsZero() || enqueued.Before(oldest)) {
+			oldest = enqueued
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return time.Since(oldest)
+}
+
+// drain waits until all queued and in-flight events are handled, or the timeout has passed.
+// Returns false if there were still events left when the timeout passed.
+func (p *Dispatcher) drain(timeout time.Duration) bool {
+	beginTime := time.Now()
+	var lastLog time.Time
+	for p.pendingEvents() > 0 || p.inFlight.Load() > 0 {
+		if time.Since(beginTime) >= timeout {
+			log.Log(log.ShimDispatcher).Warn("dispatcher did not drain in time",
+				zap.Int("remaining events", p.pendingEvents()),
+				zap.Int32("in-flight events", p.inFlight.Load()),
+				zap.Duration("timeout", timeout))
+			return false
+		}
+		if time.Since(lastLog) >= time.Second {
+			log.Log(log.ShimDispatcher).Info("wait dispatcher to drain",
+				zap.Int("remaining events", p.pendingEvents()))
+			lastLog = time.Now()
+		}
+		time.Sleep(10 * time.Millisecond)
 	}
 	log.Log(log.ShimDispatcher).Info("dispatcher is draining out")
+	return true
+}
+
+// discardPending removes all events left in the channels and overflow queues of the workers, and returns the number
+// of removed events per event type. Must only be called after the dispatcher has stopped.
+func (p *Dispatcher) discardPending() map[string]int {
+	discarded := make(map[string]int)
+	count := func(event interface{}) {
+		if schedulingEvent, ok := event.(events.SchedulingEvent); ok {
+			if eventType, ok := getEventType(schedulingEvent); ok {
+				discarded[eventType.String()]++
+			}
+			p.journal.record(schedulingEvent, JournalResultDiscarded, nil, time.Now(), 0)
+		}
+	}
+	for _, w := range p.workers {
+		for empty := false; !empty; {
+			select {
+			case event := <-w.eventChan:
+				count(event)
+			default:
+				empty = true
+			}
+		}
+		for elem := w.front(); elem != nil; elem = w.front() {
+			count(elem.Value)
+			w.remove(elem)
+			asyncDispatchCount.Add(-1)
+		}
+		w.lock.Lock()
+		w.enqueued.Init()
+		w.lock.Unlock()
+	}
+	return discarded
+}
+
+// Drain waits until all queued and in-flight events are handled, or the timeout has passed.
+// Returns false if there were still events left when the timeout passed.
+func Drain(timeout time.Duration) bool {
+	p := getDispatcher()
+	if !p.isRunning() {
+		return p.pendingEvents() == 0
+	}
+	return p.drain(timeout)
+}
+
+// ShutdownReport describes the result of a graceful shutdown of the dispatcher
+type ShutdownReport struct {
+	// Drained is true if all events were handled before the dispatcher was stopped
+	Drained bool
+	// Unprocessed is the number of events, per event type, discarded when the dispatcher was stopped
+	Unprocessed map[string]int
+}
+
+// Shutdown drains the dispatcher and then stops it. The queued events are handled until the dispatcher is empty or
+// the timeout has passed, events left after that are discarded and reported.
+// The producers of events should be stopped before calling Shutdown, events dispatched by the handlers while
+// draining are still accepted.
+func Shutdown(timeout time.Duration) *ShutdownReport {
+	p := getDispatcher()
+	report := &ShutdownReport{}
+	if p.isRunning() {
+		report.Drained = p.drain(timeout)
+	}
+	Stop()
+	report.Unprocessed = p.discardPending()
+	if report.Drained && len(report.Unprocessed) > 0 {
+		// events dispatched between the drain and the stop
+		report.Drained = false
+	}
+	return report
 }
 
 func Start() {
@@ -212,31 +528,38 @@ func Start() {
 		return
 	}
 	getDispatcher().stopChan = make(chan struct{})
-	getDispatcher().stopped.Add(1)
-	go func() {
-		for {
-			select {
-			case event := <-getDispatcher().eventChan:
-				switch v := event.(type) {
-				case events.TaskEvent:
-					getEventHandler(EventTypeTask)(v)
-				case events.ApplicationEvent:
-					getEventHandler(EventTypeApp)(v)
-				case events.SchedulerNodeEvent:
-					getEventHandler(EventTypeNode)(v)
-				default:
-					log.Log(log.ShimDispatcher).Fatal("unsupported event",
-						zap.Any("event", v))
-				}
-			case <-getDispatcher().stopChan:
-				log.Log(log.ShimDispatcher).Info("shutting down event channel")
-				getDispatcher().setRunning(false)
-				getDispatcher().stopped.Done()
-				return
+	for _, w := range getDispatcher().workers {
+		getDispatcher().stopped.Add(2)
+		go getDispatcher().run(w, getDispatcher().stopChan)
+		go getDispatcher().pump(w, getDispatcher().stopChan)
+	}
+	getDispatcher().setRunning(true)
+}
+
+// run is the event loop of a worker, the events of the channel are handled one by one in order
+func (p *Dispatcher) run(w *worker, stopChan chan struct{}) {
+	for {
+		select {
+		case event := <-w.eventChan:
+			p.inFlight.Add(1)
+			w.handled()
+			eventType, ok := getEventType(event)
+			if !ok {
+				log.Log(log.ShimDispatcher).Fatal("unsupported event",
+					zap.Any("event", event))
 			}
+			start := time.Now()
+			getEventHandler(eventType)(event)
+			getMetrics().observeHandlingLatency(eventType, start)
+			p.journal.record(event, JournalResultHandled, nil, start, time.Since(start))
+			p.inFlight.Add(-1)
+		case <-stopChan:
+			log.Log(log.ShimDispatcher).Info("shutting down event channel")
+			p.setRunning(false)
+			p.stopped.Done()
+			return
 		}
-	}()
-	getDispatcher().setRunning(true)
+	}
 }
 
 // stop the dispatcher and wait at most 5 seconds gracefully
@@ -262,9 +585,10 @@ func Stop() {
 	close(getDispatcher().stopChan)
 	stopWait := make(chan struct{})
 
+	p := getDispatcher()
 	go func() {
 		defer close(stopWait)
-		getDispatcher().stopped.Wait()
+		p.stopped.Wait()
 	}()
 
 	// wait until the main event loop stops properly
@@ -0,0 +1,221 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/yunikorn-k8shim/pkg/locking"
+)
+
+// results of the events recorded in the journal
+const (
+	JournalResultHandled   = "handled"   // handled by the event handlers
+	JournalResultFailed    = "failed"    // handled outside the dispatcher, the state transition failed
+	JournalResultCoalesced = "coalesced" // not handled, duplicate of a queued event
+	JournalResultDiscarded = "discarded" // not handled, left in the queue when the dispatcher stopped
+)
+
+// JournalEntry is a scheduling event recorded in the journal
+type JournalEntry struct {
+	Time          time.Time     `json:"time"`
+	Type          string        `json:"type"`
+	Event         string        `json:"event"`
+	ApplicationID string        `json:"applicationID,omitempty"`
+	TaskID        string        `json:"taskID,omitempty"`
+	NodeID        string        `json:"nodeID,omitempty"`
+	Args          []JournalArg  `json:"args,omitempty"`
+	Result        string        `json:"result"`
+	Error         string        `json:"error,omitempty"`
+	Duration      time.Duration `json:"duration,omitempty"`
+}
+
+// JournalArg is an argument of a recorded event with its type, to rebuild the argument when the event is replayed
+type JournalArg struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// types of the arguments which can be decoded, by the name of the type
+var (
+	journalArgTypes = map[string]reflect.Type{}
+	journalArgLock  locking.RWMutex
+)
+
+func init() {
+	for _, example := range []interface{}{"", false, 0, int32(0), int64(0), float64(0)} {
+		RegisterJournalArgType(example)
+	}
+}
+
+// RegisterJournalArgType registers the type of the example value, recorded arguments of the registered types can
+// be decoded. Strings and the basic numeric types are registered by default.
+func RegisterJournalArgType(example interface{}) {
+	argType := reflect.TypeOf(example)
+	journalArgLock.Lock()
+	defer journalArgLock.Unlock()
+	journalArgTypes[argType.String()] = argType
+}
+
+// NewJournalArg records the argument as JSON together with its type
+func NewJournalArg(arg interface{}) JournalArg {
+	value, err := json.Marshal(arg)
+	if err != nil {
+		// cannot be decoded, keep the string representation for the reader of the journal
+		value, _ = json.Marshal(fmt.Sprint(arg)) //nolint:errcheck
+	}
+	return JournalArg{
+		Type:  fmt.Sprintf("%T", arg),
+		Value: value,
+	}
+}
+
+// Decode returns the argument as a value of the recorded type, the type must have been registered
+func (a JournalArg) Decode() (interface{}, error) {
+	journalArgLock.RLock()
+	argType, ok := journalArgTypes[a.Type]
+	journalArgLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("journal argument has unregistered type %s", a.Type)
+	}
+	value := reflect.New(argType)
+	if err := json.Unmarshal(a.Value, value.Interface()); err != nil {
+		return nil, fmt.Errorf("journal argument cannot be decoded as %s: %w", a.Type, err)
+	}
+	return value.Elem().Interface(), nil
+}
+
+// journal keeps the last recorded events in a ring buffer
+type journal struct {
+	entries []JournalEntry
+	next    int
+	full    bool
+	lock    locking.Mutex
+}
+
+func newJournal(size int) *journal {
+	if size <= 0 {
+		return nil
+	}
+	return &journal{
+		entries: make([]JournalEntry, size),
+	}
+}
+
+// record adds the event to the journal, overwriting the oldest entry if the journal is full
+func (j *journal) record(event events.SchedulingEvent, result string, err error, start time.Time, duration time.Duration) {
+	if j == nil {
+		return
+	}
+	entry := newJournalEntry(event)
+	entry.Time = start
+	entry.Result = result
+	entry.Duration = duration
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.entries[j.next] = entry
+	j.next = (j.next + 1) % len(j.entries)
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+// list returns the recorded entries, oldest first
+func (j *journal) list() []JournalEntry {
+	if j == nil {
+		return nil
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if !j.full {
+		return append([]JournalEntry(nil), j.entries[:j.next]...)
+	}
+	entries := make([]JournalEntry, 0, len(j.entries))
+	entries = append(entries, j.entries[j.next:]...)
+	return append(entries, j.entries[:j.next]...)
+}
+
+func newJournalEntry(event events.SchedulingEvent) JournalEntry {
+	entry := JournalEntry{}
+	if eventType, ok := getEventType(event); ok {
+		entry.Type = eventType.String()
+	}
+	switch v := event.(type) {
+	case events.TaskEvent:
+		entry.Event = v.GetEvent()
+		entry.ApplicationID = v.GetApplicationID()
+		entry.TaskID = v.GetTaskID()
+	case events.ApplicationEvent:
+		entry.Event = v.GetEvent()
+		entry.ApplicationID = v.GetApplicationID()
+	case events.SchedulerNodeEvent:
+		entry.Event = v.GetEvent()
+		entry.NodeID = v.GetNodeID()
+	}
+	for _, arg := range event.GetArgs() {
+		entry.Args = append(entry.Args, NewJournalArg(arg))
+	}
+	return entry
+}
+
+// RecordEvent adds an event which was handled outside the dispatcher to the journal, if the journal is enabled.
+// Events that move an application or task to the next state in sync mode should be recorded to be able to replay
+// the state transitions from the journal.
+func RecordEvent(event events.SchedulingEvent, err error) {
+	result := JournalResultHandled
+	if err != nil {
+		result = JournalResultFailed
+	}
+	getDispatcher().journal.record(event, result, err, time.Now(), 0)
+}
+
+// GetJournal returns the events recorded in the journal, oldest first. Returns nil if the journal is disabled.
+func GetJournal() []JournalEntry {
+	return getDispatcher().journal.list()
+}
+
+// ExportJournal writes the events recorded in the journal as JSON to the file
+func ExportJournal(path string) error {
+	data, err := json.MarshalIndent(GetJournal(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// LoadJournal reads the events from a file written by ExportJournal, or saved from the journal endpoint of the shim
+func LoadJournal(path string) ([]JournalEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []JournalEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,156 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+)
+
+const (
+	// Namespace for all metrics of the scheduler, shared with the core
+	Namespace = constants.MetricsNamespace
+	// DispatcherSubsystem - subsystem name used by the shim dispatcher
+	DispatcherSubsystem = "k8shim_dispatcher"
+)
+
+var metrics *dispatcherMetrics
+var metricsOnce sync.Once
+
+// dispatcherMetrics are registered on the default prometheus registry, which is exposed by the REST service of the core
+type dispatcherMetrics struct {
+	channelDepth        prometheus.GaugeFunc
+	asyncDispatchCount  prometheus.GaugeFunc
+	oldestEventAge      prometheus.GaugeFunc
+	handlingLatency     *prometheus.HistogramVec
+	overLimitEvents     prometheus.Counter
+	timedOutEvents      prometheus.Counter
+	coalescedEvents     prometheus.Counter
+	handlingLatencyType map[EventType]prometheus.Observer
+}
+
+func getMetrics() *dispatcherMetrics {
+	metricsOnce.Do(initMetrics)
+	return metrics
+}
+
+func initMetrics() {
+	m := &dispatcherMetrics{}
+	m.channelDepth = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: DispatcherSubsystem,
+			Name:      "channel_depth",
+			Help:      "Number of events waiting in the event channels of the dispatcher.",
+		}, func() float64 {
+			return float64(getDispatcher().channelDepth())
+		})
+	m.asyncDispatchCount = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: DispatcherSubsystem,
+			Name:      "async_dispatch_count",
+			Help:      "Number of events waiting in the overflow queues of the dispatcher (async-dispatch).",
+		}, func() float64 {
+			return float64(asyncDispatchCount.Load())
+		})
+	m.oldestEventAge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: DispatcherSubsystem,
+			Name:      "oldest_event_age_seconds",
+			Help:      "Age of the oldest event waiting to be handled by the dispatcher, 0 if no events are waiting.",
+		}, func() float64 {
+			return getDispatcher().oldestEventAge().Seconds()
+		})
+	m.handlingLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: DispatcherSubsystem,
+			Name:      "event_handling_latency_seconds",
+			Help:      "Latency of handling a dispatched event, by event type.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10), // 0.1ms - 26s
+		}, []string{"type"})
+	m.overLimitEvents = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: DispatcherSubsystem,
+			Name:      "over_limit_events_total",
+			Help:      "Total number of events queued while the async-dispatch limit was exceeded.",
+		})
+	m.timedOutEvents = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: DispatcherSubsystem,
+			Name:      "timed_out_events_total",
+			Help:      "Total number of events for which the backpressure wait exceeded the dispatch timeout.",
+		})
+	m.coalescedEvents = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: DispatcherSubsystem,
+			Name:      "coalesced_events_total",
+			Help:      "Total number of duplicate task events coalesced in the overflow queues.",
+		})
+	m.handlingLatencyType = map[EventType]prometheus.Observer{
+		EventTypeApp:  m.handlingLatency.WithLabelValues(EventTypeApp.String()),
+		EventTypeTask: m.handlingLatency.WithLabelValues(EventTypeTask.String()),
+		EventTypeNode: m.handlingLatency.WithLabelValues(EventTypeNode.String()),
+	}
+
+	for _, collector := range []prometheus.Collector{
+		m.channelDepth,
+		m.asyncDispatchCount,
+		m.oldestEventAge,
+		m.handlingLatency,
+		m.overLimitEvents,
+		m.timedOutEvents,
+		m.coalescedEvents,
+	} {
+		if err := prometheus.Register(collector); err != nil {
+			log.Log(log.ShimDispatcher).Warn("failed to register dispatcher metrics",
+				zap.Error(err))
+		}
+	}
+	metrics = m
+}
+
+func (m *dispatcherMetrics) observeHandlingLatency(eventType EventType, start time.Time) {
+	if observer, ok := m.handlingLatencyType[eventType]; ok {
+		observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *dispatcherMetrics) incOverLimitEvents() {
+	m.overLimitEvents.Inc()
+}
+
+func (m *dispatcherMetrics) incTimedOutEvents() {
+	m.timedOutEvents.Inc()
+}
+
+func (m *dispatcherMetrics) incCoalescedEvents() {
+	m.coalescedEvents.Inc()
+}
@@ -0,0 +1,149 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"container/list"
+	"reflect"
+	"time"
+
+	"github.com/apache/yunikorn-k8shim/pkg/common/events"
+	"github.com/apache/yunikorn-k8shim/pkg/locking"
+)
+
+// worker handles the events of one shard in order. Events that do not fit in the event channel wait in the
+// overflow queue and are moved into the channel, in order, by the pump of the worker.
+type worker struct {
+	eventChan chan events.SchedulingEvent
+	overflow  *list.List
+	lastTask  map[string]*list.Element // last queued overflow event per task, used for coalescing
+	enqueued  *list.List               // enqueue times of the events not yet handled, in order
+	signal    chan struct{}
+	lock      locking.Mutex
+}
+
+func newWorker(capacity int) *worker {
+	return &worker{
+		eventChan: make(chan events.SchedulingEvent, capacity),
+		overflow:  list.New(),
+		lastTask:  make(map[string]*list.Element),
+		enqueued:  list.New(),
+		signal:    make(chan struct{}, 1),
+	}
+}
+
+// push adds the event to the channel, or to the overflow queue if the channel is full or other events are already
+// waiting in the queue. Returns true if the event was added to the overflow queue.
+// Should be called holding the worker lock.
+func (w *worker) push(event events.SchedulingEvent) bool {
+	w.enqueued.PushBack(time.Now())
+	if w.overflow.Len() == 0 {
+		select {
+		case w.eventChan <- event:
+			return false
+		default:
+		}
+	}
+	elem := w.overflow.PushBack(event)
+	if key := taskKey(event); key != "" {
+		w.lastTask[key] = elem
+	}
+	// wake up the pump
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// isDuplicate returns true if the event is a task event which is the same as the last event queued in the overflow
+// queue for the task. Such a duplicate state transition does not need to be handled twice.
+// Should be called holding the worker lock.
+func (w *worker) isDuplicate(event events.SchedulingEvent) bool {
+	key := taskKey(event)
+	if key == "" {
+		return false
+	}
+	elem, ok := w.lastTask[key]
+	if !ok {
+		return false
+	}
+	last, ok := elem.Value.(events.TaskEvent)
+	if !ok {
+		return false
+	}
+	taskEvent, ok := event.(events.TaskEvent)
+	return ok && last.GetEvent() == taskEvent.GetEvent() && reflect.DeepEqual(last.GetArgs(), taskEvent.GetArgs())
+}
+
+// front returns the oldest event in the overflow queue, nil if the queue is empty
+func (w *worker) front() *list.Element {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.overflow.Front()
+}
+
+// remove removes the event from the overflow queue after it was moved into the channel
+func (w *worker) remove(elem *list.Element) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.overflow.Remove(elem)
+	if key := taskKey(elem.Value); key != "" && w.lastTask[key] == elem {
+		delete(w.lastTask, key)
+	}
+}
+
+// handled removes the enqueue time of the oldest event after it was taken from the channel
+func (w *worker) handled() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if front := w.enqueued.Front(); front != nil {
+		w.enqueued.Remove(front)
+	}
+}
+
+// oldestEnqueueTime returns the enqueue time of the oldest event not yet handled, false if there is none
+func (w *worker) oldestEnqueueTime() (time.Time, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if front := w.enqueued.Front(); front != nil {
+		return front.Value.(time.Time), true //nolint:errcheck
+	}
+	return time.Time{}, false
+}
+
+// depth returns the number of events in the channel
+func (w *worker) depth() int {
+	return len(w.eventChan)
+}
+
+// pending returns the number of events in the channel and the overflow queue
+func (w *worker) pending() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.eventChan) + w.overflow.Len()
+}
+
+// taskKey returns the key used for coalescing task events, empty for all other events
+func taskKey(event interface{}) string {
+	if taskEvent, ok := event.(events.TaskEvent); ok {
+		return taskEvent.GetApplicationID() + "/" + taskEvent.GetTaskID()
+	}
+	return ""
+}
@@ -27,6 +27,7 @@ import (
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/component-base/config/v1alpha1"
 	"k8s.io/klog/v2"
@@ -40,6 +41,7 @@ import (
 	fwruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 	"k8s.io/kubernetes/pkg/scheduler/metrics"
 
+	"github.com/apache/yunikorn-k8shim/pkg/locking"
 	"github.com/apache/yunikorn-k8shim/pkg/log"
 )
 
@@ -47,18 +49,37 @@ type PredicateManager interface {
 	EventsToRegister(queueingHintFn framework.QueueingHintFn) []framework.ClusterEventWithHint
 	Predicates(pod *v1.Pod, node *framework.NodeInfo, allocate bool) (plugin string, error error)
 	PreemptionPredicates(pod *v1.Pod, node *framework.NodeInfo, victims []*v1.Pod, startIndex int) (index int)
+	ReservePod(pod *v1.Pod, node *framework.NodeInfo) (plugin string, error error)
+	PreBindPod(pod *v1.Pod) (plugin string, error error)
+	UnreservePod(pod *v1.Pod)
 }
 
 var _ PredicateManager = &predicateManagerImpl{}
 
 var configDecoder = scheme.Codecs.UniversalDecoder()
 
+// bindPluginNames are the plugins that reserve and bind resources for the pod on the node that the shim does not
+// handle itself. Volumes are bound by the volume binder of the shim.
+var bindPluginNames = map[string]bool{
+	names.DynamicResources: true,
+}
+
 type predicateManagerImpl struct {
 	reservationPreFilters *[]framework.PreFilterPlugin
 	allocationPreFilters  *[]framework.PreFilterPlugin
 	reservationFilters    *[]framework.FilterPlugin
 	allocationFilters     *[]framework.FilterPlugin
+	bindPlugins           *[]framework.Plugin
+	reservations          map[types.UID]*reservation
 	klogger               klog.Logger
+	lock                  locking.Mutex
+}
+
+// reservation is the cycle state of the bind plugins for a pod, kept from Reserve until PreBind or Unreserve
+type reservation struct {
+	state    *framework.CycleState
+	nodeName string
+	plugins  []framework.ReservePlugin
 }
 
 func (p *predicateManagerImpl) EventsToRegister(queueingHintFn framework.QueueingHintFn) []framework.ClusterEventWithHint {
@@ -274,6 +295,100 @@ func (p *predicateManagerImpl) runFilterPlugin(ctx context.Context, pl framework
 	return pl.Filter(ctx, state, pod, nodeInfo)
 }
 
+// ReservePod runs the bind plugins for the pod on the node up to the Reserve extension point. For the DynamicResources
+// plugin this allocates the devices of the ResourceClaims of the pod in memory, which makes them unavailable to other
+// pods. The reservation is kept until PreBindPod or UnreservePod is called for the pod.
+func (p *predicateManagerImpl) ReservePod(pod *v1.Pod, node *framework.NodeInfo) (string, error) {
+	if len(*p.bindPlugins) == 0 {
+		return "", nil
+	}
+	ctx := context.Background()
+	res := &reservation{
+		state:    framework.NewCycleState(),
+		nodeName: node.Node().Name,
+	}
+	for _, pl := range *p.bindPlugins {
+		if preFilter, ok := pl.(framework.PreFilterPlugin); ok {
+			_, status := preFilter.PreFilter(ctx, res.state, pod)
+			if status.IsSkip() {
+				continue
+			}
+			if !status.IsSuccess() {
+				p.unreserve(ctx, res, pod)
+				return pl.Name(), errors.New(status.Message())
+			}
+		}
+		if filter, ok := pl.(framework.FilterPlugin); ok {
+			if status := filter.Filter(ctx, res.state, pod, node); !status.IsSuccess() {
+				p.unreserve(ctx, res, pod)
+				return pl.Name(), errors.New(status.Message())
+			}
+		}
+		if reserve, ok := pl.(framework.ReservePlugin); ok {
+			// a plugin that fails to reserve must still be unreserved
+			res.plugins = append(res.plugins, reserve)
+			if status := reserve.Reserve(ctx, res.state, pod, res.nodeName); !status.IsSuccess() {
+				p.unreserve(ctx, res, pod)
+				return pl.Name(), errors.New(status.Message())
+			}
+		}
+	}
+	if len(res.plugins) == 0 {
+		return "", nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.reservations[pod.UID] = res
+	return "", nil
+}
+
+// PreBindPod runs the PreBind extension point of the bind plugins that reserved resources for the pod. For the
+// DynamicResources plugin this writes the allocation and the reservation of the ResourceClaims of the pod. The
+// reservation is undone if a plugin fails.
+func (p *predicateManagerImpl) PreBindPod(pod *v1.Pod) (string, error) {
+	res := p.removeReservation(pod.UID)
+	if res == nil {
+		return "", nil
+	}
+	ctx := context.Background()
+	for _, pl := range res.plugins {
+		preBind, ok := pl.(framework.PreBindPlugin)
+		if !ok {
+			continue
+		}
+		if status := preBind.PreBind(ctx, res.state, pod, res.nodeName); !status.IsSuccess() {
+			p.unreserve(ctx, res, pod)
+			return pl.Name(), errors.New(status.Message())
+		}
+	}
+	return "", nil
+}
+
+// UnreservePod undoes the reservation of the bind plugins for the pod, if the pod has one
+func (p *predicateManagerImpl) UnreservePod(pod *v1.Pod) {
+	if res := p.removeReservation(pod.UID); res != nil {
+		p.unreserve(context.Background(), res, pod)
+	}
+}
+
+func (p *predicateManagerImpl) removeReservation(uid types.UID) *reservation {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	res, ok := p.reservations[uid]
+	if !ok {
+		return nil
+	}
+	delete(p.reservations, uid)
+	return res
+}
+
+// unreserve runs the Unreserve extension point of the plugins in the reverse order of the reservation
+func (p *predicateManagerImpl) unreserve(ctx context.Context, res *reservation, pod *v1.Pod) {
+	for i := len(res.plugins) - 1; i >= 0; i-- {
+		res.plugins[i].Unreserve(ctx, res.state, pod, res.nodeName)
+	}
+}
+
 // EnableOptionalKubernetesFeatureGates ensures that any optional Kubernetes feature gates that YuniKorn supports are
 // enabled. Currently, as of Kubernetes 1.32, this includes PodLevelResources and InPlacePodVerticalScaling. These are
 // both safe to enable as part of our default configuration, as they also require the appropriate feature gates to be
@@ -295,6 +410,16 @@ func EnableOptionalKubernetesFeatureGates() {
 	}
 }
 
+// EnableDynamicResourceAllocationFeatureGate enables the DynamicResourceAllocation feature gate, which turns on the
+// DynamicResources plugin. This is opt-in as the plugin requires the resource.k8s.io API to be served by the API server.
+// This needs to be called before the predicate manager is created.
+func EnableDynamicResourceAllocationFeatureGate() {
+	log.Log(log.ShimPredicates).Debug("Enabling DynamicResourceAllocation feature gate")
+	if err := feature.DefaultMutableFeatureGate.Set(fmt.Sprintf("%s=true", features.DynamicResourceAllocation)); err != nil {
+		log.Log(log.ShimPredicates).Fatal("Unable to set DynamicResourceAllocation feature gate", zap.Error(err))
+	}
+}
+
 func NewPredicateManager(handle framework.Handle) PredicateManager {
 	/*
 		Default K8S plugins as of 1.32 that implement PreFilter:
@@ -307,6 +432,7 @@ func NewPredicateManager(handle framework.Handle) PredicateManager {
 			VolumeZone
 			PodTopologySpread
 			InterPodAffinity
+			DynamicResources (only if the DynamicResourceAllocation feature gate is enabled)
 	*/
 
 	// run only the simpler PreFilter plugins during reservation phase
@@ -320,6 +446,7 @@ func NewPredicateManager(handle framework.Handle) PredicateManager {
 		// VolumeRestrictions
 		// VolumeBinding
 		// VolumeZone
+		// DynamicResources
 	}
 
 	// run all PreFilter plugins during allocation phase
@@ -341,6 +468,7 @@ func NewPredicateManager(handle framework.Handle) PredicateManager {
 			VolumeZone
 			PodTopologySpread
 			InterPodAffinity
+			DynamicResources (only if the DynamicResourceAllocation feature gate is enabled)
 	*/
 
 	// run only the simpler Filter plugins during reservation phase
@@ -357,6 +485,7 @@ func NewPredicateManager(handle framework.Handle) PredicateManager {
 		// NodeVolumeLimits
 		// VolumeBinding
 		// VolumeZone
+		// DynamicResources
 	}
 
 	// run all Filter plugins during allocation phase
@@ -397,17 +526,21 @@ func newPredicateManagerInternal(
 	allocPre := make([]framework.Plugin, 0)
 	resFilt := make([]framework.Plugin, 0)
 	allocFilt := make([]framework.Plugin, 0)
+	bind := make([]framework.Plugin, 0)
 
 	addPlugins("PreFilter", createdPlugins, &resPre, reservationPreFilters)
 	addPlugins("PreFilter", createdPlugins, &allocPre, allocationPreFilters)
 	addPlugins("Filter", createdPlugins, &resFilt, reservationFilters)
 	addPlugins("Filter", createdPlugins, &allocFilt, allocationFilters)
+	addPlugins("Reserve", createdPlugins, &bind, bindPluginNames)
 
 	pm := &predicateManagerImpl{
 		reservationPreFilters: preFilterPlugins(resPre),
 		allocationPreFilters:  preFilterPlugins(allocPre),
 		reservationFilters:    filterPlugins(resFilt),
 		allocationFilters:     filterPlugins(allocFilt),
+		bindPlugins:           &bind,
+		reservations:          make(map[types.UID]*reservation),
 		klogger:               klog.NewKlogr(),
 	}
 
@@ -19,6 +19,7 @@
 package predicates
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"strings"
@@ -29,6 +30,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -2317,3 +2319,111 @@ func (s *storageListerMock) IsPVCUsedByPods(key string) bool {
 var _ framework.SharedLister = &sharedListerMock{}
 var _ framework.NodeInfoLister = &nodeListerMock{}
 var _ framework.StorageInfoLister = &storageListerMock{}
+
+type fakeBindPlugin struct {
+	calls      []string
+	failFilter bool
+	failBind   bool
+}
+
+func (f *fakeBindPlugin) Name() string {
+	return "FakeBind"
+}
+
+func (f *fakeBindPlugin) PreFilter(_ context.Context, _ *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
+	f.calls = append(f.calls, "PreFilter")
+	if len(pod.Spec.ResourceClaims) == 0 {
+		return nil, framework.NewStatus(framework.Skip)
+	}
+	return nil, nil
+}
+
+func (f *fakeBindPlugin) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+func (f *fakeBindPlugin) Filter(_ context.Context, _ *framework.CycleState, _ *v1.Pod, _ *framework.NodeInfo) *framework.Status {
+	f.calls = append(f.calls, "Filter")
+	if f.failFilter {
+		return framework.NewStatus(framework.Unschedulable, "no devices")
+	}
+	return nil
+}
+
+func (f *fakeBindPlugin) Reserve(_ context.Context, _ *framework.CycleState, _ *v1.Pod, nodeName string) *framework.Status {
+	f.calls = append(f.calls, "Reserve:"+nodeName)
+	return nil
+}
+
+func (f *fakeBindPlugin) Unreserve(_ context.Context, _ *framework.CycleState, _ *v1.Pod, nodeName string) {
+	f.calls = append(f.calls, "Unreserve:"+nodeName)
+}
+
+func (f *fakeBindPlugin) PreBind(_ context.Context, _ *framework.CycleState, _ *v1.Pod, nodeName string) *framework.Status {
+	f.calls = append(f.calls, "PreBind:"+nodeName)
+	if f.failBind {
+		return framework.NewStatus(framework.Error, "claim update failed")
+	}
+	return nil
+}
+
+func TestReservePod(t *testing.T) {
+	plugin := &fakeBindPlugin{}
+	pm := &predicateManagerImpl{
+		bindPlugins:  &[]framework.Plugin{plugin},
+		reservations: make(map[types.UID]*reservation),
+	}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(node)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", UID: "uid-1"},
+		Spec:       v1.PodSpec{ResourceClaims: []v1.PodResourceClaim{{Name: "gpu"}}},
+	}
+
+	// pods without claims are skipped
+	_, err := pm.ReservePod(&v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "uid-0"}}, nodeInfo)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pm.reservations), 0)
+
+	// reserve and bind
+	plugin.calls = nil
+	_, err = pm.ReservePod(pod, nodeInfo)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pm.reservations), 1)
+	_, err = pm.PreBindPod(pod)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pm.reservations), 0)
+	assert.DeepEqual(t, plugin.calls, []string{"PreFilter", "Filter", "Reserve:node-1", "PreBind:node-1"})
+
+	// a pod without reservation is not bound
+	plugin.calls = nil
+	_, err = pm.PreBindPod(pod)
+	assert.NilError(t, err)
+	assert.Equal(t, len(plugin.calls), 0)
+
+	// a failed bind undoes the reservation
+	plugin.failBind = true
+	_, err = pm.ReservePod(pod, nodeInfo)
+	assert.NilError(t, err)
+	name, err := pm.PreBindPod(pod)
+	assert.ErrorContains(t, err, "claim update failed")
+	assert.Equal(t, name, "FakeBind")
+	assert.DeepEqual(t, plugin.calls, []string{"PreFilter", "Filter", "Reserve:node-1", "PreBind:node-1", "Unreserve:node-1"})
+
+	// forgetting the pod undoes the reservation
+	plugin.calls = nil
+	_, err = pm.ReservePod(pod, nodeInfo)
+	assert.NilError(t, err)
+	pm.UnreservePod(pod)
+	assert.Equal(t, len(pm.reservations), 0)
+	assert.DeepEqual(t, plugin.calls, []string{"PreFilter", "Filter", "Reserve:node-1", "Unreserve:node-1"})
+
+	// nothing is reserved if the node does not fit
+	plugin.calls = nil
+	plugin.failFilter = true
+	_, err = pm.ReservePod(pod, nodeInfo)
+	assert.ErrorContains(t, err, "no devices")
+	assert.Equal(t, len(pm.reservations), 0)
+	assert.DeepEqual(t, plugin.calls, []string{"PreFilter", "Filter"})
+}
@@ -290,6 +290,7 @@ func NewSchedulerPlugin(_ context.Context, _ runtime.Object, handle framework.Ha
 	// we need our own informer factory here because the informers we get from the framework handle aren't yet initialized
 	informerFactory := informers.NewSharedInformerFactory(handle.ClientSet(), 0)
 	ss := shim.NewShimSchedulerForPlugin(serviceContext.RMProxy, informerFactory, conf.GetSchedulerConf(), configMaps)
+	ss.GetContext().SetCoreClusterContext(serviceContext.Scheduler.GetClusterContext())
 	if err := ss.Run(); err != nil {
 		log.Log(log.ShimSchedulerPlugin).Fatal("Unable to start scheduler", zap.Error(err))
 	}
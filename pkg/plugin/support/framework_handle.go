@@ -23,13 +23,16 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/events"
 	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/features"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"k8s.io/kubernetes/pkg/scheduler/framework/parallelize"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/dynamicresources"
 	"k8s.io/kubernetes/pkg/scheduler/util/assumecache"
 
 	"github.com/apache/yunikorn-k8shim/pkg/log"
@@ -40,6 +43,8 @@ type frameworkHandle struct {
 	sharedInformerFactory informers.SharedInformerFactory
 	clientSet             kubernetes.Interface
 	parallelizer          parallelize.Parallelizer
+	resourceClaimCache    *assumecache.AssumeCache
+	draManager            framework.SharedDRAManager
 }
 
 func (p frameworkHandle) SnapshotSharedLister() framework.SharedLister {
@@ -59,8 +64,10 @@ func (p frameworkHandle) Parallelizer() parallelize.Parallelizer {
 }
 
 func (p frameworkHandle) ResourceClaimCache() *assumecache.AssumeCache {
-	log.Log(log.ShimFramework).Fatal("BUG: Should not be used by plugins")
-	return nil
+	if p.resourceClaimCache == nil {
+		log.Log(log.ShimFramework).Fatal("BUG: Should not be used by plugins if DRA is disabled")
+	}
+	return p.resourceClaimCache
 }
 
 // PodNominator stubs
@@ -151,18 +158,28 @@ func (p frameworkHandle) Activate(logger klog.Logger, pods map[string]*v1.Pod) {
 }
 
 func (p frameworkHandle) SharedDRAManager() framework.SharedDRAManager {
-	// currently only used by DRA
-	log.Log(log.ShimFramework).Fatal("BUG: Should not be used by plugins")
-	return nil
+	// only used by the DynamicResources plugin, which is a no-op unless the feature gate is enabled
+	if p.draManager == nil {
+		log.Log(log.ShimFramework).Fatal("BUG: Should not be used by plugins if DRA is disabled")
+	}
+	return p.draManager
 }
 
 var _ framework.Handle = frameworkHandle{}
 
 func NewFrameworkHandle(sharedLister framework.SharedLister, informerFactory informers.SharedInformerFactory, clientSet kubernetes.Interface) framework.Handle {
-	return &frameworkHandle{
+	handle := &frameworkHandle{
 		sharedLister:          sharedLister,
 		sharedInformerFactory: informerFactory,
 		clientSet:             clientSet,
 		parallelizer:          parallelize.NewParallelizer(parallelize.DefaultParallelism),
 	}
+	if informerFactory != nil && feature.DefaultFeatureGate.Enabled(features.DynamicResourceAllocation) {
+		// the claim informer is shared with the clients, which make sure it is started and synced
+		logger := klog.NewKlogr()
+		claimInformer := informerFactory.Resource().V1beta1().ResourceClaims().Informer()
+		handle.resourceClaimCache = assumecache.NewAssumeCache(logger, claimInformer, "ResourceClaim", "", nil)
+		handle.draManager = dynamicresources.NewDRAManager(klog.NewContext(context.Background(), logger), handle.resourceClaimCache, informerFactory)
+	}
+	return handle
 }
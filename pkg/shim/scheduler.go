@@ -47,6 +47,7 @@ type KubernetesShim struct {
 	apiFactory           client.APIProvider
 	context              *cache.Context
 	phManager            *cache.PlaceholderManager
+	webService           *webService
 	callback             api.ResourceManagerCallback
 	stopChan             chan struct{}
 	lock                 *locking.RWMutex
@@ -197,6 +198,10 @@ func (ss *KubernetesShim) Run() error {
 	// it needs to be started at first
 	dispatcher.Start()
 
+	// serve the health check and the event journal of the shim
+	ss.webService = newWebService(conf.GetSchedulerConf().WebServicePort)
+	ss.webService.start()
+
 	// run the placeholder manager
 	ss.phManager.Start()
 
@@ -227,15 +232,45 @@ func (ss *KubernetesShim) Stop() {
 	log.Log(log.ShimScheduler).Info("stopping scheduler")
 	select {
 	case ss.stopChan <- struct{}{}:
-		// stop the dispatcher
-		dispatcher.Stop()
+		ss.shutdown()
 		// stop the placeholder manager
 		ss.phManager.Stop()
+		ss.webService.stop()
 	default:
 		log.Log(log.ShimScheduler).Info("scheduler is already stopped")
 	}
 }
 
+// shutdown stops the intake of new events, from the API server, the core and the application timers, and handles the
+// queued events and in-flight binds, within the configured drain timeout, before stopping the dispatcher. Events left
+// unprocessed are reported.
+func (ss *KubernetesShim) shutdown() {
+	timeout := conf.GetSchedulerConf().ShutdownDrainTimeout
+	begin := time.Now()
+	deadline := begin.Add(timeout)
+	// ignore the updates from the core, no new allocations are bound while draining
+	ss.context.StopCoreCallbacks()
+	// no timers of the applications dispatch new events
+	ss.context.StopApplicationTimers()
+	// stop the informers, no new events are received from the API server
+	ss.apiFactory.Stop()
+	// handle the queued events, the binds started while draining are tracked by the context
+	dispatcher.Drain(timeout)
+	// the binds dispatch the events of the outcome, those are handled while shutting down the dispatcher
+	pendingBinds := ss.context.WaitForBinds(max(0, time.Until(deadline)))
+	report := dispatcher.Shutdown(max(0, time.Until(deadline)))
+
+	if !report.Drained || pendingBinds > 0 {
+		log.Log(log.ShimScheduler).Warn("scheduler stopped with unprocessed events",
+			zap.Duration("drainTimeout", timeout),
+			zap.Any("unprocessedEvents", report.Unprocessed),
+			zap.Int("pendingBinds", pendingBinds))
+		return
+	}
+	log.Log(log.ShimScheduler).Info("scheduler drained all events",
+		zap.Duration("elapsed", time.Since(begin)))
+}
+
 func (ss *KubernetesShim) checkOutstandingApps() {
 	if !ss.getOutstandingAppsFound() {
 		log.Log(log.ShimScheduler).Info("No outstanding apps found for a while", zap.Duration("timeout", outstandingAppLogTimeout))
@@ -67,6 +67,7 @@ func (fc *MockScheduler) init() {
 	events.SetRecorder(events.NewMockedRecorder())
 
 	context := cache.NewContext(mockedAPIProvider)
+	context.SetCoreClusterContext(serviceContext.Scheduler.GetClusterContext())
 	rmCallback := cache.NewAsyncRMCallback(context)
 	ss := newShimSchedulerInternal(context, mockedAPIProvider, rmCallback)
 
@@ -34,6 +34,7 @@ import (
 	"github.com/apache/yunikorn-k8shim/pkg/common"
 	"github.com/apache/yunikorn-k8shim/pkg/common/constants"
 	"github.com/apache/yunikorn-k8shim/pkg/common/test"
+	"github.com/apache/yunikorn-k8shim/pkg/conf"
 	"github.com/apache/yunikorn-scheduler-interface/lib/go/api"
 	siCommon "github.com/apache/yunikorn-scheduler-interface/lib/go/common"
 	"github.com/apache/yunikorn-scheduler-interface/lib/go/si"
@@ -111,6 +112,70 @@ func TestApplicationScheduling(t *testing.T) {
 	assert.Equal(t, 0, len(app.GetAllAllocations()), "allocations were not removed from the application")
 }
 
+// the placeholders of a task group are released by the shim after the timeout of the task group: the core does not
+// time out the placeholders itself and the pods of the released task group must still be allocated
+func TestReleasedTaskGroupScheduling(t *testing.T) {
+	cluster := MockScheduler{}
+	cluster.init()
+	// the placeholders are created and deleted by the shim
+	cluster.apiProvider.MockCreateFn(func(pod *v1.Pod) (*v1.Pod, error) {
+		pod.UID = types.UID(pod.Name)
+		go cluster.AddPod(pod)
+		return pod, nil
+	})
+	cluster.apiProvider.MockDeleteFn(func(pod *v1.Pod) error {
+		go cluster.DeletePod(pod)
+		return nil
+	})
+	assert.NilError(t, cluster.start(), "failed to start cluster")
+	defer cluster.stop()
+
+	// the gang does not fit in the cluster: the check against the queue maximum would fail it before reserving
+	err := conf.UpdateConfigMaps([]*v1.ConfigMap{nil, {Data: map[string]string{conf.CMSvcDisableGangQueueCheck: "true"}}}, true)
+	assert.NilError(t, err, "failed to update config")
+	defer func() {
+		assert.NilError(t, conf.UpdateConfigMaps([]*v1.ConfigMap{nil, nil}, true), "failed to reset config")
+	}()
+	err = cluster.updateConfig(`
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+`, nil)
+	assert.NilError(t, err, "update config failed")
+	err = cluster.addNode("test.host.01", map[string]string{}, 100000000, 10, 10)
+	assert.NilError(t, err, "add node failed")
+
+	// the placeholders of the workers never fit on the node, the real workers do
+	taskGroups := `[{"name": "driver", "minMember": 1, "minResource": {"cpu": "1m", "memory": "1000"}},
+		{"name": "workers", "minMember": 2, "minResource": {"cpu": "20m", "memory": "1000"}}]`
+	taskResource := common.NewResourceBuilder().
+		AddResource(siCommon.Memory, 1000).
+		AddResource(siCommon.CPU, 1).
+		Build()
+	driver := createTestPod("root.a", "app0001", "driver", taskResource)
+	driver.Annotations = map[string]string{
+		constants.AnnotationTaskGroupName:         "driver",
+		constants.AnnotationTaskGroups:            taskGroups,
+		constants.AnnotationSchedulingPolicyParam: "placeholderTimeoutInSeconds.workers=1 placeholderTimeoutRelease=Unsatisfied",
+	}
+	cluster.AddPod(driver)
+	for _, name := range []string{"worker-1", "worker-2"} {
+		worker := createTestPod("root.a", "app0001", name, taskResource)
+		worker.Annotations = map[string]string{constants.AnnotationTaskGroupName: "workers"}
+		cluster.AddPod(worker)
+	}
+
+	// the workers are released, the driver replaces its placeholder and the workers are scheduled without placeholders
+	cluster.waitAndAssertApplicationState(t, "app0001", cache.ApplicationStates().Running)
+	cluster.waitAndAssertTaskState(t, "app0001", "driver", cache.TaskStates().Bound)
+	cluster.waitAndAssertTaskState(t, "app0001", "worker-1", cache.TaskStates().Bound)
+	cluster.waitAndAssertTaskState(t, "app0001", "worker-2", cache.TaskStates().Bound)
+}
+
 func TestRejectApplications(t *testing.T) {
 	// init and register scheduler
 	cluster := MockScheduler{}
@@ -0,0 +1,138 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package shim
+
+import (
+	ctx "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/yunikorn-core/pkg/webservice/dao"
+	"github.com/apache/yunikorn-k8shim/pkg/dispatcher"
+	"github.com/apache/yunikorn-k8shim/pkg/log"
+)
+
+// the web service of the shim complements the REST service of the core, which cannot see the shim internals
+const (
+	healthCheckURL = "/ws/v1/shim/healthcheck"
+	journalURL     = "/ws/v1/shim/journal"
+)
+
+type webService struct {
+	server *http.Server
+}
+
+// newWebService creates the web service of the shim, nil if the port is 0
+func newWebService(port int) *webService {
+	if port == 0 {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthCheckURL, healthCheck)
+	mux.HandleFunc(journalURL, getJournal)
+	return &webService{
+		server: &http.Server{
+			Addr:              fmt.Sprintf(":%d", port),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func (ws *webService) start() {
+	if ws == nil {
+		return
+	}
+	go func() {
+		if err := ws.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Log(log.ShimScheduler).Error("failed to serve the shim web service", zap.Error(err))
+		}
+	}()
+	log.Log(log.ShimScheduler).Info("shim web service started",
+		zap.String("address", ws.server.Addr),
+		zap.Strings("listeningOn", []string{healthCheckURL, journalURL}))
+}
+
+func (ws *webService) stop() {
+	if ws == nil {
+		return
+	}
+	if err := ws.server.Shutdown(ctx.Background()); err != nil {
+		log.Log(log.ShimScheduler).Warn("failed to stop the shim web service", zap.Error(err))
+	}
+}
+
+// getHealthStatus runs the health checks of the shim, the result uses the same format as the core health check
+func getHealthStatus() dao.SchedulerHealthDAOInfo {
+	checks := []dao.HealthCheckInfo{
+		checkDispatcher(),
+	}
+	healthy := true
+	for _, check := range checks {
+		healthy = healthy && check.Succeeded
+	}
+	return dao.SchedulerHealthDAOInfo{
+		Healthy:      healthy,
+		HealthChecks: checks,
+	}
+}
+
+// checkDispatcher fails if the dispatcher is not running, or is degraded because it cannot keep up with the events
+func checkDispatcher() dao.HealthCheckInfo {
+	state := dispatcher.GetState()
+	return dao.HealthCheckInfo{
+		Name:        "Dispatcher",
+		Succeeded:   state.Running && !state.Degraded,
+		Description: "Check the dispatcher is running and keeps up with the scheduling events",
+		DiagnosisMessage: fmt.Sprintf("running: %t, degraded: %t, queued events: %d, async-dispatched events: %d (limit %d), events over the limit: %d",
+			state.Running, state.Degraded, state.QueueDepth, state.AsyncDispatchCount, state.AsyncDispatchLimit, state.OverLimitEvents),
+	}
+}
+
+// healthCheck writes the result of the health checks, an unhealthy shim is reported with status 503
+func healthCheck(w http.ResponseWriter, _ *http.Request) {
+	result := getHealthStatus()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if !result.Healthy {
+		log.Log(log.ShimScheduler).Warn("shim is not healthy", zap.Any("healthCheckInfo", result))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Log(log.ShimScheduler).Error("unable to write health check result", zap.Error(err))
+	}
+}
+
+// getJournal writes the events recorded in the dispatcher journal, the response can be loaded with
+// dispatcher.LoadJournal to replay the events
+func getJournal(w http.ResponseWriter, _ *http.Request) {
+	entries := dispatcher.GetJournal()
+	if entries == nil {
+		http.Error(w, "the event journal is disabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Log(log.ShimScheduler).Error("unable to write the event journal", zap.Error(err))
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package shim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/apache/yunikorn-core/pkg/webservice/dao"
+	"github.com/apache/yunikorn-k8shim/pkg/dispatcher"
+)
+
+func TestHealthCheck(t *testing.T) {
+	assert.Assert(t, newWebService(0) == nil, "web service should be disabled for port 0")
+
+	// dispatcher not running
+	result := getHealthCheckResult(t, http.StatusServiceUnavailable)
+	assert.Assert(t, !result.Healthy, "shim should not be healthy")
+	assert.Equal(t, len(result.HealthChecks), 1)
+	assert.Equal(t, result.HealthChecks[0].Name, "Dispatcher")
+	assert.Assert(t, !result.HealthChecks[0].Succeeded, "dispatcher check should fail")
+
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	result = getHealthCheckResult(t, http.StatusOK)
+	assert.Assert(t, result.Healthy, "shim should be healthy")
+	assert.Assert(t, result.HealthChecks[0].Succeeded, "dispatcher check should succeed")
+}
+
+func getHealthCheckResult(t *testing.T, status int) dao.SchedulerHealthDAOInfo {
+	recorder := httptest.NewRecorder()
+	healthCheck(recorder, httptest.NewRequest(http.MethodGet, healthCheckURL, nil))
+	assert.Equal(t, recorder.Code, status)
+	var result dao.SchedulerHealthDAOInfo
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	return result
+}
+
+func TestGetJournal(t *testing.T) {
+	// the journal is disabled by default
+	recorder := httptest.NewRecorder()
+	getJournal(recorder, httptest.NewRequest(http.MethodGet, journalURL, nil))
+	assert.Equal(t, recorder.Code, http.StatusNotFound)
+	assert.Assert(t, strings.Contains(recorder.Body.String(), "the event journal is disabled"))
+}